@@ -448,10 +448,7 @@ func TriggerCommand(ctx context.Context, rawCommand string, id RequestorIdentity
 	le.Debug("Found matching command+bundle")
 	addSpanAttributes(ctx, sp, cmdEntry)
 
-	env := rules.EvaluationEnvironment{
-		"option": cmdInput.OptionsValues(),
-		"arg":    cmdInput.Parameters,
-	}
+	env := rules.NewEvaluationEnvironment(cmdInput)
 
 	perms, err := da.UserPermissionList(ctx, id.GortUser.Username)
 	if err != nil {
@@ -39,26 +39,53 @@ var (
 // EvaluateRules returns true if the provided permissions meet the requirements
 // defined by the given rules and EvaluationEnvironment. It returns an error if
 // there isn't at least one rule in the Rule slice.
+//
+// Only the matching rules with the highest Priority are applied, so a rule
+// can override any rule with a lower priority. Among those, a "deny" rule
+// always wins: if any deny rule's conditions match, the command is denied,
+// regardless of the order of the rules and of any "allow" rules that also
+// match. Otherwise the permissions must satisfy every one of them.
 func EvaluateRules(perms []string, r []rules.Rule, env rules.EvaluationEnvironment) (bool, error) {
 	if commandsRequireAtLeastOneRule && len(r) == 0 {
 		return false, ErrNoRulesDefined
 	}
 
-	allowed := false
-
-	// Loop over the rules and evaluate them one-by-one.
+	// If the rule's conditions don't evaluate to true, ignore it.
+	matching := []rules.Rule{}
 	for _, r := range r {
-		// If the rule's conditions don't evaluate to true, ignore it.
-		if !r.Matches(env) {
-			continue
+		if r.Matches(env) {
+			matching = append(matching, r)
 		}
+	}
 
-		if allowed = r.Allowed(perms); !allowed {
+	if len(matching) == 0 {
+		return false, nil
+	}
+
+	// Discard any rules that are overridden by a higher-priority rule.
+	rules.SortByPriority(matching)
+	for i, r := range matching {
+		if r.Priority < matching[0].Priority {
+			matching = matching[:i]
+			break
+		}
+	}
+
+	// Deny rules take precedence over everything else.
+	for _, r := range matching {
+		if r.Deny {
+			return false, nil
+		}
+	}
+
+	// Loop over the rules and evaluate them one-by-one.
+	for _, r := range matching {
+		if !r.Allowed(perms) {
 			return false, nil
 		}
 	}
 
-	return allowed, nil
+	return true, nil
 }
 
 // EvaluateCommandEntry is equivalent to EvaluateRules, except that it accepts
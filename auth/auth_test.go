@@ -90,6 +90,91 @@ func TestEvaluateCommandEntry2(t *testing.T) {
 	assert.False(t, result)
 }
 
+func TestEvaluateRulesDenyWins(t *testing.T) {
+	var rr []rules.Rule
+	for _, s := range []string{
+		`test:foo with option["force"] == true deny`,
+		`test:foo allow`,
+		`test:foo with option["force"] == true allow`,
+	} {
+		r, err := rules.TokenizeAndParse(s)
+		if !assert.NoError(t, err, s) {
+			return
+		}
+		rr = append(rr, r)
+	}
+
+	envForce := rules.EvaluationEnvironment{"option": map[string]types.Value{"force": types.BoolValue{V: true}}}
+	envNoForce := rules.EvaluationEnvironment{"option": map[string]types.Value{}}
+
+	// The allow rules and the deny rule all match: deny wins.
+	result, err := EvaluateRules([]string{"test:foo"}, rr, envForce)
+	assert.NoError(t, err)
+	assert.False(t, result)
+
+	// Deny wins regardless of the order of the rules.
+	reversed := []rules.Rule{rr[2], rr[1], rr[0]}
+	result, err = EvaluateRules([]string{"test:foo"}, reversed, envForce)
+	assert.NoError(t, err)
+	assert.False(t, result)
+
+	// The deny rule doesn't match, so the allow rule applies.
+	result, err = EvaluateRules([]string{}, rr, envNoForce)
+	assert.NoError(t, err)
+	assert.True(t, result)
+}
+
+func TestEvaluateRulesPriority(t *testing.T) {
+	rule := func(s string, priority int) rules.Rule {
+		r, err := rules.TokenizeAndParse(s)
+		if !assert.NoError(t, err, s) {
+			t.FailNow()
+		}
+		r.Priority = priority
+		return r
+	}
+
+	envForce := rules.EvaluationEnvironment{"option": map[string]types.Value{"force": types.BoolValue{V: true}}}
+	envNoForce := rules.EvaluationEnvironment{"option": map[string]types.Value{}}
+
+	// A high-priority deny overrides a low-priority allow.
+	rr := []rules.Rule{
+		rule(`test:foo allow`, 0),
+		rule(`test:foo with option["force"] == true deny`, 10),
+	}
+	result, err := EvaluateRules([]string{}, rr, envForce)
+	assert.NoError(t, err)
+	assert.False(t, result)
+
+	// The deny rule doesn't match, so the allow rule applies.
+	result, err = EvaluateRules([]string{}, rr, envNoForce)
+	assert.NoError(t, err)
+	assert.True(t, result)
+
+	// A high-priority allow overrides a low-priority deny.
+	rr = []rules.Rule{
+		rule(`test:foo with option["force"] == true deny`, -1),
+		rule(`test:foo must have test:admin`, 0),
+	}
+	result, err = EvaluateRules([]string{"test:admin"}, rr, envForce)
+	assert.NoError(t, err)
+	assert.True(t, result)
+
+	result, err = EvaluateRules([]string{}, rr, envForce)
+	assert.NoError(t, err)
+	assert.False(t, result)
+
+	// Rules with the same priority are combined as usual.
+	rr = []rules.Rule{
+		rule(`test:foo allow`, 5),
+		rule(`test:foo must have test:admin`, 5),
+		rule(`test:foo allow`, 1),
+	}
+	result, err = EvaluateRules([]string{}, rr, envForce)
+	assert.NoError(t, err)
+	assert.False(t, result)
+}
+
 func parse(s string) (command.Command, rules.EvaluationEnvironment, error) {
 	cmd, err := command.TokenizeAndParse(s)
 	if err != nil {
@@ -34,23 +34,3 @@ func groupNames(groups []rest.Group) []string {
 
 	return names
 }
-
-func userNames(users []rest.User) []string {
-	names := make([]string, 0)
-
-	for _, u := range users {
-		names = append(names, u.Username)
-	}
-
-	return names
-}
-
-func roleNames(roles []rest.Role) []string {
-	names := make([]string, 0)
-
-	for _, r := range roles {
-		names = append(names, r.Name)
-	}
-
-	return names
-}
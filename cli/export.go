@@ -0,0 +1,89 @@
+/*
+ * Copyright 2021 The Gort Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cli
+
+import (
+	"encoding/json"
+	"os"
+
+	"github.com/getgort/gort/client"
+	"github.com/spf13/cobra"
+)
+
+const (
+	exportUse   = "export"
+	exportShort = "Export all users, groups, and roles as JSON"
+	exportLong  = `Export all users, groups, and roles, and the relationships between them, as
+JSON. The output can be restored using "gort import".
+
+Users' passwords (or their hashes) are excluded unless --include-passwords is
+set, and their access tokens unless --include-tokens is set. Users imported
+from an export without passwords keep their existing passwords, if they have
+any. An export that includes either should be stored securely.
+`
+	exportUsage = `Usage:
+  gort export [flags]
+
+Flags:
+  -h, --help                Show this message and exit
+  -p, --include-passwords   Include users' passwords (or their hashes) in the export
+  -t, --include-tokens      Include users' access tokens in the export
+
+Global Flags:
+  -P, --profile string   The Gort profile within the config file to use
+`
+)
+
+var (
+	flagExportIncludePasswords bool
+	flagExportIncludeTokens    bool
+)
+
+// GetExportCmd is a command
+func GetExportCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   exportUse,
+		Short: exportShort,
+		Long:  exportLong,
+		RunE:  exportCmd,
+		Args:  cobra.NoArgs,
+	}
+
+	cmd.Flags().BoolVarP(&flagExportIncludePasswords, "include-passwords", "p", false, "Include users' passwords (or their hashes) in the export")
+	cmd.Flags().BoolVarP(&flagExportIncludeTokens, "include-tokens", "t", false, "Include users' access tokens in the export")
+
+	cmd.SetUsageTemplate(exportUsage)
+
+	return cmd
+}
+
+func exportCmd(cmd *cobra.Command, args []string) error {
+	c, err := client.Connect(FlagGortProfile)
+	if err != nil {
+		return err
+	}
+
+	snapshot, err := c.Export(flagExportIncludePasswords, flagExportIncludeTokens)
+	if err != nil {
+		return err
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+
+	return encoder.Encode(snapshot)
+}
@@ -33,10 +33,10 @@ import (
 
 const (
 	groupAddUse   = "add"
-	groupAddShort = "Add a user to an existing group"
-	groupAddLong  = "Add a user to an existing group."
+	groupAddShort = "Add one or more users to an existing group"
+	groupAddLong  = "Add one or more users to an existing group."
 	groupAddUsage = `Usage:
-  gort group add [flags] group_name user_name
+  gort group add [flags] group_name user_name...
 
 Flags:
   -h, --help   Show this message and exit
@@ -53,7 +53,7 @@ func GetGroupAddCmd() *cobra.Command {
 		Short: groupAddShort,
 		Long:  groupAddLong,
 		RunE:  groupAddCmd,
-		Args:  cobra.ExactArgs(2),
+		Args:  cobra.MinimumNArgs(2),
 	}
 
 	cmd.SetUsageTemplate(groupAddUsage)
@@ -63,19 +63,23 @@ func GetGroupAddCmd() *cobra.Command {
 
 func groupAddCmd(cmd *cobra.Command, args []string) error {
 	groupname := args[0]
-	username := args[1]
+	usernames := args[1:]
 
 	gortClient, err := client.Connect(FlagGortProfile)
 	if err != nil {
 		return err
 	}
 
-	err = gortClient.GroupMemberAdd(groupname, username)
-	if err != nil {
-		return err
-	}
+	errs := multiError{}
 
-	fmt.Printf("User added to %s: %s\n", groupname, username)
+	for _, username := range usernames {
+		err = gortClient.GroupMemberAdd(groupname, username)
+		errs.Add(username, err)
+
+		if err == nil {
+			fmt.Printf("User added to %s: %s\n", groupname, username)
+		}
+	}
 
-	return nil
+	return errs.ErrorOrNil()
 }
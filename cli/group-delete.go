@@ -33,10 +33,10 @@ import (
 
 const (
 	groupDeleteUse   = "delete"
-	groupDeleteShort = "Delete an existing group"
-	groupDeleteLong  = "Delete an existing group."
+	groupDeleteShort = "Delete one or more existing groups"
+	groupDeleteLong  = "Delete one or more existing groups."
 	groupDeleteUsage = `Usage:
-  gort group delete [flags] group_name
+  gort group delete [flags] group_name...
 
 Flags:
   -h, --help   Show this message and exit
@@ -53,7 +53,7 @@ func GetGroupDeleteCmd() *cobra.Command {
 		Short: groupDeleteShort,
 		Long:  groupDeleteLong,
 		RunE:  groupDeleteCmd,
-		Args:  cobra.ExactArgs(1),
+		Args:  cobra.MinimumNArgs(1),
 	}
 
 	cmd.SetUsageTemplate(groupDeleteUsage)
@@ -67,8 +67,17 @@ func groupDeleteCmd(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
-	groupname := args[0]
+	errs := multiError{}
 
+	for _, groupname := range args {
+		err := groupDelete(gortClient, groupname)
+		errs.Add(groupname, err)
+	}
+
+	return errs.ErrorOrNil()
+}
+
+func groupDelete(gortClient *client.GortClient, groupname string) error {
 	group, err := gortClient.GroupGet(groupname)
 	if err != nil {
 		return err
@@ -78,6 +87,7 @@ func groupDeleteCmd(cmd *cobra.Command, args []string) error {
 
 	err = gortClient.GroupDelete(group.Name)
 	if err != nil {
+		fmt.Println("Failed")
 		return err
 	}
 
@@ -34,10 +34,10 @@ import (
 
 const (
 	groupGrantUse   = "grant"
-	groupGrantShort = "Grant a role to an existing group"
-	groupGrantLong  = "Grant a role to an existing group."
+	groupGrantShort = "Grant one or more roles to an existing group"
+	groupGrantLong  = "Grant one or more roles to an existing group."
 	groupGrantUsage = `Usage:
-  gort group grant [flags] group_name role_name
+  gort group grant [flags] group_name role_name...
 
 Flags:
   -h, --help   Show this message and exit
@@ -54,7 +54,7 @@ func GetGroupGrantCmd() *cobra.Command {
 		Short: groupGrantShort,
 		Long:  groupGrantLong,
 		RunE:  groupGrantCmd,
-		Args:  cobra.ExactArgs(2),
+		Args:  cobra.MinimumNArgs(2),
 	}
 
 	cmd.SetUsageTemplate(groupGrantUsage)
@@ -64,19 +64,23 @@ func GetGroupGrantCmd() *cobra.Command {
 
 func groupGrantCmd(cmd *cobra.Command, args []string) error {
 	groupname := args[0]
-	rolename := args[1]
+	rolenames := args[1:]
 
 	gortClient, err := client.Connect(FlagGortProfile)
 	if err != nil {
 		return err
 	}
 
-	err = gortClient.GroupRoleAdd(groupname, rolename)
-	if err != nil {
-		return err
-	}
+	errs := multiError{}
 
-	fmt.Printf("role added to %s: %s\n", groupname, rolename)
+	for _, rolename := range rolenames {
+		err = gortClient.GroupRoleAdd(groupname, rolename)
+		errs.Add(rolename, err)
+
+		if err == nil {
+			fmt.Printf("role added to %s: %s\n", groupname, rolename)
+		}
+	}
 
-	return nil
+	return errs.ErrorOrNil()
 }
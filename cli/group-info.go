@@ -17,12 +17,16 @@
 package cli
 
 import (
+	"encoding/json"
 	"fmt"
-	"strings"
+	"net/http"
+	"os"
+	"sort"
 
 	"github.com/spf13/cobra"
 
 	"github.com/getgort/gort/client"
+	"github.com/getgort/gort/data/rest"
 )
 
 // $ cogctl group info --help
@@ -36,18 +40,24 @@ import (
 const (
 	groupInfoUse   = "info"
 	groupInfoShort = "Show info on a specific group"
-	groupInfoLong  = "Show info on a specific group."
+	groupInfoLong  = `Show info on a specific group: its members, and the roles granted to it
+along with their permissions.`
 	groupInfoUsage = `Usage:
   gort group info [flags] group_name
 
 Flags:
-  -h, --help   Show this message and exit
+  -h, --help            Show this message and exit
+  -o, --output string   Output format: "text" or "json" (default "text")
 
 Global Flags:
   -P, --profile string   The Gort profile within the config file to use
 `
 )
 
+var (
+	flagGroupInfoOutput string
+)
+
 // GetGroupInfoCmd is a command
 func GetGroupInfoCmd() *cobra.Command {
 	cmd := &cobra.Command{
@@ -58,6 +68,8 @@ func GetGroupInfoCmd() *cobra.Command {
 		Args:  cobra.ExactArgs(1),
 	}
 
+	cmd.Flags().StringVarP(&flagGroupInfoOutput, "output", "o", "text", "Output format: \"text\" or \"json\"")
+
 	cmd.SetUsageTemplate(groupInfoUsage)
 
 	return cmd
@@ -66,36 +78,73 @@ func GetGroupInfoCmd() *cobra.Command {
 func groupInfoCmd(cmd *cobra.Command, args []string) error {
 	groupname := args[0]
 
+	if flagGroupInfoOutput != "text" && flagGroupInfoOutput != "json" {
+		return fmt.Errorf("unsupported output format: %q", flagGroupInfoOutput)
+	}
+
 	gortClient, err := client.Connect(FlagGortProfile)
 	if err != nil {
 		return err
 	}
 
-	//
-	// TODO Maybe multiplex the following queries with gofuncs?
-	//
-
-	users, err := gortClient.GroupMemberList(groupname)
+	group, err := gortClient.GroupGet(groupname)
 	if err != nil {
+		if ce, ok := err.(client.Error); ok && ce.Status() == http.StatusNotFound {
+			return fmt.Errorf("no such group: %s", groupname)
+		}
 		return err
 	}
 
-	roles, err := gortClient.GroupRoleList(groupname)
-	if err != nil {
-		return err
+	// Older servers don't include the group's roles.
+	if group.Roles == nil {
+		if group.Roles, err = gortClient.GroupRoleList(groupname); err != nil {
+			return err
+		}
 	}
 
-	const format = `Name   %s
-Users  %s
-Roles  %s
-`
+	if flagGroupInfoOutput == "json" {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
 
-	fmt.Printf(
-		format,
-		groupname,
-		strings.Join(userNames(users), ", "),
-		strings.Join(roleNames(roles), ", "),
-	)
+		return encoder.Encode(group)
+	}
+
+	printGroupInfo(group)
 
 	return nil
 }
+
+// printGroupInfo prints a group's name, followed by a table of its members
+// and a table of its roles, each with one permission per line.
+func printGroupInfo(group rest.Group) {
+	const userFormat = "%-20s%-30s%s\n"
+	const roleFormat = "%-20s%s\n"
+
+	fmt.Printf("Name  %s\n\n", group.Name)
+
+	fmt.Printf(userFormat, "USERNAME", "FULL NAME", "EMAIL ADDRESS")
+	for _, u := range group.Users {
+		fmt.Printf(userFormat, u.Username, u.FullName, u.Email)
+	}
+
+	fmt.Println()
+
+	fmt.Printf(roleFormat, "ROLE", "PERMISSIONS")
+	for _, r := range group.Roles {
+		perms := r.Permissions.Strings()
+		sort.Strings(perms)
+
+		if len(perms) == 0 {
+			fmt.Printf(roleFormat, r.Name, "")
+			continue
+		}
+
+		for i, p := range perms {
+			name := r.Name
+			if i > 0 {
+				name = ""
+			}
+			fmt.Printf(roleFormat, name, p)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+/*
+ * Copyright 2021 The Gort Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/getgort/gort/client"
+	"github.com/getgort/gort/data/rest"
+	"github.com/spf13/cobra"
+)
+
+const (
+	importUse   = "import"
+	importShort = "Import users, groups, and roles from JSON"
+	importLong  = `Import users, groups, and roles, and the relationships between them, from
+JSON produced by "gort export". Use "-" to read from standard input.
+
+By default the import is merged into the existing data: missing users,
+groups, and roles are created, and existing ones gain any memberships and
+permissions in the import. If --replace is set, anything that isn't in the
+import is deleted; the import must then include the admin user, group, and
+role, which can't be deleted.
+
+The server accepts imports of up to 64MiB, or up to its maximum request body
+size if that's larger.
+`
+	importUsage = `Usage:
+  gort import [flags] file
+
+Flags:
+  -h, --help      Show this message and exit
+  -r, --replace   Replace all existing users, groups, and roles rather than merging
+
+Global Flags:
+  -P, --profile string   The Gort profile within the config file to use
+`
+)
+
+var (
+	flagImportReplace bool
+)
+
+// GetImportCmd is a command
+func GetImportCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   importUse,
+		Short: importShort,
+		Long:  importLong,
+		RunE:  importCmd,
+		Args:  cobra.ExactArgs(1),
+	}
+
+	cmd.Flags().BoolVarP(&flagImportReplace, "replace", "r", false, "Replace all existing users, groups, and roles rather than merging")
+
+	cmd.SetUsageTemplate(importUsage)
+
+	return cmd
+}
+
+func importCmd(cmd *cobra.Command, args []string) error {
+	var r io.Reader = os.Stdin
+
+	if args[0] != "-" {
+		f, err := os.Open(args[0])
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r = f
+	}
+
+	var snapshot rest.Snapshot
+	if err := json.NewDecoder(r).Decode(&snapshot); err != nil {
+		return fmt.Errorf("invalid import file: %w", err)
+	}
+
+	c, err := client.Connect(FlagGortProfile)
+	if err != nil {
+		return err
+	}
+
+	if err := c.Import(snapshot, flagImportReplace); err != nil {
+		return err
+	}
+
+	fmt.Printf("Imported %d users, %d groups, and %d roles.\n",
+		len(snapshot.Users), len(snapshot.Groups), len(snapshot.Roles))
+
+	return nil
+}
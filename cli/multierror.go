@@ -0,0 +1,77 @@
+/*
+ * Copyright 2021 The Gort Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cli
+
+import (
+	"fmt"
+	"strings"
+)
+
+// multiError collects the outcomes of a bulk operation, one per item, so
+// that a failure for one item doesn't prevent the others from being
+// attempted. Its zero value is ready to use.
+type multiError struct {
+	total    int
+	failures []itemError
+
+	// alwaysLabel causes the failure of a lone item to be reported with its
+	// label, as it would be alongside other items, rather than by itself.
+	alwaysLabel bool
+}
+
+type itemError struct {
+	item string
+	err  error
+}
+
+// Add records the outcome of the operation on item. A nil err counts as a
+// success.
+func (m *multiError) Add(item string, err error) {
+	m.total++
+
+	if err != nil {
+		m.failures = append(m.failures, itemError{item, err})
+	}
+}
+
+// ErrorOrNil returns m if any item failed, or nil otherwise. Returning it
+// from a RunE function causes the command to exit with a non-zero status.
+func (m *multiError) ErrorOrNil() error {
+	if len(m.failures) == 0 {
+		return nil
+	}
+
+	return m
+}
+
+// Error returns a summary of the failures, with one line per failed item. If
+// the only item failed, its error is returned alone unless alwaysLabel is
+// set.
+func (m *multiError) Error() string {
+	if len(m.failures) == 1 && m.total == 1 && !m.alwaysLabel {
+		return m.failures[0].err.Error()
+	}
+
+	b := strings.Builder{}
+	fmt.Fprintf(&b, "%d of %d failed:", len(m.failures), m.total)
+
+	for _, f := range m.failures {
+		fmt.Fprintf(&b, "\n  %s: %v", f.item, f.err)
+	}
+
+	return b.String()
+}
@@ -18,6 +18,7 @@ package cli
 
 import (
 	"fmt"
+	"sort"
 	"strings"
 
 	"github.com/getgort/gort/client"
@@ -74,8 +75,25 @@ Groups       %s
 
 	fmt.Printf(format,
 		role.Name,
-		strings.Join(role.Permissions.Strings(), ", "),
+		rolePermissionsByBundle(role.PermissionsByBundle()),
 		strings.Join(groupNames(role.Groups), ", "))
 
 	return nil
 }
+
+// rolePermissionsByBundle renders one "bundle: perm1, perm2" line per bundle,
+// sorted by bundle name and aligned with the Permissions column.
+func rolePermissionsByBundle(bundles map[string][]string) string {
+	var names []string
+	for b := range bundles {
+		names = append(names, b)
+	}
+	sort.Strings(names)
+
+	var lines []string
+	for _, b := range names {
+		lines = append(lines, fmt.Sprintf("%s: %s", b, strings.Join(bundles[b], ", ")))
+	}
+
+	return strings.Join(lines, "\n             ")
+}
@@ -64,11 +64,16 @@ func roleRevokeCmd(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
-	err = gortClient.RolePermissionRevoke(rolename, bundlename, permissionname)
+	count, err := gortClient.RolePermissionRevoke(rolename, bundlename, permissionname)
 	if err != nil {
 		return err
 	}
 
+	if count == 0 {
+		fmt.Printf("Nothing to revoke: %s doesn't have permission %s:%s\n", rolename, bundlename, permissionname)
+		return nil
+	}
+
 	fmt.Printf("Permission Revoked from %s: %s\n", rolename, permissionname)
 
 	return nil
@@ -0,0 +1,94 @@
+/*
+ * Copyright 2021 The Gort Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cli
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/getgort/gort/rules"
+	"github.com/spf13/cobra"
+)
+
+const (
+	ruleValidateUse   = "validate"
+	ruleValidateShort = "Validate a file of rules"
+	ruleValidateLong  = `Validate a file of rules, one per line, without connecting to a Gort
+server. Blank lines and lines beginning with "#" are skipped. The line
+number and error of each invalid rule are reported, and the command exits
+with a non-zero status if any rule is invalid.`
+	ruleValidateUsage = `Usage:
+  gort rule validate [flags] file_name
+
+Flags:
+  -h, --help   Show this message and exit
+`
+)
+
+// GetRuleValidateCmd is a command
+func GetRuleValidateCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   ruleValidateUse,
+		Short: ruleValidateShort,
+		Long:  ruleValidateLong,
+		RunE:  ruleValidateCmd,
+		Args:  cobra.ExactArgs(1),
+	}
+
+	cmd.SetUsageTemplate(ruleValidateUsage)
+
+	return cmd
+}
+
+func ruleValidateCmd(cmd *cobra.Command, args []string) error {
+	filename := args[0]
+
+	f, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	// Every failure is reported with its line number, even if the file
+	// only contains one rule.
+	errs := multiError{alwaysLabel: true}
+	scanner := bufio.NewScanner(f)
+
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		_, err := rules.TokenizeAndParse(line)
+		errs.Add(fmt.Sprintf("line %d", n), err)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	if err := errs.ErrorOrNil(); err != nil {
+		return err
+	}
+
+	fmt.Printf("%s: OK (%d rules checked)\n", filename, errs.total)
+
+	return nil
+}
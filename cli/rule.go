@@ -0,0 +1,51 @@
+/*
+ * Copyright 2021 The Gort Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cli
+
+import (
+	"github.com/spf13/cobra"
+)
+
+// # gort rule --help
+// Usage: gort rule [OPTIONS] COMMAND [ARGS]...
+//
+//   Work with command rules.
+//
+// Options:
+//   --help  Show this message and exit.
+//
+// Commands:
+//   validate  Validate a file of rules.
+
+const (
+	ruleUse   = "rule"
+	ruleShort = "Perform operations on command rules"
+	ruleLong  = "Allows you to work with command rules."
+)
+
+// GetRuleCmd rule
+func GetRuleCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   ruleUse,
+		Short: ruleShort,
+		Long:  ruleLong,
+	}
+
+	cmd.AddCommand(GetRuleValidateCmd())
+
+	return cmd
+}
@@ -0,0 +1,125 @@
+/*
+ * Copyright 2021 The Gort Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cli
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/getgort/gort/client"
+	"github.com/getgort/gort/data/rest"
+	"github.com/spf13/cobra"
+)
+
+const (
+	tokenListUse   = "list"
+	tokenListShort = "List authentication tokens"
+	tokenListLong  = `List authentication tokens.
+
+By default all tokens are listed, including expired tokens that haven't yet
+been removed. Token values are never shown.`
+	tokenListUsage = `Usage:
+  gort token list [flags]
+
+Flags:
+  -a, --active         List only currently valid tokens
+  -h, --help           Show this message and exit
+      --since string   List only tokens valid at or after this time (RFC 3339)
+      --until string   List only tokens valid at or before this time (RFC 3339)
+
+Global Flags:
+  -P, --profile string   The Gort profile within the config file to use
+`
+)
+
+var (
+	flagTokenListActive bool
+	flagTokenListSince  string
+	flagTokenListUntil  string
+)
+
+// GetTokenListCmd is a command
+func GetTokenListCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   tokenListUse,
+		Short: tokenListShort,
+		Long:  tokenListLong,
+		RunE:  tokenListCmd,
+	}
+
+	cmd.Flags().BoolVarP(&flagTokenListActive, "active", "a", false, "List only currently valid tokens")
+	cmd.Flags().StringVar(&flagTokenListSince, "since", "", "List only tokens valid at or after this time (RFC 3339)")
+	cmd.Flags().StringVar(&flagTokenListUntil, "until", "", "List only tokens valid at or before this time (RFC 3339)")
+
+	cmd.SetUsageTemplate(tokenListUsage)
+
+	return cmd
+}
+
+func tokenListCmd(cmd *cobra.Command, args []string) error {
+	const format = "%-20s%-30s%-30s%s\n"
+
+	if flagTokenListActive && (flagTokenListSince != "" || flagTokenListUntil != "") {
+		return fmt.Errorf("--active can't be used with --since or --until")
+	}
+
+	var since, until time.Time
+	var err error
+
+	if flagTokenListSince != "" {
+		if since, err = time.Parse(time.RFC3339, flagTokenListSince); err != nil {
+			return fmt.Errorf("invalid --since time: %w", err)
+		}
+	}
+
+	if flagTokenListUntil != "" {
+		if until, err = time.Parse(time.RFC3339, flagTokenListUntil); err != nil {
+			return fmt.Errorf("invalid --until time: %w", err)
+		}
+	}
+
+	gortClient, err := client.Connect(FlagGortProfile)
+	if err != nil {
+		return err
+	}
+
+	var tokens []rest.Token
+
+	if flagTokenListActive {
+		tokens, err = gortClient.TokenListActive()
+	} else {
+		tokens, err = gortClient.TokenListValidBetween(since, until)
+	}
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf(format, "USERNAME", "VALID FROM", "VALID UNTIL", "STATUS")
+	for _, t := range tokens {
+		status := "active"
+		if t.IsExpired() {
+			status = "expired"
+		}
+
+		fmt.Printf(format, t.User,
+			t.ValidFrom.Local().Format(time.RFC3339),
+			t.ValidUntil.Local().Format(time.RFC3339),
+			status)
+	}
+
+	return nil
+}
@@ -0,0 +1,51 @@
+/*
+ * Copyright 2021 The Gort Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cli
+
+import (
+	"github.com/spf13/cobra"
+)
+
+// # gort token --help
+// Usage: gort token [OPTIONS] COMMAND [ARGS]...
+//
+//   Inspect Gort authentication tokens.
+//
+// Options:
+//   --help  Show this message and exit.
+//
+// Commands:
+//   list    List authentication tokens.
+
+const (
+	tokenUse   = "token"
+	tokenShort = "Perform operations on authentication tokens"
+	tokenLong  = "Allows you to inspect authentication tokens."
+)
+
+// GetTokenCmd token
+func GetTokenCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   tokenUse,
+		Short: tokenShort,
+		Long:  tokenLong,
+	}
+
+	cmd.AddCommand(GetTokenListCmd())
+
+	return cmd
+}
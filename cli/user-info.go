@@ -76,13 +76,24 @@ func userInfoCmd(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
-	const format = `Name       %s
-Full Name  %s
-Email      %s
-Groups     %s
+	perms, err := gortClient.UserPermissionList(username)
+	if err != nil {
+		return err
+	}
+
+	const format = `Name         %s
+Full Name    %s
+Email        %s
+Groups       %s
+Permissions  %s
 `
 
-	fmt.Printf(format, user.Username, user.FullName, user.Email, strings.Join(groupNames(groups), ", "))
+	fmt.Printf(format,
+		user.Username,
+		user.FullName,
+		user.Email,
+		strings.Join(groupNames(groups), ", "),
+		strings.Join(perms.Strings(), ", "))
 
 	return nil
 }
@@ -21,17 +21,22 @@ import (
 
 	"github.com/spf13/cobra"
 
+	"github.com/getgort/gort/client"
 	"github.com/getgort/gort/version"
 )
 
 const (
 	versionUse   = "version"
 	versionShort = "Display version and build information"
-	versionLong  = "Displays version and build information."
+	versionLong  = `Displays version and build information.
+
+If --server is set, the version of the Gort server is also displayed, and a
+warning is printed if it differs from the version of this client.`
 )
 
 var (
-	flagVersionShort bool
+	flagVersionServer bool
+	flagVersionShort  bool
 )
 
 // GetVersionCmd version
@@ -43,18 +48,47 @@ func GetVersionCmd() *cobra.Command {
 		RunE:  versionCmd,
 	}
 
+	cmd.Flags().BoolVarP(&flagVersionServer, "server", "", false, "Also print the version of the Gort server")
 	cmd.Flags().BoolVarP(&flagVersionShort, "short", "s", false, "Print only the version number")
 
 	return cmd
 }
 
 func versionCmd(cmd *cobra.Command, args []string) error {
-	if flagVersionShort {
-		fmt.Println(version.Version)
+	if !flagVersionServer {
+		if flagVersionShort {
+			fmt.Println(version.Version)
+			return nil
+		}
+
+		fmt.Printf("Gort ChatOps Engine v%s\n", version.Version)
 		return nil
 	}
 
-	fmt.Printf("Gort ChatOps Engine v%s\n", version.Version)
+	gortClient, err := client.Connect(FlagGortProfile)
+	if err != nil {
+		return err
+	}
+
+	info, err := gortClient.Version()
+	if err != nil {
+		return err
+	}
+
+	if flagVersionShort {
+		fmt.Printf("Client: %s\n", version.Version)
+		fmt.Printf("Server: %s\n", info.Version)
+	} else {
+		fmt.Printf("Client: Gort ChatOps Engine v%s (commit %s, built %s)\n",
+			version.Version, version.GitCommit, version.BuildTime)
+		fmt.Printf("Server: Gort ChatOps Engine v%s (commit %s, built %s)\n",
+			info.Version, info.GitCommit, info.BuildTime)
+	}
+
+	if info.Version != version.Version {
+		fmt.Printf("WARNING: client version %s doesn't match server version %s\n",
+			version.Version, info.Version)
+	}
 
 	return nil
 }
@@ -17,10 +17,9 @@
 package client
 
 import (
-	"bytes"
 	"encoding/json"
 	"fmt"
-	"io/ioutil"
+	"io"
 	"net/http"
 	"os"
 	"time"
@@ -51,23 +50,19 @@ func (c *GortClient) Authenticate() (rest.Token, error) {
 		return rest.Token{}, gerrs.Wrap(gerrs.ErrMarshal, err)
 	}
 
-	resp, err := http.Post(endpointURL, "application/json", bytes.NewBuffer(postBytes))
+	resp, err := c.post(endpointURL, postBytes)
 	if err != nil {
-		return rest.Token{}, gerrs.Wrap(ErrConnectionFailed, err)
+		return rest.Token{}, err
 	}
+	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		bytes, _ := ioutil.ReadAll(resp.Body)
+		bytes, _ := io.ReadAll(io.LimitReader(resp.Body, DefaultMaxResponseSize))
 		return rest.Token{}, fmt.Errorf(string(bytes))
 	}
 
-	body, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return rest.Token{}, gerrs.Wrap(ErrResponseReadFailure, err)
-	}
-
 	token := rest.Token{}
-	err = json.Unmarshal(body, &token)
+	err = c.decodeResponse(resp, &token)
 	if err != nil {
 		return rest.Token{}, gerrs.Wrap(gerrs.ErrUnmarshal, err)
 	}
@@ -84,7 +79,7 @@ func (c *GortClient) Authenticate() (rest.Token, error) {
 	}
 	defer f.Close()
 
-	_, err = f.Write(body)
+	err = json.NewEncoder(f).Encode(token)
 	if err != nil {
 		return token, gerrs.Wrap(gerrs.ErrIO, err)
 	}
@@ -95,6 +90,15 @@ func (c *GortClient) Authenticate() (rest.Token, error) {
 // Authenticated looks for any cached tokens associated with the current
 // server. Returns false if no tokens exist or tokens are expired.
 func (c *GortClient) Authenticated() (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.authenticated()
+}
+
+// authenticated is the implementation of Authenticated. The caller must hold
+// c.mu.
+func (c *GortClient) authenticated() (bool, error) {
 	if c.token != nil {
 		return !c.token.IsExpired(), nil
 	}
@@ -130,9 +134,9 @@ func (c *GortClient) Bootstrap(user rest.User) (rest.User, error) {
 		return rest.User{}, gerrs.Wrap(gerrs.ErrMarshal, err)
 	}
 
-	resp, err := http.Post(endpointURL, "application/json", bytes.NewBuffer(postBytes))
+	resp, err := c.post(endpointURL, postBytes)
 	if err != nil {
-		return rest.User{}, gerrs.Wrap(ErrConnectionFailed, err)
+		return rest.User{}, err
 	}
 	defer resp.Body.Close()
 
@@ -145,17 +149,12 @@ func (c *GortClient) Bootstrap(user rest.User) (rest.User, error) {
 		err := fmt.Errorf("internal server error; check the server logs for details")
 		return rest.User{}, err
 	default:
-		bytes, _ := ioutil.ReadAll(resp.Body)
+		bytes, _ := io.ReadAll(io.LimitReader(resp.Body, DefaultMaxResponseSize))
 		return rest.User{}, fmt.Errorf(string(bytes))
 	}
 
-	body, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return rest.User{}, gerrs.Wrap(gerrs.ErrIO, err)
-	}
-
 	// Re-using "user" instance. Sorry.
-	err = json.Unmarshal(body, &user)
+	err = c.decodeResponse(resp, &user)
 	if err != nil {
 		return rest.User{}, gerrs.Wrap(gerrs.ErrUnmarshal, err)
 	}
@@ -181,7 +180,10 @@ func (c *GortClient) Bootstrap(user rest.User) (rest.User, error) {
 // Token is just a wrapper around a call to Authenticated() followed by a
 // call to Authenticate() if false.
 func (c *GortClient) Token() (rest.Token, error) {
-	authed, err := c.Authenticated()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	authed, err := c.authenticated()
 	if err != nil {
 		return rest.Token{}, err
 	}
@@ -190,5 +192,11 @@ func (c *GortClient) Token() (rest.Token, error) {
 		return *c.token, nil
 	}
 
-	return c.Authenticate()
+	token, err := c.Authenticate()
+	if err != nil {
+		return rest.Token{}, err
+	}
+
+	c.token = &token
+	return token, nil
 }
@@ -19,8 +19,8 @@ package client
 import (
 	"encoding/json"
 	"fmt"
-	"io/ioutil"
 	"net/http"
+	"net/url"
 
 	"github.com/getgort/gort/data"
 )
@@ -39,7 +39,7 @@ func (c *GortClient) BundleEnable(bundlename string, version string) error {
 // bundlename; false otherwise.
 func (c *GortClient) BundleExists(bundlename string, version string) (bool, error) {
 	url := fmt.Sprintf("%s/v2/bundles/%s/version/%s",
-		c.profile.URL.String(), bundlename, version)
+		c.profile.URL.String(), url.PathEscape(bundlename), url.PathEscape(version))
 
 	resp, err := c.doRequest("GET", url, []byte{})
 	if err != nil {
@@ -60,7 +60,7 @@ func (c *GortClient) BundleExists(bundlename string, version string) (bool, erro
 // BundleGet comments to be written...
 func (c *GortClient) BundleGet(bundlename string, version string) (data.Bundle, error) {
 	url := fmt.Sprintf("%s/v2/bundles/%s/versions/%s",
-		c.profile.URL.String(), bundlename, version)
+		c.profile.URL.String(), url.PathEscape(bundlename), url.PathEscape(version))
 
 	resp, err := c.doRequest("GET", url, []byte{})
 	if err != nil {
@@ -72,13 +72,8 @@ func (c *GortClient) BundleGet(bundlename string, version string) (data.Bundle,
 		return data.Bundle{}, getResponseError(resp)
 	}
 
-	body, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return data.Bundle{}, err
-	}
-
 	bundle := data.Bundle{}
-	err = json.Unmarshal(body, &bundle)
+	err = c.decodeResponse(resp, &bundle)
 	if err != nil {
 		return data.Bundle{}, err
 	}
@@ -100,13 +95,8 @@ func (c *GortClient) BundleList() ([]data.Bundle, error) {
 		return []data.Bundle{}, getResponseError(resp)
 	}
 
-	body, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return []data.Bundle{}, err
-	}
-
 	bundles := []data.Bundle{}
-	err = json.Unmarshal(body, &bundles)
+	err = c.decodeResponse(resp, &bundles)
 	if err != nil {
 		return []data.Bundle{}, err
 	}
@@ -116,7 +106,7 @@ func (c *GortClient) BundleList() ([]data.Bundle, error) {
 
 // BundleListVersions comments to be written...
 func (c *GortClient) BundleListVersions(bundlename string) ([]data.Bundle, error) {
-	url := fmt.Sprintf("%s/v2/bundles/%s/versions", c.profile.URL.String(), bundlename)
+	url := fmt.Sprintf("%s/v2/bundles/%s/versions", c.profile.URL.String(), url.PathEscape(bundlename))
 
 	resp, err := c.doRequest("GET", url, []byte{})
 	if err != nil {
@@ -128,13 +118,8 @@ func (c *GortClient) BundleListVersions(bundlename string) ([]data.Bundle, error
 		return []data.Bundle{}, getResponseError(resp)
 	}
 
-	body, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return []data.Bundle{}, err
-	}
-
 	bundles := []data.Bundle{}
-	err = json.Unmarshal(body, &bundles)
+	err = c.decodeResponse(resp, &bundles)
 	if err != nil {
 		return []data.Bundle{}, err
 	}
@@ -145,7 +130,7 @@ func (c *GortClient) BundleListVersions(bundlename string) ([]data.Bundle, error
 // BundleInstall comments to be written...
 func (c *GortClient) BundleInstall(bundle data.Bundle) error {
 	url := fmt.Sprintf("%s/v2/bundles/%s/versions/%s",
-		c.profile.URL.String(), bundle.Name, bundle.Version)
+		c.profile.URL.String(), url.PathEscape(bundle.Name), url.PathEscape(bundle.Version))
 
 	bytes, err := json.Marshal(bundle)
 	if err != nil {
@@ -168,7 +153,7 @@ func (c *GortClient) BundleInstall(bundle data.Bundle) error {
 // BundleUninstall comments to be written...
 func (c *GortClient) BundleUninstall(bundlename string, version string) error {
 	url := fmt.Sprintf("%s/v2/bundles/%s/versions/%s",
-		c.profile.URL.String(), bundlename, version)
+		c.profile.URL.String(), url.PathEscape(bundlename), url.PathEscape(version))
 
 	resp, err := c.doRequest("DELETE", url, []byte{})
 	if err != nil {
@@ -187,7 +172,7 @@ func (c *GortClient) BundleUninstall(bundlename string, version string) error {
 // version is ignored when disabling a bundle.
 func (c *GortClient) doBundleEnable(bundlename string, version string, enabled bool) error {
 	url := fmt.Sprintf("%s/v2/bundles/%s/versions/%s?enabled=%v",
-		c.profile.URL.String(), bundlename, version, enabled)
+		c.profile.URL.String(), url.PathEscape(bundlename), url.PathEscape(version), enabled)
 
 	// TODO Get latest if version == 'latest'
 
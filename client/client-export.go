@@ -0,0 +1,89 @@
+/*
+ * Copyright 2021 The Gort Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/getgort/gort/data/rest"
+)
+
+// Export returns a snapshot of all of the users, groups, and roles known to
+// the server. Users' passwords are only included if includePasswords is
+// true, and their access tokens only if includeTokens is true.
+func (c *GortClient) Export(includePasswords, includeTokens bool) (rest.Snapshot, error) {
+	query := url.Values{}
+	if includePasswords {
+		query.Set("passwords", "true")
+	}
+	if includeTokens {
+		query.Set("tokens", "true")
+	}
+
+	endpointURL := fmt.Sprintf("%s/v2/export", c.profile.URL.String())
+	if len(query) > 0 {
+		endpointURL += "?" + query.Encode()
+	}
+
+	resp, err := c.doRequest("GET", endpointURL, []byte{})
+	if err != nil {
+		return rest.Snapshot{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return rest.Snapshot{}, getResponseError(resp)
+	}
+
+	snapshot := rest.Snapshot{}
+	err = c.decodeResponse(resp, &snapshot)
+	if err != nil {
+		return rest.Snapshot{}, err
+	}
+
+	return snapshot, nil
+}
+
+// Import restores a snapshot returned by Export. If replace is true, the
+// server's users, groups, and roles are replaced by the snapshot's;
+// otherwise the snapshot is merged into them.
+func (c *GortClient) Import(snapshot rest.Snapshot, replace bool) error {
+	url := fmt.Sprintf("%s/v2/import", c.profile.URL.String())
+	if replace {
+		url += "?replace=true"
+	}
+
+	bytes, err := json.Marshal(snapshot)
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.doRequest("POST", url, bytes)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return getResponseError(resp)
+	}
+
+	return nil
+}
@@ -19,15 +19,15 @@ package client
 import (
 	"encoding/json"
 	"fmt"
-	"io/ioutil"
 	"net/http"
+	"net/url"
 
 	"github.com/getgort/gort/data/rest"
 )
 
 // GroupDelete comments to be written...
 func (c *GortClient) GroupDelete(groupname string) error {
-	url := fmt.Sprintf("%s/v2/groups/%s", c.profile.URL.String(), groupname)
+	url := fmt.Sprintf("%s/v2/groups/%s", c.profile.URL.String(), url.PathEscape(groupname))
 
 	resp, err := c.doRequest("DELETE", url, []byte{})
 	if err != nil {
@@ -45,7 +45,7 @@ func (c *GortClient) GroupDelete(groupname string) error {
 // GroupExists simply returns true if a group exists with the specified
 // groupname; false otherwise.
 func (c *GortClient) GroupExists(groupname string) (bool, error) {
-	url := fmt.Sprintf("%s/v2/groups/%s", c.profile.URL.String(), groupname)
+	url := fmt.Sprintf("%s/v2/groups/%s", c.profile.URL.String(), url.PathEscape(groupname))
 	resp, err := c.doRequest("GET", url, []byte{})
 	if err != nil {
 		return false, err
@@ -64,7 +64,7 @@ func (c *GortClient) GroupExists(groupname string) (bool, error) {
 
 // GroupGet comments to be written...
 func (c *GortClient) GroupGet(groupname string) (rest.Group, error) {
-	url := fmt.Sprintf("%s/v2/groups/%s", c.profile.URL.String(), groupname)
+	url := fmt.Sprintf("%s/v2/groups/%s", c.profile.URL.String(), url.PathEscape(groupname))
 	resp, err := c.doRequest("GET", url, []byte{})
 	if err != nil {
 		return rest.Group{}, err
@@ -75,13 +75,8 @@ func (c *GortClient) GroupGet(groupname string) (rest.Group, error) {
 		return rest.Group{}, getResponseError(resp)
 	}
 
-	body, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return rest.Group{}, err
-	}
-
 	group := rest.Group{}
-	err = json.Unmarshal(body, &group)
+	err = c.decodeResponse(resp, &group)
 	if err != nil {
 		return rest.Group{}, err
 	}
@@ -102,23 +97,42 @@ func (c *GortClient) GroupList() ([]rest.Group, error) {
 		return []rest.Group{}, getResponseError(resp)
 	}
 
-	body, err := ioutil.ReadAll(resp.Body)
+	groups := []rest.Group{}
+	err = c.decodeResponse(resp, &groups)
 	if err != nil {
 		return []rest.Group{}, err
 	}
 
-	groups := []rest.Group{}
-	err = json.Unmarshal(body, &groups)
+	return groups, nil
+}
+
+// GroupListSummary returns the name and the number of members and roles of
+// every group, sorted by name. Unlike GroupList, it doesn't require the
+// members of each group to be sent.
+func (c *GortClient) GroupListSummary() ([]rest.GroupSummary, error) {
+	url := fmt.Sprintf("%s/v2/groups?summary=true", c.profile.URL.String())
+	resp, err := c.doRequest("GET", url, []byte{})
 	if err != nil {
-		return []rest.Group{}, err
+		return []rest.GroupSummary{}, err
 	}
+	defer resp.Body.Close()
 
-	return groups, nil
+	if resp.StatusCode != http.StatusOK {
+		return []rest.GroupSummary{}, getResponseError(resp)
+	}
+
+	summaries := []rest.GroupSummary{}
+	err = c.decodeResponse(resp, &summaries)
+	if err != nil {
+		return []rest.GroupSummary{}, err
+	}
+
+	return summaries, nil
 }
 
 // GroupMemberAdd comments to be written...
 func (c *GortClient) GroupMemberAdd(groupname string, username string) error {
-	url := fmt.Sprintf("%s/v2/groups/%s/members/%s", c.profile.URL.String(), groupname, username)
+	url := fmt.Sprintf("%s/v2/groups/%s/members/%s", c.profile.URL.String(), url.PathEscape(groupname), url.PathEscape(username))
 	resp, err := c.doRequest("PUT", url, []byte{})
 	if err != nil {
 		return err
@@ -134,7 +148,7 @@ func (c *GortClient) GroupMemberAdd(groupname string, username string) error {
 
 // GroupMemberDelete comments to be written...
 func (c *GortClient) GroupMemberDelete(groupname string, username string) error {
-	url := fmt.Sprintf("%s/v2/groups/%s/members/%s", c.profile.URL.String(), groupname, username)
+	url := fmt.Sprintf("%s/v2/groups/%s/members/%s", c.profile.URL.String(), url.PathEscape(groupname), url.PathEscape(username))
 	resp, err := c.doRequest("DELETE", url, []byte{})
 	if err != nil {
 		return err
@@ -150,7 +164,7 @@ func (c *GortClient) GroupMemberDelete(groupname string, username string) error
 
 // GroupMemberList comments to be written...
 func (c *GortClient) GroupMemberList(groupname string) ([]rest.User, error) {
-	url := fmt.Sprintf("%s/v2/groups/%s/members", c.profile.URL.String(), groupname)
+	url := fmt.Sprintf("%s/v2/groups/%s/members", c.profile.URL.String(), url.PathEscape(groupname))
 	resp, err := c.doRequest("GET", url, []byte{})
 	if err != nil {
 		return []rest.User{}, err
@@ -161,13 +175,8 @@ func (c *GortClient) GroupMemberList(groupname string) ([]rest.User, error) {
 		return []rest.User{}, getResponseError(resp)
 	}
 
-	body, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return []rest.User{}, err
-	}
-
 	users := []rest.User{}
-	err = json.Unmarshal(body, &users)
+	err = c.decodeResponse(resp, &users)
 	if err != nil {
 		return []rest.User{}, err
 	}
@@ -175,9 +184,11 @@ func (c *GortClient) GroupMemberList(groupname string) ([]rest.User, error) {
 	return users, nil
 }
 
-// GroupSave comments to be written...
+// GroupSave creates a group, or updates it if it already exists. The group
+// is identified by its Name, which is used for both the URL and the request
+// body so that the two always agree.
 func (c *GortClient) GroupSave(group rest.Group) error {
-	url := fmt.Sprintf("%s/v2/groups/%s", c.profile.URL.String(), group.Name)
+	url := fmt.Sprintf("%s/v2/groups/%s", c.profile.URL.String(), url.PathEscape(group.Name))
 
 	bytes, err := json.Marshal(group)
 	if err != nil {
@@ -197,9 +208,25 @@ func (c *GortClient) GroupSave(group rest.Group) error {
 	return nil
 }
 
+// GroupRename renames a group. Its members and roles are preserved.
+func (c *GortClient) GroupRename(oldName string, newName string) error {
+	url := fmt.Sprintf("%s/v2/groups/%s/rename/%s", c.profile.URL.String(), url.PathEscape(oldName), url.PathEscape(newName))
+	resp, err := c.doRequest("POST", url, []byte{})
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return getResponseError(resp)
+	}
+
+	return nil
+}
+
 // GroupRoleAdd adds a role to a group.
 func (c *GortClient) GroupRoleAdd(groupname string, rolename string) error {
-	url := fmt.Sprintf("%s/v2/groups/%s/roles/%s", c.profile.URL.String(), groupname, rolename)
+	url := fmt.Sprintf("%s/v2/groups/%s/roles/%s", c.profile.URL.String(), url.PathEscape(groupname), url.PathEscape(rolename))
 	resp, err := c.doRequest("PUT", url, []byte{})
 	if err != nil {
 		return err
@@ -215,7 +242,7 @@ func (c *GortClient) GroupRoleAdd(groupname string, rolename string) error {
 
 // GroupMemberDelete deletes a role from a group.
 func (c *GortClient) GroupRoleDelete(groupname string, rolename string) error {
-	url := fmt.Sprintf("%s/v2/groups/%s/roles/%s", c.profile.URL.String(), groupname, rolename)
+	url := fmt.Sprintf("%s/v2/groups/%s/roles/%s", c.profile.URL.String(), url.PathEscape(groupname), url.PathEscape(rolename))
 	resp, err := c.doRequest("DELETE", url, []byte{})
 	if err != nil {
 		return err
@@ -229,9 +256,10 @@ func (c *GortClient) GroupRoleDelete(groupname string, rolename string) error {
 	return nil
 }
 
-// GroupRoleList retrieves all roles added to a group.
+// GroupRoleList retrieves all roles added to a group. Each role includes the
+// permissions it grants.
 func (c *GortClient) GroupRoleList(groupname string) ([]rest.Role, error) {
-	url := fmt.Sprintf("%s/v2/groups/%s/roles", c.profile.URL.String(), groupname)
+	url := fmt.Sprintf("%s/v2/groups/%s/roles", c.profile.URL.String(), url.PathEscape(groupname))
 	resp, err := c.doRequest("GET", url, []byte{})
 	if err != nil {
 		return []rest.Role{}, err
@@ -242,13 +270,8 @@ func (c *GortClient) GroupRoleList(groupname string) ([]rest.Role, error) {
 		return []rest.Role{}, getResponseError(resp)
 	}
 
-	body, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return []rest.Role{}, err
-	}
-
 	roles := []rest.Role{}
-	err = json.Unmarshal(body, &roles)
+	err = c.decodeResponse(resp, &roles)
 	if err != nil {
 		return []rest.Role{}, err
 	}
@@ -17,17 +17,16 @@
 package client
 
 import (
-	"encoding/json"
 	"fmt"
-	"io/ioutil"
 	"net/http"
+	"net/url"
 
 	"github.com/getgort/gort/data/rest"
 )
 
 // RoleDelete deletes an existing role.
 func (c *GortClient) RoleDelete(rolename string) error {
-	url := fmt.Sprintf("%s/v2/roles/%s", c.profile.URL.String(), rolename)
+	url := fmt.Sprintf("%s/v2/roles/%s", c.profile.URL.String(), url.PathEscape(rolename))
 
 	resp, err := c.doRequest("DELETE", url, []byte{})
 	if err != nil {
@@ -44,7 +43,7 @@ func (c *GortClient) RoleDelete(rolename string) error {
 
 // RoleCreate creates a new role.
 func (c *GortClient) RoleCreate(rolename string) error {
-	url := fmt.Sprintf("%s/v2/roles/%s", c.profile.URL.String(), rolename)
+	url := fmt.Sprintf("%s/v2/roles/%s", c.profile.URL.String(), url.PathEscape(rolename))
 
 	resp, err := c.doRequest("PUT", url, []byte{})
 	if err != nil {
@@ -72,13 +71,8 @@ func (c *GortClient) RoleList() ([]rest.Group, error) {
 		return []rest.Group{}, getResponseError(resp)
 	}
 
-	body, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return []rest.Group{}, err
-	}
-
 	roles := []rest.Group{}
-	err = json.Unmarshal(body, &roles)
+	err = c.decodeResponse(resp, &roles)
 	if err != nil {
 		return []rest.Group{}, err
 	}
@@ -89,7 +83,7 @@ func (c *GortClient) RoleList() ([]rest.Group, error) {
 // RoleExists simply returns true if a role exists with the specified
 // rolename; false otherwise.
 func (c *GortClient) RoleExists(rolename string) (bool, error) {
-	url := fmt.Sprintf("%s/v2/roles/%s", c.profile.URL.String(), rolename)
+	url := fmt.Sprintf("%s/v2/roles/%s", c.profile.URL.String(), url.PathEscape(rolename))
 	resp, err := c.doRequest("GET", url, []byte{})
 	if err != nil {
 		return false, err
@@ -108,7 +102,7 @@ func (c *GortClient) RoleExists(rolename string) (bool, error) {
 
 // RoleGet gets an existing role.
 func (c *GortClient) RoleGet(rolename string) (rest.Role, error) {
-	url := fmt.Sprintf("%s/v2/roles/%s", c.profile.URL.String(), rolename)
+	url := fmt.Sprintf("%s/v2/roles/%s", c.profile.URL.String(), url.PathEscape(rolename))
 	resp, err := c.doRequest("GET", url, []byte{})
 	if err != nil {
 		return rest.Role{}, err
@@ -119,13 +113,8 @@ func (c *GortClient) RoleGet(rolename string) (rest.Role, error) {
 		return rest.Role{}, getResponseError(resp)
 	}
 
-	body, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return rest.Role{}, err
-	}
-
 	role := rest.Role{}
-	err = json.Unmarshal(body, &role)
+	err = c.decodeResponse(resp, &role)
 	if err != nil {
 		return rest.Role{}, err
 	}
@@ -133,9 +122,9 @@ func (c *GortClient) RoleGet(rolename string) (rest.Role, error) {
 	return role, nil
 }
 
-// RolePermissionList comments to be written...
-func (c *GortClient) RolePermissionList(username string) (rest.RolePermissionList, error) {
-	url := fmt.Sprintf("%s/v2/roles/%s/permissions", c.profile.URL.String(), username)
+// RolePermissionList returns the permissions granted to an existing role.
+func (c *GortClient) RolePermissionList(rolename string) (rest.RolePermissionList, error) {
+	url := fmt.Sprintf("%s/v2/roles/%s/permissions", c.profile.URL.String(), url.PathEscape(rolename))
 	resp, err := c.doRequest("GET", url, []byte{})
 	if err != nil {
 		return rest.RolePermissionList{}, err
@@ -146,13 +135,8 @@ func (c *GortClient) RolePermissionList(username string) (rest.RolePermissionLis
 		return rest.RolePermissionList{}, getResponseError(resp)
 	}
 
-	body, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return rest.RolePermissionList{}, err
-	}
-
 	rpl := rest.RolePermissionList{}
-	err = json.Unmarshal(body, &rpl)
+	err = c.decodeResponse(resp, &rpl)
 	if err != nil {
 		return rest.RolePermissionList{}, err
 	}
@@ -160,26 +144,34 @@ func (c *GortClient) RolePermissionList(username string) (rest.RolePermissionLis
 	return rpl, nil
 }
 
-// RolePermissionRevoke revokes an existing permission from a role
-func (c *GortClient) RolePermissionRevoke(rolename string, bundlename string, permissionname string) error {
-	url := fmt.Sprintf("%s/v2/roles/%s/bundles/%s/permissions/%s", c.profile.URL.String(), rolename, bundlename, permissionname)
+// RolePermissionRevoke revokes an existing permission from a role, and
+// returns the number of permissions that were revoked: 0 if the role hadn't
+// been granted the permission.
+func (c *GortClient) RolePermissionRevoke(rolename string, bundlename string, permissionname string) (int, error) {
+	url := fmt.Sprintf("%s/v2/roles/%s/bundles/%s/permissions/%s", c.profile.URL.String(), url.PathEscape(rolename), url.PathEscape(bundlename), url.PathEscape(permissionname))
 
 	resp, err := c.doRequest("DELETE", url, []byte{})
 	if err != nil {
-		return err
+		return 0, err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return getResponseError(resp)
+		return 0, getResponseError(resp)
 	}
 
-	return nil
+	var count int
+	err = c.decodeResponse(resp, &count)
+	if err != nil {
+		return 0, err
+	}
+
+	return count, nil
 }
 
 // RolePermissionGrant grants a permission to an existing role
 func (c *GortClient) RolePermissionGrant(rolename string, bundlename string, permissionname string) error {
-	url := fmt.Sprintf("%s/v2/roles/%s/bundles/%s/permissions/%s", c.profile.URL.String(), rolename, bundlename, permissionname)
+	url := fmt.Sprintf("%s/v2/roles/%s/bundles/%s/permissions/%s", c.profile.URL.String(), url.PathEscape(rolename), url.PathEscape(bundlename), url.PathEscape(permissionname))
 
 	resp, err := c.doRequest("PUT", url, nil)
 	if err != nil {
@@ -0,0 +1,79 @@
+/*
+ * Copyright 2021 The Gort Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package client
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/getgort/gort/data/rest"
+)
+
+// TokenList returns all authentication tokens known to the server, including
+// expired ones. Token values are not included.
+func (c *GortClient) TokenList() ([]rest.Token, error) {
+	return c.doTokenList(url.Values{})
+}
+
+// TokenListActive returns all authentication tokens that are currently
+// valid. Token values are not included.
+func (c *GortClient) TokenListActive() ([]rest.Token, error) {
+	return c.doTokenList(url.Values{"active": {"true"}})
+}
+
+// TokenListValidBetween returns all authentication tokens that are valid at
+// some point in the interval [since, until]. A zero since or until leaves
+// that end of the interval unbounded. Token values are not included.
+func (c *GortClient) TokenListValidBetween(since, until time.Time) ([]rest.Token, error) {
+	query := url.Values{}
+
+	if !since.IsZero() {
+		query.Set("since", since.Format(time.RFC3339))
+	}
+	if !until.IsZero() {
+		query.Set("until", until.Format(time.RFC3339))
+	}
+
+	return c.doTokenList(query)
+}
+
+func (c *GortClient) doTokenList(query url.Values) ([]rest.Token, error) {
+	endpointURL := fmt.Sprintf("%s/v2/tokens", c.profile.URL.String())
+	if len(query) > 0 {
+		endpointURL += "?" + query.Encode()
+	}
+
+	resp, err := c.doRequest("GET", endpointURL, []byte{})
+	if err != nil {
+		return []rest.Token{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return []rest.Token{}, getResponseError(resp)
+	}
+
+	tokens := []rest.Token{}
+	err = c.decodeResponse(resp, &tokens)
+	if err != nil {
+		return []rest.Token{}, err
+	}
+
+	return tokens, nil
+}
@@ -19,15 +19,15 @@ package client
 import (
 	"encoding/json"
 	"fmt"
-	"io/ioutil"
 	"net/http"
+	"net/url"
 
 	"github.com/getgort/gort/data/rest"
 )
 
 // UserDelete comments to be written...
 func (c *GortClient) UserDelete(username string) error {
-	url := fmt.Sprintf("%s/v2/users/%s", c.profile.URL.String(), username)
+	url := fmt.Sprintf("%s/v2/users/%s", c.profile.URL.String(), url.PathEscape(username))
 
 	resp, err := c.doRequest("DELETE", url, []byte{})
 	if err != nil {
@@ -45,7 +45,7 @@ func (c *GortClient) UserDelete(username string) error {
 // UserExists simply returns true if a user exists with the specified
 // username; false otherwise.
 func (c *GortClient) UserExists(username string) (bool, error) {
-	url := fmt.Sprintf("%s/v2/users/%s", c.profile.URL.String(), username)
+	url := fmt.Sprintf("%s/v2/users/%s", c.profile.URL.String(), url.PathEscape(username))
 	resp, err := c.doRequest("GET", url, []byte{})
 	if err != nil {
 		return false, err
@@ -64,7 +64,7 @@ func (c *GortClient) UserExists(username string) (bool, error) {
 
 // UserGet comments to be written...
 func (c *GortClient) UserGet(username string) (rest.User, error) {
-	url := fmt.Sprintf("%s/v2/users/%s", c.profile.URL.String(), username)
+	url := fmt.Sprintf("%s/v2/users/%s", c.profile.URL.String(), url.PathEscape(username))
 	resp, err := c.doRequest("GET", url, []byte{})
 	if err != nil {
 		return rest.User{}, err
@@ -75,13 +75,8 @@ func (c *GortClient) UserGet(username string) (rest.User, error) {
 		return rest.User{}, getResponseError(resp)
 	}
 
-	body, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return rest.User{}, err
-	}
-
 	user := rest.User{}
-	err = json.Unmarshal(body, &user)
+	err = c.decodeResponse(resp, &user)
 	if err != nil {
 		return rest.User{}, err
 	}
@@ -91,7 +86,7 @@ func (c *GortClient) UserGet(username string) (rest.User, error) {
 
 // UserGroupList comments to be written...
 func (c *GortClient) UserGroupList(username string) ([]rest.Group, error) {
-	url := fmt.Sprintf("%s/v2/users/%s/groups", c.profile.URL.String(), username)
+	url := fmt.Sprintf("%s/v2/users/%s/groups", c.profile.URL.String(), url.PathEscape(username))
 	resp, err := c.doRequest("GET", url, []byte{})
 	if err != nil {
 		return []rest.Group{}, err
@@ -102,13 +97,8 @@ func (c *GortClient) UserGroupList(username string) ([]rest.Group, error) {
 		return []rest.Group{}, getResponseError(resp)
 	}
 
-	body, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return []rest.Group{}, err
-	}
-
 	user := []rest.Group{}
-	err = json.Unmarshal(body, &user)
+	err = c.decodeResponse(resp, &user)
 	if err != nil {
 		return []rest.Group{}, err
 	}
@@ -129,13 +119,8 @@ func (c *GortClient) UserList() ([]rest.User, error) {
 		return []rest.User{}, getResponseError(resp)
 	}
 
-	body, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return []rest.User{}, err
-	}
-
 	users := []rest.User{}
-	err = json.Unmarshal(body, &users)
+	err = c.decodeResponse(resp, &users)
 	if err != nil {
 		return []rest.User{}, err
 	}
@@ -143,9 +128,11 @@ func (c *GortClient) UserList() ([]rest.User, error) {
 	return users, nil
 }
 
-// UserPermissionList comments to be written...
+// UserPermissionList returns an alphabetically-sorted list of the effective
+// permissions of the specified user: the permissions of every role attached
+// to every group that the user belongs to.
 func (c *GortClient) UserPermissionList(username string) (rest.RolePermissionList, error) {
-	url := fmt.Sprintf("%s/v2/users/%s/permissions", c.profile.URL.String(), username)
+	url := fmt.Sprintf("%s/v2/users/%s/permissions", c.profile.URL.String(), url.PathEscape(username))
 	resp, err := c.doRequest("GET", url, []byte{})
 	if err != nil {
 		return rest.RolePermissionList{}, err
@@ -156,13 +143,8 @@ func (c *GortClient) UserPermissionList(username string) (rest.RolePermissionLis
 		return rest.RolePermissionList{}, getResponseError(resp)
 	}
 
-	body, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return rest.RolePermissionList{}, err
-	}
-
 	rpl := rest.RolePermissionList{}
-	err = json.Unmarshal(body, &rpl)
+	err = c.decodeResponse(resp, &rpl)
 	if err != nil {
 		return rest.RolePermissionList{}, err
 	}
@@ -174,7 +156,7 @@ func (c *GortClient) UserPermissionList(username string) (rest.RolePermissionLis
 // this is called with a user whose username exists that user is updated
 // (empty fields will not be overwritten); otherwise a new user is created.
 func (c *GortClient) UserSave(user rest.User) error {
-	url := fmt.Sprintf("%s/v2/users/%s", c.profile.URL.String(), user.Username)
+	url := fmt.Sprintf("%s/v2/users/%s", c.profile.URL.String(), url.PathEscape(user.Username))
 
 	bytes, err := json.Marshal(user)
 	if err != nil {
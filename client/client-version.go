@@ -0,0 +1,54 @@
+/*
+ * Copyright 2021 The Gort Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package client
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/getgort/gort/data/rest"
+	gerrs "github.com/getgort/gort/errors"
+)
+
+// Version calls the GET /v2/version endpoint, which returns the version and
+// build information of the server. It doesn't require authentication.
+func (c *GortClient) Version() (rest.VersionInfo, error) {
+	url := fmt.Sprintf("%s/v2/version", c.profile.URL.String())
+
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return rest.VersionInfo{}, gerrs.Wrap(ErrBadRequest, err)
+	}
+
+	resp, err := c.do(req)
+	if err != nil {
+		return rest.VersionInfo{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return rest.VersionInfo{}, getResponseError(resp)
+	}
+
+	info := rest.VersionInfo{}
+	err = c.decodeResponse(resp, &info)
+	if err != nil {
+		return rest.VersionInfo{}, err
+	}
+
+	return info, nil
+}
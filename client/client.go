@@ -21,16 +21,18 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"io/ioutil"
+	"io"
 	"net/http"
 	"net/url"
 	"os"
 	"regexp"
 	"strings"
+	"sync"
 	"time"
 
 	homedir "github.com/mitchellh/go-homedir"
 
+	"github.com/getgort/gort/data"
 	"github.com/getgort/gort/data/rest"
 	gerrs "github.com/getgort/gort/errors"
 )
@@ -56,16 +58,67 @@ var (
 	// ErrResponseReadFailure indicates an error in reading a server response.
 	ErrResponseReadFailure = errors.New("error reading a server response")
 
+	// ErrResponseTooLarge is returned if a server response body exceeds the
+	// client's maximum response size.
+	ErrResponseTooLarge = errors.New("server response exceeds maximum size")
+
 	// ErrURLFormat indicates badly formatted URL.
 	ErrURLFormat = errors.New("invalid URL format")
 
 	ErrInsecureURL = errors.New("insecure URL provided, please use https or set the allow insecure flag in the config or clients")
 )
 
+// DefaultMaxResponseSize is the default maximum size, in bytes, of a server
+// response body that a GortClient will read.
+const DefaultMaxResponseSize int64 = 10 << 20
+
+// maxDrainSize is the number of bytes of an unread response body that are
+// discarded when it's closed, so that its connection can be reused. Larger
+// bodies are closed without being drained: it's cheaper to open a new
+// connection than to read them.
+const maxDrainSize int64 = 64 << 10
+
+// TransportOptions tunes the connection pooling of a GortClient's HTTP
+// transport. Zero values leave the corresponding settings of
+// http.DefaultTransport unchanged.
+type TransportOptions struct {
+	// MaxIdleConns is the maximum number of idle (keep-alive) connections
+	// across all hosts.
+	MaxIdleConns int
+
+	// MaxIdleConnsPerHost is the maximum number of idle (keep-alive)
+	// connections to keep per host.
+	MaxIdleConnsPerHost int
+
+	// IdleConnTimeout is the maximum amount of time that an idle
+	// (keep-alive) connection remains idle before closing itself.
+	IdleConnTimeout time.Duration
+}
+
 // GortClient comments to be written...
 type GortClient struct {
 	profile ProfileEntry
-	token   *rest.Token
+
+	mu    sync.Mutex // guards token
+	token *rest.Token
+
+	maxResponseSize int64
+
+	httpClient *http.Client
+
+	requestMu     sync.Mutex // guards requestID and lastRequestID
+	requestID     string
+	lastRequestID string
+
+	cacheMu sync.Mutex // guards cache
+	cache   map[string]cachedResponse
+}
+
+// cachedResponse is a response body cached by a GortClient, along with the
+// ETag that the server sent with it.
+type cachedResponse struct {
+	etag string
+	body []byte
 }
 
 // Error is an error implementation that represents either a a non-2XX
@@ -73,8 +126,9 @@ type GortClient struct {
 // case Status() will return 0).
 type Error struct {
 	error
-	profile ProfileEntry
-	status  uint
+	profile   ProfileEntry
+	requestID string
+	status    uint
 }
 
 // Error returns the error message for this error.
@@ -88,6 +142,14 @@ func (c Error) Profile() ProfileEntry {
 	return c.profile
 }
 
+// RequestID returns the ID of the request that failed, as reported by the
+// server in the response's X-Request-ID header, which can be used to find
+// the request in the server's logs. It's empty if the server didn't report
+// one.
+func (c Error) RequestID() string {
+	return c.requestID
+}
+
 // Status returns the HTTP status code provided by the server. A status of
 // 0 indicates that the client failed to connect entirely.
 func (c Error) Status() uint {
@@ -151,6 +213,47 @@ func Connect(profileName string) (*GortClient, error) {
 	return NewClient(entry)
 }
 
+// ConnectAll loads the client profile file and returns a map of profile
+// names to configured clients, one for each profile entry. Unlike Connect,
+// the GORT_SERVICE_TOKEN environment variable is ignored. Each client carries
+// its own profile and token, so the returned clients may be used
+// concurrently.
+func ConnectAll() (map[string]*GortClient, error) {
+	profile, err := LoadClientProfile()
+	if err != nil {
+		return nil, gerrs.Wrap(ErrBadProfile, err)
+	}
+
+	return NewClients(profile)
+}
+
+// NewClients creates a GortClient for each entry in the provided Profile,
+// keyed by profile name. An error is returned if any entry is invalid.
+func NewClients(profile Profile) (map[string]*GortClient, error) {
+	clients := make(map[string]*GortClient, len(profile.Profiles))
+
+	for name, entry := range profile.Profiles {
+		entry.Name = name
+
+		if entry.URL == nil {
+			url, err := parseHostURL(entry.URLString)
+			if err != nil {
+				return nil, fmt.Errorf("profile %q: %w", name, err)
+			}
+			entry.URL = url
+		}
+
+		client, err := NewClient(entry)
+		if err != nil {
+			return nil, fmt.Errorf("profile %q: %w", name, err)
+		}
+
+		clients[name] = client
+	}
+
+	return clients, nil
+}
+
 // ConnectWithNewProfile generates a connection using the supplied profile
 // entry data.
 func ConnectWithNewProfile(entry ProfileEntry) (*GortClient, error) {
@@ -177,10 +280,164 @@ func NewClient(entry ProfileEntry) (*GortClient, error) {
 	}
 
 	return &GortClient{
-		profile: entry,
+		profile:         entry,
+		maxResponseSize: DefaultMaxResponseSize,
+		httpClient:      &http.Client{Transport: newTransport(TransportOptions{})},
 	}, nil
 }
 
+// Profile returns the profile entry used by this client.
+func (c *GortClient) Profile() ProfileEntry {
+	return c.profile
+}
+
+// SetMaxResponseSize sets the maximum size, in bytes, of a server response
+// body that this client will read. Reading a larger body will return
+// ErrResponseTooLarge. A value <= 0 resets it to DefaultMaxResponseSize.
+func (c *GortClient) SetMaxResponseSize(size int64) {
+	if size <= 0 {
+		size = DefaultMaxResponseSize
+	}
+
+	c.maxResponseSize = size
+}
+
+// SetRequestID sets the ID that's sent in the X-Request-ID header of each
+// of this client's requests, so that a series of requests (such as those
+// made by a single CLI command) can be correlated in the server's logs. If
+// id is empty (default), a new ID is generated for each request.
+func (c *GortClient) SetRequestID(id string) {
+	c.requestMu.Lock()
+	defer c.requestMu.Unlock()
+
+	c.requestID = id
+}
+
+// LastRequestID returns the ID sent in the X-Request-ID header of this
+// client's most recent request, or "" if it hasn't sent one.
+func (c *GortClient) LastRequestID() string {
+	c.requestMu.Lock()
+	defer c.requestMu.Unlock()
+
+	return c.lastRequestID
+}
+
+// nextRequestID returns the ID to send with the next request.
+func (c *GortClient) nextRequestID() (string, error) {
+	c.requestMu.Lock()
+	defer c.requestMu.Unlock()
+
+	id := c.requestID
+	if id == "" {
+		var err error
+		if id, err = data.GenerateRequestID(); err != nil {
+			return "", err
+		}
+	}
+
+	c.lastRequestID = id
+	return id, nil
+}
+
+// SetTransportOptions replaces this client's HTTP transport with one tuned
+// according to opts. Connections are pooled and reused by each client, so
+// an integration that makes many requests should reuse a single client.
+// Connections pooled by the previous transport are closed. It shouldn't be
+// called while requests are in flight.
+func (c *GortClient) SetTransportOptions(opts TransportOptions) {
+	c.httpClient.CloseIdleConnections()
+	c.httpClient = &http.Client{Transport: newTransport(opts)}
+}
+
+// newTransport returns a copy of http.DefaultTransport tuned according to
+// opts.
+func newTransport(opts TransportOptions) *http.Transport {
+	t := http.DefaultTransport.(*http.Transport).Clone()
+
+	if opts.MaxIdleConns > 0 {
+		t.MaxIdleConns = opts.MaxIdleConns
+	}
+	if opts.MaxIdleConnsPerHost > 0 {
+		t.MaxIdleConnsPerHost = opts.MaxIdleConnsPerHost
+	}
+	if opts.IdleConnTimeout > 0 {
+		t.IdleConnTimeout = opts.IdleConnTimeout
+	}
+
+	return t
+}
+
+// SetResponseCache enables or disables this client's response cache. When
+// it's enabled, the body of each successful GET response that includes an
+// ETag header is cached, keyed by URL. Later GET requests for the same URL
+// send the ETag in an If-None-Match header, and if the server responds with
+// 304 Not Modified the cached body is used as if it had been sent again.
+// Disabling the cache discards its contents. The cache is disabled by
+// default.
+func (c *GortClient) SetResponseCache(enabled bool) {
+	c.cacheMu.Lock()
+	defer c.cacheMu.Unlock()
+
+	if !enabled {
+		c.cache = nil
+	} else if c.cache == nil {
+		c.cache = map[string]cachedResponse{}
+	}
+}
+
+// lookupCache returns the cached response for url, if the cache is
+// enabled and there is one.
+func (c *GortClient) lookupCache(url string) (cachedResponse, bool) {
+	c.cacheMu.Lock()
+	defer c.cacheMu.Unlock()
+
+	cr, ok := c.cache[url]
+	return cr, ok
+}
+
+// cacheResponse caches the body of resp for url if the cache is enabled and
+// resp is a successful response with an ETag. Because the body has to be
+// read to be cached, resp.Body is replaced with a reader over the cached
+// body.
+func (c *GortClient) cacheResponse(url string, resp *http.Response) error {
+	etag := resp.Header.Get("ETag")
+	if resp.StatusCode != http.StatusOK || etag == "" {
+		return nil
+	}
+
+	c.cacheMu.Lock()
+	enabled := c.cache != nil
+	c.cacheMu.Unlock()
+
+	if !enabled {
+		return nil
+	}
+
+	max := c.maxResponseSize
+	if max <= 0 {
+		max = DefaultMaxResponseSize
+	}
+
+	body, err := io.ReadAll(&limitedReader{r: resp.Body, n: max})
+	resp.Body.Close()
+	if err != nil {
+		if errors.Is(err, ErrResponseTooLarge) {
+			return err
+		}
+		return gerrs.Wrap(ErrResponseReadFailure, err)
+	}
+
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	c.cacheMu.Lock()
+	if c.cache != nil {
+		c.cache[url] = cachedResponse{etag: etag, body: body}
+	}
+	c.cacheMu.Unlock()
+
+	return nil
+}
+
 func (c *GortClient) doRequest(method string, url string, body []byte) (*http.Response, error) {
 	token, err := c.Token()
 	if err != nil {
@@ -193,13 +450,138 @@ func (c *GortClient) doRequest(method string, url string, body []byte) (*http.Re
 	}
 	req.Header.Add("X-Session-Token", token.Token)
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	cached, isCached := cachedResponse{}, false
+	if method == http.MethodGet {
+		if cached, isCached = c.lookupCache(url); isCached {
+			req.Header.Set("If-None-Match", cached.etag)
+		}
+	}
+
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if method != http.MethodGet {
+		return resp, nil
+	}
+
+	// The cached body is still current, so respond as if it had been sent
+	// again.
+	if isCached && resp.StatusCode == http.StatusNotModified {
+		resp.Body.Close()
+		resp.StatusCode = http.StatusOK
+		resp.Status = "200 OK"
+		resp.ContentLength = int64(len(cached.body))
+		resp.Body = io.NopCloser(bytes.NewReader(cached.body))
+		return resp, nil
+	}
+
+	if err := c.cacheResponse(url, resp); err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// do sends req using the client's HTTP transport, with an X-Request-ID
+// header if it doesn't already have one. The body of the response is
+// drained when it's closed, so that the connection can be reused even if
+// the body wasn't read in full.
+func (c *GortClient) do(req *http.Request) (*http.Response, error) {
+	if req.Header.Get("X-Request-ID") == "" {
+		id, err := c.nextRequestID()
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("X-Request-ID", id)
+	}
+
+	resp, err := c.httpClient.Do(req)
 	if err != nil {
 		return nil, gerrs.Wrap(ErrConnectionFailed, err)
 	}
 
-	return resp, err
+	resp.Body = drainingBody{resp.Body}
+	return resp, nil
+}
+
+// post sends a POST request with a JSON body, without a session token.
+func (c *GortClient) post(url string, body []byte) (*http.Response, error) {
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, gerrs.Wrap(ErrBadRequest, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	return c.do(req)
+}
+
+// drainingBody is a response body that discards up to maxDrainSize bytes of
+// any unread content before it's closed.
+type drainingBody struct {
+	io.ReadCloser
+}
+
+func (b drainingBody) Close() error {
+	io.Copy(io.Discard, io.LimitReader(b.ReadCloser, maxDrainSize))
+	return b.ReadCloser.Close()
+}
+
+// decodeResponse decodes the JSON body of resp into v, which must be a
+// pointer. The body is decoded as it's read rather than being buffered. An
+// error is returned if the body isn't a single valid JSON value, or if it's
+// larger than the client's maximum response size.
+func (c *GortClient) decodeResponse(resp *http.Response, v interface{}) error {
+	max := c.maxResponseSize
+	if max <= 0 {
+		max = DefaultMaxResponseSize
+	}
+
+	dec := json.NewDecoder(&limitedReader{r: resp.Body, n: max})
+
+	if err := dec.Decode(v); err != nil {
+		return err
+	}
+
+	// There should be nothing left but (maybe) whitespace.
+	if _, err := dec.Token(); err != io.EOF {
+		if errors.Is(err, ErrResponseTooLarge) {
+			return err
+		}
+		return gerrs.Wrap(ErrResponseReadFailure, errors.New("unexpected data after response body"))
+	}
+
+	return nil
+}
+
+// limitedReader is like io.LimitedReader, except that it returns
+// ErrResponseTooLarge instead of io.EOF if the underlying reader has more
+// than n bytes remaining.
+type limitedReader struct {
+	r io.Reader
+	n int64
+}
+
+func (l *limitedReader) Read(p []byte) (int, error) {
+	if l.n < 0 {
+		return 0, ErrResponseTooLarge
+	}
+
+	// Read up to one byte more than the limit so that we can tell whether
+	// it's been exceeded.
+	if int64(len(p)) > l.n+1 {
+		p = p[:l.n+1]
+	}
+
+	n, err := l.r.Read(p)
+	l.n -= int64(n)
+
+	if l.n < 0 {
+		return n + int(l.n), ErrResponseTooLarge
+	}
+
+	return n, err
 }
 
 // getGortTokenFilename finds and returns the full-qualified filename for this
@@ -231,7 +613,7 @@ func (c *GortClient) loadHostToken() (rest.Token, error) {
 		return rest.Token{}, nil
 	}
 
-	bytes, err := ioutil.ReadFile(tokenFileName)
+	bytes, err := os.ReadFile(tokenFileName)
 	if err != nil {
 		return rest.Token{}, gerrs.Wrap(gerrs.ErrIO, err)
 	}
@@ -296,7 +678,7 @@ func getGortTokenDir() (string, error) {
 // getResponseError receives an http.Response pointer and returns an Error
 // from its status message and code.
 func getResponseError(resp *http.Response) Error {
-	bytes, _ := ioutil.ReadAll(resp.Body)
+	bytes, _ := io.ReadAll(io.LimitReader(resp.Body, DefaultMaxResponseSize))
 	status := strings.TrimSpace(string(bytes))
 	code := uint(resp.StatusCode)
 
@@ -308,7 +690,7 @@ func getResponseError(resp *http.Response) Error {
 		status = status[4:]
 	}
 
-	return Error{error: errors.New(status), status: code}
+	return Error{error: errors.New(status), requestID: resp.Header.Get("X-Request-ID"), status: code}
 }
 
 // parseHostURL receives a host url string and returns a pointer *url.URL
@@ -17,11 +17,21 @@
 package client_test
 
 import (
+	"bytes"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 
 	"github.com/getgort/gort/client"
+	"github.com/getgort/gort/data/rest"
 )
 
 func TestAllowInsecure(t *testing.T) {
@@ -63,3 +73,540 @@ func TestAllowInsecure(t *testing.T) {
 		})
 	}
 }
+
+func TestNewClients(t *testing.T) {
+	profile := client.Profile{
+		Defaults: client.ProfileDefaults{Profile: "dev"},
+		Profiles: map[string]client.ProfileEntry{
+			"dev":     {URLString: "http://localhost:4000", AllowInsecure: true, Username: "dev-admin"},
+			"staging": {URLString: "https://staging.example.com", Username: "staging-admin"},
+			"prod":    {URLString: "https://prod.example.com", Username: "prod-admin"},
+		},
+	}
+
+	clients, err := client.NewClients(profile)
+	assert.NoError(t, err)
+	assert.Len(t, clients, 3)
+
+	for name, c := range clients {
+		assert.Equal(t, name, c.Profile().Name)
+		assert.Equal(t, profile.Profiles[name].URLString, c.Profile().URL.String())
+		assert.Equal(t, profile.Profiles[name].Username, c.Profile().Username)
+	}
+
+	profile.Profiles["insecure"] = client.ProfileEntry{URLString: "http://example.com"}
+	_, err = client.NewClients(profile)
+	assert.ErrorIs(t, err, client.ErrInsecureURL)
+}
+
+// connectToStub returns a client connected to an httptest server using the
+// provided handler. The GORT_SERVICE_TOKEN environment variable is set for
+// the duration of the test so no authentication request is made.
+func connectToStub(t *testing.T, handler http.HandlerFunc) *client.GortClient {
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	return connectToServer(t, server)
+}
+
+// connectToServer returns a client that's connected to a running test
+// server.
+func connectToServer(t testing.TB, server *httptest.Server) *client.GortClient {
+	os.Setenv("GORT_SERVICE_TOKEN", "test-token")
+	os.Setenv("GORT_SERVICES_ROOT", server.URL)
+	t.Cleanup(func() {
+		os.Unsetenv("GORT_SERVICE_TOKEN")
+		os.Unsetenv("GORT_SERVICES_ROOT")
+	})
+
+	c, err := client.Connect("")
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	return c
+}
+
+func TestPathEscaping(t *testing.T) {
+	var paths []string
+
+	c := connectToStub(t, func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.EscapedPath())
+		w.Write([]byte("{}"))
+	})
+
+	const name = "a b/c?d#e%f"
+	const escaped = "a%20b%2Fc%3Fd%23e%25f"
+
+	c.GroupGet(name)
+	c.GroupMemberAdd(name, name)
+	c.RoleGet(name)
+	c.RolePermissionRevoke(name, name, name)
+	c.UserGet(name)
+	c.BundleGet(name, name)
+
+	assert.Equal(t, []string{
+		"/v2/groups/" + escaped,
+		"/v2/groups/" + escaped + "/members/" + escaped,
+		"/v2/roles/" + escaped,
+		"/v2/roles/" + escaped + "/bundles/" + escaped + "/permissions/" + escaped,
+		"/v2/users/" + escaped,
+		"/v2/bundles/" + escaped + "/versions/" + escaped,
+	}, paths)
+}
+
+func TestResponseSizeLimit(t *testing.T) {
+	name := strings.Repeat("x", 1024)
+
+	c := connectToStub(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"name":"` + name + `"}`))
+	})
+
+	_, err := c.GroupGet("foo")
+	assert.NoError(t, err)
+
+	c.SetMaxResponseSize(512)
+	_, err = c.GroupGet("foo")
+	assert.ErrorIs(t, err, client.ErrResponseTooLarge)
+
+	c.SetMaxResponseSize(0)
+	group, err := c.GroupGet("foo")
+	assert.NoError(t, err)
+	assert.Equal(t, name, group.Name)
+}
+
+func TestResponseDecodeErrors(t *testing.T) {
+	tests := map[string]string{
+		"truncated body":   `[{"name":"foo"},{"name":"ba`,
+		"empty body":       ``,
+		"trailing garbage": `[{"name":"foo"}] [{"name":"bar"}]`,
+	}
+
+	for name, body := range tests {
+		t.Run(name, func(t *testing.T) {
+			c := connectToStub(t, func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte(body))
+			})
+
+			groups, err := c.GroupList()
+			assert.Error(t, err)
+			assert.Empty(t, groups)
+		})
+	}
+
+	c := connectToStub(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("[{\"name\":\"foo\"},{\"name\":\"bar\"}]\n"))
+	})
+
+	groups, err := c.GroupList()
+	assert.NoError(t, err)
+	assert.Equal(t, []rest.Group{{Name: "foo"}, {Name: "bar"}}, groups)
+}
+
+func TestTokenList(t *testing.T) {
+	var query string
+
+	c := connectToStub(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v2/tokens", r.URL.Path)
+		query = r.URL.RawQuery
+		w.Write([]byte(`[{"User":"admin"}]`))
+	})
+
+	tokens, err := c.TokenList()
+	assert.NoError(t, err)
+	assert.Equal(t, []rest.Token{{User: "admin"}}, tokens)
+	assert.Equal(t, "", query)
+
+	_, err = c.TokenListActive()
+	assert.NoError(t, err)
+	assert.Equal(t, "active=true", query)
+
+	since := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+	_, err = c.TokenListValidBetween(since, time.Time{})
+	assert.NoError(t, err)
+	assert.Equal(t, "since=2021-06-01T12%3A00%3A00Z", query)
+}
+
+func TestRolePermissionList(t *testing.T) {
+	c := connectToStub(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v2/roles/admin/permissions" {
+			http.Error(w, "no such role", http.StatusNotFound)
+			return
+		}
+
+		w.Write([]byte(`[{"BundleName":"gort","Permission":"manage_roles"}]`))
+	})
+
+	rpl, err := c.RolePermissionList("admin")
+	assert.NoError(t, err)
+	assert.Equal(t, rest.RolePermissionList{{BundleName: "gort", Permission: "manage_roles"}}, rpl)
+
+	_, err = c.RolePermissionList("nobody")
+	assert.Error(t, err)
+}
+
+func TestUserPermissionList(t *testing.T) {
+	c := connectToStub(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v2/users/admin/permissions" {
+			http.Error(w, "no such user", http.StatusNotFound)
+			return
+		}
+
+		w.Write([]byte(`[{"BundleName":"gort","Permission":"manage_roles"},{"BundleName":"gort","Permission":"manage_users"}]`))
+	})
+
+	rpl, err := c.UserPermissionList("admin")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"gort:manage_roles", "gort:manage_users"}, rpl.Strings())
+
+	_, err = c.UserPermissionList("nobody")
+	assert.Error(t, err)
+}
+
+func TestGroupRoleList(t *testing.T) {
+	c := connectToStub(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v2/groups/admin/roles" {
+			http.Error(w, "no such group", http.StatusNotFound)
+			return
+		}
+
+		w.Write([]byte(`[{"Name":"admin","Permissions":[{"BundleName":"gort","Permission":"manage_roles"},{"BundleName":"gort","Permission":"manage_users"}]}]`))
+	})
+
+	roles, err := c.GroupRoleList("admin")
+	assert.NoError(t, err)
+	assert.Equal(t, []rest.Role{
+		{
+			Name: "admin",
+			Permissions: rest.RolePermissionList{
+				{BundleName: "gort", Permission: "manage_roles"},
+				{BundleName: "gort", Permission: "manage_users"},
+			},
+		},
+	}, roles)
+
+	_, err = c.GroupRoleList("nobody")
+	assert.Error(t, err)
+}
+
+func TestRolePermissionRevoke(t *testing.T) {
+	c := connectToStub(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodDelete {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		switch r.URL.Path {
+		case "/v2/roles/admin/bundles/gort/permissions/granted":
+			w.Write([]byte("1\n"))
+		case "/v2/roles/admin/bundles/gort/permissions/not-granted":
+			w.Write([]byte("0\n"))
+		default:
+			http.Error(w, "no such role", http.StatusNotFound)
+		}
+	})
+
+	count, err := c.RolePermissionRevoke("admin", "gort", "granted")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, count)
+
+	count, err = c.RolePermissionRevoke("admin", "gort", "not-granted")
+	assert.NoError(t, err)
+	assert.Equal(t, 0, count)
+
+	_, err = c.RolePermissionRevoke("nobody", "gort", "granted")
+	assert.Error(t, err)
+}
+
+func TestGroupRename(t *testing.T) {
+	c := connectToStub(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		switch r.URL.Path {
+		case "/v2/groups/old/rename/new":
+			w.WriteHeader(http.StatusOK)
+		case "/v2/groups/old/rename/taken":
+			http.Error(w, "group already exists", http.StatusConflict)
+		default:
+			http.Error(w, "no such group", http.StatusNotFound)
+		}
+	})
+
+	assert.NoError(t, c.GroupRename("old", "new"))
+	assert.Error(t, c.GroupRename("old", "taken"))
+	assert.Error(t, c.GroupRename("nobody", "new"))
+}
+
+func TestResponseCache(t *testing.T) {
+	var requests, notModified int
+	var ifNoneMatch string
+
+	c := connectToStub(t, func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		ifNoneMatch = r.Header.Get("If-None-Match")
+
+		switch r.URL.Path {
+		case "/v2/groups":
+			if ifNoneMatch == `"v1"` {
+				notModified++
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+
+			w.Header().Set("ETag", `"v1"`)
+			w.Write([]byte(`[{"name":"foo"},{"name":"bar"}]`))
+		default:
+			w.Write([]byte(`{"name":"foo"}`))
+		}
+	})
+
+	expected := []rest.Group{{Name: "foo"}, {Name: "bar"}}
+
+	// Disabled by default: no conditional requests are made.
+	c.GroupList()
+	groups, err := c.GroupList()
+	assert.NoError(t, err)
+	assert.Equal(t, expected, groups)
+	assert.Empty(t, ifNoneMatch)
+	assert.Equal(t, 0, notModified)
+
+	c.SetResponseCache(true)
+
+	// The first request populates the cache...
+	groups, err = c.GroupList()
+	assert.NoError(t, err)
+	assert.Equal(t, expected, groups)
+	assert.Empty(t, ifNoneMatch)
+
+	// ...and later ones revalidate it, using the cached body on a 304.
+	for i := 1; i <= 2; i++ {
+		groups, err = c.GroupList()
+		assert.NoError(t, err)
+		assert.Equal(t, expected, groups)
+		assert.Equal(t, `"v1"`, ifNoneMatch)
+		assert.Equal(t, i, notModified)
+	}
+
+	// Responses without an ETag aren't cached.
+	c.GroupGet("foo")
+	_, err = c.GroupGet("foo")
+	assert.NoError(t, err)
+	assert.Empty(t, ifNoneMatch)
+
+	// Disabling the cache discards it.
+	c.SetResponseCache(false)
+	c.SetResponseCache(true)
+	groups, err = c.GroupList()
+	assert.NoError(t, err)
+	assert.Equal(t, expected, groups)
+	assert.Empty(t, ifNoneMatch)
+	assert.Equal(t, 2, notModified)
+	assert.Equal(t, 8, requests)
+}
+
+func TestExportImport(t *testing.T) {
+	var stored rest.Snapshot
+	var query string
+
+	c := connectToStub(t, func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.RawQuery
+
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/v2/export":
+			s := stored
+			s.Users = append([]rest.User{}, stored.Users...)
+			if r.URL.Query().Get("passwords") != "true" {
+				for i := range s.Users {
+					s.Users[i].Password = ""
+				}
+			}
+			if r.URL.Query().Get("tokens") != "true" {
+				s.Tokens = nil
+			}
+			json.NewEncoder(w).Encode(s)
+		case r.Method == http.MethodPost && r.URL.Path == "/v2/import":
+			if err := json.NewDecoder(r.Body).Decode(&stored); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+			}
+		default:
+			http.Error(w, "not found", http.StatusNotFound)
+		}
+	})
+
+	snapshot := rest.Snapshot{
+		Users:  []rest.User{{Username: "alice", Email: "alice@example.com", Password: "hash"}},
+		Groups: []rest.SnapshotGroup{{Name: "ops", Users: []string{"alice"}, Roles: []string{"deployer"}}},
+		Roles: []rest.SnapshotRole{{
+			Name:        "deployer",
+			Permissions: rest.RolePermissionList{{BundleName: "ops", Permission: "deploy"}},
+			Parents:     []string{},
+		}},
+		Tokens: []rest.Token{{Token: "secret", User: "alice"}},
+	}
+
+	assert.NoError(t, c.Import(snapshot, false))
+	assert.Equal(t, "", query)
+
+	assert.NoError(t, c.Import(snapshot, true))
+	assert.Equal(t, "replace=true", query)
+
+	exported, err := c.Export(false, false)
+	assert.NoError(t, err)
+	assert.Equal(t, "", query)
+	assert.Equal(t, []rest.User{{Username: "alice", Email: "alice@example.com"}}, exported.Users)
+	assert.Equal(t, snapshot.Groups, exported.Groups)
+	assert.Equal(t, snapshot.Roles, exported.Roles)
+	assert.Empty(t, exported.Tokens)
+
+	exported, err = c.Export(false, true)
+	assert.NoError(t, err)
+	assert.Equal(t, "tokens=true", query)
+	assert.Equal(t, snapshot.Tokens, exported.Tokens)
+
+	exported, err = c.Export(true, true)
+	assert.NoError(t, err)
+	assert.Equal(t, "passwords=true&tokens=true", query)
+	assert.Equal(t, snapshot.Users, exported.Users)
+	assert.Equal(t, snapshot.Tokens, exported.Tokens)
+}
+
+func TestVersion(t *testing.T) {
+	expected := rest.VersionInfo{Version: "1.2.3", GitCommit: "abc1234", BuildTime: "2021-01-01T00:00:00Z"}
+
+	var token string
+	c := connectToStub(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v2/version" {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		token = r.Header.Get("X-Session-Token")
+		json.NewEncoder(w).Encode(expected)
+	})
+
+	info, err := c.Version()
+	assert.NoError(t, err)
+	assert.Equal(t, expected, info)
+	assert.Empty(t, token)
+}
+
+func TestGroupSaveBodyName(t *testing.T) {
+	var path string
+	var body rest.Group
+
+	c := connectToStub(t, func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		json.NewDecoder(r.Body).Decode(&body)
+	})
+
+	assert.NoError(t, c.GroupSave(rest.Group{Name: "ops"}))
+	assert.Equal(t, "/v2/groups/ops", path)
+	assert.Equal(t, "ops", body.Name)
+}
+
+// newCountingServer starts a test server that counts the connections that
+// are opened to it.
+func newCountingServer(t testing.TB, handler http.HandlerFunc) (*httptest.Server, *int32) {
+	var conns int32
+
+	server := httptest.NewUnstartedServer(handler)
+	server.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			atomic.AddInt32(&conns, 1)
+		}
+	}
+	server.Start()
+	t.Cleanup(server.Close)
+
+	return server, &conns
+}
+
+func TestConnectionReuse(t *testing.T) {
+	// The client never reads the body of a successful PUT, so it has to be
+	// drained for the connection to be reused.
+	server, conns := newCountingServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write(bytes.Repeat([]byte("x"), 4096))
+	})
+
+	c := connectToServer(t, server)
+	c.SetTransportOptions(client.TransportOptions{
+		MaxIdleConns:        10,
+		MaxIdleConnsPerHost: 10,
+		IdleConnTimeout:     time.Minute,
+	})
+
+	for i := 0; i < 10; i++ {
+		assert.NoError(t, c.GroupSave(rest.Group{Name: "ops"}))
+	}
+
+	assert.Equal(t, int32(1), atomic.LoadInt32(conns))
+}
+
+func BenchmarkConnectionReuse(b *testing.B) {
+	server, conns := newCountingServer(b, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"name":"ops"}`))
+	})
+
+	c := connectToServer(b, server)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := c.GroupSave(rest.Group{Name: "ops"}); err != nil {
+			b.Fatal(err)
+		}
+	}
+
+	b.ReportMetric(float64(atomic.LoadInt32(conns)), "conns")
+}
+
+func TestRequestID(t *testing.T) {
+	var ids []string
+
+	c := connectToStub(t, func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get("X-Request-ID")
+		ids = append(ids, id)
+		w.Header().Set("X-Request-ID", id)
+		http.Error(w, "no such group", http.StatusNotFound)
+	})
+
+	// Each request gets a new ID, which is reported by the error.
+	_, err := c.GroupGet("a")
+	_, err2 := c.GroupGet("b")
+	if assert.Len(t, ids, 2) {
+		assert.NotEmpty(t, ids[0])
+		assert.NotEqual(t, ids[0], ids[1])
+		assert.Equal(t, ids[1], c.LastRequestID())
+
+		var cerr client.Error
+		if assert.ErrorAs(t, err, &cerr) {
+			assert.Equal(t, ids[0], cerr.RequestID())
+		}
+		if assert.ErrorAs(t, err2, &cerr) {
+			assert.Equal(t, ids[1], cerr.RequestID())
+		}
+	}
+
+	// A fixed ID is sent with every request.
+	ids = nil
+	c.SetRequestID("cli-action-1")
+	c.GroupGet("a")
+	c.GroupGet("b")
+	assert.Equal(t, []string{"cli-action-1", "cli-action-1"}, ids)
+	assert.Equal(t, "cli-action-1", c.LastRequestID())
+}
+
+func TestGroupListSummary(t *testing.T) {
+	var query string
+
+	c := connectToStub(t, func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.RawQuery
+		w.Write([]byte(`[{"name":"ops","member_count":12,"role_count":2}]`))
+	})
+
+	summaries, err := c.GroupListSummary()
+	assert.NoError(t, err)
+	assert.Equal(t, "summary=true", query)
+	assert.Equal(t, []rest.GroupSummary{{Name: "ops", MemberCount: 12, RoleCount: 2}}, summaries)
+}
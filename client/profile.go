@@ -17,7 +17,6 @@
 package client
 
 import (
-	"io/ioutil"
 	"net/url"
 	"os"
 
@@ -93,7 +92,7 @@ func LoadClientProfile() (Profile, error) {
 	}
 
 	// The file exists!
-	bytes, err := ioutil.ReadFile(profileFile)
+	bytes, err := os.ReadFile(profileFile)
 	if err != nil {
 		return profile, gerrs.Wrap(gerrs.ErrIO, err)
 	}
@@ -131,7 +130,7 @@ func SaveClientProfile(profile Profile) error {
 		return err
 	}
 
-	err = ioutil.WriteFile(profileFile, bytes, 0600)
+	err = os.WriteFile(profileFile, bytes, 0600)
 	if err != nil {
 		return err
 	}
@@ -40,11 +40,15 @@ func GetRootCmd() *cobra.Command {
 	root.AddCommand(GetStartCmd())
 	root.AddCommand(cli.GetBootstrapCmd())
 	root.AddCommand(cli.GetBundleCmd())
+	root.AddCommand(cli.GetExportCmd())
 	root.AddCommand(cli.GetGroupCmd())
 	root.AddCommand(cli.GetHiddenCmd())
+	root.AddCommand(cli.GetImportCmd())
 	root.AddCommand(cli.GetPermissionCmd())
 	root.AddCommand(cli.GetProfileCmd())
 	root.AddCommand(cli.GetRoleCmd())
+	root.AddCommand(cli.GetRuleCmd())
+	root.AddCommand(cli.GetTokenCmd())
 	root.AddCommand(cli.GetUserCmd())
 	root.AddCommand(cli.GetVersionCmd())
 
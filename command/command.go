@@ -19,36 +19,301 @@ package command
 import (
 	"errors"
 	"fmt"
+	"sort"
+	"strconv"
 	"strings"
+	"unicode/utf8"
 
 	"github.com/getgort/gort/types"
 )
 
+var (
+	// parseInferrer is the default Inferrer used by Parse to infer the types
+	// of option values and parameters. Inferrer is immutable, so it can be
+	// safely shared. Its depth limit only has an effect once complex types
+	// are enabled using ParseComplexTypes.
+	parseInferrer = types.Inferrer{}.ComplexTypes(false).StrictStrings(false).MaxDepth(types.RecommendedMaxDepth)
+)
+
 var (
 	// ErrInvalidBundleCommandPair is returned by FindCommandEntry when the
 	// command entry string doesn't look like  "command" or "bundle:command".
 	ErrInvalidBundleCommandPair = errors.New("invalid bundle:comand pair")
+
+	// ErrMissingOptionArgument is returned by Parse when an option that's
+	// been declared as taking an argument (using ParseOptionHasArgument) is
+	// the last option token and has no value. It's wrapped in a message of
+	// the form "option --name expects an argument".
+	ErrMissingOptionArgument = errors.New("expects an argument")
+
+	// ErrTooFewOptionArguments is returned by Parse when an option that's
+	// been declared as taking n arguments (using ParseOptionNArgs) is
+	// followed by fewer than n arguments.
+	ErrTooFewOptionArguments = errors.New("too few arguments")
+
+	// ErrTooManyParameters is returned by Parse when a command has more
+	// parameters than allowed by ParseMaxParameters.
+	ErrTooManyParameters = errors.New("too many parameters")
+
+	// ErrEmptyOptionName is returned by Parse when the function registered
+	// using ParseOptionNormalizer returns an empty name. It's wrapped in a
+	// message of the form "option --name normalizes to an empty name".
+	ErrEmptyOptionName = errors.New("normalizes to an empty name")
+
+	// ErrEmptyOptionTerminator is returned by Parse when the terminator set
+	// using ParseOptionTerminator is empty.
+	ErrEmptyOptionTerminator = errors.New("empty option terminator")
+
+	// ErrAmbiguousOption is returned by Parse when an abbreviated option is
+	// a prefix of more than one of the options registered using
+	// ParseOptionAbbreviations. It's wrapped in a message of the form
+	// "option --verb is ambiguous: could be --verbatim, --verbose".
+	ErrAmbiguousOption = errors.New("is ambiguous")
 )
 
 // Command represents a command typed in by a user. It is typically
 // generated by the Parse function.
 type Command struct {
-	Bundle     string
-	Command    string
-	Options    map[string]CommandOption
+	Bundle  string
+	Command string
+
+	// Subcommands contains the tokens that immediately follow the command
+	// name and were captured as subcommands, as requested by
+	// ParseSubcommands. It's nil if none were captured.
+	Subcommands []string
+
+	Options map[string]CommandOption
+
+	// OptionOrder lists the names of the options in Options in the order
+	// that they were first seen. It's nil if the command has no options.
+	OptionOrder []string
+
 	Parameters CommandParameters
+
+	// NamedParameters contains the parameters of the form "key=value", as
+	// requested by ParseNamedParameters. It's nil if there are none.
+	NamedParameters map[string]types.Value
+
+	// RawText contains everything after the command name, uninterpreted, as
+	// requested by ParseRawTail. It's empty otherwise.
+	RawText string
+
+	// Remainder contains the tokens that follow "--", exactly as supplied,
+	// as requested by ParseRawRemainder. It's nil if there's no "--".
+	Remainder []string
+
+	// Raw contains the exact string passed to TokenizeAndParse, as requested
+	// by ParseKeepRaw. It's empty otherwise, and is never set by Parse.
+	Raw string
 }
 
+// OptionsValues returns a map of option names to their values. A scalar
+// option maps to its Value. An option whose Value is a list (such as a
+// repeatable option) maps to a types.ListValue containing a copy of its
+// elements; likewise a map-valued option maps to a copy of its
+// types.MapValue. In both cases the collection's Name is the option name,
+// and modifying the result doesn't modify the Command.
 func (c Command) OptionsValues() map[string]types.Value {
 	m := map[string]types.Value{}
 
 	for _, o := range c.Options {
-		m[o.Name] = o.Value
+		switch v := o.Value.(type) {
+		case types.ListValue:
+			m[o.Name] = types.ListValue{Name: o.Name, V: append([]types.Value{}, v.V...)}
+		case types.MapValue:
+			mv := make(map[string]types.Value, len(v.V))
+			for k, e := range v.V {
+				mv[k] = e
+			}
+			m[o.Name] = types.MapValue{Name: o.Name, V: mv}
+		default:
+			m[o.Name] = o.Value
+		}
 	}
 
 	return m
 }
 
+// HasOption returns true if the named option was supplied. Aliases are
+// resolved by Parse, so name should be the option's canonical name.
+func (c Command) HasOption(name string) bool {
+	_, ok := c.Options[name]
+	return ok
+}
+
+// OptionValue returns the value of the named option, and a boolean that
+// indicates whether the option was supplied. Aliases are resolved by Parse,
+// so name should be the option's canonical name.
+func (c Command) OptionValue(name string) (types.Value, bool) {
+	o, ok := c.Options[name]
+	if !ok {
+		return nil, false
+	}
+
+	return o.Value, true
+}
+
+// OptionString returns the value of the named option as a string. If the
+// option wasn't supplied def is returned.
+func (c Command) OptionString(name string, def string) string {
+	v, ok := c.OptionValue(name)
+	if !ok {
+		return def
+	}
+
+	if s, ok := v.(types.StringValue); ok {
+		return s.V
+	}
+
+	return fmt.Sprintf("%v", v.Value())
+}
+
+// OptionBool returns the value of the named option as a bool. If the option
+// wasn't supplied, or if its value can't be interpreted as a bool, def is
+// returned.
+func (c Command) OptionBool(name string, def bool) bool {
+	v, ok := c.OptionValue(name)
+	if !ok {
+		return def
+	}
+
+	switch o := v.(type) {
+	case types.BoolValue:
+		return o.V
+	case types.StringValue:
+		if b, err := strconv.ParseBool(o.V); err == nil {
+			return b
+		}
+	}
+
+	return def
+}
+
+// OptionInt returns the value of the named option as an int. If the option
+// wasn't supplied, or if its value can't be interpreted as an int, def is
+// returned.
+func (c Command) OptionInt(name string, def int) int {
+	v, ok := c.OptionValue(name)
+	if !ok {
+		return def
+	}
+
+	switch o := v.(type) {
+	case types.IntValue:
+		return o.V
+	case types.StringValue:
+		if i, err := strconv.Atoi(o.V); err == nil {
+			return i
+		}
+	}
+
+	return def
+}
+
+// OrderedOptions returns the command's options in the order that they were
+// first seen, as recorded in OptionOrder.
+func (c Command) OrderedOptions() []CommandOption {
+	opts := make([]CommandOption, 0, len(c.OptionOrder))
+
+	for _, name := range c.OptionOrder {
+		if o, ok := c.Options[name]; ok {
+			opts = append(opts, o)
+		}
+	}
+
+	return opts
+}
+
+// MergeCommands returns a new Command that overlays override onto base,
+// which is typically a set of defaults (such as per-channel default options
+// from configuration). Neither argument is modified. The rules are:
+//
+//   - Bundle, Command, and Subcommands are taken from override unless they're
+//     empty, in which case base's are used.
+//   - Options are combined. If both commands have an option with the same
+//     name, override's value wins. Base's options come first in OptionOrder,
+//     followed by any options that only override has.
+//   - Parameters are not concatenated: if override has any parameters they
+//     replace base's entirely; otherwise base's parameters are used.
+//   - NamedParameters are combined, with override's values winning.
+func MergeCommands(base, override Command) Command {
+	merged := Command{
+		Bundle:      base.Bundle,
+		Command:     base.Command,
+		Subcommands: base.Subcommands,
+		Options:     map[string]CommandOption{},
+		Parameters:  base.Parameters,
+	}
+
+	if override.Bundle != "" {
+		merged.Bundle = override.Bundle
+	}
+	if override.Command != "" {
+		merged.Command = override.Command
+	}
+	if len(override.Subcommands) > 0 {
+		merged.Subcommands = override.Subcommands
+	}
+	if len(override.Parameters) > 0 {
+		merged.Parameters = override.Parameters
+	}
+
+	merged.Subcommands = append([]string(nil), merged.Subcommands...)
+	merged.Parameters = append(CommandParameters{}, merged.Parameters...)
+
+	for _, c := range []Command{base, override} {
+		for _, o := range c.OrderedOptions() {
+			merged.setOption(o)
+		}
+
+		// Include any options that are missing from OptionOrder, such as
+		// in a Command that wasn't constructed by Parse.
+		for _, name := range sortedOptionNames(c) {
+			if !containsString(c.OptionOrder, name) {
+				merged.setOption(c.Options[name])
+			}
+		}
+
+		for k, v := range c.NamedParameters {
+			if merged.NamedParameters == nil {
+				merged.NamedParameters = map[string]types.Value{}
+			}
+			merged.NamedParameters[k] = v
+		}
+	}
+
+	return merged
+}
+
+// sortedOptionNames returns the names of c's options in lexical order.
+func sortedOptionNames(c Command) []string {
+	names := make([]string, 0, len(c.Options))
+	for name := range c.Options {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func containsString(strs []string, s string) bool {
+	for _, e := range strs {
+		if e == s {
+			return true
+		}
+	}
+	return false
+}
+
+// setOption adds (or replaces) an option, recording its position in
+// OptionOrder if it hasn't been seen before.
+func (c *Command) setOption(o CommandOption) {
+	if _, ok := c.Options[o.Name]; !ok {
+		c.OptionOrder = append(c.OptionOrder, o.Name)
+	}
+
+	c.Options[o.Name] = o
+}
+
 // CommandOption represents a command option or flag, and its string
 // value (if any).
 type CommandOption struct {
@@ -58,37 +323,50 @@ type CommandOption struct {
 
 type CommandParameters []types.Value
 
+// String returns the parameters, separated by spaces, with each formatted
+// using types.FormatValue so that they can be tokenized and parsed again.
 func (c CommandParameters) String() string {
-	if len(c) == 0 {
-		return ""
+	params := make([]string, len(c))
+	for i, p := range c {
+		params[i] = types.FormatValue(p)
 	}
 
-	b := strings.Builder{}
-	b.WriteString(fmt.Sprintf("%v", c[0]))
-
-	for i := 0; i < len(c); i++ {
-		b.WriteRune(' ')
-		b.WriteString(fmt.Sprintf("%v", c[i]))
-	}
-
-	return b.String()
+	return strings.Join(params, " ")
 }
 
 // Parse accepts a slice of token strings and constructs a Command value.
 // Its behavior may be modified by passing one or more ParseOptions.
+//
+// The types of option values and parameters are inferred. Tokenize preserves
+// the quotes around quoted tokens, so a quoted token (such as "true" or '42')
+// is always a types.StringValue, regardless of what it contains.
+//
+// The first "--" token ends option parsing, and isn't itself a parameter:
+// every token after it is a parameter, including any that look like options
+// and any later "--", which is an ordinary parameter. So "cmd -a -- -b -- c"
+// has the option "a" and the parameters "-b", "--", and "c". If
+// ParseKeepTerminator is set the first "--" is a parameter too. A different
+// terminator may be set using ParseOptionTerminator.
 func Parse(tokens []string, options ...ParseOption) (Command, error) {
-	infer := types.Inferrer{}.ComplexTypes(false).StrictStrings(false)
-
-	po := &parseOptions{false, false, map[string]string{}, map[string]bool{}}
+	po := &parseOptions{optionsFirst: true, inferrer: parseInferrer, terminator: "--"}
 	for _, o := range options {
 		o(po)
 	}
 
+	if po.terminator == "" {
+		return Command{}, ErrEmptyOptionTerminator
+	}
+
 	if len(tokens) == 0 {
 		return Command{}, fmt.Errorf("empty tokens list")
 	}
 
-	bundleName, commandName, err := SplitCommand(tokens[0])
+	name := tokens[0]
+	if target, ok := po.commandAliases[name]; ok {
+		name = target
+	}
+
+	bundleName, commandName, err := SplitCommand(name)
 	if err != nil {
 		return Command{}, fmt.Errorf("command parse failure: %w", err)
 	}
@@ -102,35 +380,209 @@ func Parse(tokens []string, options ...ParseOption) (Command, error) {
 
 	tokens = tokens[1:]
 
+	// The remaining tokens are raw text, not options or parameters.
+	if po.rawTail {
+		cmd.RawText = strings.Join(tokens, " ")
+		return cmd, nil
+	}
+
+	// Capture up to po.subcommands leading non-option tokens as subcommands.
+	n := 0
+	for n < po.subcommands && n < len(tokens) && tokens[n] != po.terminator && dashCount(tokens[n]) == 0 {
+		if po.plusOptions && len(tokens[n]) >= 2 && tokens[n][0] == '+' {
+			break
+		}
+		n++
+	}
+	if n > 0 {
+		cmd.Subcommands = append([]string{}, tokens[:n]...)
+		tokens = tokens[n:]
+	}
+
+	// lastOption points to last if the previous token was an option. Reusing
+	// a single variable avoids allocating one for each option.
+	var last CommandOption
 	var lastOption *CommandOption = nil
 
+	// nargs collects the arguments of lastOption if it was declared as
+	// taking a number of arguments using ParseOptionNArgs.
+	var nargs []types.Value
+
+	// addNArg adds v to the arguments collected for lastOption, and sets
+	// the option's value once all of them have been collected.
+	addNArg := func(v types.Value) {
+		nargs = append(nargs, v)
+		if len(nargs) == po.nargs[lastOption.Name] {
+			lastOption.Value = types.ListValue{V: nargs}
+			cmd.setOption(*lastOption)
+			lastOption, nargs = nil, nil
+		}
+	}
+
+	// supplied counts the parameters seen so far, including any that exceed
+	// po.maxParameters: those are counted but never inferred.
+	supplied := 0
+
+	// Without options, every token is a parameter.
+	if po.noOptions {
+		if supplied = len(tokens); !po.tooManyParameters(supplied) {
+			for _, t := range tokens {
+				if err := cmd.addParameter(t, po); err != nil {
+					return cmd, err
+				}
+			}
+		}
+		tokens = nil
+	}
+
 	for i, t := range tokens {
-		// Double slash indicates the end of options
-		if t == "--" {
-			cmd.Parameters, err = infer.InferAll(tokens[i+1:])
+		// An option that takes n arguments consumes the next n tokens, none
+		// of which may be an option or "--".
+		if lastOption != nil && po.nargs[lastOption.Name] > 0 && !po.isOption(t) {
+			term, err := po.inferrer.Infer(t)
 			if err != nil {
 				return cmd, err
 			}
+
+			addNArg(term)
+			continue
+		}
+
+		// The terminator (usually "--") indicates the end of options
+		if t == po.terminator {
+			if err := po.checkOptionArgument(lastOption, len(nargs)); err != nil {
+				return cmd, err
+			}
+			if po.rawRemainder {
+				cmd.Remainder = append([]string{}, tokens[i+1:]...)
+				break
+			}
+			params := tokens[i+1:]
+			if po.keepTerminator {
+				params = tokens[i:]
+			}
+			if supplied += len(params); po.tooManyParameters(supplied) {
+				break
+			}
+			if err := cmd.addParameters(params, po); err != nil {
+				return cmd, err
+			}
 			break
 		}
 
-		// Format: --option
-		if len(t) >= 2 && dashCount(t) == 2 {
-			lastOption = buildOption(t[2:], po)
-			cmd.Options[lastOption.Name] = *lastOption
+		// Format: --option or --option=value. A bare "--" is only reached
+		// if a different terminator is set, in which case it's a parameter.
+		if len(t) > 2 && dashCount(t) == 2 {
+			if err := po.checkNArgs(lastOption, len(nargs)); err != nil {
+				return cmd, err
+			}
+
+			if name, value, ok := splitOptionValue(t[2:]); ok {
+				if last, err = buildOptionWithValue(name, value, true, po); err != nil {
+					return cmd, err
+				}
+				lastOption = nil
+				cmd.setOption(last)
+
+				// The value is the first of the option's arguments.
+				if po.nargs[last.Name] > 0 {
+					lastOption = &last
+					addNArg(last.Value)
+				}
+				continue
+			}
+
+			if last, err = buildOption(t[2:], true, po); err != nil {
+				return cmd, err
+			}
+			lastOption = &last
+			cmd.setOption(last)
 			continue
 		}
 
-		// Format: -I or -Ik
-		if len(t) >= 1 && dashCount(t) == 1 {
-			if po.agnosticDashes {
-				lastOption = buildOption(t[1:], po)
-				cmd.Options[lastOption.Name] = *lastOption
-			} else {
-				for _, ch := range t[1:] {
-					lastOption = buildOption(string(ch), po)
-					cmd.Options[lastOption.Name] = *lastOption
+		// Format: -I or -Ik, or +I or +Ik if plus options are enabled
+		plus := po.plusOptions && len(t) >= 2 && t[0] == '+'
+		if plus || (len(t) >= 1 && dashCount(t) == 1) {
+			if err := po.checkNArgs(lastOption, len(nargs)); err != nil {
+				return cmd, err
+			}
+
+			if po.agnosticDashes || (!plus && po.isSingleDashLongOption(t[1:])) {
+				if name, value, ok := splitOptionValue(t[1:]); ok && !plus {
+					if last, err = buildOptionWithValue(name, value, true, po); err != nil {
+						return cmd, err
+					}
+					lastOption = nil
+					cmd.setOption(last)
+
+					if po.nargs[last.Name] > 0 {
+						lastOption = &last
+						addNArg(last.Value)
+					}
+					continue
 				}
+
+				if last, err = buildOption(t[1:], true, po); err != nil {
+					return cmd, err
+				}
+				lastOption = &last
+				if plus {
+					last.Value, lastOption = types.BoolValue{V: false}, nil
+				}
+				cmd.setOption(last)
+				continue
+			}
+
+			// Short options are expanded left to right. If a character is
+			// an option explicitly registered as taking an argument, the
+			// remainder of the token (if any) becomes its value: if "b"
+			// takes an argument, "-abc" is equivalent to "-a -b c".
+			//
+			// If plus options are enabled, a "+" or "-" within the token
+			// switches between disabling and enabling the options that
+			// follow it, so "+a-b" is equivalent to "+a -b". A disabled
+			// option never takes an argument.
+			enable := !plus
+			chars := t[1:]
+			for j, ch := range chars {
+				if po.plusOptions && (ch == '+' || ch == '-') {
+					enable = ch == '-'
+					continue
+				}
+
+				if last, err = buildOption(string(ch), false, po); err != nil {
+					return cmd, err
+				}
+				lastOption = &last
+				if !enable {
+					last.Value, lastOption = types.BoolValue{V: false}, nil
+					cmd.setOption(last)
+					continue
+				}
+				cmd.setOption(last)
+
+				// Use the rune's width in the token rather than RuneLen(ch),
+				// which differs for invalid UTF-8 (decoded as RuneError).
+				_, width := utf8.DecodeRuneInString(chars[j:])
+				rest := chars[j+width:]
+				if rest == "" || !po.hasArg[lastOption.Name] {
+					continue
+				}
+
+				term, err := po.inferrer.Infer(rest)
+				if err != nil {
+					return cmd, err
+				}
+
+				if po.nargs[lastOption.Name] > 0 {
+					addNArg(term)
+					break
+				}
+
+				lastOption.Value = term
+				cmd.setOption(*lastOption)
+				lastOption = nil
+				break
 			}
 
 			continue
@@ -146,34 +598,274 @@ func Parse(tokens []string, options ...ParseOption) (Command, error) {
 
 			// Expect an option:
 			if hasArgument {
-				term, err := infer.Infer(t)
+				term, err := po.inferrer.Infer(t)
 				if err != nil {
 					return cmd, err
 				}
 
 				lastOption.Value = term
-				cmd.Options[lastOption.Name] = *lastOption
+				cmd.setOption(*lastOption)
 				lastOption = nil
 				continue
 			}
 		}
 
-		// Not an option; not an argument. Must be command args.
-		cmd.Parameters, err = infer.InferAll(tokens[i:])
-		if err != nil {
-			return cmd, err
+		// Not an option; not an argument. Must be a command parameter.
+		if !po.optionsFirst {
+			lastOption = nil
+			if supplied++; po.tooManyParameters(supplied) {
+				continue
+			}
+
+			if err := cmd.addParameter(t, po); err != nil {
+				return cmd, err
+			}
+			continue
+		}
+
+		// All remaining tokens are command parameters.
+		if supplied += len(tokens[i:]); po.tooManyParameters(supplied) {
+			break
+		}
+
+		if !po.namedParameters {
+			if err := cmd.addParameters(tokens[i:], po); err != nil {
+				return cmd, err
+			}
+			break
+		}
+
+		for _, t := range tokens[i:] {
+			if err := cmd.addParameter(t, po); err != nil {
+				return cmd, err
+			}
 		}
 		break
 	}
 
+	if po.tooManyParameters(supplied) {
+		return cmd, fmt.Errorf("%w: %d supplied, the maximum is %d",
+			ErrTooManyParameters, supplied, po.maxParameters)
+	}
+
+	// The last token was an option that's still waiting for its argument.
+	if err := po.checkOptionArgument(lastOption, len(nargs)); err != nil {
+		return cmd, err
+	}
+
 	return cmd, nil
 }
 
+// isSingleDashLongOption returns true if single-dash long options are enabled
+// and t, a single-dash token without its dash, names a registered option
+// that's longer than one character.
+func (po *parseOptions) isSingleDashLongOption(t string) bool {
+	if !po.singleDashLong {
+		return false
+	}
+
+	name := t
+	if n, _, ok := splitOptionValue(t); ok {
+		name = n
+	}
+
+	if utf8.RuneCountInString(name) <= 1 {
+		return false
+	}
+
+	if _, ok := po.aliases[name]; ok {
+		return true
+	}
+
+	if po.caseInsensitive {
+		name = strings.ToLower(name)
+	}
+
+	if po.normalize != nil {
+		name = po.normalize(name)
+	}
+
+	_, ok := po.hasArg[name]
+	return ok
+}
+
+// tooManyParameters returns true if a maximum number of parameters has been
+// set and supplied exceeds it.
+func (po *parseOptions) tooManyParameters(supplied int) bool {
+	return po.maxParameters > 0 && supplied > po.maxParameters
+}
+
+// checkOptionArgument returns an error if o is non-nil (meaning that it's
+// waiting for an argument) and o was explicitly declared as taking an
+// argument. If o was declared as taking n arguments, got is the number that
+// it's received so far.
+func (po *parseOptions) checkOptionArgument(o *CommandOption, got int) error {
+	if o == nil || !po.hasArg[o.Name] {
+		return nil
+	}
+
+	if po.nargs[o.Name] > 0 {
+		return po.checkNArgs(o, got)
+	}
+
+	return fmt.Errorf("option %s %w", optionString(o.Name), ErrMissingOptionArgument)
+}
+
+// checkNArgs returns an error if o is non-nil and was declared as taking n
+// arguments (using ParseOptionNArgs), but got is fewer than n.
+func (po *parseOptions) checkNArgs(o *CommandOption, got int) error {
+	if o == nil {
+		return nil
+	}
+
+	if n := po.nargs[o.Name]; got < n {
+		return fmt.Errorf("option %s has %w: expects %d, got %d",
+			optionString(o.Name), ErrTooFewOptionArguments, n, got)
+	}
+
+	return nil
+}
+
+// optionString returns the name of an option with its dashes, as it would
+// typically be typed: "-o" for a single-character name, or "--name".
+func optionString(name string) string {
+	if utf8.RuneCountInString(name) == 1 {
+		return "-" + name
+	}
+
+	return "--" + name
+}
+
+// isOption returns true if Parse would treat t as an option, or as the
+// terminator that ends the options.
+func (po *parseOptions) isOption(t string) bool {
+	switch {
+	case t == po.terminator:
+		return true
+	case len(t) > 2 && dashCount(t) == 2:
+		return true
+	case po.plusOptions && len(t) >= 2 && t[0] == '+':
+		return true
+	default:
+		return len(t) >= 1 && dashCount(t) == 1
+	}
+}
+
+// addParameter infers the type of a single parameter token, unless
+// ParseRawParameters is set, in which case it's stored as a string. If named
+// parameters are enabled and the token has the form "key=value" the value is
+// added to the command's named parameters; otherwise the token is appended to
+// its parameters.
+func (c *Command) addParameter(t string, po *parseOptions) error {
+	if po.namedParameters {
+		if key, value, ok := splitNamedParameter(t); ok {
+			term, err := po.inferParameter(value)
+			if err != nil {
+				return err
+			}
+
+			if c.NamedParameters == nil {
+				c.NamedParameters = map[string]types.Value{}
+			}
+			c.NamedParameters[key] = term
+			return nil
+		}
+	}
+
+	term, err := po.inferParameter(t)
+	if err != nil {
+		return err
+	}
+
+	c.Parameters = append(c.Parameters, term)
+	return nil
+}
+
+// inferParameter infers the type of a parameter token. If ParseRawParameters
+// is set the token is returned, exactly as supplied, as a StringValue.
+func (po *parseOptions) inferParameter(t string) (types.Value, error) {
+	if po.rawParameters {
+		return types.StringValue{V: t}, nil
+	}
+
+	return po.inferrer.Infer(t)
+}
+
+// splitNamedParameter splits a token of the form "key=value". The key must
+// be non-empty and contain only letters, digits, underscores, periods, and
+// hyphens, so quoted strings and regular expressions that contain an "=" are
+// never split.
+func splitNamedParameter(t string) (key, value string, ok bool) {
+	i := strings.IndexByte(t, '=')
+	if i <= 0 {
+		return "", "", false
+	}
+
+	for _, ch := range t[:i] {
+		switch {
+		case ch >= 'a' && ch <= 'z', ch >= 'A' && ch <= 'Z', ch >= '0' && ch <= '9':
+		case ch == '_', ch == '.', ch == '-':
+		default:
+			return "", "", false
+		}
+	}
+
+	return t[:i], t[i+1:], true
+}
+
+// addParameters infers the types of tokens and appends them to the
+// command's parameters.
+func (c *Command) addParameters(tokens []string, po *parseOptions) error {
+	var params []types.Value
+
+	if po.rawParameters {
+		params = make([]types.Value, len(tokens))
+		for i, t := range tokens {
+			params[i] = types.StringValue{V: t}
+		}
+	} else {
+		var err error
+		if params, err = po.inferrer.InferAll(tokens); err != nil {
+			return err
+		}
+	}
+
+	if len(c.Parameters) == 0 {
+		c.Parameters = params
+	} else {
+		c.Parameters = append(c.Parameters, params...)
+	}
+
+	return nil
+}
+
+// parseOptions holds the settings applied by ParseOption functions. The
+// maps are nil until an option needs them.
 type parseOptions struct {
+	abbreviations         map[string]bool
 	agnosticDashes        bool
 	assumeOptionArguments bool
+	caseInsensitive       bool
+	keepRaw               bool
+	keepTerminator        bool
+	maxParameters         int
+	namedParameters       bool
+	noOptions             bool
+	optionsFirst          bool
+	plusOptions           bool
+	rawParameters         bool
+	rawRemainder          bool
+	rawTail               bool
+	singleDashLong        bool
+	subcommands           int
+	terminator            string
 	aliases               map[string]string
+	commandAliases        map[string]string
 	hasArg                map[string]bool
+	nargs                 map[string]int
+	normalize             func(name string) string
+	inferrer              types.Inferrer
+	unknownOption         func(name string)
 }
 
 type ParseOption func(*parseOptions)
@@ -196,36 +888,320 @@ func ParseAssumeOptionArguments(assume bool) ParseOption {
 	}
 }
 
+// ParseCaseInsensitiveOptions determines whether option names are
+// case-sensitive. If true, option names are lowercased after alias
+// resolution, so "--Verbose", "--VERBOSE", and "--verbose" all refer to the
+// "verbose" option. Names passed to ParseOptionHasArgument should then be
+// lowercase. If false (default), option names are case-sensitive.
+func ParseCaseInsensitiveOptions(insensitive bool) ParseOption {
+	return func(po *parseOptions) {
+		po.caseInsensitive = insensitive
+	}
+}
+
+// ParseCommandAlias makes alias, when it's the first token, refer to the
+// target command, which may be of the form "bundle:command" or "command", so
+// that with the alias "deploy" for "ops:deploy", "deploy web" is parsed
+// exactly like "ops:deploy web". The alias must match the first token
+// exactly, and the target isn't itself checked for aliases. A first token
+// that isn't an alias is parsed as usual.
+func ParseCommandAlias(alias, target string) ParseOption {
+	return func(po *parseOptions) {
+		if po.commandAliases == nil {
+			po.commandAliases = map[string]string{}
+		}
+		po.commandAliases[alias] = target
+	}
+}
+
+// ParseComplexTypes enables (or disables) the inference of complex types:
+// list literals ([a,b,c]), map literals ({k:v}), collection references, and
+// regular expressions. This applies to parameters and option values,
+// including those supplied as --option=value. The default is false.
+func ParseComplexTypes(enabled bool) ParseOption {
+	return func(po *parseOptions) {
+		po.inferrer = po.inferrer.ComplexTypes(enabled)
+	}
+}
+
+// ParseKeepRaw determines whether TokenizeAndParse stores its input string,
+// exactly as supplied (including its whitespace and quotes), in the
+// Command's Raw field, which is useful for audit logs and for echoing the
+// command back to the user. The string is stored even if it can't be
+// tokenized or parsed. It has no effect on Parse, which only sees tokens.
+// The default is false.
+func ParseKeepRaw(keep bool) ParseOption {
+	return func(po *parseOptions) {
+		po.keepRaw = keep
+	}
+}
+
+// ParseKeepTerminator determines whether the "--" that ends option parsing
+// is kept as a parameter. If true, it's the first of the parameters that
+// follow it, and counts towards ParseMaxParameters, so that the parameters
+// can be forwarded intact to a command that expects it: "cmd -a -- -b" has
+// the parameters "--" and "-b". If false (default), it's discarded. Either
+// way, any later "--" is an ordinary parameter. It has no effect on the
+// Remainder captured by ParseRawRemainder.
+func ParseKeepTerminator(keep bool) ParseOption {
+	return func(po *parseOptions) {
+		po.keepTerminator = keep
+	}
+}
+
+// ParseMaxParameters limits the number of parameters, including named
+// parameters, that a command may have. If more than max are supplied Parse
+// returns an error wrapping ErrTooManyParameters that reports the number
+// supplied; the types of the excess parameters aren't inferred. A max of 0
+// or less (default) means that there's no limit.
+func ParseMaxParameters(max int) ParseOption {
+	return func(po *parseOptions) {
+		po.maxParameters = max
+	}
+}
+
+// ParseNamedParameters determines whether parameters of the form "key=value"
+// are treated as named parameters. If true, such parameters are removed from
+// Command.Parameters, and their values, with inferred types, are stored in
+// Command.NamedParameters by key, so "set foo=1 bar" has the named parameter
+// "foo" with a value of 1, and the parameter "bar". If a key appears more than
+// once the last value is used. Parameters that follow "--" are never treated
+// as named parameters. If false (default), "key=value" is an ordinary
+// parameter.
+func ParseNamedParameters(named bool) ParseOption {
+	return func(po *parseOptions) {
+		po.namedParameters = named
+	}
+}
+
+// ParseNoOptions disables option parsing. If true, every token after the
+// command (and any subcommands) is a parameter, including tokens that begin
+// with a dash and "--" itself, so "rm -f" has the parameter "-f". If false
+// (default), options are parsed as usual.
+func ParseNoOptions(none bool) ParseOption {
+	return func(po *parseOptions) {
+		po.noOptions = none
+	}
+}
+
+// ParseOptionsFirst determines whether options must precede parameters. If
+// true (default), the first token that's neither an option nor an option's
+// argument switches the parser into parameter mode, and all remaining tokens
+// (including any that look like options) are treated as parameters, as in
+// POSIX getopt. If false, options and parameters may be interleaved, so
+// "cmd a --b" has the parameter "a" and the option "b". In either case "--"
+// ends option parsing.
+func ParseOptionsFirst(first bool) ParseOption {
+	return func(po *parseOptions) {
+		po.optionsFirst = first
+	}
+}
+
+// ParsePlusOptions determines whether tokens beginning with a single "+" are
+// treated as options. If true, "+x" sets the option "x" to false: the
+// inverse of "-x". Plus options may be bundled like short options, and "+"
+// and "-" may be mixed within a token, so "+a-b" sets "a" to false and "b"
+// to true. If false (default), such tokens are parameters or option
+// arguments.
+func ParsePlusOptions(plus bool) ParseOption {
+	return func(po *parseOptions) {
+		po.plusOptions = plus
+	}
+}
+
+// ParseRawParameters determines whether the types of parameters are
+// inferred. If true, each parameter, including the value of a named
+// parameter, is stored exactly as supplied (including any quotes) as a
+// StringValue, so "cmd 42 'foo'" has the parameters "42" and "'foo'". This
+// avoids the cost of inference, and any surprising coercion, for commands
+// that only need strings. Option values are still inferred. If false
+// (default), the types of parameters are inferred.
+func ParseRawParameters(raw bool) ParseOption {
+	return func(po *parseOptions) {
+		po.rawParameters = raw
+	}
+}
+
+// ParseRawRemainder determines what's done with the tokens that follow "--".
+// If true, they're stored in Command.Remainder exactly as supplied, without
+// being inferred or counted as parameters, so that they can be forwarded to
+// another parser. If false (default), they're inferred as parameters.
+func ParseRawRemainder(raw bool) ParseOption {
+	return func(po *parseOptions) {
+		po.rawRemainder = raw
+	}
+}
+
+// ParseRawTail, if true, causes everything after the command name to be
+// captured verbatim in the Command's RawText, with no option or parameter
+// interpretation: the Command's Options and Parameters are left empty. This
+// is useful for commands that accept free-form text, such as a search query.
+// Unlike "--", which ends the options but still infers the types of the
+// parameters that follow, nothing is inferred.
+//
+// Parse joins the remaining tokens using single spaces. TokenizeAndParse
+// doesn't tokenize the text after the command name at all, so RawText
+// preserves its original spacing and quotes, and unbalanced quotes aren't
+// an error.
+func ParseRawTail(raw bool) ParseOption {
+	return func(po *parseOptions) {
+		po.rawTail = raw
+	}
+}
+
+// ParseSingleDashLongOptions determines whether a single-dash token can name
+// a long option, as in "-verbose". If true, a single-dash token whose name
+// (the part before any "=") is longer than one character, and is either an
+// alias (see ParseOptionAlias) or an option declared using
+// ParseOptionHasArgument or ParseOptionNArgs, is treated exactly like its
+// double-dash form. Other single-dash tokens, such as "-v" and "-vx", are
+// short options as usual. If false (default), "-verbose" is equivalent to
+// "-v -e -r -b -o -s -e". It has no effect if ParseAgnosticDashes is true.
+func ParseSingleDashLongOptions(enabled bool) ParseOption {
+	return func(po *parseOptions) {
+		po.singleDashLong = enabled
+	}
+}
+
+// ParseSubcommands specifies the number of subcommand tokens expected to
+// follow the command name, as in "bundle:resource action". Up to depth
+// tokens are removed from the start of the command's arguments and stored
+// in Command.Subcommands before options and parameters are parsed. Capture
+// stops early at the first token that looks like an option (including "--"),
+// so Subcommands may be shorter than depth. The default is 0.
+func ParseSubcommands(depth int) ParseOption {
+	return func(po *parseOptions) {
+		po.subcommands = depth
+	}
+}
+
 // ParseOptionHasArgument allows specific options to be specified as expecting
 // an option (or not). Options not specified are treated according to
 // ParseAssumeOptionArguments.
+//
+// When short options are bundled (as in "-abc") an option registered here
+// as having an argument consumes the rest of the token as its value, so
+// "-ofile" is equivalent to "-o file". If it's the last character of the
+// bundle, the following token is used instead. ParseAssumeOptionArguments
+// does not affect bundled options.
 func ParseOptionHasArgument(option string, hasArg bool) ParseOption {
 	return func(po *parseOptions) {
+		if po.hasArg == nil {
+			po.hasArg = map[string]bool{}
+		}
 		po.hasArg[option] = hasArg
 	}
 }
 
+// ParseOptionNArgs declares that an option takes exactly n arguments. The n
+// tokens that follow the option become the elements of a types.ListValue
+// (even if n is 1), which is the option's value. If the option has the form
+// "--option=value", the value is its first argument.
+//
+// Arguments may not be options or "--": if an option or "--" is found, or
+// the tokens run out, before n arguments have been collected, Parse returns
+// an error wrapping ErrTooFewOptionArguments. If n is 0, the option never
+// takes an argument, exactly as ParseOptionHasArgument(option, false).
+func ParseOptionNArgs(option string, n int) ParseOption {
+	return func(po *parseOptions) {
+		if po.hasArg == nil {
+			po.hasArg = map[string]bool{}
+		}
+		if po.nargs == nil {
+			po.nargs = map[string]int{}
+		}
+
+		if n <= 0 {
+			po.hasArg[option] = false
+			delete(po.nargs, option)
+			return
+		}
+
+		po.hasArg[option] = true
+		po.nargs[option] = n
+	}
+}
+
+// ParseOptionTerminator sets the token that ends option parsing, such as
+// "::", to separate a command's options from a payload. Everything said
+// about "--" elsewhere applies to the terminator instead, and "--" becomes
+// an ordinary token: "cmd -a :: -- -b" has the option "a" and the parameters
+// "--" and "-b". The default is "--". If the terminator is empty Parse
+// returns ErrEmptyOptionTerminator.
+func ParseOptionTerminator(terminator string) ParseOption {
+	return func(po *parseOptions) {
+		po.terminator = terminator
+	}
+}
+
+// ParseOptionAbbreviations enables the abbreviation of long options, as in
+// GNU getopt, and registers the long options that may be abbreviated. A long
+// option that's a unique prefix of one of them is expanded to it, so if
+// "verbose" is registered "--verb" is equivalent to "--verbose". An exact
+// match is never expanded, and a name that isn't a prefix of any registered
+// option is left alone. If a name is a prefix of more than one, Parse
+// returns an error wrapping ErrAmbiguousOption. Aliases (see
+// ParseOptionAlias) are never expanded, and short options, including
+// bundled ones, are unaffected.
+func ParseOptionAbbreviations(options ...string) ParseOption {
+	return func(po *parseOptions) {
+		if po.abbreviations == nil {
+			po.abbreviations = map[string]bool{}
+		}
+		for _, o := range options {
+			po.abbreviations[o] = true
+		}
+	}
+}
+
 // ParseOptionAlias allows option aliases to be set, most often "short options"
 // to "long options". All references to "alias" are treated as "name".
 func ParseOptionAlias(alias, name string) ParseOption {
 	return func(po *parseOptions) {
+		if po.aliases == nil {
+			po.aliases = map[string]string{}
+		}
 		po.aliases[alias] = name
 	}
 }
 
+// ParseOptionNormalizer registers a function that's applied to the name of
+// each option after alias resolution and case folding, so that variants of a
+// name can be collapsed onto a single canonical option: for example, a
+// function that replaces underscores with hyphens makes "--dry_run" and
+// "--dry-run" the same option. Options are stored (and reported by
+// OptionsValues) under the normalized name, and names passed to
+// ParseOptionHasArgument should be normalized. If the function returns an
+// empty name, Parse returns an error wrapping ErrEmptyOptionName.
+func ParseOptionNormalizer(f func(name string) string) ParseOption {
+	return func(po *parseOptions) {
+		po.normalize = f
+	}
+}
+
+// ParseUnknownOption registers a function that's called with the name of
+// each option that's neither an alias (see ParseOptionAlias) nor declared
+// using ParseOptionHasArgument. This allows callers to collect diagnostics
+// about unexpected options. It doesn't alter how the option is parsed.
+func ParseUnknownOption(f func(name string)) ParseOption {
+	return func(po *parseOptions) {
+		po.unknownOption = f
+	}
+}
+
 // SplitCommand accepts a string in the style of "bundle:command" or "command"
 // and returns the bundle and command as a pair of strings. If there's no
 // indicated bundle, the bundle string (the first string) will be empty. If
 // there's more than one colon, an error will be returned.
 func SplitCommand(name string) (bundle, command string, err error) {
-	split := strings.Split(name, ":")
+	i := strings.IndexByte(name, ':')
 
-	switch len(split) {
-	case 1:
-		command = split[0]
-	case 2:
-		bundle = split[0]
-		command = split[1]
+	switch {
+	case i < 0:
+		command = name
+	case strings.IndexByte(name[i+1:], ':') < 0:
+		bundle = name[:i]
+		command = name[i+1:]
 	default:
 		err = ErrInvalidBundleCommandPair
 	}
@@ -233,12 +1209,108 @@ func SplitCommand(name string) (bundle, command string, err error) {
 	return
 }
 
-func buildOption(name string, po *parseOptions) *CommandOption {
-	if n, ok := po.aliases[name]; ok {
+// buildOption builds an option with the given name, after expanding any
+// abbreviation (if it's a long option), resolving any alias, folding its
+// case, and normalizing it, as configured by po.
+func buildOption(name string, long bool, po *parseOptions) (CommandOption, error) {
+	original := name
+
+	n, aliased := po.aliases[name]
+	if aliased {
 		name = n
+	} else if long && len(po.abbreviations) > 0 {
+		var err error
+		if name, err = po.expandAbbreviation(name); err != nil {
+			return CommandOption{}, err
+		}
+	}
+
+	if po.caseInsensitive {
+		name = strings.ToLower(name)
+	}
+
+	if po.normalize != nil {
+		if name = po.normalize(name); name == "" {
+			return CommandOption{}, fmt.Errorf("option %s %w", optionString(original), ErrEmptyOptionName)
+		}
+	}
+
+	if po.unknownOption != nil && !aliased {
+		if _, ok := po.hasArg[name]; !ok {
+			po.unknownOption(name)
+		}
 	}
 
-	return &CommandOption{Name: name, Value: types.BoolValue{V: true}}
+	return CommandOption{Name: name, Value: types.BoolValue{V: true}}, nil
+}
+
+// expandAbbreviation returns the option registered using
+// ParseOptionAbbreviations that name is a prefix of. If name is itself a
+// registered option, or isn't a prefix of any, it's returned unchanged. If
+// it's a prefix of more than one an error wrapping ErrAmbiguousOption is
+// returned.
+func (po *parseOptions) expandAbbreviation(name string) (string, error) {
+	fold := func(s string) string {
+		if po.caseInsensitive {
+			return strings.ToLower(s)
+		}
+		return s
+	}
+
+	prefix := fold(name)
+	var matches []string
+
+	for known := range po.abbreviations {
+		switch k := fold(known); {
+		case k == prefix:
+			return known, nil
+		case strings.HasPrefix(k, prefix):
+			matches = append(matches, known)
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return name, nil
+	case 1:
+		return matches[0], nil
+	}
+
+	sort.Strings(matches)
+	for i, m := range matches {
+		matches[i] = optionString(m)
+	}
+
+	return "", fmt.Errorf("option %s %w: could be %s",
+		optionString(name), ErrAmbiguousOption, strings.Join(matches, ", "))
+}
+
+// buildOptionWithValue builds an option from the "name" and "value" halves
+// of an --option=value token, inferring the type of the value.
+func buildOptionWithValue(name, value string, long bool, po *parseOptions) (CommandOption, error) {
+	o, err := buildOption(name, long, po)
+	if err != nil {
+		return o, err
+	}
+
+	term, err := po.inferrer.Infer(value)
+	if err != nil {
+		return o, err
+	}
+
+	o.Value = term
+	return o, nil
+}
+
+// splitOptionValue splits an option token (without its leading dashes) of
+// the form "name=value". The name must be non-empty; the value may be empty.
+func splitOptionValue(t string) (name, value string, ok bool) {
+	i := strings.IndexByte(t, '=')
+	if i <= 0 {
+		return "", "", false
+	}
+
+	return t[:i], t[i+1:], true
 }
 
 func dashCount(str string) int {
@@ -257,10 +1329,49 @@ func dashCount(str string) int {
 
 // TokenizeAndParse is a helper function that combines the Tokenize and Parse functions.
 func TokenizeAndParse(str string, options ...ParseOption) (Command, error) {
-	t, err := Tokenize(str)
-	if err != nil {
+	po := &parseOptions{}
+	for _, o := range options {
+		o(po)
+	}
+
+	var cmd Command
+	var err error
+
+	if po.rawTail {
+		cmd, err = tokenizeAndParseRawTail(str, options...)
+	} else {
+		var t []string
+		if t, err = Tokenize(str); err == nil {
+			cmd, err = Parse(t, options...)
+		}
+	}
+
+	if po.keepRaw {
+		cmd.Raw = str
+	}
+
+	return cmd, err
+}
+
+// tokenizeAndParseRawTail tokenizes only the command name, and uses the
+// remainder of str, verbatim, as the Command's RawText.
+func tokenizeAndParseRawTail(str string, options ...ParseOption) (Command, error) {
+	t := NewTokenizer(str)
+	if !t.Scan() {
+		if err := t.Err(); err != nil {
+			return Command{}, err
+		}
+		return Parse([]string{}, options...)
+	}
+	if err := t.Err(); err != nil {
 		return Command{}, err
 	}
 
-	return Parse(t, options...)
+	cmd, err := Parse([]string{t.Token()}, options...)
+	if err != nil {
+		return cmd, err
+	}
+
+	cmd.RawText = t.remainder()
+	return cmd, nil
 }
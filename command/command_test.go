@@ -17,7 +17,10 @@
 package command
 
 import (
+	"strconv"
+	"strings"
 	"testing"
+	"unicode"
 
 	"github.com/stretchr/testify/assert"
 
@@ -31,13 +34,13 @@ func TestCommandParseEmpty(t *testing.T) {
 
 func TestCommandParseDefaults(t *testing.T) {
 	tests := map[string]Command{
-		`foo:curl localhost`:              {`foo`, `curl`, map[string]CommandOption{}, []Value{stringValue("localhost")}},
-		`foo:curl -Ik localhost`:          {`foo`, `curl`, map[string]CommandOption{"I": {"I", BoolValue{V: true}}, "k": {"k", BoolValue{V: true}}}, []Value{stringValue("localhost")}},
-		`foo:curl --ssl localhost`:        {`foo`, `curl`, map[string]CommandOption{"ssl": {"ssl", BoolValue{V: true}}}, []Value{stringValue("localhost")}},
-		`foo:curl -Ik -- --ssl localhost`: {`foo`, `curl`, map[string]CommandOption{"I": {"I", BoolValue{V: true}}, "k": {"k", BoolValue{V: true}}}, []Value{stringValue("--ssl"), stringValue("localhost")}},
-		`bar:echo -n foo bar`:             {`bar`, `echo`, map[string]CommandOption{"n": {"n", BoolValue{V: true}}}, []Value{stringValue("foo"), stringValue("bar")}},
-		`bar:echo -n foo -E bar`:          {`bar`, `echo`, map[string]CommandOption{"n": {"n", BoolValue{V: true}}}, []Value{stringValue("foo"), stringValue("-E"), stringValue("bar")}},
-		`bar:echo -n "foo bar"`:           {`bar`, `echo`, map[string]CommandOption{"n": {"n", BoolValue{V: true}}}, []Value{StringValue{V: "foo bar", Quote: '"'}}},
+		`foo:curl localhost`:              {Bundle: `foo`, Command: `curl`, Options: map[string]CommandOption{}, Parameters: []Value{stringValue("localhost")}},
+		`foo:curl -Ik localhost`:          {Bundle: `foo`, Command: `curl`, Options: map[string]CommandOption{"I": {"I", BoolValue{V: true}}, "k": {"k", BoolValue{V: true}}}, OptionOrder: []string{"I", "k"}, Parameters: []Value{stringValue("localhost")}},
+		`foo:curl --ssl localhost`:        {Bundle: `foo`, Command: `curl`, Options: map[string]CommandOption{"ssl": {"ssl", BoolValue{V: true}}}, OptionOrder: []string{"ssl"}, Parameters: []Value{stringValue("localhost")}},
+		`foo:curl -Ik -- --ssl localhost`: {Bundle: `foo`, Command: `curl`, Options: map[string]CommandOption{"I": {"I", BoolValue{V: true}}, "k": {"k", BoolValue{V: true}}}, OptionOrder: []string{"I", "k"}, Parameters: []Value{stringValue("--ssl"), stringValue("localhost")}},
+		`bar:echo -n foo bar`:             {Bundle: `bar`, Command: `echo`, Options: map[string]CommandOption{"n": {"n", BoolValue{V: true}}}, OptionOrder: []string{"n"}, Parameters: []Value{stringValue("foo"), stringValue("bar")}},
+		`bar:echo -n foo -E bar`:          {Bundle: `bar`, Command: `echo`, Options: map[string]CommandOption{"n": {"n", BoolValue{V: true}}}, OptionOrder: []string{"n"}, Parameters: []Value{stringValue("foo"), stringValue("-E"), stringValue("bar")}},
+		`bar:echo -n "foo bar"`:           {Bundle: `bar`, Command: `echo`, Options: map[string]CommandOption{"n": {"n", BoolValue{V: true}}}, OptionOrder: []string{"n"}, Parameters: []Value{StringValue{V: "foo bar", Quote: '"'}}},
 	}
 
 	for test, expected := range tests {
@@ -54,15 +57,18 @@ func TestCommandParseDefaults(t *testing.T) {
 func TestCommandOptionTypes(t *testing.T) {
 	test := `test --flag --int 10 --float 0.1 --notregex "/^foo$/" --string str this is text`
 
-	expected := Command{"", "test",
-		map[string]CommandOption{
+	expected := Command{
+		Bundle:  "",
+		Command: "test",
+		Options: map[string]CommandOption{
 			"flag":     {"flag", BoolValue{V: true}},
 			"int":      {"int", IntValue{V: 10}},
 			"float":    {"float", FloatValue{V: 0.1}},
 			"notregex": {"notregex", StringValue{V: `/^foo$/`, Quote: '"'}},
 			"string":   {"string", StringValue{V: "str"}},
 		},
-		[]Value{stringValue("this"), stringValue("is"), stringValue("text")},
+		OptionOrder: []string{"flag", "int", "float", "notregex", "string"},
+		Parameters:  []Value{stringValue("this"), stringValue("is"), stringValue("text")},
 	}
 
 	options := []ParseOption{ParseAssumeOptionArguments(true)}
@@ -79,9 +85,11 @@ func TestCommandOptionTypes(t *testing.T) {
 func TestCommandParameterTypes(t *testing.T) {
 	test := `test string 10.0 42 false "foo bar" /^.*$/`
 
-	expected := Command{"", "test",
-		map[string]CommandOption{},
-		[]Value{
+	expected := Command{
+		Bundle:  "",
+		Command: "test",
+		Options: map[string]CommandOption{},
+		Parameters: []Value{
 			StringValue{V: "string", Quote: '\u0000'},
 			FloatValue{V: 10.0},
 			IntValue{V: 42},
@@ -101,13 +109,39 @@ func TestCommandParameterTypes(t *testing.T) {
 	assert.Equal(t, expected, actual, test)
 }
 
+func TestCommandParseQuotedValuesAreStrings(t *testing.T) {
+	test := `test --a "true" --b '42' --c 42 "false" '1.5' "10" 10 true`
+
+	expected := Command{
+		Bundle:  "",
+		Command: "test",
+		Options: map[string]CommandOption{
+			"a": {"a", StringValue{V: "true", Quote: '"'}},
+			"b": {"b", StringValue{V: "42", Quote: '\''}},
+			"c": {"c", IntValue{V: 42}},
+		},
+		OptionOrder: []string{"a", "b", "c"},
+		Parameters: []Value{
+			StringValue{V: "false", Quote: '"'},
+			StringValue{V: "1.5", Quote: '\''},
+			StringValue{V: "10", Quote: '"'},
+			IntValue{V: 10},
+			BoolValue{V: true},
+		},
+	}
+
+	actual, err := TokenizeAndParse(test, ParseAssumeOptionArguments(true))
+	assert.NoError(t, err, test)
+	assert.Equal(t, expected, actual, test)
+}
+
 func TestCommandParseBareFlagsAreTrue(t *testing.T) {
 	tv := BoolValue{V: true}
 
 	tests := map[string]Command{
-		`foo:curl -Ik localhost`: {`foo`, `curl`, map[string]CommandOption{"I": {"I", tv}, "k": {"k", tv}}, []Value{stringValue("localhost")}},
-		`bar:echo -n foo -E bar`: {`bar`, `echo`, map[string]CommandOption{"n": {"n", tv}}, []Value{stringValue("foo"), stringValue("-E"), stringValue("bar")}},
-		`bar:echo -n "foo bar"`:  {`bar`, `echo`, map[string]CommandOption{"n": {"n", tv}}, []Value{StringValue{V: "foo bar", Quote: '"'}}},
+		`foo:curl -Ik localhost`: {Bundle: `foo`, Command: `curl`, Options: map[string]CommandOption{"I": {"I", tv}, "k": {"k", tv}}, OptionOrder: []string{"I", "k"}, Parameters: []Value{stringValue("localhost")}},
+		`bar:echo -n foo -E bar`: {Bundle: `bar`, Command: `echo`, Options: map[string]CommandOption{"n": {"n", tv}}, OptionOrder: []string{"n"}, Parameters: []Value{stringValue("foo"), stringValue("-E"), stringValue("bar")}},
+		`bar:echo -n "foo bar"`:  {Bundle: `bar`, Command: `echo`, Options: map[string]CommandOption{"n": {"n", tv}}, OptionOrder: []string{"n"}, Parameters: []Value{StringValue{V: "foo bar", Quote: '"'}}},
 	}
 
 	for test, expected := range tests {
@@ -123,11 +157,11 @@ func TestCommandParseBareFlagsAreTrue(t *testing.T) {
 
 func TestCommandParseAgnosticDashesTrue(t *testing.T) {
 	tests := map[string]Command{
-		`foo:curl localhost`:              {`foo`, `curl`, map[string]CommandOption{}, []Value{stringValue("localhost")}},
-		`foo:curl -Ik localhost`:          {`foo`, `curl`, map[string]CommandOption{"Ik": {"Ik", BoolValue{V: true}}}, []Value{stringValue("localhost")}},
-		`foo:curl --ssl localhost`:        {`foo`, `curl`, map[string]CommandOption{"ssl": {"ssl", BoolValue{V: true}}}, []Value{stringValue("localhost")}},
-		`foo:curl -Ik --ssl localhost`:    {`foo`, `curl`, map[string]CommandOption{"Ik": {"Ik", BoolValue{V: true}}, "ssl": {"ssl", BoolValue{V: true}}}, []Value{stringValue("localhost")}},
-		`foo:curl -Ik -- --ssl localhost`: {`foo`, `curl`, map[string]CommandOption{"Ik": {"Ik", BoolValue{V: true}}}, []Value{stringValue("--ssl"), stringValue("localhost")}},
+		`foo:curl localhost`:              {Bundle: `foo`, Command: `curl`, Options: map[string]CommandOption{}, Parameters: []Value{stringValue("localhost")}},
+		`foo:curl -Ik localhost`:          {Bundle: `foo`, Command: `curl`, Options: map[string]CommandOption{"Ik": {"Ik", BoolValue{V: true}}}, OptionOrder: []string{"Ik"}, Parameters: []Value{stringValue("localhost")}},
+		`foo:curl --ssl localhost`:        {Bundle: `foo`, Command: `curl`, Options: map[string]CommandOption{"ssl": {"ssl", BoolValue{V: true}}}, OptionOrder: []string{"ssl"}, Parameters: []Value{stringValue("localhost")}},
+		`foo:curl -Ik --ssl localhost`:    {Bundle: `foo`, Command: `curl`, Options: map[string]CommandOption{"Ik": {"Ik", BoolValue{V: true}}, "ssl": {"ssl", BoolValue{V: true}}}, OptionOrder: []string{"Ik", "ssl"}, Parameters: []Value{stringValue("localhost")}},
+		`foo:curl -Ik -- --ssl localhost`: {Bundle: `foo`, Command: `curl`, Options: map[string]CommandOption{"Ik": {"Ik", BoolValue{V: true}}}, OptionOrder: []string{"Ik"}, Parameters: []Value{stringValue("--ssl"), stringValue("localhost")}},
 	}
 
 	options := []ParseOption{ParseAgnosticDashes(true)}
@@ -145,13 +179,13 @@ func TestCommandParseAgnosticDashesTrue(t *testing.T) {
 
 func TestCommandParseAssumeOptionArgumentsTrue(t *testing.T) {
 	tests := map[string]Command{
-		`foo:curl localhost`:              {`foo`, `curl`, map[string]CommandOption{}, []Value{stringValue("localhost")}},
-		`foo:curl -Ik localhost`:          {`foo`, `curl`, map[string]CommandOption{"I": {"I", BoolValue{V: true}}, "k": {"k", StringValue{V: "localhost", Quote: '\u0000'}}}, []Value{}},
-		`foo:curl --ssl localhost`:        {`foo`, `curl`, map[string]CommandOption{"ssl": {"ssl", StringValue{V: "localhost", Quote: '\u0000'}}}, []Value{}},
-		`foo:curl -Ik --ssl localhost`:    {`foo`, `curl`, map[string]CommandOption{"I": {"I", BoolValue{V: true}}, "k": {"k", BoolValue{V: true}}, "ssl": {"ssl", StringValue{V: "localhost", Quote: '\u0000'}}}, []Value{}},
-		`foo:curl -Ik -- --ssl localhost`: {`foo`, `curl`, map[string]CommandOption{"I": {"I", BoolValue{V: true}}, "k": {"k", BoolValue{V: true}}}, []Value{stringValue("--ssl"), stringValue("localhost")}},
-		`bar:echo -n foo bar`:             {`bar`, `echo`, map[string]CommandOption{"n": {"n", StringValue{V: "foo", Quote: '\u0000'}}}, []Value{stringValue("bar")}},
-		`bar:echo -n "foo bar"`:           {`bar`, `echo`, map[string]CommandOption{"n": {"n", StringValue{V: "foo bar", Quote: '"'}}}, []Value{}},
+		`foo:curl localhost`:              {Bundle: `foo`, Command: `curl`, Options: map[string]CommandOption{}, Parameters: []Value{stringValue("localhost")}},
+		`foo:curl -Ik localhost`:          {Bundle: `foo`, Command: `curl`, Options: map[string]CommandOption{"I": {"I", BoolValue{V: true}}, "k": {"k", StringValue{V: "localhost", Quote: '\u0000'}}}, OptionOrder: []string{"I", "k"}, Parameters: []Value{}},
+		`foo:curl --ssl localhost`:        {Bundle: `foo`, Command: `curl`, Options: map[string]CommandOption{"ssl": {"ssl", StringValue{V: "localhost", Quote: '\u0000'}}}, OptionOrder: []string{"ssl"}, Parameters: []Value{}},
+		`foo:curl -Ik --ssl localhost`:    {Bundle: `foo`, Command: `curl`, Options: map[string]CommandOption{"I": {"I", BoolValue{V: true}}, "k": {"k", BoolValue{V: true}}, "ssl": {"ssl", StringValue{V: "localhost", Quote: '\u0000'}}}, OptionOrder: []string{"I", "k", "ssl"}, Parameters: []Value{}},
+		`foo:curl -Ik -- --ssl localhost`: {Bundle: `foo`, Command: `curl`, Options: map[string]CommandOption{"I": {"I", BoolValue{V: true}}, "k": {"k", BoolValue{V: true}}}, OptionOrder: []string{"I", "k"}, Parameters: []Value{stringValue("--ssl"), stringValue("localhost")}},
+		`bar:echo -n foo bar`:             {Bundle: `bar`, Command: `echo`, Options: map[string]CommandOption{"n": {"n", StringValue{V: "foo", Quote: '\u0000'}}}, OptionOrder: []string{"n"}, Parameters: []Value{stringValue("bar")}},
+		`bar:echo -n "foo bar"`:           {Bundle: `bar`, Command: `echo`, Options: map[string]CommandOption{"n": {"n", StringValue{V: "foo bar", Quote: '"'}}}, OptionOrder: []string{"n"}, Parameters: []Value{}},
 	}
 
 	options := []ParseOption{ParseAssumeOptionArguments(true)}
@@ -169,13 +203,13 @@ func TestCommandParseAssumeOptionArgumentsTrue(t *testing.T) {
 
 func TestCommandParseAssumeOptionArgumentsFalse(t *testing.T) {
 	tests := map[string]Command{
-		`foo:curl localhost`:              {`foo`, `curl`, map[string]CommandOption{}, []Value{stringValue("localhost")}},
-		`foo:curl -Ik localhost`:          {`foo`, `curl`, map[string]CommandOption{"I": {"I", BoolValue{V: true}}, "k": {"k", BoolValue{V: true}}}, []Value{stringValue("localhost")}},
-		`foo:curl --ssl localhost`:        {`foo`, `curl`, map[string]CommandOption{"ssl": {"ssl", BoolValue{V: true}}}, []Value{stringValue("localhost")}},
-		`foo:curl -Ik --ssl localhost`:    {`foo`, `curl`, map[string]CommandOption{"I": {"I", BoolValue{V: true}}, "k": {"k", BoolValue{V: true}}, "ssl": {"ssl", BoolValue{V: true}}}, []Value{stringValue("localhost")}},
-		`foo:curl -Ik -- --ssl localhost`: {`foo`, `curl`, map[string]CommandOption{"I": {"I", BoolValue{V: true}}, "k": {"k", BoolValue{V: true}}}, []Value{stringValue("--ssl"), stringValue("localhost")}},
-		`bar:echo -n foo bar`:             {`bar`, `echo`, map[string]CommandOption{"n": {"n", BoolValue{V: true}}}, []Value{stringValue("foo"), stringValue("bar")}},
-		`bar:echo -n "foo bar"`:           {`bar`, `echo`, map[string]CommandOption{"n": {"n", BoolValue{V: true}}}, []Value{StringValue{V: "foo bar", Quote: '"'}}},
+		`foo:curl localhost`:              {Bundle: `foo`, Command: `curl`, Options: map[string]CommandOption{}, Parameters: []Value{stringValue("localhost")}},
+		`foo:curl -Ik localhost`:          {Bundle: `foo`, Command: `curl`, Options: map[string]CommandOption{"I": {"I", BoolValue{V: true}}, "k": {"k", BoolValue{V: true}}}, OptionOrder: []string{"I", "k"}, Parameters: []Value{stringValue("localhost")}},
+		`foo:curl --ssl localhost`:        {Bundle: `foo`, Command: `curl`, Options: map[string]CommandOption{"ssl": {"ssl", BoolValue{V: true}}}, OptionOrder: []string{"ssl"}, Parameters: []Value{stringValue("localhost")}},
+		`foo:curl -Ik --ssl localhost`:    {Bundle: `foo`, Command: `curl`, Options: map[string]CommandOption{"I": {"I", BoolValue{V: true}}, "k": {"k", BoolValue{V: true}}, "ssl": {"ssl", BoolValue{V: true}}}, OptionOrder: []string{"I", "k", "ssl"}, Parameters: []Value{stringValue("localhost")}},
+		`foo:curl -Ik -- --ssl localhost`: {Bundle: `foo`, Command: `curl`, Options: map[string]CommandOption{"I": {"I", BoolValue{V: true}}, "k": {"k", BoolValue{V: true}}}, OptionOrder: []string{"I", "k"}, Parameters: []Value{stringValue("--ssl"), stringValue("localhost")}},
+		`bar:echo -n foo bar`:             {Bundle: `bar`, Command: `echo`, Options: map[string]CommandOption{"n": {"n", BoolValue{V: true}}}, OptionOrder: []string{"n"}, Parameters: []Value{stringValue("foo"), stringValue("bar")}},
+		`bar:echo -n "foo bar"`:           {Bundle: `bar`, Command: `echo`, Options: map[string]CommandOption{"n": {"n", BoolValue{V: true}}}, OptionOrder: []string{"n"}, Parameters: []Value{StringValue{V: "foo bar", Quote: '"'}}},
 	}
 
 	options := []ParseOption{ParseAssumeOptionArguments(false)}
@@ -193,10 +227,10 @@ func TestCommandParseAssumeOptionArgumentsFalse(t *testing.T) {
 
 func TestCommandParseOptionHasArgument(t *testing.T) {
 	tests := map[string]Command{
-		`foo:curl localhost`:                 {`foo`, `curl`, map[string]CommandOption{}, []Value{stringValue("localhost")}},
-		`foo:curl -Ik localhost`:             {`foo`, `curl`, map[string]CommandOption{"I": {"I", BoolValue{V: true}}, "k": {"k", BoolValue{V: true}}}, []Value{stringValue("localhost")}},
-		`foo:curl --ssl localhost`:           {`foo`, `curl`, map[string]CommandOption{"ssl": {"ssl", BoolValue{V: true}}}, []Value{stringValue("localhost")}},
-		`foo:curl -Ik --cert file localhost`: {`foo`, `curl`, map[string]CommandOption{"I": {"I", BoolValue{V: true}}, "k": {"k", BoolValue{V: true}}, "cert": {"cert", StringValue{V: "file", Quote: '\u0000'}}}, []Value{stringValue("localhost")}},
+		`foo:curl localhost`:                 {Bundle: `foo`, Command: `curl`, Options: map[string]CommandOption{}, Parameters: []Value{stringValue("localhost")}},
+		`foo:curl -Ik localhost`:             {Bundle: `foo`, Command: `curl`, Options: map[string]CommandOption{"I": {"I", BoolValue{V: true}}, "k": {"k", BoolValue{V: true}}}, OptionOrder: []string{"I", "k"}, Parameters: []Value{stringValue("localhost")}},
+		`foo:curl --ssl localhost`:           {Bundle: `foo`, Command: `curl`, Options: map[string]CommandOption{"ssl": {"ssl", BoolValue{V: true}}}, OptionOrder: []string{"ssl"}, Parameters: []Value{stringValue("localhost")}},
+		`foo:curl -Ik --cert file localhost`: {Bundle: `foo`, Command: `curl`, Options: map[string]CommandOption{"I": {"I", BoolValue{V: true}}, "k": {"k", BoolValue{V: true}}, "cert": {"cert", StringValue{V: "file", Quote: '\u0000'}}}, OptionOrder: []string{"I", "k", "cert"}, Parameters: []Value{stringValue("localhost")}},
 	}
 
 	options := []ParseOption{
@@ -217,13 +251,164 @@ func TestCommandParseOptionHasArgument(t *testing.T) {
 	}
 }
 
+func TestCommandParseMissingOptionArgument(t *testing.T) {
+	tests := map[string]string{
+		`foo:curl --cert`:       "option --cert expects an argument",
+		`foo:curl -Ik --cert`:   "option --cert expects an argument",
+		`foo:curl --cert -- x`:  "option --cert expects an argument",
+		`foo:curl -o`:           "option -o expects an argument",
+		`foo:curl -Io`:          "option -o expects an argument",
+		`foo:curl --ssl`:        "",
+		`foo:curl --cert file`:  "",
+		`foo:curl -ofile`:       "",
+		`foo:curl --ssl -- --x`: "",
+	}
+
+	for _, assume := range []bool{false, true} {
+		options := []ParseOption{
+			ParseAssumeOptionArguments(assume),
+			ParseOptionHasArgument("cert", true),
+			ParseOptionHasArgument("o", true),
+		}
+
+		for test, expected := range tests {
+			_, err := TokenizeAndParse(test, options...)
+
+			if expected == "" {
+				assert.NoError(t, err, "%s (assume=%v)", test, assume)
+				continue
+			}
+
+			assert.ErrorIs(t, err, ErrMissingOptionArgument, "%s (assume=%v)", test, assume)
+			assert.EqualError(t, err, expected, "%s (assume=%v)", test, assume)
+		}
+	}
+}
+
+func TestCommandParseOptionNArgs(t *testing.T) {
+	point := CommandOption{"point", ListValue{V: []Value{IntValue{V: 1}, IntValue{V: 2}}}}
+
+	type Test struct {
+		Input    string
+		Expected Command
+	}
+
+	tests := []Test{
+		// n=0: the option never takes an argument
+		{`foo:plot --flag x`, Command{Bundle: `foo`, Command: `plot`, Options: map[string]CommandOption{"flag": {"flag", BoolValue{V: true}}}, OptionOrder: []string{"flag"}, Parameters: []Value{stringValue("x")}}},
+
+		// n=1: a single argument, still as a list
+		{`foo:plot --label x y`, Command{Bundle: `foo`, Command: `plot`, Options: map[string]CommandOption{"label": {"label", ListValue{V: []Value{stringValue("x")}}}}, OptionOrder: []string{"label"}, Parameters: []Value{stringValue("y")}}},
+		{`foo:plot --label=x y`, Command{Bundle: `foo`, Command: `plot`, Options: map[string]CommandOption{"label": {"label", ListValue{V: []Value{stringValue("x")}}}}, OptionOrder: []string{"label"}, Parameters: []Value{stringValue("y")}}},
+
+		// n=2
+		{`foo:plot --point 1 2 x`, Command{Bundle: `foo`, Command: `plot`, Options: map[string]CommandOption{"point": point}, OptionOrder: []string{"point"}, Parameters: []Value{stringValue("x")}}},
+		{`foo:plot --point=1 2 x`, Command{Bundle: `foo`, Command: `plot`, Options: map[string]CommandOption{"point": point}, OptionOrder: []string{"point"}, Parameters: []Value{stringValue("x")}}},
+		{`foo:plot -p 1 2 --flag -- x`, Command{Bundle: `foo`, Command: `plot`, Options: map[string]CommandOption{"point": point, "flag": {"flag", BoolValue{V: true}}}, OptionOrder: []string{"point", "flag"}, Parameters: []Value{stringValue("x")}}},
+		{`foo:plot -fp1 2`, Command{Bundle: `foo`, Command: `plot`, Options: map[string]CommandOption{"flag": {"flag", BoolValue{V: true}}, "point": point}, OptionOrder: []string{"flag", "point"}, Parameters: []Value{}}},
+	}
+
+	options := []ParseOption{
+		ParseAssumeOptionArguments(true),
+		ParseOptionAlias("f", "flag"),
+		ParseOptionAlias("p", "point"),
+		ParseOptionNArgs("flag", 0),
+		ParseOptionNArgs("label", 1),
+		ParseOptionNArgs("point", 2),
+	}
+
+	for _, test := range tests {
+		actual, err := TokenizeAndParse(test.Input, options...)
+		assert.NoError(t, err, test.Input)
+		assert.Equal(t, test.Expected, actual, test.Input)
+	}
+
+	// Underflow: the tokens run out, or an option or "--" is found, before
+	// all of the arguments are collected.
+	errors := map[string]string{
+		`foo:plot --point`:          "option --point has too few arguments: expects 2, got 0",
+		`foo:plot --point 1`:        "option --point has too few arguments: expects 2, got 1",
+		`foo:plot --point=1`:        "option --point has too few arguments: expects 2, got 1",
+		`foo:plot --point 1 -- 2`:   "option --point has too few arguments: expects 2, got 1",
+		`foo:plot --point 1 --flag`: "option --point has too few arguments: expects 2, got 1",
+		`foo:plot --point 1 -f 2`:   "option --point has too few arguments: expects 2, got 1",
+		`foo:plot --label`:          "option --label has too few arguments: expects 1, got 0",
+	}
+
+	for input, expected := range errors {
+		_, err := TokenizeAndParse(input, options...)
+		assert.ErrorIs(t, err, ErrTooFewOptionArguments, input)
+		assert.EqualError(t, err, expected, input)
+	}
+}
+
+func TestCommandParseUnknownOption(t *testing.T) {
+	options := []ParseOption{
+		ParseOptionAlias("n", "count"),
+		ParseOptionHasArgument("count", true),
+		ParseOptionHasArgument("v", false),
+	}
+
+	tests := map[string][]string{
+		`foo:cmd`:                      nil,
+		`foo:cmd --count 3 -v`:         nil,
+		`foo:cmd -n 3 x`:               nil,
+		`foo:cmd --bogus -n 3`:         {"bogus"},
+		`foo:cmd -vxy --count 3 --zzz`: {"x", "y", "zzz"},
+	}
+
+	for test, expected := range tests {
+		var unknown []string
+		f := func(name string) { unknown = append(unknown, name) }
+
+		withCallback, err := TokenizeAndParse(test, append(options, ParseUnknownOption(f))...)
+		assert.NoError(t, err, test)
+		assert.Equal(t, expected, unknown, test)
+
+		// The callback doesn't alter parsing
+		without, err := TokenizeAndParse(test, options...)
+		assert.NoError(t, err, test)
+		assert.Equal(t, without, withCallback, test)
+	}
+}
+
+func TestCommandParsePlusOptions(t *testing.T) {
+	tv, fv := BoolValue{V: true}, BoolValue{V: false}
+
+	type Test struct {
+		Plus     bool
+		Input    string
+		Expected Command
+	}
+
+	tests := []Test{
+		{true, `foo:cmd -x`, Command{Bundle: `foo`, Command: `cmd`, Options: map[string]CommandOption{"x": {"x", tv}}, OptionOrder: []string{"x"}, Parameters: []Value{}}},
+		{true, `foo:cmd +x`, Command{Bundle: `foo`, Command: `cmd`, Options: map[string]CommandOption{"x": {"x", fv}}, OptionOrder: []string{"x"}, Parameters: []Value{}}},
+		{true, `foo:cmd +xy p`, Command{Bundle: `foo`, Command: `cmd`, Options: map[string]CommandOption{"x": {"x", fv}, "y": {"y", fv}}, OptionOrder: []string{"x", "y"}, Parameters: []Value{stringValue("p")}}},
+		{true, `foo:cmd +a-b`, Command{Bundle: `foo`, Command: `cmd`, Options: map[string]CommandOption{"a": {"a", fv}, "b": {"b", tv}}, OptionOrder: []string{"a", "b"}, Parameters: []Value{}}},
+		{true, `foo:cmd -a+b`, Command{Bundle: `foo`, Command: `cmd`, Options: map[string]CommandOption{"a": {"a", tv}, "b": {"b", fv}}, OptionOrder: []string{"a", "b"}, Parameters: []Value{}}},
+		{true, `foo:cmd -x +x`, Command{Bundle: `foo`, Command: `cmd`, Options: map[string]CommandOption{"x": {"x", fv}}, OptionOrder: []string{"x"}, Parameters: []Value{}}},
+		{true, `foo:cmd +n p`, Command{Bundle: `foo`, Command: `cmd`, Options: map[string]CommandOption{"n": {"n", fv}}, OptionOrder: []string{"n"}, Parameters: []Value{stringValue("p")}}},
+		{true, `foo:cmd -n p +x`, Command{Bundle: `foo`, Command: `cmd`, Options: map[string]CommandOption{"n": {"n", stringValue("p")}, "x": {"x", fv}}, OptionOrder: []string{"n", "x"}, Parameters: []Value{}}},
+		{true, `foo:cmd + p`, Command{Bundle: `foo`, Command: `cmd`, Options: map[string]CommandOption{}, Parameters: []Value{stringValue("+"), stringValue("p")}}},
+		{false, `foo:cmd +x`, Command{Bundle: `foo`, Command: `cmd`, Options: map[string]CommandOption{}, Parameters: []Value{stringValue("+x")}}},
+		{false, `foo:cmd -a+b`, Command{Bundle: `foo`, Command: `cmd`, Options: map[string]CommandOption{"a": {"a", tv}, "+": {"+", tv}, "b": {"b", tv}}, OptionOrder: []string{"a", "+", "b"}, Parameters: []Value{}}},
+	}
+
+	for _, test := range tests {
+		actual, err := TokenizeAndParse(test.Input, ParsePlusOptions(test.Plus), ParseOptionHasArgument("n", true))
+		assert.NoError(t, err, test.Input)
+		assert.Equal(t, test.Expected, actual, "%s (plus=%v)", test.Input, test.Plus)
+	}
+}
+
 func TestCommandParseOptionAlias(t *testing.T) {
 	tests := map[string]Command{
-		`foo:curl localhost`:                 {`foo`, `curl`, map[string]CommandOption{}, []Value{stringValue("localhost")}},
-		`foo:curl -Ik localhost`:             {`foo`, `curl`, map[string]CommandOption{"I": {"I", BoolValue{V: true}}, "k": {"k", BoolValue{V: true}}}, []Value{stringValue("localhost")}},
-		`foo:curl --ssl localhost`:           {`foo`, `curl`, map[string]CommandOption{"ssl": {"ssl", BoolValue{V: true}}}, []Value{stringValue("localhost")}},
-		`foo:curl -Ik --cert file localhost`: {`foo`, `curl`, map[string]CommandOption{"I": {"I", BoolValue{V: true}}, "k": {"k", BoolValue{V: true}}, "cert": {"cert", StringValue{V: "file", Quote: '\u0000'}}}, []Value{stringValue("localhost")}},
-		`foo:curl -E file localhost`:         {`foo`, `curl`, map[string]CommandOption{"cert": {"cert", StringValue{V: "file", Quote: '\u0000'}}}, []Value{stringValue("localhost")}},
+		`foo:curl localhost`:                 {Bundle: `foo`, Command: `curl`, Options: map[string]CommandOption{}, Parameters: []Value{stringValue("localhost")}},
+		`foo:curl -Ik localhost`:             {Bundle: `foo`, Command: `curl`, Options: map[string]CommandOption{"I": {"I", BoolValue{V: true}}, "k": {"k", BoolValue{V: true}}}, OptionOrder: []string{"I", "k"}, Parameters: []Value{stringValue("localhost")}},
+		`foo:curl --ssl localhost`:           {Bundle: `foo`, Command: `curl`, Options: map[string]CommandOption{"ssl": {"ssl", BoolValue{V: true}}}, OptionOrder: []string{"ssl"}, Parameters: []Value{stringValue("localhost")}},
+		`foo:curl -Ik --cert file localhost`: {Bundle: `foo`, Command: `curl`, Options: map[string]CommandOption{"I": {"I", BoolValue{V: true}}, "k": {"k", BoolValue{V: true}}, "cert": {"cert", StringValue{V: "file", Quote: '\u0000'}}}, OptionOrder: []string{"I", "k", "cert"}, Parameters: []Value{stringValue("localhost")}},
+		`foo:curl -E file localhost`:         {Bundle: `foo`, Command: `curl`, Options: map[string]CommandOption{"cert": {"cert", StringValue{V: "file", Quote: '\u0000'}}}, OptionOrder: []string{"cert"}, Parameters: []Value{stringValue("localhost")}},
 	}
 
 	options := []ParseOption{
@@ -242,6 +427,901 @@ func TestCommandParseOptionAlias(t *testing.T) {
 	}
 }
 
+func TestCommandParseCommandAlias(t *testing.T) {
+	type Expected struct {
+		Bundle, Command string
+	}
+
+	tests := map[string]Expected{
+		`deploy web`:      {`ops`, `deploy`},
+		`ops:deploy web`:  {`ops`, `deploy`},
+		`st`:              {``, `status`}, // Aliases aren't resolved recursively
+		`status`:          {`ops`, `status`},
+		`ops:st`:          {`ops`, `st`},
+		`foo:bar web`:     {`foo`, `bar`},
+		`bar --force web`: {``, `bar`},
+		`Deploy`:          {``, `Deploy`},
+	}
+
+	options := []ParseOption{
+		ParseCommandAlias("deploy", "ops:deploy"),
+		ParseCommandAlias("st", "status"),
+		ParseCommandAlias("status", "ops:status"),
+	}
+
+	for input, expected := range tests {
+		cmd, err := TokenizeAndParse(input, options...)
+		if !assert.NoError(t, err, input) {
+			continue
+		}
+
+		assert.Equal(t, expected.Bundle, cmd.Bundle, input)
+		assert.Equal(t, expected.Command, cmd.Command, input)
+	}
+
+	// The rest of the command is parsed as usual.
+	cmd, err := TokenizeAndParse(`deploy --env prod web`, append(options, ParseOptionHasArgument("env", true))...)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]CommandOption{"env": {"env", stringValue("prod")}}, cmd.Options)
+	assert.Equal(t, CommandParameters{stringValue("web")}, cmd.Parameters)
+
+	// An alias may be given to ParseRawTail commands too.
+	cmd, err = TokenizeAndParse(`deploy  web  now`, append(options, ParseRawTail(true))...)
+	assert.NoError(t, err)
+	assert.Equal(t, `ops`, cmd.Bundle)
+	assert.Equal(t, `web  now`, cmd.RawText)
+
+	// An invalid target is an error.
+	_, err = TokenizeAndParse(`x`, ParseCommandAlias("x", "a:b:c"))
+	assert.ErrorIs(t, err, ErrInvalidBundleCommandPair)
+}
+
+func TestCommandParseBundledShortOptions(t *testing.T) {
+	tv := BoolValue{V: true}
+
+	tests := map[string]Command{
+		`foo:tar -xv archive`:     {Bundle: `foo`, Command: `tar`, Options: map[string]CommandOption{"x": {"x", tv}, "v": {"v", tv}}, OptionOrder: []string{"x", "v"}, Parameters: []Value{stringValue("archive")}},
+		`foo:tar -xf archive`:     {Bundle: `foo`, Command: `tar`, Options: map[string]CommandOption{"x": {"x", tv}, "f": {"f", stringValue("archive")}}, OptionOrder: []string{"x", "f"}, Parameters: []Value{}},
+		`foo:tar -xfarchive file`: {Bundle: `foo`, Command: `tar`, Options: map[string]CommandOption{"x": {"x", tv}, "f": {"f", stringValue("archive")}}, OptionOrder: []string{"x", "f"}, Parameters: []Value{stringValue("file")}},
+		`foo:tar -fxv archive`:    {Bundle: `foo`, Command: `tar`, Options: map[string]CommandOption{"f": {"f", stringValue("xv")}}, OptionOrder: []string{"f"}, Parameters: []Value{stringValue("archive")}},
+		`foo:tar -xf10 archive`:   {Bundle: `foo`, Command: `tar`, Options: map[string]CommandOption{"x": {"x", tv}, "f": {"f", IntValue{V: 10}}}, OptionOrder: []string{"x", "f"}, Parameters: []Value{stringValue("archive")}},
+		`foo:tar -x -f archive`:   {Bundle: `foo`, Command: `tar`, Options: map[string]CommandOption{"x": {"x", tv}, "f": {"f", stringValue("archive")}}, OptionOrder: []string{"x", "f"}, Parameters: []Value{}},
+	}
+
+	options := []ParseOption{
+		ParseOptionHasArgument("f", true),
+		ParseAssumeOptionArguments(false),
+	}
+
+	for test, expected := range tests {
+		tokens, err := Tokenize(test)
+		assert.NoError(t, err, test)
+
+		actual, err := Parse(tokens, options...)
+		assert.NoError(t, err, test)
+
+		assert.Equal(t, expected, actual, test)
+	}
+}
+
+func TestCommandParseCaseInsensitiveOptions(t *testing.T) {
+	tv := BoolValue{V: true}
+
+	type Test struct {
+		Insensitive bool
+		Input       string
+		Expected    Command
+	}
+
+	tests := []Test{
+		{false, `foo:cmd --Verbose --VERBOSE --verbose`, Command{Bundle: `foo`, Command: `cmd`, Options: map[string]CommandOption{"Verbose": {"Verbose", tv}, "VERBOSE": {"VERBOSE", tv}, "verbose": {"verbose", tv}}, OptionOrder: []string{"Verbose", "VERBOSE", "verbose"}, Parameters: []Value{}}},
+		{true, `foo:cmd --Verbose --VERBOSE --verbose`, Command{Bundle: `foo`, Command: `cmd`, Options: map[string]CommandOption{"verbose": {"verbose", tv}}, OptionOrder: []string{"verbose"}, Parameters: []Value{}}},
+		{true, `foo:cmd -vV`, Command{Bundle: `foo`, Command: `cmd`, Options: map[string]CommandOption{"v": {"v", tv}}, OptionOrder: []string{"v"}, Parameters: []Value{}}},
+		{true, `foo:cmd --NAME bob --Name alice x`, Command{Bundle: `foo`, Command: `cmd`, Options: map[string]CommandOption{"name": {"name", stringValue("alice")}}, OptionOrder: []string{"name"}, Parameters: []Value{stringValue("x")}}},
+		{true, `foo:cmd -N bob`, Command{Bundle: `foo`, Command: `cmd`, Options: map[string]CommandOption{"name": {"name", stringValue("bob")}}, OptionOrder: []string{"name"}, Parameters: []Value{}}},
+	}
+
+	for _, test := range tests {
+		actual, err := TokenizeAndParse(test.Input,
+			ParseCaseInsensitiveOptions(test.Insensitive),
+			ParseOptionAlias("N", "Name"),
+			ParseOptionHasArgument("name", true))
+		assert.NoError(t, err, test.Input)
+		assert.Equal(t, test.Expected, actual, "%s (insensitive=%v)", test.Input, test.Insensitive)
+		assert.Len(t, actual.OptionsValues(), len(test.Expected.Options), test.Input)
+	}
+}
+
+func TestCommandParseOptionNormalizer(t *testing.T) {
+	tv := BoolValue{V: true}
+
+	// kebab converts snake_case and camelCase option names to kebab-case.
+	kebab := func(name string) string {
+		var b strings.Builder
+		for i, r := range name {
+			switch {
+			case r == '_':
+				b.WriteRune('-')
+			case unicode.IsUpper(r):
+				if i > 0 {
+					b.WriteRune('-')
+				}
+				b.WriteRune(unicode.ToLower(r))
+			default:
+				b.WriteRune(r)
+			}
+		}
+		return b.String()
+	}
+
+	tests := map[string]Command{
+		`foo:cmd --dry_run`:                    {Bundle: `foo`, Command: `cmd`, Options: map[string]CommandOption{"dry-run": {"dry-run", tv}}, OptionOrder: []string{"dry-run"}, Parameters: []Value{}},
+		`foo:cmd --dry_run --dryRun --dry-run`: {Bundle: `foo`, Command: `cmd`, Options: map[string]CommandOption{"dry-run": {"dry-run", tv}}, OptionOrder: []string{"dry-run"}, Parameters: []Value{}},
+		`foo:cmd --max_count 3 --maxCount=5 x`: {Bundle: `foo`, Command: `cmd`, Options: map[string]CommandOption{"max-count": {"max-count", IntValue{V: 5}}}, OptionOrder: []string{"max-count"}, Parameters: []Value{stringValue("x")}},
+		`foo:cmd -n 7`:                         {Bundle: `foo`, Command: `cmd`, Options: map[string]CommandOption{"max-count": {"max-count", IntValue{V: 7}}}, OptionOrder: []string{"max-count"}, Parameters: []Value{}},
+	}
+
+	for input, expected := range tests {
+		actual, err := TokenizeAndParse(input,
+			ParseOptionNormalizer(kebab),
+			ParseOptionAlias("n", "max_count"),
+			ParseOptionHasArgument("max-count", true))
+		if !assert.NoError(t, err, input) {
+			continue
+		}
+
+		assert.Equal(t, expected, actual, input)
+
+		// OptionsValues reports the normalized names.
+		for name := range actual.OptionsValues() {
+			assert.Equal(t, kebab(name), name, input)
+		}
+	}
+
+	// A normalizer that returns an empty name is an error.
+	drop := func(name string) string {
+		if name == "x" {
+			return ""
+		}
+		return name
+	}
+
+	for _, input := range []string{`foo:cmd -x`, `foo:cmd -ax`, `foo:cmd --x=1`} {
+		_, err := TokenizeAndParse(input, ParseOptionNormalizer(drop))
+		if assert.ErrorIs(t, err, ErrEmptyOptionName, input) {
+			assert.Equal(t, "option -x normalizes to an empty name", err.Error(), input)
+		}
+	}
+
+	// Parameters aren't normalized.
+	_, err := TokenizeAndParse(`foo:cmd -- -x`, ParseOptionNormalizer(drop))
+	assert.NoError(t, err)
+}
+
+func TestCommandParseOptionsFirst(t *testing.T) {
+	tv := BoolValue{V: true}
+
+	type Test struct {
+		First    bool
+		Input    string
+		Expected Command
+	}
+
+	tests := []Test{
+		{true, `foo:cmd a --b`, Command{Bundle: `foo`, Command: `cmd`, Options: map[string]CommandOption{}, Parameters: []Value{stringValue("a"), stringValue("--b")}}},
+		{false, `foo:cmd a --b`, Command{Bundle: `foo`, Command: `cmd`, Options: map[string]CommandOption{"b": {"b", tv}}, OptionOrder: []string{"b"}, Parameters: []Value{stringValue("a")}}},
+		{true, `foo:cmd -x a -y b`, Command{Bundle: `foo`, Command: `cmd`, Options: map[string]CommandOption{"x": {"x", tv}}, OptionOrder: []string{"x"}, Parameters: []Value{stringValue("a"), stringValue("-y"), stringValue("b")}}},
+		{false, `foo:cmd -x a -y b`, Command{Bundle: `foo`, Command: `cmd`, Options: map[string]CommandOption{"x": {"x", tv}, "y": {"y", tv}}, OptionOrder: []string{"x", "y"}, Parameters: []Value{stringValue("a"), stringValue("b")}}},
+		{false, `foo:cmd a --name bob b`, Command{Bundle: `foo`, Command: `cmd`, Options: map[string]CommandOption{"name": {"name", stringValue("bob")}}, OptionOrder: []string{"name"}, Parameters: []Value{stringValue("a"), stringValue("b")}}},
+		{false, `foo:cmd a -- --b c`, Command{Bundle: `foo`, Command: `cmd`, Options: map[string]CommandOption{}, Parameters: []Value{stringValue("a"), stringValue("--b"), stringValue("c")}}},
+	}
+
+	for _, test := range tests {
+		actual, err := TokenizeAndParse(test.Input, ParseOptionsFirst(test.First), ParseOptionHasArgument("name", true))
+		assert.NoError(t, err, test.Input)
+		assert.Equal(t, test.Expected, actual, "%s (first=%v)", test.Input, test.First)
+	}
+}
+
+func TestCommandParseSubcommands(t *testing.T) {
+	tv := BoolValue{V: true}
+
+	type Test struct {
+		Depth    int
+		Input    string
+		Expected Command
+	}
+
+	tests := []Test{
+		{0, `git:remote add origin`, Command{Bundle: `git`, Command: `remote`, Options: map[string]CommandOption{}, Parameters: []Value{stringValue("add"), stringValue("origin")}}},
+		{1, `git:remote add origin`, Command{Bundle: `git`, Command: `remote`, Subcommands: []string{"add"}, Options: map[string]CommandOption{}, Parameters: []Value{stringValue("origin")}}},
+		{2, `git:remote add origin`, Command{Bundle: `git`, Command: `remote`, Subcommands: []string{"add", "origin"}, Options: map[string]CommandOption{}, Parameters: []Value{}}},
+		{3, `git:remote add origin`, Command{Bundle: `git`, Command: `remote`, Subcommands: []string{"add", "origin"}, Options: map[string]CommandOption{}, Parameters: []Value{}}},
+		{1, `git:remote add -f origin`, Command{Bundle: `git`, Command: `remote`, Subcommands: []string{"add"}, Options: map[string]CommandOption{"f": {"f", tv}}, OptionOrder: []string{"f"}, Parameters: []Value{stringValue("origin")}}},
+		{2, `git:remote add -f origin`, Command{Bundle: `git`, Command: `remote`, Subcommands: []string{"add"}, Options: map[string]CommandOption{"f": {"f", tv}}, OptionOrder: []string{"f"}, Parameters: []Value{stringValue("origin")}}},
+		{1, `git:remote -v`, Command{Bundle: `git`, Command: `remote`, Options: map[string]CommandOption{"v": {"v", tv}}, OptionOrder: []string{"v"}, Parameters: []Value{}}},
+		{1, `git:remote -- add`, Command{Bundle: `git`, Command: `remote`, Options: map[string]CommandOption{}, Parameters: []Value{stringValue("add")}}},
+	}
+
+	for _, test := range tests {
+		actual, err := TokenizeAndParse(test.Input, ParseSubcommands(test.Depth))
+		assert.NoError(t, err, test.Input)
+		assert.Equal(t, test.Expected, actual, "%s (depth=%d)", test.Input, test.Depth)
+	}
+}
+
+func TestCommandParseNamedParameters(t *testing.T) {
+	type Test struct {
+		Named    bool
+		Input    string
+		Expected Command
+	}
+
+	tests := []Test{
+		{false, `foo:set a=1 b`, Command{Bundle: `foo`, Command: `set`, Options: map[string]CommandOption{}, Parameters: []Value{stringValue("a=1"), stringValue("b")}}},
+		{true, `foo:set a=1 b`, Command{Bundle: `foo`, Command: `set`, Options: map[string]CommandOption{}, Parameters: []Value{stringValue("b")}, NamedParameters: map[string]Value{"a": IntValue{V: 1}}}},
+		{true, `foo:set x a=1 y b=true c="x=y" z`, Command{Bundle: `foo`, Command: `set`, Options: map[string]CommandOption{}, Parameters: []Value{stringValue("x"), stringValue("y"), stringValue("z")}, NamedParameters: map[string]Value{"a": IntValue{V: 1}, "b": BoolValue{V: true}, "c": StringValue{V: "x=y", Quote: '"'}}}},
+		{true, `foo:set a=1 a=2`, Command{Bundle: `foo`, Command: `set`, Options: map[string]CommandOption{}, Parameters: []Value{}, NamedParameters: map[string]Value{"a": IntValue{V: 2}}}},
+		{true, `foo:set a= =b "c=d"`, Command{Bundle: `foo`, Command: `set`, Options: map[string]CommandOption{}, Parameters: []Value{stringValue("=b"), StringValue{V: "c=d", Quote: '"'}}, NamedParameters: map[string]Value{"a": stringValue("")}}},
+		{true, `foo:set --v -- b=2`, Command{Bundle: `foo`, Command: `set`, Options: map[string]CommandOption{"v": {"v", BoolValue{V: true}}}, OptionOrder: []string{"v"}, Parameters: []Value{stringValue("b=2")}}},
+	}
+
+	for _, test := range tests {
+		actual, err := TokenizeAndParse(test.Input, ParseNamedParameters(test.Named))
+		assert.NoError(t, err, test.Input)
+		assert.Equal(t, test.Expected, actual, "%s (named=%v)", test.Input, test.Named)
+
+		// Named parameters work the same way when options and parameters
+		// are interleaved.
+		actual, err = TokenizeAndParse(test.Input, ParseNamedParameters(test.Named), ParseOptionsFirst(false))
+		assert.NoError(t, err, test.Input)
+		assert.Equal(t, test.Expected, actual, "%s (named=%v)", test.Input, test.Named)
+	}
+}
+
+func TestCommandParseComplexTypes(t *testing.T) {
+	list := ListValue{V: []Value{UnknownValue{V: "a"}, UnknownValue{V: "b"}, UnknownValue{V: "c"}}}
+	meta := MapValue{V: map[string]Value{"k": UnknownValue{V: "v"}, "n": IntValue{V: 1}}}
+
+	type Test struct {
+		Complex  bool
+		Input    string
+		Expected Command
+	}
+
+	tests := []Test{
+		{false, `foo:cmd --filter=[a,b,c]`, Command{Bundle: `foo`, Command: `cmd`, Options: map[string]CommandOption{"filter": {"filter", stringValue("[a,b,c]")}}, OptionOrder: []string{"filter"}, Parameters: []Value{}}},
+		{true, `foo:cmd --filter=[a,b,c]`, Command{Bundle: `foo`, Command: `cmd`, Options: map[string]CommandOption{"filter": {"filter", list}}, OptionOrder: []string{"filter"}, Parameters: []Value{}}},
+		{false, `foo:cmd --meta={k:v,n:1}`, Command{Bundle: `foo`, Command: `cmd`, Options: map[string]CommandOption{"meta": {"meta", stringValue("{k:v,n:1}")}}, OptionOrder: []string{"meta"}, Parameters: []Value{}}},
+		{true, `foo:cmd --meta={k:v,n:1}`, Command{Bundle: `foo`, Command: `cmd`, Options: map[string]CommandOption{"meta": {"meta", meta}}, OptionOrder: []string{"meta"}, Parameters: []Value{}}},
+		{true, `foo:cmd --meta={k:v,n:1} [a,b,c]`, Command{Bundle: `foo`, Command: `cmd`, Options: map[string]CommandOption{"meta": {"meta", meta}}, OptionOrder: []string{"meta"}, Parameters: []Value{list}}},
+		{true, `foo:cmd --n=1 --s= bar`, Command{Bundle: `foo`, Command: `cmd`, Options: map[string]CommandOption{"n": {"n", IntValue{V: 1}}, "s": {"s", stringValue("")}}, OptionOrder: []string{"n", "s"}, Parameters: []Value{stringValue("bar")}}},
+	}
+
+	for _, test := range tests {
+		actual, err := TokenizeAndParse(test.Input, ParseComplexTypes(test.Complex))
+		assert.NoError(t, err, test.Input)
+		assert.Equal(t, test.Expected, actual, "%s (complex=%v)", test.Input, test.Complex)
+	}
+
+	_, err := TokenizeAndParse(`foo:cmd --filter=[a,b`, ParseComplexTypes(true))
+	assert.ErrorIs(t, err, ErrUnbalancedBrackets)
+
+	_, err = TokenizeAndParse(`foo:cmd `+strings.Repeat("[", 9)+strings.Repeat("]", 9), ParseComplexTypes(true))
+	assert.ErrorIs(t, err, ErrMaxDepthExceeded)
+}
+
+func TestCommandParseRawTail(t *testing.T) {
+	tests := map[string]string{
+		`foo:search`:                            ``,
+		`foo:search   `:                         ``,
+		`foo:search --limit 5 what's   "this"?`: `--limit 5 what's   "this"?`,
+		`foo:search -- a\tb  c`:                 `-- a\tb  c`,
+		"foo:search\tx\ny":                      "x\ny",
+	}
+
+	for input, expected := range tests {
+		cmd, err := TokenizeAndParse(input, ParseRawTail(true), ParseNamedParameters(true))
+		if !assert.NoError(t, err, input) {
+			continue
+		}
+
+		assert.Equal(t, Command{Bundle: `foo`, Command: `search`, Options: map[string]CommandOption{}, Parameters: []Value{}, RawText: expected}, cmd, input)
+	}
+
+	// Parse joins the tokens with single spaces.
+	cmd, err := Parse([]string{"foo:search", "--limit", "5", "a b", "-x"}, ParseRawTail(true))
+	assert.NoError(t, err)
+	assert.Equal(t, "--limit 5 a b -x", cmd.RawText)
+	assert.Empty(t, cmd.Options)
+	assert.Empty(t, cmd.Parameters)
+
+	// Without ParseRawTail, RawText is empty.
+	cmd, err = TokenizeAndParse(`foo:search --limit 5 query`)
+	assert.NoError(t, err)
+	assert.Equal(t, "", cmd.RawText)
+
+	// The command name is still validated.
+	_, err = TokenizeAndParse(`a:b:c text`, ParseRawTail(true))
+	assert.Error(t, err)
+	_, err = TokenizeAndParse(`  `, ParseRawTail(true))
+	assert.Error(t, err)
+}
+
+func TestCommandParseNoOptions(t *testing.T) {
+	tests := map[string][]Value{
+		`foo:rm`:                    {},
+		`foo:rm a b`:                {stringValue("a"), stringValue("b")},
+		`foo:rm -f --force`:         {stringValue("-f"), stringValue("--force")},
+		`foo:rm -- -rf`:             {stringValue("--"), stringValue("-rf")},
+		`foo:rm a --name=x -1 "-q"`: {stringValue("a"), stringValue("--name=x"), IntValue{V: -1}, StringValue{V: "-q", Quote: '"'}},
+	}
+
+	for input, expected := range tests {
+		cmd, err := TokenizeAndParse(input, ParseNoOptions(true), ParseOptionHasArgument("f", true))
+		if !assert.NoError(t, err, input) {
+			continue
+		}
+
+		assert.Equal(t, Command{Bundle: `foo`, Command: `rm`, Options: map[string]CommandOption{}, Parameters: expected}, cmd, input)
+	}
+
+	// Named parameters and parameter limits still apply.
+	cmd, err := TokenizeAndParse(`foo:set -a b=1`, ParseNoOptions(true), ParseNamedParameters(true))
+	assert.NoError(t, err)
+	assert.Equal(t, CommandParameters{stringValue("-a")}, cmd.Parameters)
+	assert.Equal(t, map[string]Value{"b": IntValue{V: 1}}, cmd.NamedParameters)
+
+	_, err = TokenizeAndParse(`foo:rm -a -b -c`, ParseNoOptions(true), ParseMaxParameters(2))
+	assert.ErrorIs(t, err, ErrTooManyParameters)
+
+	// Without ParseNoOptions, dashes introduce options.
+	cmd, err = TokenizeAndParse(`foo:rm -f`)
+	assert.NoError(t, err)
+	assert.Empty(t, cmd.Parameters)
+	assert.Contains(t, cmd.Options, "f")
+}
+
+func TestCommandParseRawRemainder(t *testing.T) {
+	tests := map[string][]string{
+		`foo:run`:     nil,
+		`foo:run a b`: nil,
+		`foo:run --`:  {},
+		`foo:run -v -- sub:cmd --flag=1 "x y" [a]`: {`sub:cmd`, `--flag=1`, `"x y"`, `[a]`},
+		`foo:run a -- -- 10 true`:                  nil,
+	}
+
+	for input, expected := range tests {
+		tokens, err := Tokenize(input)
+		if !assert.NoError(t, err, input) {
+			continue
+		}
+
+		cmd, err := Parse(tokens, ParseRawRemainder(true))
+		if !assert.NoError(t, err, input) {
+			continue
+		}
+
+		assert.Equal(t, expected, cmd.Remainder, input)
+
+		// The remainder is the original tokens after the first "--".
+		for i, tok := range tokens {
+			if tok == "--" && cmd.Remainder != nil {
+				assert.Equal(t, tokens[i+1:], cmd.Remainder, input)
+				break
+			}
+		}
+	}
+
+	// The remainder isn't inferred as parameters...
+	cmd, err := TokenizeAndParse(`foo:run -v -- sub:cmd 10 "x y"`, ParseRawRemainder(true))
+	assert.NoError(t, err)
+	assert.Equal(t, CommandParameters{}, cmd.Parameters)
+	assert.Equal(t, []string{`sub:cmd`, `10`, `"x y"`}, cmd.Remainder)
+
+	// ...so it doesn't count towards the maximum number of parameters...
+	_, err = TokenizeAndParse(`foo:run a -- b c`, ParseRawRemainder(true), ParseMaxParameters(1), ParseOptionsFirst(false))
+	assert.NoError(t, err)
+
+	// ...unless ParseRawRemainder isn't set.
+	cmd, err = TokenizeAndParse(`foo:run -v -- sub:cmd 10 "x y"`)
+	assert.NoError(t, err)
+	assert.Equal(t, CommandParameters{stringValue("sub:cmd"), IntValue{V: 10}, StringValue{V: "x y", Quote: '"'}}, cmd.Parameters)
+	assert.Nil(t, cmd.Remainder)
+
+	// An option still waiting for its argument is an error.
+	_, err = TokenizeAndParse(`foo:run --name -- x`, ParseRawRemainder(true), ParseOptionHasArgument("name", true))
+	assert.ErrorIs(t, err, ErrMissingOptionArgument)
+}
+
+func TestCommandParseRawParameters(t *testing.T) {
+	tests := map[string]CommandParameters{
+		`foo:cmd`:                    {},
+		`foo:cmd a b`:                {stringValue("a"), stringValue("b")},
+		`foo:cmd 42 1.5 true`:        {stringValue("42"), stringValue("1.5"), stringValue("true")},
+		`foo:cmd "baz qux" 'x'`:      {stringValue(`"baz qux"`), stringValue(`'x'`)},
+		`foo:cmd -v -- --not-an-opt`: {stringValue("--not-an-opt")},
+		`foo:cmd [a,b] {k:v}`:        {stringValue("[a,b]"), stringValue("{k:v}")},
+	}
+
+	for input, expected := range tests {
+		cmd, err := TokenizeAndParse(input, ParseRawParameters(true), ParseComplexTypes(true))
+		if !assert.NoError(t, err, input) {
+			continue
+		}
+
+		assert.Equal(t, expected, cmd.Parameters, input)
+	}
+
+	// Option values are still inferred.
+	cmd, err := TokenizeAndParse(`foo:cmd --n=1 -m 2 3`, ParseRawParameters(true), ParseOptionHasArgument("m", true))
+	assert.NoError(t, err)
+	assert.Equal(t, IntValue{V: 1}, cmd.Options["n"].Value)
+	assert.Equal(t, IntValue{V: 2}, cmd.Options["m"].Value)
+	assert.Equal(t, CommandParameters{stringValue("3")}, cmd.Parameters)
+
+	// Interleaved parameters, and the values of named parameters, aren't.
+	cmd, err = TokenizeAndParse(`foo:cmd 1 -v n=2 3`, ParseRawParameters(true), ParseOptionsFirst(false), ParseNamedParameters(true))
+	assert.NoError(t, err)
+	assert.Equal(t, CommandParameters{stringValue("1"), stringValue("3")}, cmd.Parameters)
+	assert.Equal(t, map[string]Value{"n": stringValue("2")}, cmd.NamedParameters)
+
+	// Without ParseRawParameters, the types are inferred.
+	cmd, err = TokenizeAndParse(`foo:cmd 42 "baz qux"`)
+	assert.NoError(t, err)
+	assert.Equal(t, CommandParameters{IntValue{V: 42}, StringValue{V: "baz qux", Quote: '"'}}, cmd.Parameters)
+}
+
+func TestCommandParseTerminators(t *testing.T) {
+	type Test struct {
+		Input   string
+		Options []string
+		Default []string
+		Keep    []string
+	}
+
+	tests := []Test{
+		{`foo:run -a -- b`, []string{"a"}, []string{"b"}, []string{"--", "b"}},
+		{`foo:run -a -- -b -- c`, []string{"a"}, []string{"-b", "--", "c"}, []string{"--", "-b", "--", "c"}},
+		{`foo:run -- --`, nil, []string{"--"}, []string{"--", "--"}},
+		{`foo:run -a -- -- -b`, []string{"a"}, []string{"--", "-b"}, []string{"--", "--", "-b"}},
+		{`foo:run -a --`, []string{"a"}, nil, []string{"--"}},
+		{`foo:run -a b`, []string{"a"}, []string{"b"}, []string{"b"}},
+	}
+
+	strs := func(params CommandParameters) []string {
+		var s []string
+		for _, p := range params {
+			s = append(s, p.String())
+		}
+		return s
+	}
+
+	for _, first := range []bool{true, false} {
+		for _, test := range tests {
+			for _, keep := range []bool{false, true} {
+				cmd, err := TokenizeAndParse(test.Input, ParseKeepTerminator(keep), ParseOptionsFirst(first))
+				if !assert.NoError(t, err, "%s (keep=%v, first=%v)", test.Input, keep, first) {
+					continue
+				}
+
+				expected := test.Default
+				if keep {
+					expected = test.Keep
+				}
+
+				assert.Equal(t, test.Options, cmd.OptionOrder, "%s (keep=%v, first=%v)", test.Input, keep, first)
+				assert.Equal(t, expected, strs(cmd.Parameters), "%s (keep=%v, first=%v)", test.Input, keep, first)
+			}
+		}
+	}
+
+	// After a parameter, "--" is always a parameter if options come first...
+	cmd, err := TokenizeAndParse(`foo:run a -- b`, ParseKeepTerminator(false))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a", "--", "b"}, strs(cmd.Parameters))
+
+	// ...but still ends the options if they don't.
+	cmd, err = TokenizeAndParse(`foo:run a -- b`, ParseOptionsFirst(false))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a", "b"}, strs(cmd.Parameters))
+
+	// A kept terminator counts as a parameter.
+	_, err = TokenizeAndParse(`foo:run -- b`, ParseKeepTerminator(true), ParseMaxParameters(1))
+	assert.ErrorIs(t, err, ErrTooManyParameters)
+	_, err = TokenizeAndParse(`foo:run -- b`, ParseMaxParameters(1))
+	assert.NoError(t, err)
+
+	// The raw remainder never includes the first terminator.
+	cmd, err = TokenizeAndParse(`foo:run -- b -- c`, ParseKeepTerminator(true), ParseRawRemainder(true))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"b", "--", "c"}, cmd.Remainder)
+	assert.Empty(t, cmd.Parameters)
+}
+
+func TestCommandParseOptionAbbreviations(t *testing.T) {
+	known := ParseOptionAbbreviations("verbose", "verbatim", "version", "force")
+
+	type Test struct {
+		Input    string
+		Expected []string
+	}
+
+	tests := []Test{
+		{`foo:run --verbo`, []string{"verbose"}},
+		{`foo:run --verbose`, []string{"verbose"}},
+		{`foo:run --verba --f`, []string{"verbatim", "force"}},
+		{`foo:run --vers=2`, []string{"version"}},
+		{`foo:run --other`, []string{"other"}},
+		{`foo:run --forced`, []string{"forced"}},
+		{`foo:run -vf`, []string{"v", "f"}},
+	}
+
+	for _, test := range tests {
+		cmd, err := TokenizeAndParse(test.Input, known)
+		if assert.NoError(t, err, test.Input) {
+			assert.Equal(t, test.Expected, cmd.OptionOrder, test.Input)
+		}
+	}
+
+	// The value of an abbreviated --option=value is kept.
+	cmd, err := TokenizeAndParse(`foo:run --vers=2`, known)
+	assert.NoError(t, err)
+	assert.Equal(t, "2", cmd.Options["version"].Value.String())
+
+	// An exact match wins over a longer option that it's a prefix of.
+	cmd, err = TokenizeAndParse(`foo:run --verb`, ParseOptionAbbreviations("verb", "verbose"))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"verb"}, cmd.OptionOrder)
+
+	// A prefix of more than one option is ambiguous.
+	for _, in := range []string{`foo:run --verb`, `foo:run --v`, `foo:run --ver=1`} {
+		_, err = TokenizeAndParse(in, known)
+		assert.ErrorIs(t, err, ErrAmbiguousOption, in)
+	}
+
+	_, err = TokenizeAndParse(`foo:run --verb`, known)
+	assert.EqualError(t, err, "option --verb is ambiguous: could be --verbatim, --verbose")
+
+	// Aliases are never expanded.
+	cmd, err = TokenizeAndParse(`foo:run --verb`, known, ParseOptionAlias("verb", "force"))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"force"}, cmd.OptionOrder)
+
+	// Abbreviations apply to single-dash long options, and fold case if
+	// option names are case-insensitive.
+	cmd, err = TokenizeAndParse(`foo:run -verbo`, known, ParseAgnosticDashes(true))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"verbose"}, cmd.OptionOrder)
+
+	cmd, err = TokenizeAndParse(`foo:run --VERBO`, known, ParseCaseInsensitiveOptions(true))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"verbose"}, cmd.OptionOrder)
+
+	// Without ParseOptionAbbreviations nothing is expanded.
+	cmd, err = TokenizeAndParse(`foo:run --verb`)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"verb"}, cmd.OptionOrder)
+}
+
+func TestCommandParseOptionTerminator(t *testing.T) {
+	type Test struct {
+		Input      string
+		Options    []string
+		Parameters []string
+	}
+
+	tests := []Test{
+		{`foo:run -a :: -b`, []string{"a"}, []string{"-b"}},
+		{`foo:run -a :: -b :: c`, []string{"a"}, []string{"-b", "::", "c"}},
+		{`foo:run -a -- :: -b`, []string{"a"}, []string{"--", "::", "-b"}},
+		{`foo:run -a :: -- -b`, []string{"a"}, []string{"--", "-b"}},
+		{`foo:run -- -a`, nil, []string{"--", "-a"}},
+		{`foo:run -a ::`, []string{"a"}, nil},
+	}
+
+	strs := func(params CommandParameters) []string {
+		var s []string
+		for _, p := range params {
+			s = append(s, p.String())
+		}
+		return s
+	}
+
+	for _, test := range tests {
+		cmd, err := TokenizeAndParse(test.Input, ParseOptionTerminator("::"))
+		if !assert.NoError(t, err, test.Input) {
+			continue
+		}
+
+		assert.Equal(t, test.Options, cmd.OptionOrder, test.Input)
+		assert.Equal(t, test.Parameters, strs(cmd.Parameters), test.Input)
+	}
+
+	// Options and parameters may be interleaved around a "--" parameter.
+	cmd, err := TokenizeAndParse(`foo:run a -- -b :: -c`, ParseOptionTerminator("::"), ParseOptionsFirst(false))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"b"}, cmd.OptionOrder)
+	assert.Equal(t, []string{"a", "--", "-c"}, strs(cmd.Parameters))
+
+	// "--" can be an option's argument.
+	cmd, err = TokenizeAndParse(`foo:run -o -- x`, ParseOptionTerminator("::"), ParseOptionHasArgument("o", true))
+	assert.NoError(t, err)
+	assert.Equal(t, "--", cmd.Options["o"].Value.String())
+	assert.Equal(t, []string{"x"}, strs(cmd.Parameters))
+
+	// The custom terminator works with the other terminator options.
+	cmd, err = TokenizeAndParse(`foo:run -a :: -b`, ParseOptionTerminator("::"), ParseKeepTerminator(true))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"::", "-b"}, strs(cmd.Parameters))
+
+	cmd, err = TokenizeAndParse(`foo:run -a :: -b --`, ParseOptionTerminator("::"), ParseRawRemainder(true))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"-b", "--"}, cmd.Remainder)
+
+	// The terminator can't be empty.
+	_, err = TokenizeAndParse(`foo:run -a`, ParseOptionTerminator(""))
+	assert.ErrorIs(t, err, ErrEmptyOptionTerminator)
+}
+
+func TestTokenizeAndParseKeepRaw(t *testing.T) {
+	inputs := []string{
+		`foo:bar`,
+		`  foo:bar   -v  "a  b"   'c'	d  `,
+		`foo:bar --name=value -- x`,
+	}
+
+	for _, in := range inputs {
+		cmd, err := TokenizeAndParse(in, ParseKeepRaw(true))
+		assert.NoError(t, err, in)
+		assert.Equal(t, in, cmd.Raw, in)
+
+		// It's also kept with ParseRawTail.
+		cmd, err = TokenizeAndParse(in, ParseKeepRaw(true), ParseRawTail(true))
+		assert.NoError(t, err, in)
+		assert.Equal(t, in, cmd.Raw, in)
+
+		// Raw is only set on request.
+		cmd, err = TokenizeAndParse(in)
+		assert.NoError(t, err, in)
+		assert.Empty(t, cmd.Raw, in)
+	}
+
+	// Input that can't be tokenized or parsed is kept too.
+	for _, in := range []string{`foo:bar "unterminated`, `a:b:c x`} {
+		cmd, err := TokenizeAndParse(in, ParseKeepRaw(true))
+		assert.Error(t, err, in)
+		assert.Equal(t, in, cmd.Raw, in)
+	}
+
+	// Parse never sets it.
+	cmd, err := Parse([]string{"foo:bar", "x"}, ParseKeepRaw(true))
+	assert.NoError(t, err)
+	assert.Empty(t, cmd.Raw)
+}
+
+func TestCommandParseSingleDashLongOptions(t *testing.T) {
+	options := []ParseOption{
+		ParseSingleDashLongOptions(true),
+		ParseOptionHasArgument("verbose", false),
+		ParseOptionHasArgument("level", true),
+		ParseOptionAlias("dry", "dry-run"),
+	}
+
+	tests := map[string][]string{
+		`foo:run -v`:          {"v"},
+		`foo:run -verbose`:    {"verbose"},
+		`foo:run -vx`:         {"v", "x"},
+		`foo:run -dry`:        {"dry-run"},
+		`foo:run -verb`:       {"v", "e", "r", "b"},
+		`foo:run -v -verbose`: {"v", "verbose"},
+		`foo:run --verbose`:   {"verbose"},
+	}
+
+	for input, expected := range tests {
+		cmd, err := TokenizeAndParse(input, options...)
+		if !assert.NoError(t, err, input) {
+			continue
+		}
+
+		assert.Equal(t, expected, cmd.OptionOrder, input)
+		assert.Empty(t, cmd.Parameters, input)
+	}
+
+	// A registered long option's argument is used as with its double-dash
+	// form, whether it's attached or in the following token.
+	for _, input := range []string{`foo:run -level 3 x`, `foo:run -level=3 x`} {
+		cmd, err := TokenizeAndParse(input, options...)
+		assert.NoError(t, err, input)
+		assert.Equal(t, 3, cmd.OptionInt("level", 0), input)
+		assert.Equal(t, CommandParameters{stringValue("x")}, cmd.Parameters, input)
+	}
+
+	// Without the option, "-verbose" is expanded into individual letters.
+	cmd, err := TokenizeAndParse(`foo:run -verbose`, ParseOptionHasArgument("verbose", false))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"v", "e", "r", "b", "o", "s"}, cmd.OptionOrder)
+	assert.False(t, cmd.HasOption("verbose"))
+}
+
+func TestCommandParseMaxParameters(t *testing.T) {
+	type Test struct {
+		Max      int
+		Input    string
+		Expected string
+	}
+
+	tests := []Test{
+		{0, `foo:cmd a b c d e`, ""},
+		{-1, `foo:cmd a b c d e`, ""},
+		{3, `foo:cmd a b`, ""},
+		{3, `foo:cmd a b c`, ""},
+		{3, `foo:cmd a b c d`, "too many parameters: 4 supplied, the maximum is 3"},
+		{3, `foo:cmd a b c d e`, "too many parameters: 5 supplied, the maximum is 3"},
+		{3, `foo:cmd --x a b c`, ""},
+		{3, `foo:cmd -x -- a b c`, ""},
+		{3, `foo:cmd -x -- a b c d`, "too many parameters: 4 supplied, the maximum is 3"},
+		{2, `foo:cmd a=1 b=2`, ""},
+		{2, `foo:cmd a=1 b=2 c`, "too many parameters: 3 supplied, the maximum is 2"},
+	}
+
+	for _, first := range []bool{true, false} {
+		for _, test := range tests {
+			cmd, err := TokenizeAndParse(test.Input,
+				ParseMaxParameters(test.Max),
+				ParseNamedParameters(true),
+				ParseOptionsFirst(first))
+
+			if test.Expected == "" {
+				assert.NoError(t, err, "%s (max=%d, first=%v)", test.Input, test.Max, first)
+				continue
+			}
+
+			assert.ErrorIs(t, err, ErrTooManyParameters, "%s (max=%d, first=%v)", test.Input, test.Max, first)
+			assert.EqualError(t, err, test.Expected, "%s (max=%d, first=%v)", test.Input, test.Max, first)
+			assert.LessOrEqual(t, len(cmd.Parameters)+len(cmd.NamedParameters), test.Max, "%s (max=%d, first=%v)", test.Input, test.Max, first)
+		}
+	}
+}
+
+func TestCommandOptionOrder(t *testing.T) {
+	tests := map[string][]string{
+		`foo:filter localhost`:                          nil,
+		`foo:filter --zeta --alpha --mu localhost`:      {"zeta", "alpha", "mu"},
+		`foo:filter -cba --alpha localhost`:             {"c", "b", "a", "alpha"},
+		`foo:filter --mu --alpha --mu --zeta localhost`: {"mu", "alpha", "zeta"},
+	}
+
+	for test, expected := range tests {
+		cmd, err := TokenizeAndParse(test)
+		assert.NoError(t, err, test)
+		assert.Equal(t, expected, cmd.OptionOrder, test)
+
+		ordered := cmd.OrderedOptions()
+		assert.Len(t, ordered, len(expected), test)
+		for i, o := range ordered {
+			assert.Equal(t, expected[i], o.Name, test)
+		}
+	}
+}
+
+func TestMergeCommands(t *testing.T) {
+	base, err := TokenizeAndParse(`foo:cmd --channel=general -v --limit=5 a b x=1`, ParseNamedParameters(true))
+	assert.NoError(t, err)
+
+	override, err := TokenizeAndParse(`foo:cmd --limit=10 --json c y=2 x=3`, ParseNamedParameters(true))
+	assert.NoError(t, err)
+
+	merged := MergeCommands(base, override)
+	assert.Equal(t, "foo", merged.Bundle)
+	assert.Equal(t, "cmd", merged.Command)
+	assert.Equal(t, []string{"channel", "v", "limit", "json"}, merged.OptionOrder)
+	assert.Equal(t, stringValue("general"), merged.Options["channel"].Value)
+	assert.Equal(t, IntValue{V: 10}, merged.Options["limit"].Value)
+	assert.Equal(t, BoolValue{V: true}, merged.Options["json"].Value)
+	assert.Equal(t, CommandParameters{stringValue("c")}, merged.Parameters)
+	assert.Equal(t, map[string]Value{"x": IntValue{V: 3}, "y": IntValue{V: 2}}, merged.NamedParameters)
+
+	// Base's parameters are used if override has none.
+	merged = MergeCommands(base, Command{Options: map[string]CommandOption{}})
+	assert.Equal(t, "cmd", merged.Command)
+	assert.Equal(t, CommandParameters{stringValue("a"), stringValue("b")}, merged.Parameters)
+	assert.Equal(t, base.OptionOrder, merged.OptionOrder)
+
+	// Options missing from OptionOrder are still merged.
+	merged = MergeCommands(base, Command{Options: map[string]CommandOption{"v": {"v", BoolValue{V: false}}}})
+	assert.Equal(t, BoolValue{V: false}, merged.Options["v"].Value)
+	assert.Equal(t, base.OptionOrder, merged.OptionOrder)
+
+	// Neither input is modified.
+	merged.Options["channel"] = CommandOption{"channel", stringValue("random")}
+	merged.Parameters[0] = stringValue("z")
+	assert.Equal(t, stringValue("general"), base.Options["channel"].Value)
+	assert.Equal(t, stringValue("a"), base.Parameters[0])
+	assert.Len(t, override.Options, 2)
+}
+
+func TestCommandOptionAccessors(t *testing.T) {
+	options := []ParseOption{
+		ParseOptionAlias("n", "count"),
+		ParseOptionAlias("E", "cert"),
+		ParseOptionHasArgument("count", true),
+		ParseOptionHasArgument("cert", true),
+		ParseOptionHasArgument("verbose", true),
+		ParseOptionHasArgument("name", true),
+	}
+
+	cmd, err := TokenizeAndParse(`foo:curl -k -n 3 -E file --verbose false --name "10" localhost`, options...)
+	assert.NoError(t, err)
+
+	// Present
+	assert.True(t, cmd.HasOption("k"))
+	v, ok := cmd.OptionValue("k")
+	assert.True(t, ok)
+	assert.Equal(t, BoolValue{V: true}, v)
+	assert.True(t, cmd.OptionBool("k", false))
+	assert.False(t, cmd.OptionBool("verbose", true))
+	assert.Equal(t, "true", cmd.OptionString("k", ""))
+	assert.Equal(t, 10, cmd.OptionInt("name", 0))
+	assert.Equal(t, "10", cmd.OptionString("name", ""))
+
+	// Present, but not convertible
+	assert.Equal(t, 5, cmd.OptionInt("k", 5))
+	assert.True(t, cmd.OptionBool("name", true))
+
+	// Absent
+	assert.False(t, cmd.HasOption("x"))
+	v, ok = cmd.OptionValue("x")
+	assert.False(t, ok)
+	assert.Nil(t, v)
+	assert.Equal(t, "def", cmd.OptionString("x", "def"))
+	assert.Equal(t, true, cmd.OptionBool("x", true))
+	assert.Equal(t, 42, cmd.OptionInt("x", 42))
+
+	// Aliased
+	assert.True(t, cmd.HasOption("count"))
+	assert.False(t, cmd.HasOption("n"))
+	assert.Equal(t, 3, cmd.OptionInt("count", 0))
+	assert.Equal(t, "file", cmd.OptionString("cert", ""))
+	assert.False(t, cmd.HasOption("E"))
+}
+
+func TestCommandOptionsValues(t *testing.T) {
+	list := ListValue{V: []Value{StringValue{V: "a"}, StringValue{V: "b"}}}
+	mp := MapValue{V: map[string]Value{"k": IntValue{V: 1}}}
+
+	cmd := Command{
+		Bundle:  "foo",
+		Command: "bar",
+		Options: map[string]CommandOption{
+			"flag": {Name: "flag", Value: BoolValue{V: true}},
+			"n":    {Name: "n", Value: IntValue{V: 3}},
+			"tag":  {Name: "tag", Value: list},
+			"meta": {Name: "meta", Value: mp},
+		},
+	}
+
+	values := cmd.OptionsValues()
+	assert.Len(t, values, 4)
+
+	// Scalars map to their Value
+	assert.Equal(t, BoolValue{V: true}, values["flag"])
+	assert.Equal(t, IntValue{V: 3}, values["n"])
+
+	// Collections are surfaced as collections named for the option
+	assert.Equal(t, ListValue{Name: "tag", V: list.V}, values["tag"])
+	assert.Equal(t, MapValue{Name: "meta", V: mp.V}, values["meta"])
+
+	// Modifying the result doesn't modify the command
+	values["tag"].(ListValue).V[0] = StringValue{V: "z"}
+	values["meta"].(MapValue).V["k"] = IntValue{V: 2}
+	assert.Equal(t, StringValue{V: "a"}, cmd.Options["tag"].Value.(ListValue).V[0])
+	assert.Equal(t, IntValue{V: 1}, cmd.Options["meta"].Value.(MapValue).V["k"])
+
+	// No options
+	assert.Empty(t, Command{}.OptionsValues())
+}
+
 func TestSplitCommand(t *testing.T) {
 	var bundle, command string
 	var err error
@@ -265,3 +1345,100 @@ func TestSplitCommand(t *testing.T) {
 func stringValue(s string) Value {
 	return StringValue{V: s, Quote: '\u0000'}
 }
+
+var benchmarkInputs = map[string]string{
+	"bare":     `foo:bar`,
+	"params":   `bar:echo -n foo bar "baz qux"`,
+	"options":  `foo:curl -Ik --ssl --retry 3 --header "Accept: application/json" localhost`,
+	"combined": `deploy --env prod --force -vv web-1 web-2 web-3 -- --not-an-option`,
+}
+
+func BenchmarkParse(b *testing.B) {
+	options := []ParseOption{ParseOptionHasArgument("retry", true), ParseOptionHasArgument("header", true), ParseOptionHasArgument("env", true)}
+
+	for name, input := range benchmarkInputs {
+		tokens, err := Tokenize(input)
+		if err != nil {
+			b.Fatal(err)
+		}
+
+		b.Run(name, func(b *testing.B) {
+			b.ReportAllocs()
+
+			for i := 0; i < b.N; i++ {
+				if _, err := Parse(tokens, options...); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkTokenizeAndParse(b *testing.B) {
+	options := []ParseOption{ParseOptionHasArgument("retry", true), ParseOptionHasArgument("header", true), ParseOptionHasArgument("env", true)}
+
+	for name, input := range benchmarkInputs {
+		b.Run(name, func(b *testing.B) {
+			b.ReportAllocs()
+
+			for i := 0; i < b.N; i++ {
+				if _, err := TokenizeAndParse(input, options...); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkParseParameters(b *testing.B) {
+	tokens := []string{"foo:cmd"}
+	for i := 0; i < 100; i++ {
+		tokens = append(tokens, strconv.Itoa(i), "true", "3.14", `"quoted string"`, "plain")
+	}
+
+	modes := map[string][]ParseOption{
+		"eager": nil,
+		"lazy":  {ParseRawParameters(true)},
+	}
+
+	for name, options := range modes {
+		b.Run(name, func(b *testing.B) {
+			b.ReportAllocs()
+
+			for i := 0; i < b.N; i++ {
+				if _, err := Parse(tokens, options...); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func TestCommandParametersString(t *testing.T) {
+	tests := map[string]string{
+		`foo:cmd`:                       ``,
+		`foo:cmd a`:                     `a`,
+		`foo:cmd a 1 true`:              `a 1 true`,
+		`foo:cmd "foo bar" "10" x,y`:    `"foo bar" "10" 'x,y'`,
+		`foo:cmd -- "what's this?" --x`: `"what's this?" '--x'`,
+		`foo:curl -- --ssl -v +x -`:     `'--ssl' '-v' '+x' '-'`,
+	}
+
+	for input, expected := range tests {
+		cmd, err := TokenizeAndParse(input)
+		if !assert.NoError(t, err, input) {
+			continue
+		}
+
+		assert.Equal(t, expected, cmd.Parameters.String(), input)
+
+		// The formatted parameters parse back to equal values, rather than
+		// to options or toggles.
+		again, err := TokenizeAndParse("foo:cmd " + cmd.Parameters.String())
+		if assert.NoError(t, err, input) && assert.Len(t, again.Parameters, len(cmd.Parameters), input) {
+			for i, p := range cmd.Parameters {
+				assert.True(t, p.Equals(again.Parameters[i]), "%s: %v", input, p)
+			}
+		}
+	}
+}
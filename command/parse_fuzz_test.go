@@ -0,0 +1,126 @@
+//go:build go1.18
+// +build go1.18
+
+/*
+ * Copyright 2021 The Gort Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package command
+
+import (
+	"strings"
+	"testing"
+)
+
+// FuzzParse feeds arbitrary token slices to Parse, with various combinations
+// of parse options, and checks that it never panics and that it returns
+// either an error or a well-formed Command. Each input is split into tokens
+// on newlines, so tokens may contain spaces.
+func FuzzParse(f *testing.F) {
+	seeds := []string{
+		"",
+		"foo",
+		"foo\n--",
+		"foo\n-",
+		"foo\n--=",
+		"foo\n-=",
+		"foo\n--\n--",
+		"foo\n-\n--\n-",
+		"a:b:c",
+		"::\n:",
+		":\n--x",
+		"foo:bar\n-abc\n--opt=value\nparam",
+		"foo:bar\n-o\n--",
+		"foo:bar\n-o",
+		"foo:bar\n--n\n1",
+		"foo:bar\n--n=1\n--\n2",
+		"foo:bar\n-n1\n2\n3",
+		"foo:bar\n+a-b\n+\n-+",
+		"foo:bar\nk=v\n=v\nk=\n--\nk=v",
+		"foo:bar\n--meta={k:v}\n[a,b\n{",
+		"foo:bar\n--a b\n\"quoted value\"\n'x'",
+	}
+
+	for _, s := range seeds {
+		for _, flags := range []uint8{0x00, 0x0f, 0xf0, 0xff} {
+			f.Add(s, flags)
+		}
+	}
+
+	f.Fuzz(func(t *testing.T, input string, flags uint8) {
+		tokens := []string{}
+		if input != "" {
+			tokens = strings.Split(input, "\n")
+		}
+
+		options := []ParseOption{
+			ParseAgnosticDashes(flags&0x01 != 0),
+			ParseAssumeOptionArguments(flags&0x02 != 0),
+			ParseOptionsFirst(flags&0x04 == 0),
+			ParsePlusOptions(flags&0x08 != 0),
+			ParseNamedParameters(flags&0x10 != 0),
+			ParseComplexTypes(flags&0x20 != 0),
+			ParseCaseInsensitiveOptions(flags&0x40 != 0),
+			ParseOptionAlias("O", "o"),
+			ParseOptionHasArgument("o", true),
+			ParseOptionNArgs("n", 2),
+			ParseUnknownOption(func(string) {}),
+		}
+
+		if flags&0x80 != 0 {
+			options = append(options, ParseSubcommands(2), ParseMaxParameters(3))
+		}
+
+		cmd, err := Parse(tokens, options...)
+		if err != nil {
+			return
+		}
+
+		if cmd.Options == nil {
+			t.Fatalf("%q: nil Options", tokens)
+		}
+		if cmd.Parameters == nil {
+			t.Fatalf("%q: nil Parameters", tokens)
+		}
+		if len(cmd.OptionOrder) != len(cmd.Options) {
+			t.Fatalf("%q: OptionOrder %q doesn't match Options", tokens, cmd.OptionOrder)
+		}
+
+		for _, name := range cmd.OptionOrder {
+			o, ok := cmd.Options[name]
+			if !ok {
+				t.Fatalf("%q: option %q in OptionOrder but not Options", tokens, name)
+			}
+			if o.Name != name {
+				t.Fatalf("%q: option %q has name %q", tokens, name, o.Name)
+			}
+			if o.Value == nil {
+				t.Fatalf("%q: option %q has a nil value", tokens, name)
+			}
+		}
+
+		for i, p := range cmd.Parameters {
+			if p == nil {
+				t.Fatalf("%q: parameter %d is nil", tokens, i)
+			}
+		}
+
+		for k, v := range cmd.NamedParameters {
+			if v == nil {
+				t.Fatalf("%q: named parameter %q is nil", tokens, k)
+			}
+		}
+	})
+}
@@ -20,6 +20,7 @@ import (
 	"fmt"
 	"strings"
 	"unicode"
+	"unicode/utf8"
 )
 
 // Tokenize takes an input string and splits it into tokens. Any control
@@ -30,51 +31,123 @@ import (
 //    echo -n "foo bar" -> {"echo", "-n", "foo bar"}
 //    echo "What's" "\"this\"?" -> {"echo", "What's", "\"this\"?"}
 func Tokenize(input string) ([]string, error) {
-	const RuneNull = rune(0)
+	t := newTokenizer(input)
+	tokens := make([]string, 0, strings.Count(t.input, " ")+1)
 
-	b := strings.Builder{}
-	tokens := []string{}
+	for t.Scan() {
+		tokens = append(tokens, t.Token())
+	}
 
+	return tokens, t.Err()
+}
+
+// Tokenizer splits an input string into tokens one at a time, following the
+// same rules as Tokenize. It's useful for very long inputs, since the caller
+// can process each token as it's found, or stop early, without collecting
+// them all into a slice. Its use is similar to bufio.Scanner:
+//
+//	t := NewTokenizer(input)
+//	for t.Scan() {
+//		fmt.Println(t.Token())
+//	}
+//	if err := t.Err(); err != nil {
+//		return err
+//	}
+type Tokenizer struct {
+	input string
+	pos   int
+	token string
+	err   error
+
+	// If the input isn't valid UTF-8, each invalid byte is replaced with
+	// utf8.RuneError, as if the token's runes had been copied individually.
+	valid bool
+}
+
+// NewTokenizer returns a Tokenizer that reads from input.
+func NewTokenizer(input string) *Tokenizer {
+	t := newTokenizer(input)
+	return &t
+}
+
+func newTokenizer(input string) Tokenizer {
 	input = strings.TrimSpace(input)
 
+	return Tokenizer{input: input, valid: utf8.ValidString(input)}
+}
+
+// Err returns the first error encountered by the Tokenizer, or nil if the
+// input was tokenized successfully. It's a TokenizeError.
+func (t *Tokenizer) Err() error {
+	return t.err
+}
+
+// Token returns the most recent token found by a call to Scan.
+func (t *Tokenizer) Token() string {
+	return t.token
+}
+
+// Scan advances the Tokenizer to the next token, which will then be
+// available through the Token method. It returns false when there are no
+// more tokens, either because the end of the input was reached or because
+// of an error; after Scan returns false, the Err method will return any
+// error that occurred. As with Tokenize, an unterminated final token is
+// still returned before the error is reported.
+func (t *Tokenizer) Scan() bool {
+	const RuneNull = rune(0)
+
+	if t.pos >= len(t.input) {
+		return false
+	}
+
+	// The byte offset of the start of the current token, or -1 if there
+	// isn't one.
+	start := -1
+
 	quote := RuneNull
 	quoteStart := 0
 
 	control := false
 
-	for i, ch := range input {
+	for t.pos < len(t.input) {
+		i := t.pos
+		ch, size := utf8.DecodeRuneInString(t.input[i:])
+		t.pos += size
+
 		switch {
 
 		// Backslash turns on the control flag.
 		case ch == '\\':
-			b.WriteRune(ch)
+			if start < 0 {
+				start = i
+			}
 			control = true
 
 		// If the control flag is set, append the entire control character to the token.
 		case control:
-			b.WriteRune(ch)
 			control = false
 
 		// Spaces outside of quotes are token delimitters.
 		case unicode.IsSpace(ch) && quote == RuneNull:
-			if t := b.String(); len(t) > 0 {
-				tokens = append(tokens, t)
+			if start >= 0 {
+				return t.setToken(t.input[start:i])
 			}
-			b.Reset()
 
 		// Everything inside a pair of quotes is added to the same token.
 		case ch == quote:
-			b.WriteRune(ch)
-			tokens = append(tokens, b.String())
-			quote = RuneNull
-			b.Reset()
+			if start < 0 {
+				start = i
+			}
+			return t.setToken(t.input[start:t.pos])
 
 		// Turn quote-mode on and off.
 		case ch == '"':
 			fallthrough
 
 		case ch == '\'':
-			b.WriteRune(ch)
+			if start < 0 {
+				start = i
+			}
 			if quote == RuneNull {
 				quote = ch
 				quoteStart = i
@@ -82,24 +155,41 @@ func Tokenize(input string) ([]string, error) {
 
 		// Anything else gets appended to the current token.
 		default:
-			b.WriteRune(ch)
+			if start < 0 {
+				start = i
+			}
 		}
 	}
 
-	// Grab that last token
-	if t := b.String(); len(t) > 0 {
-		tokens = append(tokens, t)
+	if control {
+		t.err = TokenizeError{"unterminated control character at %d", len(t.input)}
 	}
 
-	if control {
-		return tokens, TokenizeError{"unterminated control character at %d", len(input)}
+	if quote != RuneNull && t.err == nil {
+		t.err = TokenizeError{"unterminated quote at %d", quoteStart + 1}
+	}
+
+	// Grab that last token
+	if start >= 0 {
+		return t.setToken(t.input[start:])
 	}
 
-	if quote != RuneNull {
-		return tokens, TokenizeError{"unterminated quote at %d", quoteStart + 1}
+	return false
+}
+
+// remainder returns the part of the input that hasn't been scanned yet,
+// without any leading whitespace.
+func (t *Tokenizer) remainder() string {
+	return strings.TrimLeftFunc(t.input[t.pos:], unicode.IsSpace)
+}
+
+func (t *Tokenizer) setToken(token string) bool {
+	if !t.valid {
+		token = string([]rune(token))
 	}
 
-	return tokens, nil
+	t.token = token
+	return true
 }
 
 type TokenizeError struct {
@@ -17,6 +17,7 @@
 package command
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -56,3 +57,53 @@ func TestTokenizeErrors(t *testing.T) {
 		assert.IsType(t, TokenizeError{}, err, in)
 	}
 }
+
+func TestTokenizerMatchesTokenize(t *testing.T) {
+	inputs := []string{
+		`echo -n foo bar`,
+		`echo -n "foo bar"`,
+		`echo "What's" "\"this\"?"`,
+		``,
+		`"" ""`,
+		`  padded   input  `,
+		`deploy -- ` + strings.Repeat(`payload `, 1000),
+		`\`,
+		`echo "unterminated`,
+	}
+
+	for _, in := range inputs {
+		expected, expectedErr := Tokenize(in)
+
+		streamed := []string{}
+		tk := NewTokenizer(in)
+		for tk.Scan() {
+			streamed = append(streamed, tk.Token())
+		}
+
+		assert.Equal(t, expected, streamed, in)
+		assert.Equal(t, expectedErr, tk.Err(), in)
+
+		// Once exhausted, the tokenizer stays exhausted.
+		assert.False(t, tk.Scan(), in)
+	}
+}
+
+func TestTokenizerStopEarly(t *testing.T) {
+	in := `deploy --env prod -- ` + strings.Repeat(`payload `, 1000) + `"unterminated`
+
+	tk := NewTokenizer(in)
+	tokens := []string{}
+	for tk.Scan() {
+		if tk.Token() == "--" {
+			break
+		}
+		tokens = append(tokens, tk.Token())
+	}
+
+	assert.Equal(t, []string{"deploy", "--env", "prod"}, tokens)
+	assert.NoError(t, tk.Err())
+
+	// Resuming picks up where it left off.
+	assert.True(t, tk.Scan())
+	assert.Equal(t, "payload", tk.Token())
+}
@@ -35,6 +35,7 @@ type GortServerConfigs struct {
 	APIURLBase            string `yaml:"api_url_base,omitempty"`
 	DevelopmentMode       bool   `yaml:"development_mode,omitempty"`
 	EnableSpokenCommands  bool   `yaml:"enable_spoken_commands,omitempty"`
+	MaxRequestBodySize    int64  `yaml:"max_request_body_size,omitempty"`
 	TLSCertFile           string `yaml:"tls_cert_file,omitempty"`
 	TLSKeyFile            string `yaml:"tls_key_file,omitempty"`
 }
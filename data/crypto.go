@@ -19,6 +19,7 @@ package data
 import (
 	"crypto/rand"
 	"encoding/base64"
+	"encoding/hex"
 	"errors"
 
 	gerrs "github.com/getgort/gort/errors"
@@ -56,6 +57,19 @@ func GenerateRandomToken(length int) (string, error) {
 	return sEnc, nil
 }
 
+// GenerateRequestID generates a random request ID, suitable for use as the
+// value of an X-Request-ID header.
+func GenerateRequestID() (string, error) {
+	bytes := make([]byte, 16)
+
+	_, err := rand.Read(bytes)
+	if err != nil {
+		return "", gerrs.Wrap(ErrCryptoIO, err)
+	}
+
+	return hex.EncodeToString(bytes), nil
+}
+
 // HashPassword receives a plaintext password and returns its hashed equivalent.
 func HashPassword(pwd string) (string, error) {
 	hash, err := bcrypt.GenerateFromPassword([]byte(pwd), bcrypt.MinCost)
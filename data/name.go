@@ -0,0 +1,29 @@
+/*
+ * Copyright 2021 The Gort Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package data
+
+import "regexp"
+
+var reValidName = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+
+// IsValidName returns true if name is acceptable as the name of a user,
+// group, or role. Such names are used in URL paths, so they may contain only
+// ASCII letters, digits, underscores, periods, and hyphens, and may not be
+// the path segments "." or "..".
+func IsValidName(name string) bool {
+	return name != "." && name != ".." && reValidName.MatchString(name)
+}
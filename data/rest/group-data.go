@@ -16,10 +16,38 @@
 
 package rest
 
+import "encoding/json"
+
 // Group is a data struct used to exchange data between a Gort client and a
 // Gort controller's REST service.
 type Group struct {
 	Name  string `json:"name,omitempty"`
-	Roles []Role `json:"roles,omitempty"`
-	Users []User `json:"users,omitempty"`
+	Roles []Role `json:"roles"`
+	Users []User `json:"users"`
+}
+
+// GroupSummary describes a group by its number of members and roles, rather
+// than by listing them, which is much cheaper for a group with many members.
+type GroupSummary struct {
+	Name        string `json:"name"`
+	MemberCount int    `json:"member_count"`
+	RoleCount   int    `json:"role_count"`
+}
+
+// MarshalJSON encodes the group as a JSON object. Roles and Users are always
+// encoded as arrays, so a group with no roles or users has "roles":[] and
+// "users":[] rather than null.
+func (g Group) MarshalJSON() ([]byte, error) {
+	// group has the same fields as Group, but not its methods, which keeps
+	// json.Marshal from calling this method recursively.
+	type group Group
+
+	if g.Roles == nil {
+		g.Roles = []Role{}
+	}
+	if g.Users == nil {
+		g.Users = []User{}
+	}
+
+	return json.Marshal(group(g))
 }
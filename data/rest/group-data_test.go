@@ -0,0 +1,52 @@
+/*
+ * Copyright 2021 The Gort Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package rest
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGroupMarshalJSONEmpty(t *testing.T) {
+	for _, g := range []Group{{Name: "empty"}, {Name: "empty", Roles: []Role{}, Users: []User{}}} {
+		b, err := json.Marshal(g)
+		assert.NoError(t, err)
+		assert.JSONEq(t, `{"name":"empty","roles":[],"users":[]}`, string(b))
+	}
+
+	// Groups nested in other values are encoded the same way
+	b, err := json.Marshal([]Group{{Name: "empty"}})
+	assert.NoError(t, err)
+	assert.JSONEq(t, `[{"name":"empty","roles":[],"users":[]}]`, string(b))
+}
+
+func TestGroupMarshalJSONRoundTrip(t *testing.T) {
+	g := Group{
+		Name:  "group",
+		Roles: []Role{{Name: "role", Permissions: RolePermissionList{{BundleName: "foo", Permission: "bar"}}}},
+		Users: []User{{Username: "user"}},
+	}
+
+	b, err := json.Marshal(g)
+	assert.NoError(t, err)
+
+	var actual Group
+	assert.NoError(t, json.Unmarshal(b, &actual))
+	assert.Equal(t, g, actual)
+}
@@ -16,12 +16,48 @@
 
 package rest
 
-import "fmt"
+import (
+	"fmt"
+	"sort"
+)
 
 type Role struct {
-	Name        string
+	Name string
+
+	// Permissions includes the permissions granted directly to the role, as
+	// well as those it inherits from its parents.
 	Permissions RolePermissionList
 	Groups      []Group
+
+	// Parents lists the roles that this role directly inherits permissions
+	// from.
+	Parents []string `json:",omitempty"`
+}
+
+// PermissionsByBundle returns the names of the role's permissions, grouped by
+// bundle name. Each bundle's permission names are sorted, and duplicates are
+// removed. The map is empty if the role has no permissions.
+func (r Role) PermissionsByBundle() map[string][]string {
+	bundles := map[string][]string{}
+
+	for _, p := range r.Permissions {
+		bundles[p.BundleName] = append(bundles[p.BundleName], p.Permission)
+	}
+
+	for b, perms := range bundles {
+		sort.Strings(perms)
+
+		unique := perms[:0]
+		for i, p := range perms {
+			if i == 0 || p != perms[i-1] {
+				unique = append(unique, p)
+			}
+		}
+
+		bundles[b] = unique
+	}
+
+	return bundles
 }
 
 type RolePermission struct {
@@ -0,0 +1,50 @@
+/*
+ * Copyright 2021 The Gort Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package rest
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRolePermissionsByBundle(t *testing.T) {
+	role := Role{
+		Name: "role",
+		Permissions: RolePermissionList{
+			{BundleName: "gort", Permission: "manage_users"},
+			{BundleName: "foo", Permission: "write"},
+			{BundleName: "gort", Permission: "manage_groups"},
+			{BundleName: "foo", Permission: "read"},
+			{BundleName: "bar", Permission: "deploy"},
+			{BundleName: "foo", Permission: "read"},
+		},
+	}
+
+	expected := map[string][]string{
+		"bar":  {"deploy"},
+		"foo":  {"read", "write"},
+		"gort": {"manage_groups", "manage_users"},
+	}
+
+	assert.Equal(t, expected, role.PermissionsByBundle())
+
+	// The role's own permissions aren't reordered.
+	assert.Equal(t, "manage_users", role.Permissions[0].Permission)
+
+	assert.Empty(t, Role{Name: "empty"}.PermissionsByBundle())
+}
@@ -0,0 +1,29 @@
+/*
+ * Copyright 2021 The Gort Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package rest
+
+// Rule is the text of a rule, as stored for the command that it applies to.
+type Rule struct {
+	// ID uniquely identifies the stored rule.
+	ID int `json:"id"`
+
+	// Command is the "bundle:command" that the rule applies to.
+	Command string `json:"command"`
+
+	// Rule is the text of the rule, exactly as it was supplied.
+	Rule string `json:"rule"`
+}
@@ -0,0 +1,67 @@
+/*
+ * Copyright 2021 The Gort Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package rest
+
+// Snapshot is a serializable copy of all of the users, groups, and roles
+// known to Gort, and of the relationships between them, which can be used to
+// back up a data store or to migrate it to another. Each user's Password
+// holds the password exactly as the data store keeps it, which may be a
+// hash, or is empty if passwords were excluded from the snapshot.
+type Snapshot struct {
+	Users  []User          `json:"users"`
+	Groups []SnapshotGroup `json:"groups"`
+	Roles  []SnapshotRole  `json:"roles"`
+
+	// Tokens contains the users' access tokens. It may be omitted from a
+	// snapshot, in which case no tokens are restored.
+	Tokens []Token `json:"tokens,omitempty"`
+}
+
+// SnapshotGroup describes a group in a Snapshot. Its members and the roles
+// granted to it are identified by name.
+type SnapshotGroup struct {
+	Name  string   `json:"name"`
+	Users []string `json:"users"`
+	Roles []string `json:"roles"`
+}
+
+// SnapshotRole describes a role in a Snapshot: the permissions granted to it
+// directly, and the names of the roles it inherits from.
+type SnapshotRole struct {
+	Name        string             `json:"name"`
+	Permissions RolePermissionList `json:"permissions"`
+	Parents     []string           `json:"parents"`
+}
+
+// IncludesAdmin returns true if the snapshot contains the admin user, group,
+// and role, which must survive any import that replaces the data store's
+// contents.
+func (s Snapshot) IncludesAdmin() bool {
+	var user, group, role bool
+
+	for _, u := range s.Users {
+		user = user || u.Username == "admin"
+	}
+	for _, g := range s.Groups {
+		group = group || g.Name == "admin"
+	}
+	for _, r := range s.Roles {
+		role = role || r.Name == "admin"
+	}
+
+	return user && group && role
+}
@@ -31,3 +31,19 @@ type Token struct {
 func (t Token) IsExpired() bool {
 	return time.Now().After(t.ValidUntil)
 }
+
+// IsValidBetween returns true if the token's validity period overlaps the
+// interval [since, until] at all. A zero since or until leaves that end of
+// the interval unbounded, so IsValidBetween(now, now) returns true only for
+// tokens that are currently valid.
+func (t Token) IsValidBetween(since, until time.Time) bool {
+	if !since.IsZero() && t.ValidUntil.Before(since) {
+		return false
+	}
+
+	if !until.IsZero() && t.ValidFrom.After(until) {
+		return false
+	}
+
+	return true
+}
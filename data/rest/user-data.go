@@ -24,3 +24,35 @@ type User struct {
 	Password string `json:"password,omitempty"`
 	Username string `json:"username,omitempty"`
 }
+
+// UserBulkStatus is the outcome of saving one of the users in a bulk import.
+type UserBulkStatus string
+
+const (
+	// UserBulkCreated indicates that a new user was created.
+	UserBulkCreated UserBulkStatus = "created"
+
+	// UserBulkUpdated indicates that an existing user was updated.
+	UserBulkUpdated UserBulkStatus = "updated"
+
+	// UserBulkError indicates that the user couldn't be saved.
+	UserBulkError UserBulkStatus = "error"
+)
+
+// UserBulkResult reports the outcome of saving one of the users in a bulk
+// import. Error describes the failure if Status is UserBulkError.
+type UserBulkResult struct {
+	Username string         `json:"username"`
+	Status   UserBulkStatus `json:"status"`
+	Error    string         `json:"error,omitempty"`
+}
+
+// UserPatch describes a partial update of a user. Only the fields that are
+// non-nil are changed, so a PATCH request changes only the fields that it
+// includes; an empty Email or FullName clears the field. A nil or empty
+// Password leaves the password unchanged.
+type UserPatch struct {
+	Email    *string `json:"email,omitempty"`
+	FullName *string `json:"fullname,omitempty"`
+	Password *string `json:"password,omitempty"`
+}
@@ -23,6 +23,16 @@ import (
 	"github.com/getgort/gort/bundles"
 	"github.com/getgort/gort/data"
 	"github.com/getgort/gort/data/rest"
+	"github.com/getgort/gort/dataaccess/memory"
+	"github.com/getgort/gort/dataaccess/postgres"
+)
+
+// Compile-time checks that each backend implements DataAccess. These live
+// here rather than in the backend packages because those packages can't
+// import dataaccess without creating an import cycle.
+var (
+	_ DataAccess = (*memory.InMemoryDataAccess)(nil)
+	_ DataAccess = postgres.PostgresDataAccess{}
 )
 
 // DataAccess represents a common DataAccessObject, backed either by a
@@ -31,6 +41,7 @@ type DataAccess interface {
 	bundles.CommandEntryFinder
 
 	Initialize(context.Context) error
+	Ping(context.Context) error
 
 	RequestBegin(ctx context.Context, request *data.CommandRequest) error
 	RequestUpdate(ctx context.Context, request data.CommandRequest) error
@@ -48,11 +59,18 @@ type DataAccess interface {
 	BundleVersionList(ctx context.Context, name string) ([]data.Bundle, error)
 	BundleUpdate(ctx context.Context, bundle data.Bundle) error
 
+	Export(ctx context.Context) (rest.Snapshot, error)
+	Import(ctx context.Context, snapshot rest.Snapshot, replace bool) error
+
 	GroupCreate(ctx context.Context, group rest.Group) error
+	GroupCreateWithRoles(ctx context.Context, group rest.Group, roles ...string) error
 	GroupDelete(ctx context.Context, groupname string) error
 	GroupExists(ctx context.Context, groupname string) (bool, error)
 	GroupGet(ctx context.Context, groupname string) (rest.Group, error)
 	GroupList(ctx context.Context) ([]rest.Group, error)
+	GroupListPage(ctx context.Context, offset, limit int) ([]rest.Group, int, error)
+	GroupListSummary(ctx context.Context) ([]rest.GroupSummary, error)
+	GroupRename(ctx context.Context, oldName, newName string) error
 	GroupPermissionList(ctx context.Context, groupname string) (rest.RolePermissionList, error)
 	GroupRoleAdd(ctx context.Context, groupname, rolename string) error
 	GroupRoleDelete(ctx context.Context, groupname, rolename string) error
@@ -62,8 +80,10 @@ type DataAccess interface {
 	GroupUserDelete(ctx context.Context, groupname string, username string) error
 	GroupUserList(ctx context.Context, groupname string) ([]rest.User, error)
 
+	RoleAddParent(ctx context.Context, rolename, parentname string) error
 	RoleCreate(ctx context.Context, rolename string) error
 	RoleDelete(ctx context.Context, rolename string) error
+	RoleEnsure(ctx context.Context, rolename string) (rest.Role, error)
 	RoleGet(ctx context.Context, rolename string) (rest.Role, error)
 	RoleGroupAdd(ctx context.Context, rolename, groupname string) error
 	RoleGroupDelete(ctx context.Context, rolename, groupname string) error
@@ -71,19 +91,24 @@ type DataAccess interface {
 	RoleGroupList(ctx context.Context, rolename string) ([]rest.Group, error)
 	RoleList(ctx context.Context) ([]rest.Role, error)
 	RoleExists(ctx context.Context, rolename string) (bool, error)
+	RoleRename(ctx context.Context, oldName, newName string) error
 	RolePermissionAdd(ctx context.Context, rolename, bundlename, permission string) error
-	RolePermissionDelete(ctx context.Context, rolename, bundlename, permission string) error
+	RolePermissionDelete(ctx context.Context, rolename, bundlename, permission string) (int, error)
 	RolePermissionExists(ctx context.Context, rolename, bundlename, permission string) (bool, error)
 	RolePermissionList(ctx context.Context, rolename string) (rest.RolePermissionList, error)
 
 	TokenEvaluate(ctx context.Context, token string) bool
 	TokenGenerate(ctx context.Context, username string, duration time.Duration) (rest.Token, error)
 	TokenInvalidate(ctx context.Context, token string) error
+	TokenInvalidateAllForUser(ctx context.Context, username string) (int, error)
+	TokenList(ctx context.Context) ([]rest.Token, error)
+	TokenListValidBetween(ctx context.Context, since, until time.Time) ([]rest.Token, error)
 	TokenRetrieveByUser(ctx context.Context, username string) (rest.Token, error)
 	TokenRetrieveByToken(ctx context.Context, token string) (rest.Token, error)
 
 	UserAuthenticate(ctx context.Context, username string, password string) (bool, error)
 	UserCreate(ctx context.Context, user rest.User) error
+	UserCreateBulk(ctx context.Context, users []rest.User) ([]rest.UserBulkResult, error)
 	UserDelete(ctx context.Context, username string) error
 	UserExists(ctx context.Context, username string) (bool, error)
 	UserGet(ctx context.Context, username string) (rest.User, error)
@@ -92,7 +117,9 @@ type DataAccess interface {
 	UserGroupAdd(ctx context.Context, username string, groupname string) error
 	UserGroupDelete(ctx context.Context, username string, groupname string) error
 	UserList(ctx context.Context) ([]rest.User, error)
+	UserListPage(ctx context.Context, offset, limit int) ([]rest.User, int, error)
 	UserPermissionList(ctx context.Context, username string) (rest.RolePermissionList, error)
 	UserRoleList(ctx context.Context, username string) ([]rest.Role, error)
 	UserUpdate(ctx context.Context, user rest.User) error
+	UserUpdatePartial(ctx context.Context, username string, patch rest.UserPatch) error
 }
@@ -26,5 +26,17 @@ var ErrNoSuchGroup = errors.New("no such group")
 // ErrEmptyGroupName indicates...
 var ErrEmptyGroupName = errors.New("group name is empty")
 
+// ErrInvalidGroupName indicates that a group name contains characters other
+// than ASCII letters, digits, underscores, periods, and hyphens.
+var ErrInvalidGroupName = errors.New("invalid group name")
+
 // ErrGroupExists TBD
 var ErrGroupExists = errors.New("group already exists")
+
+// ErrUserAlreadyInGroup is returned by GroupUserAdd when the user is already
+// a member of the group.
+var ErrUserAlreadyInGroup = errors.New("user is already a member of the group")
+
+// ErrUserNotInGroup is returned by GroupUserDelete when the user exists but
+// isn't a member of the group.
+var ErrUserNotInGroup = errors.New("user is not a member of the group")
@@ -26,8 +26,16 @@ var ErrNoSuchRole = errors.New("no such role")
 // ErrEmptyRoleName indicates...
 var ErrEmptyRoleName = errors.New("role name is empty")
 
+// ErrInvalidRoleName indicates that a role name contains characters other
+// than ASCII letters, digits, underscores, periods, and hyphens.
+var ErrInvalidRoleName = errors.New("invalid role name")
+
 // ErrEmptyRoleName indicates...
 var ErrEmptyPermission = errors.New("permission is empty")
 
 // ErrRoleExists TBD
 var ErrRoleExists = errors.New("role already exists")
+
+// ErrRoleCycle is returned when making one role the parent of another would
+// cause a role to inherit from itself.
+var ErrRoleCycle = errors.New("role inheritance cycle")
@@ -22,3 +22,7 @@ import (
 
 // ErrNoSuchToken ...
 var ErrNoSuchToken = errors.New("no such token")
+
+// ErrTokenDurationExceeded is returned by TokenGenerate when the requested
+// duration is longer than the data access layer's maximum.
+var ErrTokenDurationExceeded = errors.New("token duration exceeds the maximum")
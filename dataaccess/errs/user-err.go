@@ -20,12 +20,15 @@ import (
 	"errors"
 )
 
-// ErrNoSuchUser indicates that the user doesn't exist, or doesn't exist in
-// a group.
+// ErrNoSuchUser indicates that the user doesn't exist.
 var ErrNoSuchUser = errors.New("no such user")
 
 // ErrEmptyUserName indicates...
 var ErrEmptyUserName = errors.New("user name is empty")
 
+// ErrInvalidUserName indicates that a user name contains characters other
+// than ASCII letters, digits, underscores, periods, and hyphens.
+var ErrInvalidUserName = errors.New("invalid user name")
+
 // ErrUserExists TBD
 var ErrUserExists = errors.New("user already exists")
@@ -31,9 +31,6 @@ var (
 )
 
 func testInitialize(t *testing.T) {
-	ctx, cancel = context.WithTimeout(context.Background(), time.Minute)
-	defer cancel()
-
 	da = NewInMemoryDataAccess()
 
 	err := da.Initialize(ctx)
@@ -41,11 +38,20 @@ func testInitialize(t *testing.T) {
 }
 
 func TestMemoryDataAccessMain(t *testing.T) {
+	ctx, cancel = context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
 	t.Run("testInitialize", testInitialize)
+	t.Run("testInitializeZeroValue", testInitializeZeroValue)
+	t.Run("testPing", testPing)
 	t.Run("testUserAccess", testUserAccess)
 	t.Run("testGroupAccess", testGroupAccess)
 	t.Run("testTokenAccess", testTokenAccess)
 	t.Run("testBundleAccess", testBundleAccess)
 	t.Run("testRoleAccess", testRoleAccess)
+	t.Run("testRuleAccess", testRuleAccess)
 	t.Run("testRequestAccess", testRequestAccess)
+	t.Run("testSnapshotRestore", testSnapshotRestore)
+	t.Run("testExportImport", testExportImport)
+	t.Run("testContextCancellation", testContextCancellation)
 }
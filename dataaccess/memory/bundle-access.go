@@ -185,6 +185,10 @@ func (da *InMemoryDataAccess) BundleList(ctx context.Context) ([]data.Bundle, er
 	list := make([]data.Bundle, 0)
 
 	for _, g := range da.bundles {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
 		list = append(list, *g)
 	}
 
@@ -196,6 +200,10 @@ func (da *InMemoryDataAccess) BundleVersionList(ctx context.Context, name string
 	list := make([]data.Bundle, 0)
 
 	for _, g := range da.bundles {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
 		if g.Name == name {
 			list = append(list, *g)
 		}
@@ -234,6 +242,10 @@ func (da *InMemoryDataAccess) FindCommandEntry(ctx context.Context, bundleName,
 	entries := make([]data.CommandEntry, 0)
 
 	for _, bundle := range da.bundles {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
 		if bundleName != "" && bundleName != bundle.Name {
 			continue
 		}
@@ -0,0 +1,240 @@
+/*
+ * Copyright 2021 The Gort Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package memory
+
+import (
+	"context"
+	"sort"
+
+	"github.com/getgort/gort/data/rest"
+	"github.com/getgort/gort/dataaccess/errs"
+)
+
+// Export returns a snapshot of all of the users, groups, and roles in the
+// data store, and of the users' tokens. Its contents are sorted by name.
+func (da *InMemoryDataAccess) Export(ctx context.Context) (rest.Snapshot, error) {
+	if err := ctx.Err(); err != nil {
+		return rest.Snapshot{}, err
+	}
+
+	s := rest.Snapshot{
+		Users:  []rest.User{},
+		Groups: []rest.SnapshotGroup{},
+		Roles:  []rest.SnapshotRole{},
+	}
+
+	for _, u := range da.users {
+		s.Users = append(s.Users, *u)
+	}
+	sort.Slice(s.Users, func(i, j int) bool { return s.Users[i].Username < s.Users[j].Username })
+
+	for _, g := range da.groups {
+		sg := rest.SnapshotGroup{Name: g.Name, Users: []string{}, Roles: []string{}}
+		for _, u := range g.Users {
+			sg.Users = append(sg.Users, u.Username)
+		}
+		for _, r := range g.Roles {
+			sg.Roles = append(sg.Roles, r.Name)
+		}
+		sort.Strings(sg.Users)
+		sort.Strings(sg.Roles)
+		s.Groups = append(s.Groups, sg)
+	}
+	sort.Slice(s.Groups, func(i, j int) bool { return s.Groups[i].Name < s.Groups[j].Name })
+
+	for _, r := range da.roles {
+		sr := rest.SnapshotRole{
+			Name:        r.Name,
+			Permissions: append(rest.RolePermissionList{}, r.Permissions...),
+			Parents:     append([]string{}, r.Parents...),
+		}
+		sort.Slice(sr.Permissions, func(i, j int) bool {
+			return sr.Permissions[i].String() < sr.Permissions[j].String()
+		})
+		sort.Strings(sr.Parents)
+		s.Roles = append(s.Roles, sr)
+	}
+	sort.Slice(s.Roles, func(i, j int) bool { return s.Roles[i].Name < s.Roles[j].Name })
+
+	for _, t := range tokensByUser {
+		s.Tokens = append(s.Tokens, t)
+	}
+	sort.Slice(s.Tokens, func(i, j int) bool { return s.Tokens[i].User < s.Tokens[j].User })
+
+	return s, nil
+}
+
+// Import restores a snapshot created by Export. If replace is true, the
+// users, groups, and roles that aren't in the snapshot are deleted, along
+// with their tokens, and those that are have their attributes, memberships,
+// and permissions replaced by the snapshot's, except that a user whose
+// password is empty in the snapshot keeps its password. If replace is false,
+// the snapshot is merged into the data store: missing users, groups, and
+// roles are created, and existing ones keep their attributes but gain the
+// snapshot's memberships, permissions, and parents. In either case a token
+// is only restored for a user that doesn't already have one. A snapshot
+// that replaces the data store must include the admin user, group, and role,
+// or errs.ErrAdminUndeletable is returned. If the snapshot can't be
+// restored, the data store is left unchanged.
+func (da *InMemoryDataAccess) Import(ctx context.Context, s rest.Snapshot, replace bool) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if replace && !s.IncludesAdmin() {
+		return errs.ErrAdminUndeletable
+	}
+
+	saved := da.Snapshot()
+
+	if err := da.importSnapshot(ctx, s, replace); err != nil {
+		da.Restore(saved)
+		return err
+	}
+
+	return nil
+}
+
+func (da *InMemoryDataAccess) importSnapshot(ctx context.Context, s rest.Snapshot, replace bool) error {
+	previous := da.users
+
+	if replace {
+		keep := map[string]bool{}
+		for _, u := range s.Users {
+			keep[u.Username] = true
+		}
+
+		for username, t := range tokensByUser {
+			if !keep[username] {
+				delete(tokensByUser, username)
+				delete(tokensByValue, t.Token)
+			}
+		}
+
+		da.groups = make(map[string]*rest.Group)
+		da.users = make(map[string]*rest.User)
+		da.roles = make(map[string]*rest.Role)
+	}
+
+	for _, u := range s.Users {
+		if _, exists := da.users[u.Username]; exists {
+			continue
+		}
+		if p, exists := previous[u.Username]; exists && u.Password == "" {
+			u.Password = p.Password
+		}
+		if err := da.UserCreate(ctx, u); err != nil {
+			return err
+		}
+	}
+
+	for _, sr := range s.Roles {
+		if _, exists := da.roles[sr.Name]; !exists {
+			if err := da.RoleCreate(ctx, sr.Name); err != nil {
+				return err
+			}
+		}
+
+		role := da.roles[sr.Name]
+		for _, p := range sr.Permissions {
+			if !containsPermission(role.Permissions, p) {
+				role.Permissions = append(role.Permissions, p)
+			}
+		}
+	}
+
+	// Parents can only be added once all of the roles exist.
+	for _, sr := range s.Roles {
+		for _, p := range sr.Parents {
+			if err := da.RoleAddParent(ctx, sr.Name, p); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, sg := range s.Groups {
+		if _, exists := da.groups[sg.Name]; !exists {
+			if err := da.GroupCreate(ctx, rest.Group{Name: sg.Name}); err != nil {
+				return err
+			}
+		}
+
+		group := da.groups[sg.Name]
+
+		for _, username := range sg.Users {
+			if containsUser(group.Users, username) {
+				continue
+			}
+			if err := da.GroupUserAdd(ctx, sg.Name, username); err != nil {
+				return err
+			}
+		}
+
+		for _, rolename := range sg.Roles {
+			if containsRole(group.Roles, rolename) {
+				continue
+			}
+			if err := da.GroupRoleAdd(ctx, sg.Name, rolename); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, t := range s.Tokens {
+		if _, exists := da.users[t.User]; !exists {
+			return errs.ErrNoSuchUser
+		}
+		if _, exists := tokensByUser[t.User]; exists {
+			continue
+		}
+		if _, exists := tokensByValue[t.Token]; exists {
+			continue
+		}
+
+		tokensByUser[t.User] = t
+		tokensByValue[t.Token] = t
+	}
+
+	return nil
+}
+
+func containsPermission(perms rest.RolePermissionList, p rest.RolePermission) bool {
+	for _, q := range perms {
+		if q == p {
+			return true
+		}
+	}
+	return false
+}
+
+func containsRole(roles []rest.Role, rolename string) bool {
+	for _, r := range roles {
+		if r.Name == rolename {
+			return true
+		}
+	}
+	return false
+}
+
+func containsUser(users []rest.User, username string) bool {
+	for _, u := range users {
+		if u.Username == username {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,152 @@
+/*
+ * Copyright 2021 The Gort Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package memory
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/getgort/gort/data/rest"
+	"github.com/getgort/gort/dataaccess/errs"
+)
+
+func testExportImport(t *testing.T) {
+	saved := da.Snapshot()
+	defer da.Restore(saved)
+
+	da.UserCreate(ctx, rest.User{Username: "admin"})
+	da.GroupCreate(ctx, rest.Group{Name: "admin"})
+	da.RoleCreate(ctx, "admin")
+	da.UserCreate(ctx, rest.User{Username: "test-export-a", Email: "a@example.com", Password: "pa"})
+	da.UserCreate(ctx, rest.User{Username: "test-export-b", Email: "b@example.com", Password: "pb"})
+	da.RoleCreate(ctx, "test-export-base")
+	da.RolePermissionAdd(ctx, "test-export-base", "foo", "read")
+	da.RoleCreate(ctx, "test-export-role")
+	da.RolePermissionAdd(ctx, "test-export-role", "foo", "write")
+	da.RolePermissionAdd(ctx, "test-export-role", "bar", "deploy")
+	da.RoleAddParent(ctx, "test-export-role", "test-export-base")
+	da.GroupCreate(ctx, rest.Group{Name: "test-export-group"})
+	da.GroupUserAdd(ctx, "test-export-group", "test-export-b")
+	da.GroupUserAdd(ctx, "test-export-group", "test-export-a")
+	da.GroupRoleAdd(ctx, "test-export-group", "test-export-role")
+	token, err := da.TokenGenerate(ctx, "test-export-a", time.Hour)
+	assert.NoError(t, err)
+
+	snapshot, err := da.Export(ctx)
+	assert.NoError(t, err)
+
+	assert.Contains(t, snapshot.Users, rest.User{Username: "test-export-a", Email: "a@example.com", Password: "pa"})
+	assert.Contains(t, snapshot.Groups, rest.SnapshotGroup{
+		Name:  "test-export-group",
+		Users: []string{"test-export-a", "test-export-b"},
+		Roles: []string{"test-export-role"},
+	})
+	assert.Contains(t, snapshot.Roles, rest.SnapshotRole{
+		Name:        "test-export-role",
+		Permissions: rest.RolePermissionList{{BundleName: "bar", Permission: "deploy"}, {BundleName: "foo", Permission: "write"}},
+		Parents:     []string{"test-export-base"},
+	})
+	if assert.Len(t, snapshot.Tokens, 1) {
+		assert.Equal(t, token.Token, snapshot.Tokens[0].Token)
+	}
+
+	// The snapshot survives serialization.
+	b, err := json.Marshal(snapshot)
+	assert.NoError(t, err)
+	decoded := rest.Snapshot{}
+	assert.NoError(t, json.Unmarshal(b, &decoded))
+	assert.Equal(t, snapshot.Users, decoded.Users)
+	assert.Equal(t, snapshot.Groups, decoded.Groups)
+	assert.Equal(t, snapshot.Roles, decoded.Roles)
+
+	// Replacing everything restores the exported state exactly, and removes
+	// anything that's been added since.
+	da.UserCreate(ctx, rest.User{Username: "test-export-extra"})
+	da.TokenGenerate(ctx, "test-export-extra", time.Hour)
+	da.GroupUserDelete(ctx, "test-export-group", "test-export-b")
+	da.RolePermissionDelete(ctx, "test-export-role", "bar", "deploy")
+
+	assert.NoError(t, da.Import(ctx, decoded, true))
+
+	exists, _ := da.UserExists(ctx, "test-export-extra")
+	assert.False(t, exists)
+	_, err = da.TokenRetrieveByUser(ctx, "test-export-extra")
+	assert.Error(t, err)
+
+	restored, err := da.Export(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, snapshot.Users, restored.Users)
+	assert.Equal(t, snapshot.Groups, restored.Groups)
+	assert.Equal(t, snapshot.Roles, restored.Roles)
+	assert.True(t, da.TokenEvaluate(ctx, token.Token))
+
+	// Users keep their passwords if the snapshot's are empty.
+	noPasswords := decoded
+	noPasswords.Users = append([]rest.User{}, decoded.Users...)
+	for i := range noPasswords.Users {
+		noPasswords.Users[i].Password = ""
+	}
+	assert.NoError(t, da.Import(ctx, noPasswords, true))
+	authenticated, err := da.UserAuthenticate(ctx, "test-export-a", "pa")
+	assert.NoError(t, err)
+	assert.True(t, authenticated)
+
+	// Merging keeps existing entities and their attributes, but adds the
+	// snapshot's memberships and permissions.
+	da.UserCreate(ctx, rest.User{Username: "test-export-extra"})
+	da.UserUpdate(ctx, rest.User{Username: "test-export-a", Email: "new@example.com"})
+	da.GroupUserDelete(ctx, "test-export-group", "test-export-b")
+
+	assert.NoError(t, da.Import(ctx, decoded, false))
+
+	exists, _ = da.UserExists(ctx, "test-export-extra")
+	assert.True(t, exists)
+	user, _ := da.UserGet(ctx, "test-export-a")
+	assert.Equal(t, "new@example.com", user.Email)
+	users, _ := da.GroupUserList(ctx, "test-export-group")
+	assert.Len(t, users, 2)
+
+	// A snapshot that can't be restored leaves the data store unchanged.
+	before, _ := da.Export(ctx)
+	bad := rest.Snapshot{
+		Users:  []rest.User{{Username: "admin"}},
+		Groups: []rest.SnapshotGroup{{Name: "admin"}, {Name: "test-export-bad", Roles: []string{"no-such-role"}}},
+		Roles:  []rest.SnapshotRole{{Name: "admin"}},
+	}
+	assert.ErrorIs(t, da.Import(ctx, bad, true), errs.ErrNoSuchRole)
+	after, _ := da.Export(ctx)
+	assert.Equal(t, before.Users, after.Users)
+	assert.Equal(t, before.Groups, after.Groups)
+	assert.Equal(t, before.Roles, after.Roles)
+
+	// A snapshot that would replace the admin user, group, or role away is
+	// rejected, and also leaves the data store unchanged.
+	noAdmin := rest.Snapshot{
+		Users:  []rest.User{{Username: "test-export-a"}},
+		Groups: []rest.SnapshotGroup{{Name: "admin"}},
+		Roles:  []rest.SnapshotRole{{Name: "admin"}},
+	}
+	assert.ErrorIs(t, da.Import(ctx, noAdmin, true), errs.ErrAdminUndeletable)
+	assert.NoError(t, da.Import(ctx, noAdmin, false))
+	after, _ = da.Export(ctx)
+	assert.Equal(t, before.Users, after.Users)
+	assert.Equal(t, before.Groups, after.Groups)
+	assert.Equal(t, before.Roles, after.Roles)
+}
@@ -20,6 +20,7 @@ import (
 	"context"
 	"sort"
 
+	"github.com/getgort/gort/data"
 	"github.com/getgort/gort/data/rest"
 	"github.com/getgort/gort/dataaccess/errs"
 )
@@ -29,6 +30,9 @@ func (da *InMemoryDataAccess) GroupCreate(ctx context.Context, group rest.Group)
 	if group.Name == "" {
 		return errs.ErrEmptyGroupName
 	}
+	if !data.IsValidName(group.Name) {
+		return errs.ErrInvalidGroupName
+	}
 
 	exists, err := da.GroupExists(ctx, group.Name)
 	if err != nil {
@@ -43,6 +47,24 @@ func (da *InMemoryDataAccess) GroupCreate(ctx context.Context, group rest.Group)
 	return nil
 }
 
+// GroupCreateWithRoles creates a new user group and grants it the listed
+// roles. If any role can't be granted (for example, because it doesn't
+// exist) the group is deleted and the error is returned.
+func (da *InMemoryDataAccess) GroupCreateWithRoles(ctx context.Context, group rest.Group, roles ...string) error {
+	if err := da.GroupCreate(ctx, group); err != nil {
+		return err
+	}
+
+	for _, rolename := range roles {
+		if err := da.GroupRoleAdd(ctx, group.Name, rolename); err != nil {
+			da.GroupDelete(ctx, group.Name)
+			return err
+		}
+	}
+
+	return nil
+}
+
 // GroupDelete delete a group.
 func (da *InMemoryDataAccess) GroupDelete(ctx context.Context, groupname string) error {
 	if groupname == "" {
@@ -88,9 +110,15 @@ func (da *InMemoryDataAccess) GroupGet(ctx context.Context, groupname string) (r
 		return rest.Group{}, errs.ErrNoSuchGroup
 	}
 
-	group := da.groups[groupname]
+	group := *da.groups[groupname]
+	group.Users = append([]rest.User(nil), group.Users...)
+
+	group.Roles, err = da.GroupRoleList(ctx, groupname)
+	if err != nil {
+		return rest.Group{}, err
+	}
 
-	return *group, nil
+	return group, nil
 }
 
 // GroupList returns a list of all known groups in the datastore.
@@ -99,12 +127,93 @@ func (da *InMemoryDataAccess) GroupList(ctx context.Context) ([]rest.Group, erro
 	list := make([]rest.Group, 0)
 
 	for _, g := range da.groups {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
 		list = append(list, *g)
 	}
 
 	return list, nil
 }
 
+// GroupListSummary returns a summary of each group, sorted by name, giving
+// its number of members and roles.
+func (da *InMemoryDataAccess) GroupListSummary(ctx context.Context) ([]rest.GroupSummary, error) {
+	list := make([]rest.GroupSummary, 0, len(da.groups))
+
+	for _, g := range da.groups {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		list = append(list, rest.GroupSummary{
+			Name:        g.Name,
+			MemberCount: len(g.Users),
+			RoleCount:   len(g.Roles),
+		})
+	}
+
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+
+	return list, nil
+}
+
+// GroupListPage returns the page of groups, sorted by name, that starts at
+// offset and contains at most limit groups, along with the total number of
+// groups. A limit of 0 or less means that there's no limit.
+func (da *InMemoryDataAccess) GroupListPage(ctx context.Context, offset, limit int) ([]rest.Group, int, error) {
+	list, err := da.GroupList(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+
+	start, end := pageBounds(len(list), offset, limit)
+
+	return list[start:end], len(list), nil
+}
+
+// GroupRename renames a group, preserving its members and the roles granted
+// to it. The admin group can't be renamed.
+func (da *InMemoryDataAccess) GroupRename(ctx context.Context, oldName, newName string) error {
+	if oldName == "" || newName == "" {
+		return errs.ErrEmptyGroupName
+	}
+	if !data.IsValidName(newName) {
+		return errs.ErrInvalidGroupName
+	}
+
+	// Renaming admin is as good as deleting it
+	if oldName == "admin" {
+		return errs.ErrAdminUndeletable
+	}
+
+	group, exists := da.groups[oldName]
+	if !exists {
+		return errs.ErrNoSuchGroup
+	}
+	if _, exists := da.groups[newName]; exists {
+		return errs.ErrGroupExists
+	}
+
+	delete(da.groups, oldName)
+	group.Name = newName
+	da.groups[newName] = group
+
+	// Roles keep their own copies of the groups they're granted to.
+	for _, role := range da.roles {
+		for i := range role.Groups {
+			if role.Groups[i].Name == oldName {
+				role.Groups[i].Name = newName
+			}
+		}
+	}
+
+	return nil
+}
+
 func (da *InMemoryDataAccess) GroupPermissionList(ctx context.Context, groupname string) (rest.RolePermissionList, error) {
 	roles, err := da.GroupRoleList(ctx, groupname)
 	if err != nil {
@@ -114,6 +223,10 @@ func (da *InMemoryDataAccess) GroupPermissionList(ctx context.Context, groupname
 	mp := map[string]rest.RolePermission{}
 
 	for _, r := range roles {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
 		rpl, err := da.RolePermissionList(ctx, r.Name)
 		if err != nil {
 			return rest.RolePermissionList{}, err
@@ -135,6 +248,8 @@ func (da *InMemoryDataAccess) GroupPermissionList(ctx context.Context, groupname
 	return pp, nil
 }
 
+// GroupRoleList returns the roles granted to a group, including each role's
+// permissions.
 func (da *InMemoryDataAccess) GroupRoleList(ctx context.Context, groupname string) ([]rest.Role, error) {
 	gr := da.groups[groupname]
 	if gr == nil {
@@ -143,7 +258,17 @@ func (da *InMemoryDataAccess) GroupRoleList(ctx context.Context, groupname strin
 
 	sort.Slice(gr.Roles, func(i, j int) bool { return gr.Roles[i].Name < gr.Roles[j].Name })
 
-	return gr.Roles, nil
+	// Group roles are copies, so use the current permissions of each role.
+	roles := make([]rest.Role, len(gr.Roles))
+	for i, r := range gr.Roles {
+		if role, ok := da.roles[r.Name]; ok {
+			r.Permissions = da.rolePermissions(r.Name)
+			r.Parents = append([]string(nil), role.Parents...)
+		}
+		roles[i] = r
+	}
+
+	return roles, nil
 }
 
 // GroupRoleAdd grants one or more roles to a group.
@@ -214,7 +339,8 @@ func (da *InMemoryDataAccess) GroupUpdate(ctx context.Context, group rest.Group)
 	return nil
 }
 
-// GroupUserAdd adds a user to a group
+// GroupUserAdd adds a user to a group. It returns errs.ErrUserAlreadyInGroup
+// if the user is already a member of the group.
 func (da *InMemoryDataAccess) GroupUserAdd(ctx context.Context, groupname string, username string) error {
 	if groupname == "" {
 		return errs.ErrEmptyGroupName
@@ -241,13 +367,21 @@ func (da *InMemoryDataAccess) GroupUserAdd(ctx context.Context, groupname string
 	}
 
 	group := da.groups[groupname]
+
+	for _, u := range group.Users {
+		if u.Username == username {
+			return errs.ErrUserAlreadyInGroup
+		}
+	}
+
 	user := da.users[username]
 	group.Users = append(group.Users, *user)
 
 	return nil
 }
 
-// GroupUserDelete removes one or more users from a group.
+// GroupUserDelete removes a user from a group. It returns
+// errs.ErrUserNotInGroup if the user exists but isn't a member of the group.
 func (da *InMemoryDataAccess) GroupUserDelete(ctx context.Context, groupname string, username string) error {
 	if groupname == "" {
 		return errs.ErrEmptyGroupName
@@ -261,6 +395,18 @@ func (da *InMemoryDataAccess) GroupUserDelete(ctx context.Context, groupname str
 		return errs.ErrNoSuchGroup
 	}
 
+	if username == "" {
+		return errs.ErrEmptyUserName
+	}
+
+	exists, err = da.UserExists(ctx, username)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return errs.ErrNoSuchUser
+	}
+
 	group := da.groups[groupname]
 
 	for i, u := range group.Users {
@@ -270,7 +416,7 @@ func (da *InMemoryDataAccess) GroupUserDelete(ctx context.Context, groupname str
 		}
 	}
 
-	return errs.ErrNoSuchUser
+	return errs.ErrUserNotInGroup
 }
 
 func (da *InMemoryDataAccess) GroupUserList(ctx context.Context, groupname string) ([]rest.User, error) {
@@ -18,11 +18,18 @@ package memory
 
 import (
 	"context"
+	"time"
 
 	"github.com/getgort/gort/data"
 	"github.com/getgort/gort/data/rest"
+	"github.com/getgort/gort/dataaccess/errs"
 )
 
+// DefaultTokenDuration is the duration of the tokens generated by
+// TokenGenerate when it's passed a duration of 0, unless the
+// InMemoryDataAccess's DefaultTokenDuration is set.
+const DefaultTokenDuration = 10 * time.Minute
+
 // InMemoryDataAccess is an entirely in-memory representation of a data access layer.
 // Great for testing and development. Terrible for production.
 type InMemoryDataAccess struct {
@@ -30,6 +37,20 @@ type InMemoryDataAccess struct {
 	groups  map[string]*rest.Group
 	users   map[string]*rest.User
 	roles   map[string]*rest.Role
+
+	// rules contains the rules stored by RuleCreate, by command. lastRuleID
+	// is the ID of the most recently created rule.
+	rules      map[string][]rest.Rule
+	lastRuleID int
+
+	// DefaultTokenDuration is the duration used by TokenGenerate when it's
+	// passed a duration of 0. If it's 0, the package's DefaultTokenDuration
+	// is used.
+	DefaultTokenDuration time.Duration
+
+	// MaxTokenDuration is the longest duration that TokenGenerate accepts.
+	// If it's 0 (default), there's no maximum.
+	MaxTokenDuration time.Duration
 }
 
 // NewInMemoryDataAccess returns a new InMemoryDataAccess instance.
@@ -39,12 +60,160 @@ func NewInMemoryDataAccess() *InMemoryDataAccess {
 		groups:  make(map[string]*rest.Group),
 		users:   make(map[string]*rest.User),
 		roles:   make(map[string]*rest.Role),
+		rules:   make(map[string][]rest.Rule),
 	}
 
 	return &da
 }
 
-// Initialize initializes an InMemoryDataAccess instance.
+// Initialize initializes an InMemoryDataAccess instance. It's safe to call
+// on a zero-value InMemoryDataAccess, and on one that's already initialized.
 func (da *InMemoryDataAccess) Initialize(ctx context.Context) error {
+	if da.bundles == nil {
+		da.bundles = make(map[string]*data.Bundle)
+	}
+	if da.groups == nil {
+		da.groups = make(map[string]*rest.Group)
+	}
+	if da.users == nil {
+		da.users = make(map[string]*rest.User)
+	}
+	if da.roles == nil {
+		da.roles = make(map[string]*rest.Role)
+	}
+	if da.rules == nil {
+		da.rules = make(map[string][]rest.Rule)
+	}
+
 	return nil
 }
+
+// pageBounds returns the bounds of the page of a list of total items that
+// starts at offset and contains at most limit items. A negative offset is
+// treated as 0, and a limit of 0 or less means that there's no limit.
+func pageBounds(total, offset, limit int) (start, end int) {
+	if offset < 0 {
+		offset = 0
+	}
+	if offset > total {
+		offset = total
+	}
+
+	end = total
+	if limit > 0 && offset+limit < total {
+		end = offset + limit
+	}
+
+	return offset, end
+}
+
+// Ping returns nil if the InMemoryDataAccess is usable, or
+// errs.ErrDataAccessNotInitialized if it hasn't been initialized.
+func (da *InMemoryDataAccess) Ping(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if da.bundles == nil || da.groups == nil || da.users == nil || da.roles == nil {
+		return errs.ErrDataAccessNotInitialized
+	}
+
+	return nil
+}
+
+// Snapshot is a point-in-time copy of the contents of an InMemoryDataAccess,
+// including its tokens. It's created by Snapshot and applied by Restore.
+type Snapshot struct {
+	bundles       map[string]*data.Bundle
+	groups        map[string]*rest.Group
+	users         map[string]*rest.User
+	roles         map[string]*rest.Role
+	rules         map[string][]rest.Rule
+	tokensByUser  map[string]rest.Token
+	tokensByValue map[string]rest.Token
+}
+
+// Snapshot returns a copy of the current contents of the data access layer.
+// Later changes to the data access layer don't affect the snapshot.
+func (da *InMemoryDataAccess) Snapshot() Snapshot {
+	return Snapshot{
+		bundles:       copyBundles(da.bundles),
+		groups:        copyGroups(da.groups),
+		users:         copyUsers(da.users),
+		roles:         copyRoles(da.roles),
+		rules:         copyRules(da.rules),
+		tokensByUser:  copyTokens(tokensByUser),
+		tokensByValue: copyTokens(tokensByValue),
+	}
+}
+
+// Restore replaces the contents of the data access layer with those of a
+// snapshot created by Snapshot. A snapshot may be restored any number of
+// times. Because tokens are shared by all InMemoryDataAccess instances they
+// are restored as well.
+func (da *InMemoryDataAccess) Restore(s Snapshot) {
+	da.bundles = copyBundles(s.bundles)
+	da.groups = copyGroups(s.groups)
+	da.users = copyUsers(s.users)
+	da.roles = copyRoles(s.roles)
+	da.rules = copyRules(s.rules)
+	tokensByUser = copyTokens(s.tokensByUser)
+	tokensByValue = copyTokens(s.tokensByValue)
+}
+
+func copyBundles(m map[string]*data.Bundle) map[string]*data.Bundle {
+	c := make(map[string]*data.Bundle, len(m))
+	for k, v := range m {
+		b := *v
+		c[k] = &b
+	}
+	return c
+}
+
+func copyGroups(m map[string]*rest.Group) map[string]*rest.Group {
+	c := make(map[string]*rest.Group, len(m))
+	for k, v := range m {
+		g := *v
+		g.Roles = append([]rest.Role(nil), v.Roles...)
+		g.Users = append([]rest.User(nil), v.Users...)
+		c[k] = &g
+	}
+	return c
+}
+
+func copyUsers(m map[string]*rest.User) map[string]*rest.User {
+	c := make(map[string]*rest.User, len(m))
+	for k, v := range m {
+		u := *v
+		c[k] = &u
+	}
+	return c
+}
+
+func copyRoles(m map[string]*rest.Role) map[string]*rest.Role {
+	c := make(map[string]*rest.Role, len(m))
+	for k, v := range m {
+		r := *v
+		r.Groups = append([]rest.Group(nil), v.Groups...)
+		r.Permissions = append(rest.RolePermissionList(nil), v.Permissions...)
+		r.Parents = append([]string(nil), v.Parents...)
+		c[k] = &r
+	}
+	return c
+}
+
+func copyRules(m map[string][]rest.Rule) map[string][]rest.Rule {
+	c := make(map[string][]rest.Rule, len(m))
+	for k, v := range m {
+		c[k] = append([]rest.Rule(nil), v...)
+	}
+	return c
+}
+
+func copyTokens(m map[string]rest.Token) map[string]rest.Token {
+	c := make(map[string]rest.Token, len(m))
+	for k, v := range m {
+		c[k] = v
+	}
+	return c
+}
@@ -0,0 +1,151 @@
+/*
+ * Copyright 2021 The Gort Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package memory
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/getgort/gort/data/rest"
+	"github.com/getgort/gort/dataaccess/errs"
+)
+
+func testSnapshotRestore(t *testing.T) {
+	snapshot := da.Snapshot()
+	defer da.Restore(snapshot)
+
+	// Make changes after the snapshot
+	err := da.UserCreate(ctx, rest.User{Username: "test-snapshot", Email: "test-snapshot"})
+	assert.NoError(t, err)
+
+	err = da.GroupCreate(ctx, rest.Group{Name: "test-snapshot"})
+	assert.NoError(t, err)
+
+	err = da.GroupUserAdd(ctx, "test-snapshot", "test-snapshot")
+	assert.NoError(t, err)
+
+	token, err := da.TokenGenerate(ctx, "test-snapshot", time.Minute)
+	assert.NoError(t, err)
+
+	_, err = da.RuleCreate(ctx, "test:snapshot", `test:snapshot allow`)
+	assert.NoError(t, err)
+
+	// Restoring removes them
+	da.Restore(snapshot)
+
+	exists, _ := da.UserExists(ctx, "test-snapshot")
+	assert.False(t, exists)
+
+	exists, _ = da.GroupExists(ctx, "test-snapshot")
+	assert.False(t, exists)
+
+	assert.False(t, da.TokenEvaluate(ctx, token.Token))
+
+	rs, _ := da.RuleList(ctx, "test:snapshot")
+	assert.Empty(t, rs)
+
+	// Take a snapshot with a group member, and check that changes to the
+	// group don't leak into the snapshot.
+	assert.NoError(t, da.UserCreate(ctx, rest.User{Username: "test-snapshot", Email: "test-snapshot"}))
+	assert.NoError(t, da.GroupCreate(ctx, rest.Group{Name: "test-snapshot"}))
+	assert.NoError(t, da.GroupUserAdd(ctx, "test-snapshot", "test-snapshot"))
+
+	populated := da.Snapshot()
+
+	assert.NoError(t, da.GroupUserDelete(ctx, "test-snapshot", "test-snapshot"))
+	users, _ := da.GroupUserList(ctx, "test-snapshot")
+	assert.Len(t, users, 0)
+
+	// A snapshot can be restored more than once
+	for i := 0; i < 2; i++ {
+		da.Restore(populated)
+
+		users, err = da.GroupUserList(ctx, "test-snapshot")
+		assert.NoError(t, err)
+		assert.Len(t, users, 1)
+
+		assert.NoError(t, da.GroupUserDelete(ctx, "test-snapshot", "test-snapshot"))
+	}
+}
+
+func testContextCancellation(t *testing.T) {
+	assert.NoError(t, da.UserCreate(ctx, rest.User{Username: "test-cancel", Email: "test-cancel"}))
+	defer da.UserDelete(ctx, "test-cancel")
+	assert.NoError(t, da.GroupCreate(ctx, rest.Group{Name: "test-cancel"}))
+	defer da.GroupDelete(ctx, "test-cancel")
+	assert.NoError(t, da.RoleCreate(ctx, "test-cancel"))
+	defer da.RoleDelete(ctx, "test-cancel")
+	assert.NoError(t, da.GroupUserAdd(ctx, "test-cancel", "test-cancel"))
+
+	token, err := da.TokenGenerate(ctx, "test-cancel", time.Minute)
+	assert.NoError(t, err)
+	defer da.TokenInvalidate(ctx, token.Token)
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+
+	_, err = da.GroupList(cancelled)
+	assert.ErrorIs(t, err, context.Canceled)
+
+	_, err = da.RoleList(cancelled)
+	assert.ErrorIs(t, err, context.Canceled)
+
+	_, err = da.UserList(cancelled)
+	assert.ErrorIs(t, err, context.Canceled)
+
+	_, err = da.UserGroupList(cancelled, "test-cancel")
+	assert.ErrorIs(t, err, context.Canceled)
+
+	_, err = da.UserGetByEmail(cancelled, "test-cancel")
+	assert.ErrorIs(t, err, context.Canceled)
+
+	_, err = da.TokenList(cancelled)
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func testInitializeZeroValue(t *testing.T) {
+	zero := &InMemoryDataAccess{}
+
+	// An uninitialized instance isn't usable
+	assert.ErrorIs(t, zero.Ping(ctx), errs.ErrDataAccessNotInitialized)
+
+	assert.NoError(t, zero.Initialize(ctx))
+	assert.NoError(t, zero.Ping(ctx))
+
+	// The maps are usable once initialized
+	assert.NoError(t, zero.GroupCreate(ctx, rest.Group{Name: "test-initialize"}))
+	exists, err := zero.GroupExists(ctx, "test-initialize")
+	assert.NoError(t, err)
+	assert.True(t, exists)
+
+	// Initializing again doesn't discard existing data
+	assert.NoError(t, zero.Initialize(ctx))
+	exists, err = zero.GroupExists(ctx, "test-initialize")
+	assert.NoError(t, err)
+	assert.True(t, exists)
+}
+
+func testPing(t *testing.T) {
+	assert.NoError(t, da.Ping(ctx))
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	assert.ErrorIs(t, da.Ping(cancelled), context.Canceled)
+}
@@ -20,15 +20,87 @@ import (
 	"context"
 	"sort"
 
+	"github.com/getgort/gort/data"
 	"github.com/getgort/gort/data/rest"
 	"github.com/getgort/gort/dataaccess/errs"
 )
 
+// RoleAddParent makes parentname a parent of rolename, so that rolename
+// inherits all of the permissions of parentname (and of its parents). It
+// returns errs.ErrRoleCycle if rolename and parentname are the same role or
+// if parentname already inherits from rolename. Adding an existing parent
+// has no effect.
+func (da *InMemoryDataAccess) RoleAddParent(ctx context.Context, rolename, parentname string) error {
+	if rolename == "" || parentname == "" {
+		return errs.ErrEmptyRoleName
+	}
+
+	role, ok := da.roles[rolename]
+	if !ok {
+		return errs.ErrNoSuchRole
+	}
+
+	if _, ok := da.roles[parentname]; !ok {
+		return errs.ErrNoSuchRole
+	}
+
+	if rolename == parentname {
+		return errs.ErrRoleCycle
+	}
+
+	for _, a := range da.roleAncestors(parentname) {
+		if a == rolename {
+			return errs.ErrRoleCycle
+		}
+	}
+
+	for _, p := range role.Parents {
+		if p == parentname {
+			return nil
+		}
+	}
+
+	role.Parents = append(role.Parents, parentname)
+
+	return nil
+}
+
+// roleAncestors returns the names of all of the roles that rolename
+// inherits from, directly or indirectly, in breadth-first order.
+func (da *InMemoryDataAccess) roleAncestors(rolename string) []string {
+	ancestors := []string{}
+	seen := map[string]bool{rolename: true}
+	queue := []string{rolename}
+
+	for len(queue) > 0 {
+		role, ok := da.roles[queue[0]]
+		queue = queue[1:]
+		if !ok {
+			continue
+		}
+
+		for _, p := range role.Parents {
+			if seen[p] {
+				continue
+			}
+
+			seen[p] = true
+			ancestors = append(ancestors, p)
+			queue = append(queue, p)
+		}
+	}
+
+	return ancestors
+}
+
 // RoleCreate creates a new role.
 func (da *InMemoryDataAccess) RoleCreate(ctx context.Context, rolename string) error {
 	if rolename == "" {
 		return errs.ErrEmptyRoleName
 	}
+	if !data.IsValidName(rolename) {
+		return errs.ErrInvalidRoleName
+	}
 
 	if nil != da.roles[rolename] {
 		return errs.ErrRoleExists
@@ -38,6 +110,19 @@ func (da *InMemoryDataAccess) RoleCreate(ctx context.Context, rolename string) e
 	return nil
 }
 
+// RoleEnsure creates a role if it doesn't already exist, and returns the
+// current role. Unlike RoleCreate, it doesn't return an error if the role
+// already exists, so it can safely be called more than once.
+func (da *InMemoryDataAccess) RoleEnsure(ctx context.Context, rolename string) (rest.Role, error) {
+	if _, exists := da.roles[rolename]; !exists {
+		if err := da.RoleCreate(ctx, rolename); err != nil {
+			return rest.Role{}, err
+		}
+	}
+
+	return da.RoleGet(ctx, rolename)
+}
+
 // RoleDelete
 func (da *InMemoryDataAccess) RoleDelete(ctx context.Context, name string) error {
 	if name == "" {
@@ -49,6 +134,64 @@ func (da *InMemoryDataAccess) RoleDelete(ctx context.Context, name string) error
 	}
 
 	delete(da.roles, name)
+
+	for _, r := range da.roles {
+		parents := r.Parents[:0]
+		for _, p := range r.Parents {
+			if p != name {
+				parents = append(parents, p)
+			}
+		}
+		r.Parents = parents
+	}
+
+	return nil
+}
+
+// RoleRename renames a role. Its permissions, parents, and the groups it's
+// granted to are preserved.
+func (da *InMemoryDataAccess) RoleRename(ctx context.Context, oldName, newName string) error {
+	if oldName == "" || newName == "" {
+		return errs.ErrEmptyRoleName
+	}
+	if !data.IsValidName(newName) {
+		return errs.ErrInvalidRoleName
+	}
+
+	// Renaming admin is as good as deleting it
+	if oldName == "admin" {
+		return errs.ErrAdminUndeletable
+	}
+
+	role, exists := da.roles[oldName]
+	if !exists {
+		return errs.ErrNoSuchRole
+	}
+	if _, exists := da.roles[newName]; exists {
+		return errs.ErrRoleExists
+	}
+
+	delete(da.roles, oldName)
+	role.Name = newName
+	da.roles[newName] = role
+
+	// Groups keep their own copies of the roles granted to them.
+	for _, group := range da.groups {
+		for i := range group.Roles {
+			if group.Roles[i].Name == oldName {
+				group.Roles[i].Name = newName
+			}
+		}
+	}
+
+	for _, r := range da.roles {
+		for i, p := range r.Parents {
+			if p == oldName {
+				r.Parents[i] = newName
+			}
+		}
+	}
+
 	return nil
 }
 
@@ -61,7 +204,8 @@ func (da *InMemoryDataAccess) RoleExists(ctx context.Context, name string) (bool
 	return da.roles[name] != nil, nil
 }
 
-// RoleGet gets a specific group.
+// RoleGet gets a specific role. Its permissions include those inherited
+// from its parents.
 func (da *InMemoryDataAccess) RoleGet(ctx context.Context, rolename string) (rest.Role, error) {
 	role, ok := da.roles[rolename]
 
@@ -73,7 +217,40 @@ func (da *InMemoryDataAccess) RoleGet(ctx context.Context, rolename string) (res
 		return rest.Role{}, errs.ErrNoSuchRole
 	}
 
-	return *role, nil
+	r := *role
+	r.Permissions = da.rolePermissions(rolename)
+
+	return r, nil
+}
+
+// rolePermissions returns the permissions granted directly to a role,
+// followed by any that it inherits that it hasn't been granted directly.
+func (da *InMemoryDataAccess) rolePermissions(rolename string) rest.RolePermissionList {
+	role, ok := da.roles[rolename]
+	if !ok {
+		return nil
+	}
+
+	perms := append(rest.RolePermissionList{}, role.Permissions...)
+	if len(role.Parents) == 0 {
+		return perms
+	}
+
+	seen := map[rest.RolePermission]bool{}
+	for _, p := range perms {
+		seen[p] = true
+	}
+
+	for _, a := range da.roleAncestors(rolename) {
+		for _, p := range da.roles[a].Permissions {
+			if !seen[p] {
+				seen[p] = true
+				perms = append(perms, p)
+			}
+		}
+	}
+
+	return perms
 }
 
 // RolePermissionExists returns true if the given role has been granted the
@@ -99,6 +276,10 @@ func (da *InMemoryDataAccess) RoleList(ctx context.Context) ([]rest.Role, error)
 	list := make([]rest.Role, 0)
 
 	for _, r := range da.roles {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
 		list = append(list, *r)
 	}
 
@@ -153,16 +334,22 @@ func (da *InMemoryDataAccess) RolePermissionAdd(ctx context.Context, rolename, b
 	return nil
 }
 
-func (da *InMemoryDataAccess) RolePermissionDelete(ctx context.Context, rolename, bundlename, permission string) error {
+// RolePermissionDelete revokes a permission from a role, and returns the
+// number of permissions that were removed: 0 if the role hadn't been granted
+// the permission. Permissions that the role inherits from its parents aren't
+// affected.
+func (da *InMemoryDataAccess) RolePermissionDelete(ctx context.Context, rolename, bundlename, permission string) (int, error) {
 	role, ok := da.roles[rolename]
 
 	if !ok {
-		return errs.ErrNoSuchRole
+		return 0, errs.ErrNoSuchRole
 	}
 
+	count := 0
 	perms := []rest.RolePermission{}
 	for _, p := range role.Permissions {
 		if p.BundleName == bundlename && p.Permission == permission {
+			count++
 			continue
 		}
 
@@ -171,7 +358,7 @@ func (da *InMemoryDataAccess) RolePermissionDelete(ctx context.Context, rolename
 
 	role.Permissions = perms
 
-	return nil
+	return count, nil
 }
 
 // RolePermissionList returns returns an alphabetically-sorted list of
@@ -29,6 +29,7 @@ func testRoleAccess(t *testing.T) {
 	t.Run("testRoleList", testRoleList)
 	t.Run("testRoleExists", testRoleExists)
 	t.Run("testRoleDelete", testRoleDelete)
+	t.Run("testRoleEnsure", testRoleEnsure)
 	t.Run("testRoleGet", testRoleGet)
 	t.Run("testRoleGroupAdd", testRoleGroupAdd)
 	t.Run("testRoleGroupDelete", testRoleGroupDelete)
@@ -37,6 +38,9 @@ func testRoleAccess(t *testing.T) {
 	t.Run("testRolePermissionExists", testRolePermissionExists)
 	t.Run("testRolePermissionAdd", testRolePermissionAdd)
 	t.Run("testRolePermissionList", testRolePermissionList)
+	t.Run("testRolePermissionDelete", testRolePermissionDelete)
+	t.Run("testRoleAddParent", testRoleAddParent)
+	t.Run("testRoleRename", testRoleRename)
 }
 
 func testRoleCreate(t *testing.T) {
@@ -46,6 +50,12 @@ func testRoleCreate(t *testing.T) {
 	err = da.RoleCreate(ctx, "")
 	assert.Error(t, err, errs.ErrEmptyRoleName)
 
+	// Expect errors for names that aren't safe to use in a URL path
+	for _, name := range []string{"test/create", "test create", "test\tcreate", "test%2Fcreate", "tést", ".", ".."} {
+		err = da.RoleCreate(ctx, name)
+		assert.ErrorIs(t, err, errs.ErrInvalidRoleName, name)
+	}
+
 	// Expect no error
 	err = da.RoleCreate(ctx, "test-create")
 	defer da.RoleDelete(ctx, "test-create")
@@ -127,6 +137,40 @@ func testRoleExists(t *testing.T) {
 	}
 }
 
+func testRoleEnsure(t *testing.T) {
+	_, err := da.RoleEnsure(ctx, "")
+	assert.ErrorIs(t, err, errs.ErrEmptyRoleName)
+
+	_, err = da.RoleEnsure(ctx, "test/ensure")
+	assert.ErrorIs(t, err, errs.ErrInvalidRoleName)
+
+	role, err := da.RoleEnsure(ctx, "test-ensure")
+	defer da.RoleDelete(ctx, "test-ensure")
+	assert.NoError(t, err)
+	assert.Equal(t, "test-ensure", role.Name)
+	assert.Empty(t, role.Permissions)
+
+	err = da.RolePermissionAdd(ctx, "test-ensure", "foo", "bar")
+	assert.NoError(t, err)
+
+	// Ensuring an existing role returns it unchanged.
+	role, err = da.RoleEnsure(ctx, "test-ensure")
+	assert.NoError(t, err)
+	assert.Equal(t, "test-ensure", role.Name)
+	assert.Equal(t, rest.RolePermissionList{{BundleName: "foo", Permission: "bar"}}, role.Permissions)
+
+	roles, err := da.RoleList(ctx)
+	assert.NoError(t, err)
+
+	count := 0
+	for _, r := range roles {
+		if r.Name == "test-ensure" {
+			count++
+		}
+	}
+	assert.Equal(t, 1, count)
+}
+
 func testRoleGet(t *testing.T) {
 	var err error
 	var role rest.Role
@@ -155,7 +199,7 @@ func testRoleGet(t *testing.T) {
 	err = da.RolePermissionAdd(ctx, "test-get", "foo", "bat")
 	assert.NoError(t, err)
 
-	err = da.RolePermissionDelete(ctx, "test-get", "foo", "bat")
+	_, err = da.RolePermissionDelete(ctx, "test-get", "foo", "bat")
 	assert.NoError(t, err)
 
 	expected := rest.Role{
@@ -415,3 +459,152 @@ func testRolePermissionList(t *testing.T) {
 
 	assert.Equal(t, expect, actual)
 }
+
+func testRoleAddParent(t *testing.T) {
+	const (
+		viewer   = "test-parent-viewer"
+		operator = "test-parent-operator"
+		owner    = "test-parent-owner"
+		group    = "test-parent-group"
+		user     = "test-parent-user"
+	)
+
+	for _, r := range []string{viewer, operator, owner} {
+		assert.NoError(t, da.RoleCreate(ctx, r))
+		defer da.RoleDelete(ctx, r)
+	}
+
+	assert.NoError(t, da.RolePermissionAdd(ctx, viewer, "test", "view"))
+	assert.NoError(t, da.RolePermissionAdd(ctx, operator, "test", "operate"))
+	assert.NoError(t, da.RolePermissionAdd(ctx, owner, "test", "own"))
+	assert.NoError(t, da.RolePermissionAdd(ctx, owner, "test", "view"))
+
+	// Invalid arguments
+	assert.ErrorIs(t, da.RoleAddParent(ctx, "", viewer), errs.ErrEmptyRoleName)
+	assert.ErrorIs(t, da.RoleAddParent(ctx, operator, ""), errs.ErrEmptyRoleName)
+	assert.ErrorIs(t, da.RoleAddParent(ctx, "test-parent-none", viewer), errs.ErrNoSuchRole)
+	assert.ErrorIs(t, da.RoleAddParent(ctx, operator, "test-parent-none"), errs.ErrNoSuchRole)
+
+	// owner -> operator -> viewer
+	assert.NoError(t, da.RoleAddParent(ctx, operator, viewer))
+	assert.NoError(t, da.RoleAddParent(ctx, owner, operator))
+
+	// Adding an existing parent is a no-op
+	assert.NoError(t, da.RoleAddParent(ctx, owner, operator))
+
+	role, err := da.RoleGet(ctx, owner)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{operator}, role.Parents)
+	assert.ElementsMatch(t, rest.RolePermissionList{
+		{BundleName: "test", Permission: "own"},
+		{BundleName: "test", Permission: "operate"},
+		{BundleName: "test", Permission: "view"},
+	}, role.Permissions)
+
+	perms, err := da.RolePermissionList(ctx, operator)
+	assert.NoError(t, err)
+	assert.Equal(t, rest.RolePermissionList{
+		{BundleName: "test", Permission: "operate"},
+		{BundleName: "test", Permission: "view"},
+	}, perms)
+
+	// Parents are unaffected by their children
+	perms, err = da.RolePermissionList(ctx, viewer)
+	assert.NoError(t, err)
+	assert.Equal(t, rest.RolePermissionList{{BundleName: "test", Permission: "view"}}, perms)
+
+	// Permissions granted to an ancestor later are inherited too
+	assert.NoError(t, da.RolePermissionAdd(ctx, viewer, "test", "list"))
+	exists, err := da.RolePermissionExists(ctx, owner, "test", "list")
+	assert.NoError(t, err)
+	assert.True(t, exists)
+
+	// Cycles are rejected
+	assert.ErrorIs(t, da.RoleAddParent(ctx, viewer, viewer), errs.ErrRoleCycle)
+	assert.ErrorIs(t, da.RoleAddParent(ctx, viewer, operator), errs.ErrRoleCycle)
+	assert.ErrorIs(t, da.RoleAddParent(ctx, viewer, owner), errs.ErrRoleCycle)
+
+	// Inherited permissions are resolved for users
+	assert.NoError(t, da.GroupCreate(ctx, rest.Group{Name: group}))
+	defer da.GroupDelete(ctx, group)
+	assert.NoError(t, da.UserCreate(ctx, rest.User{Username: user, Email: user + "@example.com"}))
+	defer da.UserDelete(ctx, user)
+	assert.NoError(t, da.GroupUserAdd(ctx, group, user))
+	assert.NoError(t, da.GroupRoleAdd(ctx, group, owner))
+
+	userPerms, err := da.UserPermissionList(ctx, user)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"test:list", "test:operate", "test:own", "test:view"}, userPerms.Strings())
+}
+
+func testRolePermissionDelete(t *testing.T) {
+	const rolename = "role-test-role-permission-delete"
+
+	assert.NoError(t, da.RoleCreate(ctx, rolename))
+	defer da.RoleDelete(ctx, rolename)
+
+	assert.NoError(t, da.RolePermissionAdd(ctx, rolename, "test", "granted"))
+
+	// Revoking a permission that was never granted removes nothing
+	count, err := da.RolePermissionDelete(ctx, rolename, "test", "not-granted")
+	assert.NoError(t, err)
+	assert.Equal(t, 0, count)
+
+	count, err = da.RolePermissionDelete(ctx, rolename, "test", "granted")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, count)
+
+	// ...and revoking it again removes nothing
+	count, err = da.RolePermissionDelete(ctx, rolename, "test", "granted")
+	assert.NoError(t, err)
+	assert.Equal(t, 0, count)
+
+	perms, err := da.RolePermissionList(ctx, rolename)
+	assert.NoError(t, err)
+	assert.Empty(t, perms)
+}
+
+func testRoleRename(t *testing.T) {
+	var (
+		oldname   = "role-test-role-rename-old"
+		newname   = "role-test-role-rename-new"
+		groupname = "group-test-role-rename"
+	)
+
+	err := da.RoleRename(ctx, oldname, newname)
+	assert.ErrorIs(t, err, errs.ErrNoSuchRole)
+
+	err = da.RoleRename(ctx, "admin", newname)
+	assert.ErrorIs(t, err, errs.ErrAdminUndeletable)
+
+	da.RoleCreate(ctx, oldname)
+	defer da.RoleDelete(ctx, oldname)
+	da.RolePermissionAdd(ctx, oldname, "foo", "bar")
+	da.GroupCreate(ctx, rest.Group{Name: groupname})
+	defer da.GroupDelete(ctx, groupname)
+	da.GroupRoleAdd(ctx, groupname, oldname)
+
+	err = da.RoleRename(ctx, oldname, "")
+	assert.ErrorIs(t, err, errs.ErrEmptyRoleName)
+
+	err = da.RoleRename(ctx, oldname, "bad/name")
+	assert.ErrorIs(t, err, errs.ErrInvalidRoleName)
+
+	err = da.RoleRename(ctx, oldname, oldname)
+	assert.ErrorIs(t, err, errs.ErrRoleExists)
+
+	err = da.RoleRename(ctx, oldname, newname)
+	defer da.RoleDelete(ctx, newname)
+	assert.NoError(t, err)
+
+	exists, _ := da.RoleExists(ctx, oldname)
+	assert.False(t, exists)
+
+	// The group now lists the new name, with the same permissions
+	roles, err := da.GroupRoleList(ctx, groupname)
+	assert.NoError(t, err)
+	if assert.Len(t, roles, 1) {
+		assert.Equal(t, newname, roles[0].Name)
+		assert.Equal(t, rest.RolePermissionList{{BundleName: "foo", Permission: "bar"}}, roles[0].Permissions)
+	}
+}
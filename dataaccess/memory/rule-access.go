@@ -0,0 +1,73 @@
+/*
+ * Copyright 2021 The Gort Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package memory
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/getgort/gort/data/rest"
+	"github.com/getgort/gort/dataaccess/errs"
+	"github.com/getgort/gort/rules"
+)
+
+// RuleCreate stores a rule for a command, and returns its ID. The rule is
+// parsed first: an error wrapping errs.ErrInvalidRule is returned if it
+// can't be parsed, or if it applies to a command other than command.
+func (da *InMemoryDataAccess) RuleCreate(ctx context.Context, command string, rule string) (int, error) {
+	parsed, err := rules.TokenizeAndParse(rule)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %v", errs.ErrInvalidRule, err)
+	}
+	if parsed.Command != command {
+		return 0, fmt.Errorf("%w: rule applies to %q, not %q", errs.ErrInvalidRule, parsed.Command, command)
+	}
+
+	da.lastRuleID++
+	r := rest.Rule{ID: da.lastRuleID, Command: command, Rule: rule}
+	da.rules[command] = append(da.rules[command], r)
+
+	return r.ID, nil
+}
+
+// RuleDelete deletes a stored rule. An error is returned if the rule
+// doesn't exist.
+func (da *InMemoryDataAccess) RuleDelete(ctx context.Context, id int) error {
+	for command, rs := range da.rules {
+		for i, r := range rs {
+			if r.ID != id {
+				continue
+			}
+
+			if len(rs) == 1 {
+				delete(da.rules, command)
+			} else {
+				da.rules[command] = append(rs[:i:i], rs[i+1:]...)
+			}
+
+			return nil
+		}
+	}
+
+	return errs.ErrNoSuchRule
+}
+
+// RuleList returns the rules stored for a command, in the order that they
+// were created. It returns an empty list if there are none.
+func (da *InMemoryDataAccess) RuleList(ctx context.Context, command string) ([]rest.Rule, error) {
+	return append([]rest.Rule{}, da.rules[command]...), nil
+}
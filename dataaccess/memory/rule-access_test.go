@@ -0,0 +1,131 @@
+/*
+ * Copyright 2021 The Gort Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package memory
+
+import (
+	"testing"
+
+	"github.com/getgort/gort/data/rest"
+	"github.com/getgort/gort/dataaccess/errs"
+	"github.com/stretchr/testify/assert"
+)
+
+func testRuleAccess(t *testing.T) {
+	t.Run("testRuleCreate", testRuleCreate)
+	t.Run("testRuleCreateInvalid", testRuleCreateInvalid)
+	t.Run("testRuleDelete", testRuleDelete)
+	t.Run("testRuleList", testRuleList)
+}
+
+func testRuleCreate(t *testing.T) {
+	const rule = `test:create with arg[0] == "x" must have test:write`
+
+	id, err := da.RuleCreate(ctx, "test:create", rule)
+	assert.NoError(t, err)
+	defer da.RuleDelete(ctx, id)
+
+	// The rule is stored exactly as supplied.
+	rs, err := da.RuleList(ctx, "test:create")
+	assert.NoError(t, err)
+	assert.Equal(t, []rest.Rule{{ID: id, Command: "test:create", Rule: rule}}, rs)
+
+	// Each rule has its own ID.
+	id2, err := da.RuleCreate(ctx, "test:create", rule)
+	assert.NoError(t, err)
+	defer da.RuleDelete(ctx, id2)
+	assert.NotEqual(t, id, id2)
+}
+
+func testRuleCreateInvalid(t *testing.T) {
+	invalid := map[string]string{
+		"test:invalid": `test:invalid with must have test:write`,
+		"test:missing": `must have test:write`,
+		"test:other":   `test:invalid allow`,
+		"":             `test:invalid allow`,
+	}
+
+	for command, rule := range invalid {
+		_, err := da.RuleCreate(ctx, command, rule)
+		assert.ErrorIs(t, err, errs.ErrInvalidRule, rule)
+
+		rs, err := da.RuleList(ctx, command)
+		assert.NoError(t, err)
+		assert.Empty(t, rs, rule)
+	}
+}
+
+func testRuleDelete(t *testing.T) {
+	err := da.RuleDelete(ctx, -1)
+	assert.ErrorIs(t, err, errs.ErrNoSuchRule)
+
+	id1, err := da.RuleCreate(ctx, "test:delete", `test:delete allow`)
+	assert.NoError(t, err)
+	id2, err := da.RuleCreate(ctx, "test:delete", `test:delete deny`)
+	assert.NoError(t, err)
+
+	err = da.RuleDelete(ctx, id1)
+	assert.NoError(t, err)
+
+	rs, err := da.RuleList(ctx, "test:delete")
+	assert.NoError(t, err)
+	assert.Equal(t, []rest.Rule{{ID: id2, Command: "test:delete", Rule: `test:delete deny`}}, rs)
+
+	// A rule can only be deleted once.
+	err = da.RuleDelete(ctx, id1)
+	assert.ErrorIs(t, err, errs.ErrNoSuchRule)
+
+	err = da.RuleDelete(ctx, id2)
+	assert.NoError(t, err)
+
+	rs, err = da.RuleList(ctx, "test:delete")
+	assert.NoError(t, err)
+	assert.Empty(t, rs)
+}
+
+func testRuleList(t *testing.T) {
+	rs, err := da.RuleList(ctx, "test:list")
+	assert.NoError(t, err)
+	assert.Empty(t, rs)
+
+	ids := []int{}
+	for _, rule := range []string{`test:list deny`, `test:list with arg[0] == 1 allow`, `test:list must have test:list`} {
+		id, err := da.RuleCreate(ctx, "test:list", rule)
+		assert.NoError(t, err)
+		defer da.RuleDelete(ctx, id)
+
+		ids = append(ids, id)
+	}
+
+	id, err := da.RuleCreate(ctx, "test:other", `test:other allow`)
+	assert.NoError(t, err)
+	defer da.RuleDelete(ctx, id)
+
+	// Only the command's rules are listed, in the order they were created.
+	rs, err = da.RuleList(ctx, "test:list")
+	assert.NoError(t, err)
+	if assert.Len(t, rs, 3) {
+		assert.Equal(t, ids, []int{rs[0].ID, rs[1].ID, rs[2].ID})
+		assert.Equal(t, `test:list deny`, rs[0].Rule)
+	}
+
+	// Changing the list doesn't change the stored rules.
+	rs[0].Rule = "changed"
+
+	rs, err = da.RuleList(ctx, "test:list")
+	assert.NoError(t, err)
+	assert.Equal(t, `test:list deny`, rs[0].Rule)
+}
@@ -18,6 +18,8 @@ package memory
 
 import (
 	"context"
+	"fmt"
+	"sort"
 	"time"
 
 	"github.com/getgort/gort/data"
@@ -49,7 +51,16 @@ func (da *InMemoryDataAccess) TokenEvaluate(ctx context.Context, tokenString str
 // TokenGenerate generates a new token for the given user with a specified
 // expiration duration. Any existing token for this user will be automatically
 // invalidated. If the user doesn't exist an error is returned.
+//
+// If duration is 0 the data access layer's DefaultTokenDuration is used. If
+// it's longer than MaxTokenDuration, an error wrapping
+// errs.ErrTokenDurationExceeded is returned.
 func (da *InMemoryDataAccess) TokenGenerate(ctx context.Context, username string, duration time.Duration) (rest.Token, error) {
+	duration, err := da.tokenDuration(duration)
+	if err != nil {
+		return rest.Token{}, err
+	}
+
 	exists, err := da.UserExists(ctx, username)
 	if err != nil {
 		return rest.Token{}, err
@@ -86,6 +97,24 @@ func (da *InMemoryDataAccess) TokenGenerate(ctx context.Context, username string
 	return token, nil
 }
 
+// tokenDuration returns the duration of a token generated with the requested
+// duration, or an error if the duration exceeds the maximum.
+func (da *InMemoryDataAccess) tokenDuration(duration time.Duration) (time.Duration, error) {
+	if duration == 0 {
+		duration = da.DefaultTokenDuration
+		if duration == 0 {
+			duration = DefaultTokenDuration
+		}
+	}
+
+	if da.MaxTokenDuration > 0 && duration > da.MaxTokenDuration {
+		return 0, fmt.Errorf("%w: %v requested, the maximum is %v",
+			errs.ErrTokenDurationExceeded, duration, da.MaxTokenDuration)
+	}
+
+	return duration, nil
+}
+
 // TokenInvalidate immediately invalidates the specified token. An error is
 // returned if the token doesn't exist.
 func (da *InMemoryDataAccess) TokenInvalidate(ctx context.Context, tokenString string) error {
@@ -100,6 +129,64 @@ func (da *InMemoryDataAccess) TokenInvalidate(ctx context.Context, tokenString s
 	return nil
 }
 
+// TokenInvalidateAllForUser immediately invalidates every token belonging to
+// the specified user, and returns the number of tokens invalidated. An error
+// is returned if the user doesn't exist.
+func (da *InMemoryDataAccess) TokenInvalidateAllForUser(ctx context.Context, username string) (int, error) {
+	exists, err := da.UserExists(ctx, username)
+	if err != nil {
+		return 0, err
+	}
+	if !exists {
+		return 0, errs.ErrNoSuchUser
+	}
+
+	count := 0
+
+	for value, token := range tokensByValue {
+		if token.User == username {
+			delete(tokensByValue, value)
+			count++
+		}
+	}
+
+	delete(tokensByUser, username)
+
+	return count, nil
+}
+
+// TokenList returns all known tokens, including those that have expired but
+// haven't yet been invalidated, ordered by ValidFrom.
+func (da *InMemoryDataAccess) TokenList(ctx context.Context) ([]rest.Token, error) {
+	return da.TokenListValidBetween(ctx, time.Time{}, time.Time{})
+}
+
+// TokenListValidBetween returns all tokens whose validity period overlaps
+// the interval [since, until], ordered by ValidFrom. A zero since or until
+// leaves that end of the interval unbounded.
+func (da *InMemoryDataAccess) TokenListValidBetween(ctx context.Context, since, until time.Time) ([]rest.Token, error) {
+	tokens := make([]rest.Token, 0)
+
+	for _, token := range tokensByValue {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		if token.IsValidBetween(since, until) {
+			tokens = append(tokens, token)
+		}
+	}
+
+	sort.Slice(tokens, func(i, j int) bool {
+		if tokens[i].ValidFrom.Equal(tokens[j].ValidFrom) {
+			return tokens[i].User < tokens[j].User
+		}
+		return tokens[i].ValidFrom.Before(tokens[j].ValidFrom)
+	})
+
+	return tokens, nil
+}
+
 // TokenRetrieveByUser retrieves the token associated with a username. An
 // error is returned if no such token (or user) exists.
 func (da *InMemoryDataAccess) TokenRetrieveByUser(ctx context.Context, username string) (rest.Token, error) {
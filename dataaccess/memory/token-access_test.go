@@ -27,10 +27,13 @@ import (
 
 func testTokenAccess(t *testing.T) {
 	t.Run("testTokenGenerate", testTokenGenerate)
+	t.Run("testTokenGenerateDuration", testTokenGenerateDuration)
 	t.Run("testTokenRetrieveByUser", testTokenRetrieveByUser)
 	t.Run("testTokenRetrieveByToken", testTokenRetrieveByToken)
 	t.Run("testTokenExpiry", testTokenExpiry)
 	t.Run("testTokenInvalidate", testTokenInvalidate)
+	t.Run("testTokenInvalidateAllForUser", testTokenInvalidateAllForUser)
+	t.Run("testTokenList", testTokenList)
 }
 
 func testTokenGenerate(t *testing.T) {
@@ -58,6 +61,49 @@ func testTokenGenerate(t *testing.T) {
 	}
 }
 
+func testTokenGenerateDuration(t *testing.T) {
+	err := da.UserCreate(ctx, rest.User{Username: "test_generate_duration"})
+	defer da.UserDelete(ctx, "test_generate_duration")
+	assert.NoError(t, err)
+
+	defer func() { da.DefaultTokenDuration, da.MaxTokenDuration = 0, 0 }()
+
+	// Zero uses the package default...
+	token, err := da.TokenGenerate(ctx, "test_generate_duration", 0)
+	defer da.TokenInvalidate(ctx, token.Token)
+	assert.NoError(t, err)
+	assert.Equal(t, DefaultTokenDuration, token.Duration)
+	assert.Equal(t, token.ValidFrom.Add(DefaultTokenDuration), token.ValidUntil)
+
+	// ...unless the data access layer has its own.
+	da.DefaultTokenDuration = time.Hour
+	token, err = da.TokenGenerate(ctx, "test_generate_duration", 0)
+	defer da.TokenInvalidate(ctx, token.Token)
+	assert.NoError(t, err)
+	assert.Equal(t, time.Hour, token.Duration)
+
+	// Durations up to the maximum are accepted.
+	da.MaxTokenDuration = 2 * time.Hour
+	token, err = da.TokenGenerate(ctx, "test_generate_duration", 2*time.Hour)
+	defer da.TokenInvalidate(ctx, token.Token)
+	assert.NoError(t, err)
+	assert.Equal(t, 2*time.Hour, token.Duration)
+
+	// Longer durations aren't, and the existing token is kept.
+	_, err = da.TokenGenerate(ctx, "test_generate_duration", 2*time.Hour+time.Second)
+	assert.ErrorIs(t, err, errs.ErrTokenDurationExceeded)
+	assert.EqualError(t, err, "token duration exceeds the maximum: 2h0m1s requested, the maximum is 2h0m0s")
+
+	existing, err := da.TokenRetrieveByUser(ctx, "test_generate_duration")
+	assert.NoError(t, err)
+	assert.Equal(t, token.Token, existing.Token)
+
+	// A default that exceeds the maximum is an error too.
+	da.DefaultTokenDuration = 3 * time.Hour
+	_, err = da.TokenGenerate(ctx, "test_generate_duration", 0)
+	assert.ErrorIs(t, err, errs.ErrTokenDurationExceeded)
+}
+
 func testTokenRetrieveByUser(t *testing.T) {
 	_, err := da.TokenRetrieveByUser(ctx, "no-such-user")
 	assert.Error(t, err, errs.ErrNoSuchToken)
@@ -144,3 +190,100 @@ func testTokenInvalidate(t *testing.T) {
 		t.FailNow()
 	}
 }
+
+func testTokenList(t *testing.T) {
+	err := da.UserCreate(ctx, rest.User{Username: "test_list_active", Email: "test_list_active"})
+	defer da.UserDelete(ctx, "test_list_active")
+	assert.NoError(t, err)
+
+	err = da.UserCreate(ctx, rest.User{Username: "test_list_expired", Email: "test_list_expired"})
+	defer da.UserDelete(ctx, "test_list_expired")
+	assert.NoError(t, err)
+
+	expired, err := da.TokenGenerate(ctx, "test_list_expired", time.Millisecond)
+	defer da.TokenInvalidate(ctx, expired.Token)
+	assert.NoError(t, err)
+
+	active, err := da.TokenGenerate(ctx, "test_list_active", 10*time.Minute)
+	defer da.TokenInvalidate(ctx, active.Token)
+	assert.NoError(t, err)
+
+	time.Sleep(10 * time.Millisecond)
+
+	// tokenUsers returns the test users with tokens in the list, in order.
+	tokenUsers := func(tokens []rest.Token) []string {
+		users := []string{}
+		for _, tk := range tokens {
+			if tk.User == "test_list_active" || tk.User == "test_list_expired" {
+				users = append(users, tk.User)
+			}
+		}
+		return users
+	}
+
+	// Expired tokens are still listed
+	tokens, err := da.TokenList(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"test_list_expired", "test_list_active"}, tokenUsers(tokens))
+
+	// Active tokens only
+	now := time.Now()
+	tokens, err = da.TokenListValidBetween(ctx, now, now)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"test_list_active"}, tokenUsers(tokens))
+
+	// Tokens valid at any point since the expired token was generated
+	tokens, err = da.TokenListValidBetween(ctx, expired.ValidFrom, time.Time{})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"test_list_expired", "test_list_active"}, tokenUsers(tokens))
+
+	// Tokens valid before either was generated
+	tokens, err = da.TokenListValidBetween(ctx, time.Time{}, expired.ValidFrom.Add(-time.Minute))
+	assert.NoError(t, err)
+	assert.Empty(t, tokenUsers(tokens))
+}
+
+func testTokenInvalidateAllForUser(t *testing.T) {
+	_, err := da.TokenInvalidateAllForUser(ctx, "test_invalidate_all_none")
+	assert.ErrorIs(t, err, errs.ErrNoSuchUser)
+
+	err = da.UserCreate(ctx, rest.User{Username: "test_invalidate_all", Email: "test_invalidate_all"})
+	defer da.UserDelete(ctx, "test_invalidate_all")
+	assert.NoError(t, err)
+
+	err = da.UserCreate(ctx, rest.User{Username: "test_invalidate_other", Email: "test_invalidate_other"})
+	defer da.UserDelete(ctx, "test_invalidate_other")
+	assert.NoError(t, err)
+
+	other, err := da.TokenGenerate(ctx, "test_invalidate_other", 10*time.Minute)
+	defer da.TokenInvalidate(ctx, other.Token)
+	assert.NoError(t, err)
+
+	// No tokens yet
+	count, err := da.TokenInvalidateAllForUser(ctx, "test_invalidate_all")
+	assert.NoError(t, err)
+	assert.Equal(t, 0, count)
+
+	// Generating a token replaces the user's previous token, so only the
+	// most recent remains.
+	tokens := []rest.Token{}
+	for i := 0; i < 3; i++ {
+		token, err := da.TokenGenerate(ctx, "test_invalidate_all", 10*time.Minute)
+		assert.NoError(t, err)
+		tokens = append(tokens, token)
+	}
+
+	count, err = da.TokenInvalidateAllForUser(ctx, "test_invalidate_all")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, count)
+
+	for _, token := range tokens {
+		assert.False(t, da.TokenEvaluate(ctx, token.Token))
+	}
+
+	_, err = da.TokenRetrieveByUser(ctx, "test_invalidate_all")
+	assert.ErrorIs(t, err, errs.ErrNoSuchToken)
+
+	// Other users' tokens are unaffected
+	assert.True(t, da.TokenEvaluate(ctx, other.Token))
+}
@@ -20,6 +20,7 @@ import (
 	"context"
 	"sort"
 
+	"github.com/getgort/gort/data"
 	"github.com/getgort/gort/data/rest"
 	"github.com/getgort/gort/dataaccess/errs"
 )
@@ -48,6 +49,9 @@ func (da *InMemoryDataAccess) UserCreate(ctx context.Context, user rest.User) er
 	if user.Username == "" {
 		return errs.ErrEmptyUserName
 	}
+	if !data.IsValidName(user.Username) {
+		return errs.ErrInvalidUserName
+	}
 
 	exists, err := da.UserExists(ctx, user.Username)
 	if err != nil {
@@ -62,6 +66,41 @@ func (da *InMemoryDataAccess) UserCreate(ctx context.Context, user rest.User) er
 	return nil
 }
 
+// UserCreateBulk creates each of the users that doesn't already exist, and
+// updates each one that does. A user that can't be saved doesn't stop the
+// others from being saved: the results, in the same order as users, report
+// whether each user was created or updated, or why it wasn't. An error is
+// returned only if ctx is done before every user has been saved, along with
+// the results for the users that were.
+func (da *InMemoryDataAccess) UserCreateBulk(ctx context.Context, users []rest.User) ([]rest.UserBulkResult, error) {
+	results := make([]rest.UserBulkResult, 0, len(users))
+
+	for _, user := range users {
+		if err := ctx.Err(); err != nil {
+			return results, err
+		}
+
+		result := rest.UserBulkResult{Username: user.Username, Status: rest.UserBulkCreated}
+
+		exists, err := da.UserExists(ctx, user.Username)
+		if err == nil && exists {
+			result.Status = rest.UserBulkUpdated
+			err = da.UserUpdate(ctx, user)
+		} else if err == nil {
+			err = da.UserCreate(ctx, user)
+		}
+
+		if err != nil {
+			result.Status = rest.UserBulkError
+			result.Error = err.Error()
+		}
+
+		results = append(results, result)
+	}
+
+	return results, nil
+}
+
 // UserDelete deletes an existing user from the data store. An error is
 // returned if the username parameter is empty of if the user doesn't
 // exist.
@@ -120,6 +159,10 @@ func (da *InMemoryDataAccess) UserGet(ctx context.Context, username string) (res
 // the email parameter is empty or if the user doesn't exist.
 func (da *InMemoryDataAccess) UserGetByEmail(ctx context.Context, email string) (rest.User, error) {
 	for _, v := range da.users {
+		if err := ctx.Err(); err != nil {
+			return rest.User{}, err
+		}
+
 		if v.Email == email {
 			return *v, nil
 		}
@@ -134,6 +177,10 @@ func (da *InMemoryDataAccess) UserGroupList(ctx context.Context, username string
 	groups := make([]rest.Group, 0)
 
 	for _, group := range da.groups {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
 		for _, user := range group.Users {
 			if user.Username == username {
 				groups = append(groups, rest.Group{Name: group.Name})
@@ -161,6 +208,10 @@ func (da *InMemoryDataAccess) UserList(ctx context.Context) ([]rest.User, error)
 	list := make([]rest.User, 0)
 
 	for _, u := range da.users {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
 		u.Password = ""
 		list = append(list, *u)
 	}
@@ -168,6 +219,22 @@ func (da *InMemoryDataAccess) UserList(ctx context.Context) ([]rest.User, error)
 	return list, nil
 }
 
+// UserListPage returns the page of users, sorted by username, that starts at
+// offset and contains at most limit users, along with the total number of
+// users. A limit of 0 or less means that there's no limit.
+func (da *InMemoryDataAccess) UserListPage(ctx context.Context, offset, limit int) ([]rest.User, int, error) {
+	list, err := da.UserList(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	sort.Slice(list, func(i, j int) bool { return list[i].Username < list[j].Username })
+
+	start, end := pageBounds(len(list), offset, limit)
+
+	return list[start:end], len(list), nil
+}
+
 // UserPermissionList returns an alphabetically-sorted list of permissions
 // available to the specified user.
 func (da *InMemoryDataAccess) UserPermissionList(ctx context.Context, username string) (rest.RolePermissionList, error) {
@@ -182,6 +249,10 @@ func (da *InMemoryDataAccess) UserPermissionList(ctx context.Context, username s
 
 	// Collect all permissions from all groups to remove any repeats.
 	for _, group := range groups {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
 		gpl, err := da.GroupPermissionList(ctx, group.Name)
 		if err != nil {
 			return nil, err
@@ -215,6 +286,10 @@ func (da *InMemoryDataAccess) UserRoleList(ctx context.Context, username string)
 	}
 
 	for _, gr := range groups {
+		if err := ctx.Err(); err != nil {
+			return []rest.Role{}, err
+		}
+
 		rl, err := da.GroupRoleList(ctx, gr.Name)
 		if err != nil {
 			return []rest.Role{}, err
@@ -256,3 +331,33 @@ func (da *InMemoryDataAccess) UserUpdate(ctx context.Context, user rest.User) er
 
 	return nil
 }
+
+// UserUpdatePartial changes only the fields of a user that are set in patch,
+// leaving the others intact. An error is returned if the username is empty
+// or if the user doesn't exist.
+func (da *InMemoryDataAccess) UserUpdatePartial(ctx context.Context, username string, patch rest.UserPatch) error {
+	if username == "" {
+		return errs.ErrEmptyUserName
+	}
+
+	existing, ok := da.users[username]
+	if !ok {
+		return errs.ErrNoSuchUser
+	}
+
+	user := *existing
+
+	if patch.Email != nil {
+		user.Email = *patch.Email
+	}
+	if patch.FullName != nil {
+		user.FullName = *patch.FullName
+	}
+	if patch.Password != nil && *patch.Password != "" {
+		user.Password = *patch.Password
+	}
+
+	da.users[username] = &user
+
+	return nil
+}
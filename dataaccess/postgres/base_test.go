@@ -67,12 +67,14 @@ func TestPostgresDataAccessMain(t *testing.T) {
 	}()
 
 	t.Run("testInitialize", testInitialize)
+	t.Run("testPing", testPing)
 	t.Run("testUserAccess", testUserAccess)
 	t.Run("testGroupAccess", testGroupAccess)
 	t.Run("testTokenAccess", testTokenAccess)
 	t.Run("testBundleAccess", testBundleAccess)
 	t.Run("testRoleAccess", testRoleAccess)
 	t.Run("testRequestAccess", testRequestAccess)
+	t.Run("testExportImport", testExportImport)
 }
 
 func startDatabaseContainer(ctx context.Context, t *testing.T) (func(), error) {
@@ -202,3 +204,7 @@ func testTablesExist(t *testing.T) {
 	assert.NoError(t, err)
 	assert.False(t, b)
 }
+
+func testPing(t *testing.T) {
+	assert.NoError(t, da.Ping(ctx))
+}
@@ -0,0 +1,396 @@
+/*
+ * Copyright 2021 The Gort Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package postgres
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/lib/pq"
+	"go.opentelemetry.io/otel"
+
+	"github.com/getgort/gort/data"
+	"github.com/getgort/gort/data/rest"
+	"github.com/getgort/gort/dataaccess/errs"
+	gerr "github.com/getgort/gort/errors"
+	"github.com/getgort/gort/telemetry"
+)
+
+// Export returns a snapshot of all of the users, groups, and roles in the
+// data store, and of the users' tokens. Its contents are sorted by name.
+// Users' passwords are exported as hashes.
+func (da PostgresDataAccess) Export(ctx context.Context) (rest.Snapshot, error) {
+	tr := otel.GetTracerProvider().Tracer(telemetry.ServiceName)
+	ctx, sp := tr.Start(ctx, "postgres.Export")
+	defer sp.End()
+
+	db, err := da.connect(ctx, DatabaseGort)
+	if err != nil {
+		return rest.Snapshot{}, err
+	}
+	defer db.Close()
+
+	s := rest.Snapshot{
+		Users:  []rest.User{},
+		Groups: []rest.SnapshotGroup{},
+		Roles:  []rest.SnapshotRole{},
+	}
+
+	query := `SELECT email, full_name, password_hash, username FROM users ORDER BY username`
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return rest.Snapshot{}, gerr.Wrap(errs.ErrDataAccess, err)
+	}
+	for rows.Next() {
+		var u rest.User
+		var fullName, hash sql.NullString
+		if err = rows.Scan(&u.Email, &fullName, &hash, &u.Username); err != nil {
+			rows.Close()
+			return rest.Snapshot{}, gerr.Wrap(errs.ErrDataAccess, err)
+		}
+		u.FullName, u.Password = fullName.String, hash.String
+		s.Users = append(s.Users, u)
+	}
+	rows.Close()
+
+	groups := map[string]*rest.SnapshotGroup{}
+	names, err := exportColumn(ctx, db, `SELECT groupname FROM groups ORDER BY groupname`)
+	if err != nil {
+		return rest.Snapshot{}, err
+	}
+	for _, name := range names {
+		s.Groups = append(s.Groups, rest.SnapshotGroup{Name: name, Users: []string{}, Roles: []string{}})
+	}
+	for i := range s.Groups {
+		groups[s.Groups[i].Name] = &s.Groups[i]
+	}
+
+	roles := map[string]*rest.SnapshotRole{}
+	names, err = exportColumn(ctx, db, `SELECT role_name FROM roles ORDER BY role_name`)
+	if err != nil {
+		return rest.Snapshot{}, err
+	}
+	for _, name := range names {
+		s.Roles = append(s.Roles, rest.SnapshotRole{Name: name, Permissions: rest.RolePermissionList{}, Parents: []string{}})
+	}
+	for i := range s.Roles {
+		roles[s.Roles[i].Name] = &s.Roles[i]
+	}
+
+	pairs, err := exportPairs(ctx, db, `SELECT groupname, username FROM groupusers ORDER BY groupname, username`)
+	if err != nil {
+		return rest.Snapshot{}, err
+	}
+	for _, p := range pairs {
+		if g, ok := groups[p[0]]; ok {
+			g.Users = append(g.Users, p[1])
+		}
+	}
+
+	pairs, err = exportPairs(ctx, db, `SELECT group_name, role_name FROM group_roles ORDER BY group_name, role_name`)
+	if err != nil {
+		return rest.Snapshot{}, err
+	}
+	for _, p := range pairs {
+		if g, ok := groups[p[0]]; ok {
+			g.Roles = append(g.Roles, p[1])
+		}
+	}
+
+	pairs, err = exportPairs(ctx, db, `SELECT role_name, parent_name FROM role_parents ORDER BY role_name, parent_name`)
+	if err != nil {
+		return rest.Snapshot{}, err
+	}
+	for _, p := range pairs {
+		if r, ok := roles[p[0]]; ok {
+			r.Parents = append(r.Parents, p[1])
+		}
+	}
+
+	query = `SELECT role_name, bundle_name, permission FROM role_permissions
+		ORDER BY role_name, bundle_name, permission`
+	rows, err = db.QueryContext(ctx, query)
+	if err != nil {
+		return rest.Snapshot{}, gerr.Wrap(errs.ErrDataAccess, err)
+	}
+	for rows.Next() {
+		var rolename string
+		var p rest.RolePermission
+		if err = rows.Scan(&rolename, &p.BundleName, &p.Permission); err != nil {
+			rows.Close()
+			return rest.Snapshot{}, gerr.Wrap(errs.ErrDataAccess, err)
+		}
+		if r, ok := roles[rolename]; ok {
+			r.Permissions = append(r.Permissions, p)
+		}
+	}
+	rows.Close()
+
+	query = `SELECT token, username, valid_from, valid_until FROM tokens ORDER BY username`
+	rows, err = db.QueryContext(ctx, query)
+	if err != nil {
+		return rest.Snapshot{}, gerr.Wrap(errs.ErrDataAccess, err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var t rest.Token
+		if err = rows.Scan(&t.Token, &t.User, &t.ValidFrom, &t.ValidUntil); err != nil {
+			return rest.Snapshot{}, gerr.Wrap(errs.ErrDataAccess, err)
+		}
+		t.Duration = t.ValidUntil.Sub(t.ValidFrom)
+		s.Tokens = append(s.Tokens, t)
+	}
+
+	if err = rows.Err(); err != nil {
+		return rest.Snapshot{}, gerr.Wrap(errs.ErrDataAccess, err)
+	}
+
+	return s, nil
+}
+
+// exportColumn returns the values of the single text column selected by
+// query.
+func exportColumn(ctx context.Context, db *sql.DB, query string) ([]string, error) {
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, gerr.Wrap(errs.ErrDataAccess, err)
+	}
+	defer rows.Close()
+
+	values := []string{}
+	for rows.Next() {
+		var v string
+		if err = rows.Scan(&v); err != nil {
+			return nil, gerr.Wrap(errs.ErrDataAccess, err)
+		}
+		values = append(values, v)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, gerr.Wrap(errs.ErrDataAccess, err)
+	}
+
+	return values, nil
+}
+
+// exportPairs returns the values of the two text columns selected by query.
+func exportPairs(ctx context.Context, db *sql.DB, query string) ([][2]string, error) {
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, gerr.Wrap(errs.ErrDataAccess, err)
+	}
+	defer rows.Close()
+
+	pairs := [][2]string{}
+	for rows.Next() {
+		var p [2]string
+		if err = rows.Scan(&p[0], &p[1]); err != nil {
+			return nil, gerr.Wrap(errs.ErrDataAccess, err)
+		}
+		pairs = append(pairs, p)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, gerr.Wrap(errs.ErrDataAccess, err)
+	}
+
+	return pairs, nil
+}
+
+// Import restores a snapshot created by Export, in a single transaction. If
+// replace is true, the users, groups, and roles that aren't in the snapshot
+// are deleted, along with their tokens, and those that are have their
+// attributes, memberships, and permissions replaced by the snapshot's, except
+// that a user whose password is empty in the snapshot keeps its password. If
+// replace is false, the snapshot is merged into the data store: missing
+// users, groups, and roles are created, and existing ones keep their
+// attributes but gain the snapshot's memberships, permissions, and parents.
+// In either case a token is only restored for a user that doesn't already
+// have one. A snapshot that replaces the data store must include the admin
+// user, group, and role, or errs.ErrAdminUndeletable is returned. Users'
+// passwords must be hashes, as exported by Export.
+func (da PostgresDataAccess) Import(ctx context.Context, s rest.Snapshot, replace bool) error {
+	tr := otel.GetTracerProvider().Tracer(telemetry.ServiceName)
+	ctx, sp := tr.Start(ctx, "postgres.Import")
+	defer sp.End()
+
+	if replace && !s.IncludesAdmin() {
+		return errs.ErrAdminUndeletable
+	}
+
+	usernames := make([]string, len(s.Users))
+	for i, u := range s.Users {
+		if u.Username == "" {
+			return errs.ErrEmptyUserName
+		}
+		if !data.IsValidName(u.Username) {
+			return errs.ErrInvalidUserName
+		}
+		usernames[i] = u.Username
+	}
+
+	groupnames := make([]string, len(s.Groups))
+	for i, g := range s.Groups {
+		if g.Name == "" {
+			return errs.ErrEmptyGroupName
+		}
+		if !data.IsValidName(g.Name) {
+			return errs.ErrInvalidGroupName
+		}
+		groupnames[i] = g.Name
+	}
+
+	rolenames := make([]string, len(s.Roles))
+	for i, r := range s.Roles {
+		if r.Name == "" {
+			return errs.ErrEmptyRoleName
+		}
+		if !data.IsValidName(r.Name) {
+			return errs.ErrInvalidRoleName
+		}
+		rolenames[i] = r.Name
+	}
+
+	db, err := da.connect(ctx, DatabaseGort)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	tx, err := db.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return gerr.Wrap(errs.ErrDataAccess, err)
+	}
+
+	exec := func(query string, args ...interface{}) error {
+		if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+			tx.Rollback()
+			return gerr.Wrap(errs.ErrDataAccess, err)
+		}
+		return nil
+	}
+
+	if replace {
+		for _, q := range []struct {
+			query string
+			args  []interface{}
+		}{
+			{`DELETE FROM groupusers;`, nil},
+			{`DELETE FROM group_roles;`, nil},
+			{`DELETE FROM role_parents;`, nil},
+			{`DELETE FROM role_permissions;`, nil},
+			{`DELETE FROM groups WHERE groupname <> ALL($1);`, []interface{}{pq.Array(groupnames)}},
+			{`DELETE FROM roles WHERE role_name <> ALL($1);`, []interface{}{pq.Array(rolenames)}},
+			{`DELETE FROM tokens WHERE username <> ALL($1);`, []interface{}{pq.Array(usernames)}},
+			{`DELETE FROM users WHERE username <> ALL($1);`, []interface{}{pq.Array(usernames)}},
+		} {
+			if err := exec(q.query, q.args...); err != nil {
+				return err
+			}
+		}
+	}
+
+	userQuery := `INSERT INTO users (email, full_name, password_hash, username)
+		VALUES ($1, $2, $3, $4) ON CONFLICT (username) DO NOTHING;`
+	if replace {
+		userQuery = `INSERT INTO users (email, full_name, password_hash, username)
+			VALUES ($1, $2, $3, $4) ON CONFLICT (username) DO UPDATE
+			SET email=EXCLUDED.email, full_name=EXCLUDED.full_name,
+			password_hash=COALESCE(NULLIF(EXCLUDED.password_hash, ''), users.password_hash);`
+	}
+
+	for _, u := range s.Users {
+		if err := exec(userQuery, u.Email, u.FullName, u.Password, u.Username); err != nil {
+			return err
+		}
+	}
+
+	for _, r := range s.Roles {
+		query := `INSERT INTO roles (role_name) VALUES ($1) ON CONFLICT DO NOTHING;`
+		if err := exec(query, r.Name); err != nil {
+			return err
+		}
+
+		for _, p := range r.Permissions {
+			query := `INSERT INTO role_permissions (role_name, bundle_name, permission)
+				VALUES ($1, $2, $3) ON CONFLICT DO NOTHING;`
+			if err := exec(query, r.Name, p.BundleName, p.Permission); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, r := range s.Roles {
+		for _, p := range r.Parents {
+			query := `INSERT INTO role_parents (role_name, parent_name)
+				VALUES ($1, $2) ON CONFLICT DO NOTHING;`
+			if err := exec(query, r.Name, p); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, g := range s.Groups {
+		query := `INSERT INTO groups (groupname) VALUES ($1) ON CONFLICT DO NOTHING;`
+		if err := exec(query, g.Name); err != nil {
+			return err
+		}
+
+		for _, u := range g.Users {
+			query := `INSERT INTO groupusers (groupname, username)
+				VALUES ($1, $2) ON CONFLICT DO NOTHING;`
+			if err := exec(query, g.Name, u); err != nil {
+				return err
+			}
+		}
+
+		for _, r := range g.Roles {
+			// group_roles doesn't reference roles, so check that it exists.
+			var exists bool
+			query := `SELECT EXISTS(SELECT 1 FROM roles WHERE role_name=$1)`
+			if err := tx.QueryRowContext(ctx, query, r).Scan(&exists); err != nil {
+				tx.Rollback()
+				return gerr.Wrap(errs.ErrDataAccess, err)
+			}
+			if !exists {
+				tx.Rollback()
+				return errs.ErrNoSuchRole
+			}
+
+			query = `INSERT INTO group_roles (group_name, role_name)
+				VALUES ($1, $2) ON CONFLICT DO NOTHING;`
+			if err := exec(query, g.Name, r); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, t := range s.Tokens {
+		query := `INSERT INTO tokens (token, username, valid_from, valid_until)
+			VALUES ($1, $2, $3, $4) ON CONFLICT DO NOTHING;`
+		if err := exec(query, t.Token, t.User, t.ValidFrom, t.ValidUntil); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		tx.Rollback()
+		return gerr.Wrap(errs.ErrDataAccess, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,137 @@
+/*
+ * Copyright 2021 The Gort Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package postgres
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/getgort/gort/data/rest"
+	"github.com/getgort/gort/dataaccess/errs"
+)
+
+func testExportImport(t *testing.T) {
+	var (
+		groupname = "group-test-export"
+		rolename  = "role-test-export"
+		basename  = "role-test-export-base"
+		username  = "user-test-export"
+		extraname = "user-test-export-extra"
+	)
+
+	// The admin user, group, and role must survive a replacing import.
+	da.UserCreate(ctx, rest.User{Username: "admin", Email: "admin"})
+	da.GroupCreate(ctx, rest.Group{Name: "admin"})
+	da.RoleCreate(ctx, "admin")
+
+	da.UserCreate(ctx, rest.User{Username: username, Email: username, Password: "secret"})
+	defer da.UserDelete(ctx, username)
+	da.RoleCreate(ctx, basename)
+	defer da.RoleDelete(ctx, basename)
+	da.RoleCreate(ctx, rolename)
+	defer da.RoleDelete(ctx, rolename)
+	da.RolePermissionAdd(ctx, rolename, "foo", "write")
+	da.RoleAddParent(ctx, rolename, basename)
+	da.GroupCreate(ctx, rest.Group{Name: groupname})
+	defer da.GroupDelete(ctx, groupname)
+	da.GroupUserAdd(ctx, groupname, username)
+	da.GroupRoleAdd(ctx, groupname, rolename)
+	token, err := da.TokenGenerate(ctx, username, time.Hour)
+	assert.NoError(t, err)
+	defer da.TokenInvalidate(ctx, token.Token)
+
+	snapshot, err := da.Export(ctx)
+	assert.NoError(t, err)
+
+	assert.Contains(t, snapshot.Groups, rest.SnapshotGroup{Name: groupname, Users: []string{username}, Roles: []string{rolename}})
+	assert.Contains(t, snapshot.Roles, rest.SnapshotRole{
+		Name:        rolename,
+		Permissions: rest.RolePermissionList{{BundleName: "foo", Permission: "write"}},
+		Parents:     []string{basename},
+	})
+
+	// Passwords are exported as hashes.
+	for _, u := range snapshot.Users {
+		if u.Username == username {
+			assert.NotEmpty(t, u.Password)
+			assert.NotEqual(t, "secret", u.Password)
+		}
+	}
+
+	// Merging restores removed memberships and permissions.
+	da.GroupUserDelete(ctx, groupname, username)
+	da.RolePermissionDelete(ctx, rolename, "foo", "write")
+
+	assert.NoError(t, da.Import(ctx, snapshot, false))
+
+	restored, err := da.Export(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, snapshot.Groups, restored.Groups)
+	assert.Equal(t, snapshot.Roles, restored.Roles)
+
+	// Replacing removes anything that's not in the snapshot, but keeps the
+	// password hashes intact.
+	da.UserCreate(ctx, rest.User{Username: extraname, Email: extraname})
+	defer da.UserDelete(ctx, extraname)
+
+	assert.NoError(t, da.Import(ctx, snapshot, true))
+
+	exists, _ := da.UserExists(ctx, extraname)
+	assert.False(t, exists)
+
+	restored, err = da.Export(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, snapshot.Users, restored.Users)
+	assert.Equal(t, snapshot.Groups, restored.Groups)
+	assert.Equal(t, snapshot.Roles, restored.Roles)
+
+	authenticated, err := da.UserAuthenticate(ctx, username, "secret")
+	assert.NoError(t, err)
+	assert.True(t, authenticated)
+	assert.True(t, da.TokenEvaluate(ctx, token.Token))
+
+	// Users keep their passwords if the snapshot's are empty.
+	noPasswords := snapshot
+	noPasswords.Users = append([]rest.User{}, snapshot.Users...)
+	for i := range noPasswords.Users {
+		noPasswords.Users[i].Password = ""
+	}
+	assert.NoError(t, da.Import(ctx, noPasswords, true))
+	authenticated, err = da.UserAuthenticate(ctx, username, "secret")
+	assert.NoError(t, err)
+	assert.True(t, authenticated)
+
+	// A snapshot that would replace the admin user, group, or role away is
+	// rejected before anything is deleted.
+	noAdmin := rest.Snapshot{Users: []rest.User{{Username: username}}}
+	assert.ErrorIs(t, da.Import(ctx, noAdmin, true), errs.ErrAdminUndeletable)
+	exists, _ = da.UserExists(ctx, "admin")
+	assert.True(t, exists)
+	exists, _ = da.GroupExists(ctx, "admin")
+	assert.True(t, exists)
+	exists, _ = da.RoleExists(ctx, "admin")
+	assert.True(t, exists)
+	restored, err = da.Export(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, snapshot.Groups, restored.Groups)
+
+	// A group can't be granted a role that doesn't exist.
+	bad := rest.Snapshot{Groups: []rest.SnapshotGroup{{Name: groupname, Roles: []string{"no-such-role"}}}}
+	assert.ErrorIs(t, da.Import(ctx, bad, false), errs.ErrNoSuchRole)
+}
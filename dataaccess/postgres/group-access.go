@@ -23,6 +23,7 @@ import (
 
 	"go.opentelemetry.io/otel"
 
+	"github.com/getgort/gort/data"
 	"github.com/getgort/gort/data/rest"
 	"github.com/getgort/gort/dataaccess/errs"
 	gerr "github.com/getgort/gort/errors"
@@ -38,6 +39,9 @@ func (da PostgresDataAccess) GroupCreate(ctx context.Context, group rest.Group)
 	if group.Name == "" {
 		return errs.ErrEmptyGroupName
 	}
+	if !data.IsValidName(group.Name) {
+		return errs.ErrInvalidGroupName
+	}
 
 	exists, err := da.GroupExists(ctx, group.Name)
 	if err != nil {
@@ -62,6 +66,83 @@ func (da PostgresDataAccess) GroupCreate(ctx context.Context, group rest.Group)
 	return err
 }
 
+// GroupCreateWithRoles creates a new user group and grants it the listed
+// roles in a single transaction. If any role doesn't exist the transaction
+// is rolled back, so the group isn't created, and errs.ErrNoSuchRole is
+// returned.
+func (da PostgresDataAccess) GroupCreateWithRoles(ctx context.Context, group rest.Group, roles ...string) error {
+	tr := otel.GetTracerProvider().Tracer(telemetry.ServiceName)
+	ctx, sp := tr.Start(ctx, "postgres.GroupCreateWithRoles")
+	defer sp.End()
+
+	if group.Name == "" {
+		return errs.ErrEmptyGroupName
+	}
+	if !data.IsValidName(group.Name) {
+		return errs.ErrInvalidGroupName
+	}
+	for _, rolename := range roles {
+		if rolename == "" {
+			return errs.ErrEmptyRoleName
+		}
+	}
+
+	exists, err := da.GroupExists(ctx, group.Name)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return errs.ErrGroupExists
+	}
+
+	db, err := da.connect(ctx, DatabaseGort)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	tx, err := db.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return gerr.Wrap(errs.ErrDataAccess, err)
+	}
+
+	query := `INSERT INTO groups (groupname) VALUES ($1);`
+	_, err = tx.ExecContext(ctx, query, group.Name)
+	if err != nil {
+		tx.Rollback()
+		return gerr.Wrap(errs.ErrDataAccess, err)
+	}
+
+	for _, rolename := range roles {
+		query = "SELECT EXISTS(SELECT 1 FROM roles WHERE role_name=$1)"
+		err = tx.QueryRowContext(ctx, query, rolename).Scan(&exists)
+		if err != nil {
+			tx.Rollback()
+			return gerr.Wrap(errs.ErrDataAccess, err)
+		}
+		if !exists {
+			tx.Rollback()
+			return errs.ErrNoSuchRole
+		}
+
+		query = `INSERT INTO group_roles (group_name, role_name)
+			VALUES ($1, $2);`
+		_, err = tx.ExecContext(ctx, query, group.Name, rolename)
+		if err != nil {
+			tx.Rollback()
+			return gerr.Wrap(errs.ErrDataAccess, err)
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		tx.Rollback()
+		return gerr.Wrap(errs.ErrDataAccess, err)
+	}
+
+	return nil
+}
+
 // GroupDelete deletes a group.
 func (da PostgresDataAccess) GroupDelete(ctx context.Context, groupname string) error {
 	tr := otel.GetTracerProvider().Tracer(telemetry.ServiceName)
@@ -106,6 +187,88 @@ func (da PostgresDataAccess) GroupDelete(ctx context.Context, groupname string)
 	return nil
 }
 
+// GroupRename renames a group, preserving its members and the roles granted
+// to it. The admin group can't be renamed.
+func (da PostgresDataAccess) GroupRename(ctx context.Context, oldName, newName string) error {
+	tr := otel.GetTracerProvider().Tracer(telemetry.ServiceName)
+	ctx, sp := tr.Start(ctx, "postgres.GroupRename")
+	defer sp.End()
+
+	if oldName == "" || newName == "" {
+		return errs.ErrEmptyGroupName
+	}
+	if !data.IsValidName(newName) {
+		return errs.ErrInvalidGroupName
+	}
+
+	// Renaming admin is as good as deleting it
+	if oldName == "admin" {
+		return errs.ErrAdminUndeletable
+	}
+
+	exists, err := da.GroupExists(ctx, oldName)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return errs.ErrNoSuchGroup
+	}
+
+	exists, err = da.GroupExists(ctx, newName)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return errs.ErrGroupExists
+	}
+
+	db, err := da.connect(ctx, DatabaseGort)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	tx, err := db.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return gerr.Wrap(errs.ErrDataAccess, err)
+	}
+
+	// The memberships and role grants reference the group, so create the
+	// new group, move them to it, and only then delete the old one.
+	query := `INSERT INTO groups (groupname) VALUES ($1);`
+	_, err = tx.ExecContext(ctx, query, newName)
+	if err != nil {
+		tx.Rollback()
+		return gerr.Wrap(errs.ErrDataAccess, err)
+	}
+
+	for _, query := range []string{
+		`UPDATE groupusers SET groupname=$2 WHERE groupname=$1;`,
+		`UPDATE group_roles SET group_name=$2 WHERE group_name=$1;`,
+	} {
+		_, err = tx.ExecContext(ctx, query, oldName, newName)
+		if err != nil {
+			tx.Rollback()
+			return gerr.Wrap(errs.ErrDataAccess, err)
+		}
+	}
+
+	query = `DELETE FROM groups WHERE groupname=$1;`
+	_, err = tx.ExecContext(ctx, query, oldName)
+	if err != nil {
+		tx.Rollback()
+		return gerr.Wrap(errs.ErrDataAccess, err)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		tx.Rollback()
+		return gerr.Wrap(errs.ErrDataAccess, err)
+	}
+
+	return nil
+}
+
 // GroupExists is used to determine whether a group exists in the data store.
 func (da PostgresDataAccess) GroupExists(ctx context.Context, groupname string) (bool, error) {
 	tr := otel.GetTracerProvider().Tracer(telemetry.ServiceName)
@@ -165,6 +328,13 @@ func (da PostgresDataAccess) GroupGet(ctx context.Context, groupname string) (re
 
 	group.Users = users
 
+	roles, err := da.GroupRoleList(ctx, groupname)
+	if err != nil {
+		return group, err
+	}
+
+	group.Roles = roles
+
 	return group, nil
 }
 
@@ -203,6 +373,92 @@ func (da PostgresDataAccess) GroupList(ctx context.Context) ([]rest.Group, error
 	return groups, nil
 }
 
+// GroupListSummary returns a summary of each group, sorted by name, giving
+// its number of members and roles.
+func (da PostgresDataAccess) GroupListSummary(ctx context.Context) ([]rest.GroupSummary, error) {
+	tr := otel.GetTracerProvider().Tracer(telemetry.ServiceName)
+	ctx, sp := tr.Start(ctx, "postgres.GroupListSummary")
+	defer sp.End()
+
+	list := make([]rest.GroupSummary, 0)
+
+	db, err := da.connect(ctx, DatabaseGort)
+	if err != nil {
+		return list, err
+	}
+	defer db.Close()
+
+	query := `SELECT g.groupname,
+		(SELECT COUNT(*) FROM groupusers gu WHERE gu.groupname = g.groupname),
+		(SELECT COUNT(*) FROM group_roles gr WHERE gr.group_name = g.groupname)
+	FROM groups g
+	ORDER BY g.groupname`
+
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return list, gerr.Wrap(errs.ErrDataAccess, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var s rest.GroupSummary
+
+		if err = rows.Scan(&s.Name, &s.MemberCount, &s.RoleCount); err != nil {
+			return list, gerr.Wrap(errs.ErrDataAccess, err)
+		}
+
+		list = append(list, s)
+	}
+
+	if err = rows.Err(); err != nil {
+		return list, gerr.Wrap(errs.ErrDataAccess, err)
+	}
+
+	return list, nil
+}
+
+// GroupListPage returns the page of groups, sorted by name, that starts at
+// offset and contains at most limit groups, along with the total number of
+// groups. A limit of 0 or less means that there's no limit.
+func (da PostgresDataAccess) GroupListPage(ctx context.Context, offset, limit int) ([]rest.Group, int, error) {
+	tr := otel.GetTracerProvider().Tracer(telemetry.ServiceName)
+	ctx, sp := tr.Start(ctx, "postgres.GroupListPage")
+	defer sp.End()
+
+	db, err := da.connect(ctx, DatabaseGort)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer db.Close()
+
+	var total int
+	query := `SELECT COUNT(*) FROM groups`
+	if err := db.QueryRowContext(ctx, query).Scan(&total); err != nil {
+		return nil, 0, gerr.Wrap(errs.ErrDataAccess, err)
+	}
+
+	off, lim := pageArgs(offset, limit)
+	query = `SELECT groupname FROM groups ORDER BY groupname OFFSET $1 LIMIT $2`
+	rows, err := db.QueryContext(ctx, query, off, lim)
+	if err != nil {
+		return nil, 0, gerr.Wrap(errs.ErrDataAccess, err)
+	}
+	defer rows.Close()
+
+	groups := make([]rest.Group, 0)
+	for rows.Next() {
+		group := rest.Group{}
+
+		if err = rows.Scan(&group.Name); err != nil {
+			return nil, 0, gerr.Wrap(errs.ErrNoSuchGroup, err)
+		}
+
+		groups = append(groups, group)
+	}
+
+	return groups, total, nil
+}
+
 func (da PostgresDataAccess) GroupPermissionList(ctx context.Context, groupname string) (rest.RolePermissionList, error) {
 	tr := otel.GetTracerProvider().Tracer(telemetry.ServiceName)
 	ctx, sp := tr.Start(ctx, "postgres.GroupPermissionList")
@@ -402,7 +658,8 @@ func (da PostgresDataAccess) GroupUpdate(ctx context.Context, group rest.Group)
 	return err
 }
 
-// GroupUserAdd adds a user to a group
+// GroupUserAdd adds a user to a group. It returns errs.ErrUserAlreadyInGroup
+// if the user is already a member of the group.
 func (da PostgresDataAccess) GroupUserAdd(ctx context.Context, groupname string, username string) error {
 	tr := otel.GetTracerProvider().Tracer(telemetry.ServiceName)
 	ctx, sp := tr.Start(ctx, "postgres.GroupUserAdd")
@@ -438,7 +695,16 @@ func (da PostgresDataAccess) GroupUserAdd(ctx context.Context, groupname string,
 	}
 	defer db.Close()
 
-	query := `INSERT INTO groupusers (groupname, username) VALUES ($1, $2);`
+	query := `SELECT EXISTS(SELECT 1 FROM groupusers WHERE groupname=$1 AND username=$2)`
+	err = db.QueryRowContext(ctx, query, groupname, username).Scan(&exists)
+	if err != nil {
+		return gerr.Wrap(errs.ErrDataAccess, err)
+	}
+	if exists {
+		return errs.ErrUserAlreadyInGroup
+	}
+
+	query = `INSERT INTO groupusers (groupname, username) VALUES ($1, $2);`
 	_, err = db.ExecContext(ctx, query, groupname, username)
 	if err != nil {
 		err = gerr.Wrap(errs.ErrDataAccess, err)
@@ -447,7 +713,8 @@ func (da PostgresDataAccess) GroupUserAdd(ctx context.Context, groupname string,
 	return err
 }
 
-// GroupUserDelete removes a user from a group.
+// GroupUserDelete removes a user from a group. It returns
+// errs.ErrUserNotInGroup if the user exists but isn't a member of the group.
 func (da PostgresDataAccess) GroupUserDelete(ctx context.Context, groupname string, username string) error {
 	tr := otel.GetTracerProvider().Tracer(telemetry.ServiceName)
 	ctx, sp := tr.Start(ctx, "postgres.GroupUserDelete")
@@ -465,6 +732,18 @@ func (da PostgresDataAccess) GroupUserDelete(ctx context.Context, groupname stri
 		return errs.ErrNoSuchGroup
 	}
 
+	if username == "" {
+		return errs.ErrEmptyUserName
+	}
+
+	exists, err = da.UserExists(ctx, username)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return errs.ErrNoSuchUser
+	}
+
 	db, err := da.connect(ctx, DatabaseGort)
 	if err != nil {
 		return err
@@ -472,12 +751,20 @@ func (da PostgresDataAccess) GroupUserDelete(ctx context.Context, groupname stri
 	defer db.Close()
 
 	query := "DELETE FROM groupusers WHERE groupname=$1 AND username=$2;"
-	_, err = db.ExecContext(ctx, query, groupname, username)
+	result, err := db.ExecContext(ctx, query, groupname, username)
 	if err != nil {
-		err = gerr.Wrap(errs.ErrDataAccess, err)
+		return gerr.Wrap(errs.ErrDataAccess, err)
 	}
 
-	return err
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return gerr.Wrap(errs.ErrDataAccess, err)
+	}
+	if rows == 0 {
+		return errs.ErrUserNotInGroup
+	}
+
+	return nil
 }
 
 // GroupUserList returns a list of all known users in a group.
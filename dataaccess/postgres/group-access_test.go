@@ -22,18 +22,23 @@ import (
 	"github.com/getgort/gort/data/rest"
 	"github.com/getgort/gort/dataaccess/errs"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func testGroupAccess(t *testing.T) {
 	t.Run("testGroupUserAdd", testGroupUserAdd)
 	t.Run("testGroupUserList", testGroupUserList)
 	t.Run("testGroupCreate", testGroupCreate)
+	t.Run("testGroupCreateWithRoles", testGroupCreateWithRoles)
 	t.Run("testGroupDelete", testGroupDelete)
 	t.Run("testGroupExists", testGroupExists)
 	t.Run("testGroupGet", testGroupGet)
 	t.Run("testGroupRoleAdd", testGroupRoleAdd)
+	t.Run("testGroupRename", testGroupRename)
 	t.Run("testGroupPermissionList", testGroupPermissionList)
 	t.Run("testGroupList", testGroupList)
+	t.Run("testGroupListPage", testGroupListPage)
+	t.Run("testGroupListSummary", testGroupListSummary)
 	t.Run("testGroupRoleList", testGroupRoleList)
 	t.Run("testGroupUserDelete", testGroupUserDelete)
 }
@@ -60,6 +65,9 @@ func testGroupUserAdd(t *testing.T) {
 	err = da.GroupUserAdd(ctx, groupname, username)
 	assert.NoError(t, err)
 
+	err = da.GroupUserAdd(ctx, groupname, username)
+	assert.ErrorIs(t, err, errs.ErrUserAlreadyInGroup)
+
 	group, _ := da.GroupGet(ctx, groupname)
 
 	if !assert.Len(t, group.Users, 1) {
@@ -114,6 +122,12 @@ func testGroupCreate(t *testing.T) {
 	err = da.GroupCreate(ctx, group)
 	assert.Error(t, err, errs.ErrEmptyGroupName)
 
+	// Expect errors for names that aren't safe to use in a URL path
+	for _, name := range []string{"test/create", "test create", "test\tcreate", "test%2Fcreate", "tést", ".", ".."} {
+		err = da.GroupCreate(ctx, rest.Group{Name: name})
+		assert.ErrorIs(t, err, errs.ErrInvalidGroupName, name)
+	}
+
 	// Expect no error
 	err = da.GroupCreate(ctx, rest.Group{Name: "test-create"})
 	defer da.GroupDelete(ctx, "test-create")
@@ -124,6 +138,95 @@ func testGroupCreate(t *testing.T) {
 	assert.Error(t, err, errs.ErrGroupExists)
 }
 
+func testGroupCreateWithRoles(t *testing.T) {
+	var (
+		groupname = "group-test-group-create-with-roles"
+		rolenames = []string{"role-test-group-create-with-roles-1", "role-test-group-create-with-roles-2"}
+	)
+
+	for _, rolename := range rolenames {
+		err := da.RoleCreate(ctx, rolename)
+		defer da.RoleDelete(ctx, rolename)
+		assert.NoError(t, err)
+	}
+
+	// One role doesn't exist: the group isn't created
+	err := da.GroupCreateWithRoles(ctx, rest.Group{Name: groupname}, rolenames[0], "no-such-role")
+	assert.ErrorIs(t, err, errs.ErrNoSuchRole)
+
+	exists, err := da.GroupExists(ctx, groupname)
+	assert.NoError(t, err)
+	assert.False(t, exists)
+
+	// All roles exist
+	err = da.GroupCreateWithRoles(ctx, rest.Group{Name: groupname}, rolenames...)
+	defer da.GroupDelete(ctx, groupname)
+	assert.NoError(t, err)
+
+	roles, err := da.GroupRoleList(ctx, groupname)
+	assert.NoError(t, err)
+	if assert.Len(t, roles, 2) {
+		assert.ElementsMatch(t, rolenames, []string{roles[0].Name, roles[1].Name})
+	}
+
+	// The group already exists
+	err = da.GroupCreateWithRoles(ctx, rest.Group{Name: groupname}, rolenames...)
+	assert.ErrorIs(t, err, errs.ErrGroupExists)
+}
+
+func testGroupRename(t *testing.T) {
+	var (
+		oldname  = "group-test-group-rename-old"
+		newname  = "group-test-group-rename-new"
+		rolename = "role-test-group-rename"
+		username = "user-test-group-rename"
+	)
+
+	err := da.GroupRename(ctx, oldname, newname)
+	assert.ErrorIs(t, err, errs.ErrNoSuchGroup)
+
+	err = da.GroupRename(ctx, "admin", newname)
+	assert.ErrorIs(t, err, errs.ErrAdminUndeletable)
+
+	da.GroupCreate(ctx, rest.Group{Name: oldname})
+	defer da.GroupDelete(ctx, oldname)
+	da.RoleCreate(ctx, rolename)
+	defer da.RoleDelete(ctx, rolename)
+	da.RolePermissionAdd(ctx, rolename, "foo", "bar")
+	da.GroupRoleAdd(ctx, oldname, rolename)
+	da.UserCreate(ctx, rest.User{Username: username})
+	defer da.UserDelete(ctx, username)
+	da.GroupUserAdd(ctx, oldname, username)
+
+	err = da.GroupRename(ctx, oldname, "")
+	assert.ErrorIs(t, err, errs.ErrEmptyGroupName)
+
+	err = da.GroupRename(ctx, oldname, "bad/name")
+	assert.ErrorIs(t, err, errs.ErrInvalidGroupName)
+
+	err = da.GroupRename(ctx, oldname, oldname)
+	assert.ErrorIs(t, err, errs.ErrGroupExists)
+
+	err = da.GroupRename(ctx, oldname, newname)
+	defer da.GroupDelete(ctx, newname)
+	assert.NoError(t, err)
+
+	exists, _ := da.GroupExists(ctx, oldname)
+	assert.False(t, exists)
+
+	// Members and roles survive the rename
+	group, err := da.GroupGet(ctx, newname)
+	assert.NoError(t, err)
+	assert.Equal(t, newname, group.Name)
+	if assert.Len(t, group.Users, 1) {
+		assert.Equal(t, username, group.Users[0].Username)
+	}
+	if assert.Len(t, group.Roles, 1) {
+		assert.Equal(t, rolename, group.Roles[0].Name)
+		assert.Equal(t, rest.RolePermissionList{{BundleName: "foo", Permission: "bar"}}, group.Roles[0].Permissions)
+	}
+}
+
 func testGroupDelete(t *testing.T) {
 	// Delete blank group
 	err := da.GroupDelete(ctx, "")
@@ -197,6 +300,30 @@ func testGroupGet(t *testing.T) {
 	if !assert.Equal(t, groupname, group.Name) {
 		t.FailNow()
 	}
+	assert.Empty(t, group.Roles)
+
+	// Grant a role and add a user, and expect GroupGet to include them
+	const rolename = "role-test-group-get"
+	const username = "user-test-group-get"
+
+	assert.NoError(t, da.RoleCreate(ctx, rolename))
+	defer da.RoleDelete(ctx, rolename)
+	assert.NoError(t, da.RolePermissionAdd(ctx, rolename, "test", "test-permission"))
+	assert.NoError(t, da.GroupRoleAdd(ctx, groupname, rolename))
+
+	assert.NoError(t, da.UserCreate(ctx, rest.User{Username: username, Email: username}))
+	defer da.UserDelete(ctx, username)
+	assert.NoError(t, da.GroupUserAdd(ctx, groupname, username))
+
+	group, err = da.GroupGet(ctx, groupname)
+	assert.NoError(t, err)
+	if assert.Len(t, group.Roles, 1) {
+		assert.Equal(t, rolename, group.Roles[0].Name)
+		assert.Equal(t, []string{"test:test-permission"}, group.Roles[0].Permissions.Strings())
+	}
+	if assert.Len(t, group.Users, 1) {
+		assert.Equal(t, username, group.Users[0].Username)
+	}
 }
 
 func testGroupPermissionList(t *testing.T) {
@@ -371,6 +498,21 @@ func testGroupRoleList(t *testing.T) {
 	}
 
 	assert.Equal(t, expected, actual)
+
+	// Permissions granted after the role was added are included
+	err = da.RolePermissionAdd(ctx, rolenames[0], "bundle-test-group-list-roles", "perm-test-group-list-roles")
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	expected[0].Permissions = []rest.RolePermission{{BundleName: "bundle-test-group-list-roles", Permission: "perm-test-group-list-roles"}}
+
+	actual, err = da.GroupRoleList(ctx, groupname)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	assert.Equal(t, expected, actual)
 }
 
 func testGroupUserDelete(t *testing.T) {
@@ -406,4 +548,78 @@ func testGroupUserDelete(t *testing.T) {
 		t.Error("User not removed")
 		t.FailNow()
 	}
+
+	err = da.GroupUserDelete(ctx, "foo", "bat")
+	assert.ErrorIs(t, err, errs.ErrUserNotInGroup)
+
+	err = da.GroupUserDelete(ctx, "foo", "no-such-user")
+	assert.ErrorIs(t, err, errs.ErrNoSuchUser)
+}
+
+func testGroupListPage(t *testing.T) {
+	for _, name := range []string{"test-page-0", "test-page-2", "test-page-1"} {
+		da.GroupCreate(ctx, rest.Group{Name: name})
+		defer da.GroupDelete(ctx, name)
+	}
+
+	all, total, err := da.GroupListPage(ctx, 0, 0)
+	require.NoError(t, err)
+	require.NotEmpty(t, all)
+	assert.Equal(t, len(all), total)
+	assert.GreaterOrEqual(t, total, 3)
+
+	// Pages are sorted and together make up the complete list
+	paged := []rest.Group{}
+	for offset := 0; offset < total; offset += 2 {
+		page, n, err := da.GroupListPage(ctx, offset, 2)
+		assert.NoError(t, err)
+		assert.Equal(t, total, n)
+		assert.LessOrEqual(t, len(page), 2)
+		paged = append(paged, page...)
+	}
+	assert.Equal(t, all, paged)
+
+	for i := 1; i < len(all); i++ {
+		assert.Less(t, all[i-1].Name, all[i].Name)
+	}
+
+	page, n, err := da.GroupListPage(ctx, total, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, total, n)
+	assert.Empty(t, page)
+
+	page, _, err = da.GroupListPage(ctx, -1, 1)
+	require.NoError(t, err)
+	assert.Equal(t, all[:1], page)
+}
+
+func testGroupListSummary(t *testing.T) {
+	assert.NoError(t, da.GroupCreate(ctx, rest.Group{Name: "test-summary-b"}))
+	defer da.GroupDelete(ctx, "test-summary-b")
+	assert.NoError(t, da.GroupCreate(ctx, rest.Group{Name: "test-summary-a"}))
+	defer da.GroupDelete(ctx, "test-summary-a")
+
+	for _, name := range []string{"test-summary-user-0", "test-summary-user-1"} {
+		assert.NoError(t, da.UserCreate(ctx, rest.User{Username: name, Email: name}))
+		defer da.UserDelete(ctx, name)
+		assert.NoError(t, da.GroupUserAdd(ctx, "test-summary-a", name))
+	}
+
+	assert.NoError(t, da.RoleCreate(ctx, "test-summary-role"))
+	defer da.RoleDelete(ctx, "test-summary-role")
+	assert.NoError(t, da.GroupRoleAdd(ctx, "test-summary-a", "test-summary-role"))
+
+	summaries, err := da.GroupListSummary(ctx)
+	assert.NoError(t, err)
+
+	found := map[string]rest.GroupSummary{}
+	for i, s := range summaries {
+		if i > 0 {
+			assert.Less(t, summaries[i-1].Name, s.Name)
+		}
+		found[s.Name] = s
+	}
+
+	assert.Equal(t, rest.GroupSummary{Name: "test-summary-a", MemberCount: 2, RoleCount: 1}, found["test-summary-a"])
+	assert.Equal(t, rest.GroupSummary{Name: "test-summary-b", MemberCount: 0, RoleCount: 0}, found["test-summary-b"])
 }
@@ -61,6 +61,22 @@ func (da PostgresDataAccess) Initialize(ctx context.Context) error {
 	return nil
 }
 
+// Ping verifies that the database is reachable.
+func (da PostgresDataAccess) Ping(ctx context.Context) error {
+	tr := otel.GetTracerProvider().Tracer(telemetry.ServiceName)
+	ctx, sp := tr.Start(ctx, "postgres.Ping")
+	defer sp.End()
+
+	// connect pings the database before returning.
+	db, err := da.connect(ctx, DatabaseGort)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	return nil
+}
+
 func (da PostgresDataAccess) initializeAuditData(ctx context.Context) error {
 	// Does the database exist? If not, create it.
 	err := da.ensureDatabaseExists(ctx, DatabaseGort)
@@ -188,6 +204,19 @@ func (da PostgresDataAccess) initializeGortData(ctx context.Context) error {
 		}
 	}
 
+	// Check whether the role_parents table exists
+	exists, err = da.tableExists(ctx, "role_parents", db)
+	if err != nil {
+		return err
+	}
+
+	if !exists {
+		err = da.createRoleParentsTable(ctx, db)
+		if err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
@@ -344,6 +373,20 @@ func (da PostgresDataAccess) createGroupUsersTable(ctx context.Context, db *sql.
 	return nil
 }
 
+// pageArgs returns the arguments for a query's "OFFSET $n LIMIT $m" clause. A
+// negative offset is treated as 0, and a limit of 0 or less becomes NULL,
+// which means that there's no limit.
+func pageArgs(offset, limit int) (interface{}, interface{}) {
+	if offset < 0 {
+		offset = 0
+	}
+	if limit <= 0 {
+		return offset, nil
+	}
+
+	return offset, limit
+}
+
 func (da PostgresDataAccess) createRolesTables(ctx context.Context, db *sql.DB) error {
 	var err error
 
@@ -380,6 +423,28 @@ func (da PostgresDataAccess) createRolesTables(ctx context.Context, db *sql.DB)
 	return nil
 }
 
+func (da PostgresDataAccess) createRoleParentsTable(ctx context.Context, db *sql.DB) error {
+	var err error
+
+	createRoleParentsQuery := `CREATE TABLE role_parents (
+		role_name			TEXT NOT NULL,
+		parent_name			TEXT NOT NULL,
+		PRIMARY KEY			(role_name, parent_name),
+		FOREIGN KEY 		(role_name) REFERENCES roles(role_name)
+		ON DELETE CASCADE,
+		FOREIGN KEY 		(parent_name) REFERENCES roles(role_name)
+		ON DELETE CASCADE
+	);
+	`
+
+	_, err = db.ExecContext(ctx, createRoleParentsQuery)
+	if err != nil {
+		return gerr.Wrap(errs.ErrDataAccess, err)
+	}
+
+	return nil
+}
+
 func (da PostgresDataAccess) createTokensTable(ctx context.Context, db *sql.DB) error {
 	var err error
 
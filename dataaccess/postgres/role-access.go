@@ -18,17 +18,75 @@ package postgres
 
 import (
 	"context"
+	"database/sql"
 	"log"
 	"sort"
 
 	"go.opentelemetry.io/otel"
 
+	"github.com/getgort/gort/data"
 	"github.com/getgort/gort/data/rest"
 	"github.com/getgort/gort/dataaccess/errs"
 	gerr "github.com/getgort/gort/errors"
 	"github.com/getgort/gort/telemetry"
 )
 
+// RoleAddParent makes parentname a parent of rolename, so that rolename
+// inherits all of the permissions of parentname (and of its parents). It
+// returns errs.ErrRoleCycle if rolename and parentname are the same role or
+// if parentname already inherits from rolename. Adding an existing parent
+// has no effect.
+func (da PostgresDataAccess) RoleAddParent(ctx context.Context, rolename, parentname string) error {
+	tr := otel.GetTracerProvider().Tracer(telemetry.ServiceName)
+	ctx, sp := tr.Start(ctx, "postgres.RoleAddParent")
+	defer sp.End()
+
+	if rolename == "" || parentname == "" {
+		return errs.ErrEmptyRoleName
+	}
+
+	for _, name := range []string{rolename, parentname} {
+		exists, err := da.RoleExists(ctx, name)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			return errs.ErrNoSuchRole
+		}
+	}
+
+	if rolename == parentname {
+		return errs.ErrRoleCycle
+	}
+
+	ancestors, err := da.doGetRoleAncestors(ctx, parentname)
+	if err != nil {
+		return err
+	}
+
+	for _, a := range ancestors {
+		if a == rolename {
+			return errs.ErrRoleCycle
+		}
+	}
+
+	db, err := da.connect(ctx, DatabaseGort)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	query := `INSERT INTO role_parents (role_name, parent_name)
+		VALUES ($1, $2)
+		ON CONFLICT DO NOTHING;`
+	_, err = db.ExecContext(ctx, query, rolename, parentname)
+	if err != nil {
+		return gerr.Wrap(errs.ErrDataAccess, err)
+	}
+
+	return nil
+}
+
 // RoleCreate creates a new role.
 func (da PostgresDataAccess) RoleCreate(ctx context.Context, name string) error {
 	tr := otel.GetTracerProvider().Tracer(telemetry.ServiceName)
@@ -38,6 +96,9 @@ func (da PostgresDataAccess) RoleCreate(ctx context.Context, name string) error
 	if name == "" {
 		return errs.ErrEmptyRoleName
 	}
+	if !data.IsValidName(name) {
+		return errs.ErrInvalidRoleName
+	}
 
 	exists, err := da.RoleExists(ctx, name)
 	if err != nil {
@@ -62,6 +123,37 @@ func (da PostgresDataAccess) RoleCreate(ctx context.Context, name string) error
 	return err
 }
 
+// RoleEnsure creates a role if it doesn't already exist, and returns the
+// current role. Unlike RoleCreate, it doesn't return an error if the role
+// already exists, even if another caller creates it concurrently, so it can
+// safely be called more than once.
+func (da PostgresDataAccess) RoleEnsure(ctx context.Context, name string) (rest.Role, error) {
+	tr := otel.GetTracerProvider().Tracer(telemetry.ServiceName)
+	ctx, sp := tr.Start(ctx, "postgres.RoleEnsure")
+	defer sp.End()
+
+	if name == "" {
+		return rest.Role{}, errs.ErrEmptyRoleName
+	}
+	if !data.IsValidName(name) {
+		return rest.Role{}, errs.ErrInvalidRoleName
+	}
+
+	db, err := da.connect(ctx, DatabaseGort)
+	if err != nil {
+		return rest.Role{}, err
+	}
+	defer db.Close()
+
+	query := `INSERT INTO roles (role_name) VALUES ($1) ON CONFLICT DO NOTHING;`
+	_, err = db.ExecContext(ctx, query, name)
+	if err != nil {
+		return rest.Role{}, gerr.Wrap(errs.ErrDataAccess, err)
+	}
+
+	return da.RoleGet(ctx, name)
+}
+
 // RoleDelete
 func (da PostgresDataAccess) RoleDelete(ctx context.Context, name string) error {
 	tr := otel.GetTracerProvider().Tracer(telemetry.ServiceName)
@@ -106,6 +198,90 @@ func (da PostgresDataAccess) RoleDelete(ctx context.Context, name string) error
 	return nil
 }
 
+// RoleRename renames a role. Its permissions, parents, and the groups it's
+// granted to are preserved.
+func (da PostgresDataAccess) RoleRename(ctx context.Context, oldName, newName string) error {
+	tr := otel.GetTracerProvider().Tracer(telemetry.ServiceName)
+	ctx, sp := tr.Start(ctx, "postgres.RoleRename")
+	defer sp.End()
+
+	if oldName == "" || newName == "" {
+		return errs.ErrEmptyRoleName
+	}
+	if !data.IsValidName(newName) {
+		return errs.ErrInvalidRoleName
+	}
+
+	// Renaming admin is as good as deleting it
+	if oldName == "admin" {
+		return errs.ErrAdminUndeletable
+	}
+
+	exists, err := da.RoleExists(ctx, oldName)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return errs.ErrNoSuchRole
+	}
+
+	exists, err = da.RoleExists(ctx, newName)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return errs.ErrRoleExists
+	}
+
+	db, err := da.connect(ctx, DatabaseGort)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	tx, err := db.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return gerr.Wrap(errs.ErrDataAccess, err)
+	}
+
+	// Permissions and parents reference the role, so create the new role,
+	// move everything to it, and only then delete the old one.
+	query := `INSERT INTO roles (role_name) VALUES ($1);`
+	_, err = tx.ExecContext(ctx, query, newName)
+	if err != nil {
+		tx.Rollback()
+		return gerr.Wrap(errs.ErrDataAccess, err)
+	}
+
+	for _, query := range []string{
+		`UPDATE group_roles SET role_name=$2 WHERE role_name=$1;`,
+		`UPDATE role_permissions SET role_name=$2 WHERE role_name=$1;`,
+		`UPDATE role_parents SET role_name=$2 WHERE role_name=$1;`,
+		`UPDATE role_parents SET parent_name=$2 WHERE parent_name=$1;`,
+	} {
+		_, err = tx.ExecContext(ctx, query, oldName, newName)
+		if err != nil {
+			tx.Rollback()
+			return gerr.Wrap(errs.ErrDataAccess, err)
+		}
+	}
+
+	query = `DELETE FROM roles WHERE role_name=$1;`
+	_, err = tx.ExecContext(ctx, query, oldName)
+	if err != nil {
+		tx.Rollback()
+		return gerr.Wrap(errs.ErrDataAccess, err)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		tx.Rollback()
+		return gerr.Wrap(errs.ErrDataAccess, err)
+	}
+
+	return nil
+}
+
 // RoleExists is used to determine whether a group exists in the data store.
 func (da PostgresDataAccess) RoleExists(ctx context.Context, rolename string) (bool, error) {
 	tr := otel.GetTracerProvider().Tracer(telemetry.ServiceName)
@@ -129,7 +305,8 @@ func (da PostgresDataAccess) RoleExists(ctx context.Context, rolename string) (b
 	return exists, nil
 }
 
-// RoleGet gets a specific group.
+// RoleGet gets a specific role. Its permissions include those inherited
+// from its parents.
 func (da PostgresDataAccess) RoleGet(ctx context.Context, name string) (rest.Role, error) {
 	tr := otel.GetTracerProvider().Tracer(telemetry.ServiceName)
 	ctx, sp := tr.Start(ctx, "postgres.RoleGet")
@@ -161,7 +338,39 @@ func (da PostgresDataAccess) RoleGet(ctx context.Context, name string) (rest.Rol
 		return role, err
 	}
 
+	parents, err := da.doGetRoleParents(ctx, name)
+	if err != nil {
+		return role, err
+	}
+
+	if len(parents) > 0 {
+		ancestors, err := da.doGetRoleAncestors(ctx, name)
+		if err != nil {
+			return role, err
+		}
+
+		seen := map[rest.RolePermission]bool{}
+		for _, p := range perms {
+			seen[p] = true
+		}
+
+		for _, a := range ancestors {
+			aperms, err := da.doGetRolePermissions(ctx, a)
+			if err != nil {
+				return role, err
+			}
+
+			for _, p := range aperms {
+				if !seen[p] {
+					seen[p] = true
+					perms = append(perms, p)
+				}
+			}
+		}
+	}
+
 	role.Permissions = perms
+	role.Parents = parents
 
 	return role, nil
 }
@@ -361,37 +570,46 @@ func (da PostgresDataAccess) RolePermissionAdd(ctx context.Context, rolename, bu
 	return err
 }
 
-func (da PostgresDataAccess) RolePermissionDelete(ctx context.Context, rolename, bundle, permission string) error {
+// RolePermissionDelete revokes a permission from a role, and returns the
+// number of permissions that were removed: 0 if the role hadn't been granted
+// the permission. Permissions that the role inherits from its parents aren't
+// affected.
+func (da PostgresDataAccess) RolePermissionDelete(ctx context.Context, rolename, bundle, permission string) (int, error) {
 	tr := otel.GetTracerProvider().Tracer(telemetry.ServiceName)
 	ctx, sp := tr.Start(ctx, "postgres.RolePermissionDelete")
 	defer sp.End()
 
 	if rolename == "" {
-		return errs.ErrEmptyRoleName
+		return 0, errs.ErrEmptyRoleName
 	}
 
 	if bundle == "" {
-		return errs.ErrEmptyBundleName
+		return 0, errs.ErrEmptyBundleName
 	}
 
 	if permission == "" {
-		return errs.ErrEmptyPermission
+		return 0, errs.ErrEmptyPermission
 	}
 
 	db, err := da.connect(ctx, DatabaseGort)
 	if err != nil {
-		return err
+		return 0, err
 	}
 	defer db.Close()
 
 	query := `DELETE FROM role_permissions
 		WHERE role_name=$1 AND bundle_name=$2 AND permission=$3;`
-	_, err = db.ExecContext(ctx, query, rolename, bundle, permission)
+	result, err := db.ExecContext(ctx, query, rolename, bundle, permission)
 	if err != nil {
-		return gerr.Wrap(errs.ErrDataAccess, err)
+		return 0, gerr.Wrap(errs.ErrDataAccess, err)
 	}
 
-	return err
+	count, err := result.RowsAffected()
+	if err != nil {
+		return 0, gerr.Wrap(errs.ErrDataAccess, err)
+	}
+
+	return int(count), nil
 }
 
 // RolePermissionExists returns true if the given role has been granted the
@@ -467,3 +685,87 @@ func (da PostgresDataAccess) doGetRolePermissions(ctx context.Context, name stri
 
 	return perms, nil
 }
+
+// doGetRoleParents returns the names of the roles that the named role
+// directly inherits from, or nil if it has none.
+func (da PostgresDataAccess) doGetRoleParents(ctx context.Context, name string) ([]string, error) {
+	tr := otel.GetTracerProvider().Tracer(telemetry.ServiceName)
+	ctx, sp := tr.Start(ctx, "postgres.doGetRoleParents")
+	defer sp.End()
+
+	db, err := da.connect(ctx, DatabaseGort)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	query := `SELECT parent_name
+		FROM role_parents
+		WHERE role_name = $1
+		ORDER BY parent_name`
+
+	rows, err := db.QueryContext(ctx, query, name)
+	if err != nil {
+		return nil, gerr.Wrap(errs.ErrDataAccess, err)
+	}
+	defer rows.Close()
+
+	var parents []string
+
+	for rows.Next() {
+		var parent string
+
+		if err = rows.Scan(&parent); err != nil {
+			return nil, gerr.Wrap(errs.ErrDataAccess, err)
+		}
+
+		parents = append(parents, parent)
+	}
+
+	return parents, nil
+}
+
+// doGetRoleAncestors returns the names of all of the roles that the named
+// role inherits from, directly or indirectly.
+func (da PostgresDataAccess) doGetRoleAncestors(ctx context.Context, name string) ([]string, error) {
+	tr := otel.GetTracerProvider().Tracer(telemetry.ServiceName)
+	ctx, sp := tr.Start(ctx, "postgres.doGetRoleAncestors")
+	defer sp.End()
+
+	db, err := da.connect(ctx, DatabaseGort)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	// UNION (rather than UNION ALL) discards duplicates, which guarantees
+	// termination even if the table somehow contains a cycle.
+	query := `WITH RECURSIVE ancestors(role_name) AS (
+			SELECT parent_name FROM role_parents WHERE role_name = $1
+			UNION
+			SELECT rp.parent_name
+			FROM role_parents rp
+			JOIN ancestors a ON rp.role_name = a.role_name
+		)
+		SELECT role_name FROM ancestors ORDER BY role_name`
+
+	rows, err := db.QueryContext(ctx, query, name)
+	if err != nil {
+		return nil, gerr.Wrap(errs.ErrDataAccess, err)
+	}
+	defer rows.Close()
+
+	ancestors := []string{}
+
+	for rows.Next() {
+		var ancestor string
+
+		if err = rows.Scan(&ancestor); err != nil {
+			return nil, gerr.Wrap(errs.ErrDataAccess, err)
+		}
+
+		ancestors = append(ancestors, ancestor)
+	}
+
+	return ancestors, nil
+}
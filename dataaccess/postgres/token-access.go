@@ -120,6 +120,96 @@ func (da PostgresDataAccess) TokenInvalidate(ctx context.Context, tokenString st
 	return nil
 }
 
+// TokenInvalidateAllForUser immediately invalidates every token belonging to
+// the specified user, and returns the number of tokens invalidated. An error
+// is returned if the user doesn't exist.
+func (da PostgresDataAccess) TokenInvalidateAllForUser(ctx context.Context, username string) (int, error) {
+	tr := otel.GetTracerProvider().Tracer(telemetry.ServiceName)
+	ctx, sp := tr.Start(ctx, "postgres.TokenInvalidateAllForUser")
+	defer sp.End()
+
+	exists, err := da.UserExists(ctx, username)
+	if err != nil {
+		return 0, err
+	}
+	if !exists {
+		return 0, errs.ErrNoSuchUser
+	}
+
+	db, err := da.connect(ctx, DatabaseGort)
+	if err != nil {
+		return 0, err
+	}
+	defer db.Close()
+
+	query := `DELETE FROM tokens WHERE username=$1;`
+	result, err := db.ExecContext(ctx, query, username)
+	if err != nil {
+		return 0, gerr.Wrap(errs.ErrDataAccess, err)
+	}
+
+	count, err := result.RowsAffected()
+	if err != nil {
+		return 0, gerr.Wrap(errs.ErrDataAccess, err)
+	}
+
+	return int(count), nil
+}
+
+// TokenList returns all known tokens, including those that have expired but
+// haven't yet been invalidated, ordered by ValidFrom.
+func (da PostgresDataAccess) TokenList(ctx context.Context) ([]rest.Token, error) {
+	tr := otel.GetTracerProvider().Tracer(telemetry.ServiceName)
+	ctx, sp := tr.Start(ctx, "postgres.TokenList")
+	defer sp.End()
+
+	return da.TokenListValidBetween(ctx, time.Time{}, time.Time{})
+}
+
+// TokenListValidBetween returns all tokens whose validity period overlaps
+// the interval [since, until], ordered by ValidFrom. A zero since or until
+// leaves that end of the interval unbounded.
+func (da PostgresDataAccess) TokenListValidBetween(ctx context.Context, since, until time.Time) ([]rest.Token, error) {
+	tr := otel.GetTracerProvider().Tracer(telemetry.ServiceName)
+	ctx, sp := tr.Start(ctx, "postgres.TokenListValidBetween")
+	defer sp.End()
+
+	db, err := da.connect(ctx, DatabaseGort)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	query := `SELECT token, username, valid_from, valid_until
+		FROM tokens
+		WHERE ($1 OR valid_until >= $2) AND ($3 OR valid_from <= $4)
+		ORDER BY valid_from, username`
+
+	rows, err := db.QueryContext(ctx, query, since.IsZero(), since, until.IsZero(), until)
+	if err != nil {
+		return nil, gerr.Wrap(errs.ErrDataAccess, err)
+	}
+	defer rows.Close()
+
+	tokens := make([]rest.Token, 0)
+	for rows.Next() {
+		token := rest.Token{}
+		err = rows.Scan(&token.Token, &token.User, &token.ValidFrom, &token.ValidUntil)
+		if err != nil {
+			return nil, gerr.Wrap(errs.ErrDataAccess, err)
+		}
+
+		token.Duration = token.ValidUntil.Sub(token.ValidFrom)
+		tokens = append(tokens, token)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, gerr.Wrap(errs.ErrDataAccess, err)
+	}
+
+	return tokens, nil
+}
+
 // TokenRetrieveByUser retrieves the token associated with a username. An
 // error is returned if no such token (or user) exists.
 func (da PostgresDataAccess) TokenRetrieveByUser(ctx context.Context, username string) (rest.Token, error) {
@@ -71,6 +71,9 @@ func (da PostgresDataAccess) UserCreate(ctx context.Context, user rest.User) err
 	if user.Username == "" {
 		return errs.ErrEmptyUserName
 	}
+	if !data.IsValidName(user.Username) {
+		return errs.ErrInvalidUserName
+	}
 
 	exists, err := da.UserExists(ctx, user.Username)
 	if err != nil {
@@ -104,6 +107,45 @@ func (da PostgresDataAccess) UserCreate(ctx context.Context, user rest.User) err
 	return err
 }
 
+// UserCreateBulk creates each of the users that doesn't already exist, and
+// updates each one that does. A user that can't be saved doesn't stop the
+// others from being saved: the results, in the same order as users, report
+// whether each user was created or updated, or why it wasn't. An error is
+// returned only if ctx is done before every user has been saved, along with
+// the results for the users that were.
+func (da PostgresDataAccess) UserCreateBulk(ctx context.Context, users []rest.User) ([]rest.UserBulkResult, error) {
+	tr := otel.GetTracerProvider().Tracer(telemetry.ServiceName)
+	ctx, sp := tr.Start(ctx, "postgres.UserCreateBulk")
+	defer sp.End()
+
+	results := make([]rest.UserBulkResult, 0, len(users))
+
+	for _, user := range users {
+		if err := ctx.Err(); err != nil {
+			return results, err
+		}
+
+		result := rest.UserBulkResult{Username: user.Username, Status: rest.UserBulkCreated}
+
+		exists, err := da.UserExists(ctx, user.Username)
+		if err == nil && exists {
+			result.Status = rest.UserBulkUpdated
+			err = da.UserUpdate(ctx, user)
+		} else if err == nil {
+			err = da.UserCreate(ctx, user)
+		}
+
+		if err != nil {
+			result.Status = rest.UserBulkError
+			result.Error = err.Error()
+		}
+
+		results = append(results, result)
+	}
+
+	return results, nil
+}
+
 // UserDelete deletes an existing user from the data store. An error is
 // returned if the username parameter is empty or if the user doesn't
 // exist.
@@ -402,6 +444,48 @@ func (da PostgresDataAccess) UserList(ctx context.Context) ([]rest.User, error)
 	return users, err
 }
 
+// UserListPage returns the page of users, sorted by username, that starts at
+// offset and contains at most limit users, along with the total number of
+// users. A limit of 0 or less means that there's no limit.
+func (da PostgresDataAccess) UserListPage(ctx context.Context, offset, limit int) ([]rest.User, int, error) {
+	tr := otel.GetTracerProvider().Tracer(telemetry.ServiceName)
+	ctx, sp := tr.Start(ctx, "postgres.UserListPage")
+	defer sp.End()
+
+	db, err := da.connect(ctx, DatabaseGort)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer db.Close()
+
+	var total int
+	query := `SELECT COUNT(*) FROM users`
+	if err := db.QueryRowContext(ctx, query).Scan(&total); err != nil {
+		return nil, 0, gerr.Wrap(errs.ErrDataAccess, err)
+	}
+
+	off, lim := pageArgs(offset, limit)
+	query = `SELECT email, full_name, username FROM users ORDER BY username OFFSET $1 LIMIT $2`
+	rows, err := db.QueryContext(ctx, query, off, lim)
+	if err != nil {
+		return nil, 0, gerr.Wrap(errs.ErrDataAccess, err)
+	}
+	defer rows.Close()
+
+	users := make([]rest.User, 0)
+	for rows.Next() {
+		user := rest.User{}
+
+		if err = rows.Scan(&user.Email, &user.FullName, &user.Username); err != nil {
+			return nil, 0, gerr.Wrap(errs.ErrNoSuchUser, err)
+		}
+
+		users = append(users, user)
+	}
+
+	return users, total, nil
+}
+
 // UserPermissionList returns an alphabetically-sorted list of permissions
 // available to the specified user.
 func (da PostgresDataAccess) UserPermissionList(ctx context.Context, username string) (rest.RolePermissionList, error) {
@@ -539,3 +623,53 @@ func (da PostgresDataAccess) UserUpdate(ctx context.Context, user rest.User) err
 
 	return err
 }
+
+// UserUpdatePartial changes only the fields of a user that are set in patch,
+// leaving the others intact. An error is returned if the username is empty
+// or if the user doesn't exist.
+func (da PostgresDataAccess) UserUpdatePartial(ctx context.Context, username string, patch rest.UserPatch) error {
+	tr := otel.GetTracerProvider().Tracer(telemetry.ServiceName)
+	ctx, sp := tr.Start(ctx, "postgres.UserUpdatePartial")
+	defer sp.End()
+
+	if username == "" {
+		return errs.ErrEmptyUserName
+	}
+
+	// A nil value is NULL, which leaves the column unchanged.
+	var passwordHash *string
+	if patch.Password != nil && *patch.Password != "" {
+		hash, err := data.HashPassword(*patch.Password)
+		if err != nil {
+			return err
+		}
+		passwordHash = &hash
+	}
+
+	db, err := da.connect(ctx, DatabaseGort)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	query := `UPDATE users
+	SET email=COALESCE($1, email),
+		full_name=COALESCE($2, full_name),
+		password_hash=COALESCE($3, password_hash)
+	WHERE username=$4;`
+
+	result, err := db.ExecContext(ctx, query, patch.Email, patch.FullName, passwordHash, username)
+	if err != nil {
+		return gerr.Wrap(errs.ErrDataAccess, err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return gerr.Wrap(errs.ErrDataAccess, err)
+	}
+	if rows == 0 {
+		return errs.ErrNoSuchUser
+	}
+
+	return nil
+}
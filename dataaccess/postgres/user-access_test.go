@@ -17,24 +17,29 @@
 package postgres
 
 import (
+	"context"
 	"testing"
 
 	"github.com/getgort/gort/data/rest"
 	"github.com/getgort/gort/dataaccess/errs"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func testUserAccess(t *testing.T) {
 	t.Run("testUserAuthenticate", testUserAuthenticate)
 	t.Run("testUserCreate", testUserCreate)
+	t.Run("testUserCreateBulk", testUserCreateBulk)
 	t.Run("testUserDelete", testUserDelete)
 	t.Run("testUserExists", testUserExists)
 	t.Run("testUserGet", testUserGet)
 	t.Run("testUserGroupList", testUserGroupList)
 	t.Run("testUserList", testUserList)
+	t.Run("testUserListPage", testUserListPage)
 	t.Run("testUserNotExists", testUserNotExists)
 	t.Run("testUserPermissionList", testUserPermissionList)
 	t.Run("testUserUpdate", testUserUpdate)
+	t.Run("testUserUpdatePartial", testUserUpdatePartial)
 }
 
 func testUserAuthenticate(t *testing.T) {
@@ -79,6 +84,12 @@ func testUserCreate(t *testing.T) {
 	err = da.UserCreate(ctx, user)
 	assert.Error(t, err, errs.ErrEmptyUserName)
 
+	// Expect errors for names that aren't safe to use in a URL path
+	for _, name := range []string{"test/create", "test create", "test\tcreate", "test%2Fcreate", "tést", ".", ".."} {
+		err = da.UserCreate(ctx, rest.User{Username: name, Email: "test-create@bar.com"})
+		assert.ErrorIs(t, err, errs.ErrInvalidUserName, name)
+	}
+
 	// Expect no error
 	err = da.UserCreate(ctx, rest.User{Username: "test-create", Email: "test-create@bar.com"})
 	defer da.UserDelete(ctx, "test-create")
@@ -89,6 +100,52 @@ func testUserCreate(t *testing.T) {
 	assert.Error(t, err, errs.ErrUserExists)
 }
 
+func testUserCreateBulk(t *testing.T) {
+	err := da.UserCreate(ctx, rest.User{Username: "test-bulk-existing", Email: "old@example.com"})
+	defer da.UserDelete(ctx, "test-bulk-existing")
+	assert.NoError(t, err)
+
+	users := []rest.User{
+		{Username: "test-bulk-new", Email: "new@example.com", Password: "password"},
+		{Username: "", Email: "nobody@example.com"},
+		{Username: "test-bulk-existing", Email: "updated@example.com"},
+		{Username: "not a valid name"},
+	}
+	defer da.UserDelete(ctx, "test-bulk-new")
+
+	results, err := da.UserCreateBulk(ctx, users)
+	assert.NoError(t, err)
+
+	expected := []rest.UserBulkResult{
+		{Username: "test-bulk-new", Status: rest.UserBulkCreated},
+		{Username: "", Status: rest.UserBulkError, Error: errs.ErrEmptyUserName.Error()},
+		{Username: "test-bulk-existing", Status: rest.UserBulkUpdated},
+		{Username: "not a valid name", Status: rest.UserBulkError, Error: errs.ErrInvalidUserName.Error()},
+	}
+	assert.Equal(t, expected, results)
+
+	// The invalid users don't prevent the valid ones from being saved.
+	user, err := da.UserGet(ctx, "test-bulk-new")
+	assert.NoError(t, err)
+	assert.Equal(t, "new@example.com", user.Email)
+
+	user, err = da.UserGet(ctx, "test-bulk-existing")
+	assert.NoError(t, err)
+	assert.Equal(t, "updated@example.com", user.Email)
+
+	exists, err := da.UserExists(ctx, "not a valid name")
+	assert.NoError(t, err)
+	assert.False(t, exists)
+
+	// A done context stops the import.
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+
+	results, err = da.UserCreateBulk(cancelled, []rest.User{{Username: "test-bulk-cancelled"}})
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Empty(t, results)
+}
+
 func testUserDelete(t *testing.T) {
 	// Delete blank user
 	err := da.UserDelete(ctx, "")
@@ -324,3 +381,97 @@ func testUserUpdate(t *testing.T) {
 		t.FailNow()
 	}
 }
+
+func testUserListPage(t *testing.T) {
+	for _, name := range []string{"test-page-0", "test-page-2", "test-page-1"} {
+		da.UserCreate(ctx, rest.User{Username: name, Email: name})
+		defer da.UserDelete(ctx, name)
+	}
+
+	all, total, err := da.UserListPage(ctx, 0, 0)
+	require.NoError(t, err)
+	require.NotEmpty(t, all)
+	assert.Equal(t, len(all), total)
+	assert.GreaterOrEqual(t, total, 3)
+
+	// Pages are sorted and together make up the complete list
+	paged := []rest.User{}
+	for offset := 0; offset < total; offset += 2 {
+		page, n, err := da.UserListPage(ctx, offset, 2)
+		assert.NoError(t, err)
+		assert.Equal(t, total, n)
+		assert.LessOrEqual(t, len(page), 2)
+		paged = append(paged, page...)
+	}
+	assert.Equal(t, all, paged)
+
+	for i := 1; i < len(all); i++ {
+		assert.Less(t, all[i-1].Username, all[i].Username)
+	}
+
+	page, n, err := da.UserListPage(ctx, total, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, total, n)
+	assert.Empty(t, page)
+
+	page, _, err = da.UserListPage(ctx, -1, 1)
+	require.NoError(t, err)
+	assert.Equal(t, all[:1], page)
+}
+
+func testUserUpdatePartial(t *testing.T) {
+	str := func(s string) *string { return &s }
+
+	err := da.UserUpdatePartial(ctx, "", rest.UserPatch{Email: str("x")})
+	assert.ErrorIs(t, err, errs.ErrEmptyUserName)
+
+	err = da.UserUpdatePartial(ctx, "no-such-user", rest.UserPatch{Email: str("x")})
+	assert.ErrorIs(t, err, errs.ErrNoSuchUser)
+
+	err = da.UserCreate(ctx, rest.User{
+		Username: "test-update-partial",
+		Email:    "partial@example.com",
+		FullName: "Partial User",
+		Password: "password",
+	})
+	defer da.UserDelete(ctx, "test-update-partial")
+	assert.NoError(t, err)
+
+	// Only the email changes.
+	err = da.UserUpdatePartial(ctx, "test-update-partial", rest.UserPatch{Email: str("new@example.com")})
+	assert.NoError(t, err)
+
+	user, err := da.UserGet(ctx, "test-update-partial")
+	assert.NoError(t, err)
+	assert.Equal(t, "new@example.com", user.Email)
+	assert.Equal(t, "Partial User", user.FullName)
+
+	authenticated, err := da.UserAuthenticate(ctx, "test-update-partial", "password")
+	assert.NoError(t, err)
+	assert.True(t, authenticated)
+
+	// An empty value clears a field, except for the password.
+	err = da.UserUpdatePartial(ctx, "test-update-partial", rest.UserPatch{FullName: str(""), Password: str("")})
+	assert.NoError(t, err)
+
+	user, err = da.UserGet(ctx, "test-update-partial")
+	assert.NoError(t, err)
+	assert.Equal(t, "new@example.com", user.Email)
+	assert.Equal(t, "", user.FullName)
+
+	authenticated, err = da.UserAuthenticate(ctx, "test-update-partial", "password")
+	assert.NoError(t, err)
+	assert.True(t, authenticated)
+
+	// Only the password changes.
+	err = da.UserUpdatePartial(ctx, "test-update-partial", rest.UserPatch{Password: str("secret")})
+	assert.NoError(t, err)
+
+	authenticated, err = da.UserAuthenticate(ctx, "test-update-partial", "secret")
+	assert.NoError(t, err)
+	assert.True(t, authenticated)
+
+	user, err = da.UserGet(ctx, "test-update-partial")
+	assert.NoError(t, err)
+	assert.Equal(t, "new@example.com", user.Email)
+}
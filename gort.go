@@ -18,6 +18,7 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"os/signal"
@@ -27,6 +28,7 @@ import (
 
 	"github.com/getgort/gort/adapter"
 	"github.com/getgort/gort/adapter/slack"
+	"github.com/getgort/gort/client"
 	"github.com/getgort/gort/config"
 	"github.com/getgort/gort/data"
 	"github.com/getgort/gort/relay"
@@ -156,6 +158,7 @@ func startServer(ctx context.Context, config data.GortServerConfigs) {
 			log.WithTime(event.Timestamp).
 				WithField("addr", event.Addr).
 				WithField("request", event.Request).
+				WithField("request.id", event.RequestID).
 				WithField("size", event.Size).
 				WithField("status", event.Status).
 				WithField("user", event.UserID).
@@ -180,5 +183,14 @@ func startServer(ctx context.Context, config data.GortServerConfigs) {
 }
 
 func main() {
-	GetRootCmd().Execute()
+	if err := GetRootCmd().Execute(); err != nil {
+		// Print the ID of a failed request, so that it can be found in the
+		// server's logs.
+		var cerr client.Error
+		if errors.As(err, &cerr) && cerr.RequestID() != "" {
+			fmt.Fprintf(os.Stderr, "Request ID: %s\n", cerr.RequestID())
+		}
+
+		os.Exit(1)
+	}
 }
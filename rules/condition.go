@@ -17,6 +17,8 @@
 package rules
 
 import (
+	"strconv"
+
 	"github.com/getgort/gort/command"
 	"github.com/getgort/gort/types"
 )
@@ -35,6 +37,25 @@ const (
 	CollOne CollectionOperationModifier = iota
 	CollAny
 	CollAll
+
+	// CollCount replaces a collection with its number of elements, so
+	// "count arg > 2" is true if more than two parameters were supplied.
+	CollCount
+)
+
+// CoercionPolicy determines whether Evaluate converts between strings and
+// numbers before comparing two values.
+type CoercionPolicy int
+
+const (
+	// StrictCoercion (default) compares values as they are, so a string is
+	// never equal to a number: arg[0] == 5 is false if arg[0] is "5".
+	StrictCoercion CoercionPolicy = iota
+
+	// LenientCoercion converts a string to a number before it's compared to
+	// a number, if it can be parsed as one, so arg[0] == 5 is true if arg[0]
+	// is "5". Strings that aren't numbers are compared as they are.
+	LenientCoercion
 )
 
 // Expression describes a single.
@@ -44,13 +65,90 @@ type Expression struct {
 	Operator  Operator
 	Modifier  CollectionOperationModifier
 	Condition LogicalOperator
+
+	// Negated is true if the expression is preceded by the "not" keyword,
+	// in which case its result is inverted.
+	Negated bool
 }
 
+// EvaluationEnvironment maps the names that may be referenced by a rule's
+// conditions to their values. The following names are reserved, and are
+// populated by NewEvaluationEnvironment:
+//
+//	option   the command's options, as a map (option["force"])
+//	arg      the command's parameters, as a list (arg[0])
+//	bundle   the name of the invoked command's bundle (bundle == "ops")
+//	command  the name of the invoked command (command == "deploy")
+//
+// Other names may be added using Set and its typed variants, such as
+// SetString, or loaded from JSON using LoadJSON, so that an environment can be
+// built without a command: EvaluationEnvironment{} is an empty environment.
 type EvaluationEnvironment map[string]interface{}
 
+// NewEvaluationEnvironment returns an EvaluationEnvironment populated with
+// the reserved names derived from cmd.
+func NewEvaluationEnvironment(cmd command.Command) EvaluationEnvironment {
+	return EvaluationEnvironment{
+		"option":  cmd.OptionsValues(),
+		"arg":     cmd.Parameters,
+		"bundle":  cmd.Bundle,
+		"command": cmd.Command,
+	}
+}
+
+// Evaluate resolves any references in the expression against env, and
+// returns the result of applying its operator.
+//
+// A reference to a value that doesn't exist in env, such as an option that
+// wasn't supplied or an argument index that's out of range, is undefined.
+// The bare word undefined can be used to test for this, so a rule can
+// require a flag with a condition like `option["env"] != undefined`.
+// Undefined values are equal only to other undefined values, and the
+// ordering operators (<, <=, >, >=) always return false if either side is
+// undefined.
+//
+// Evaluate, and so the expression's Operator, must be free of side effects:
+// Rule.Matches skips any condition that can't change its result, so there's
+// no guarantee that a given condition is evaluated at all.
+//
+// If the expression's modifier is CollCount, A is replaced by its number of
+// elements before the operator is applied. A may be a collection or a
+// reference to a collection element whose value is itself a collection, such
+// as a list option; anything else, including a missing option, is undefined.
+//
+// If the expression is Negated, the result is inverted after the operator
+// (and any collection modifier) is applied, so "not any arg == 'x'" is true
+// if no argument is "x".
+//
+// Values are compared using StrictCoercion; use EvaluateWithCoercion to
+// compare them using another policy.
 func (e Expression) Evaluate(env EvaluationEnvironment) bool {
+	return e.EvaluateWithCoercion(env, StrictCoercion)
+}
+
+// EvaluateWithCoercion is like Evaluate, but compares values according to
+// the given coercion policy. If the expression has a collection modifier,
+// the policy is applied to each of the collection's elements in turn.
+func (e Expression) EvaluateWithCoercion(env EvaluationEnvironment, policy CoercionPolicy) bool {
+	return e.evaluate(env, policy) != e.Negated
+}
+
+// evaluate returns the result of EvaluateWithCoercion, ignoring Negated.
+func (e Expression) evaluate(env EvaluationEnvironment, policy CoercionPolicy) bool {
 	e.A = define(e.A, env)
 	e.B = define(e.B, env)
+
+	if policy == LenientCoercion {
+		op := e.Operator
+		e.Operator = func(a, b types.Value) bool {
+			return op(coerce(a, b))
+		}
+	}
+
+	if e.Modifier == CollCount {
+		return e.Operator(count(e.A), e.B)
+	}
+
 	coll, isColl := e.A.(types.CollectionValue)
 
 	if isColl && e.Modifier == CollAny {
@@ -74,11 +172,88 @@ func (e Expression) Evaluate(env EvaluationEnvironment) bool {
 	return e.Operator(e.A, e.B)
 }
 
+// count returns the number of elements in v, or in the collection that v
+// references, as an IntValue. If v isn't a collection, it returns an
+// UndefinedValue.
+func count(v types.Value) types.Value {
+	switch o := v.(type) {
+	case types.ListElementValue, types.MapElementValue:
+		if e, ok := o.Value().(types.Value); ok {
+			v = e
+		}
+	}
+
+	if coll, ok := v.(types.CollectionValue); ok {
+		return types.IntValue{V: len(coll.Elements())}
+	}
+
+	return types.UndefinedValue{}
+}
+
+// coerce returns a and b, except that if one of them is a string (or a
+// reference to a string) that can be parsed as a number, and the other is a
+// number, the string is replaced by its numeric value.
+func coerce(a, b types.Value) (types.Value, types.Value) {
+	sa, sb := scalar(a), scalar(b)
+
+	if n, ok := stringToNumber(sa, sb); ok {
+		return n, sb
+	}
+
+	if n, ok := stringToNumber(sb, sa); ok {
+		return sa, n
+	}
+
+	return a, b
+}
+
+// stringToNumber converts s to a number if s is a StringValue that can be
+// parsed as one and other is an IntValue or FloatValue.
+func stringToNumber(s, other types.Value) (types.Value, bool) {
+	str, ok := s.(types.StringValue)
+	if !ok {
+		return s, false
+	}
+
+	switch other.(type) {
+	case types.IntValue, types.FloatValue:
+	default:
+		return s, false
+	}
+
+	if i, err := strconv.Atoi(str.V); err == nil {
+		return types.IntValue{V: i}, true
+	}
+
+	if f, err := strconv.ParseFloat(str.V, 64); err == nil {
+		return types.FloatValue{V: f}, true
+	}
+
+	return s, false
+}
+
+// scalar returns the value referenced by v if v is a reference to an
+// existing collection element, or v itself otherwise.
+func scalar(v types.Value) types.Value {
+	switch o := v.(type) {
+	case types.ListElementValue, types.MapElementValue:
+		if e, ok := o.Value().(types.Value); ok {
+			return e
+		}
+	}
+
+	return v
+}
+
 func define(v types.Value, env EvaluationEnvironment) types.Value {
 	switch o := v.(type) {
 	case types.UnknownValue:
 		i, exists := env[o.V]
 		if !exists {
+			if o.V == "undefined" {
+				return types.UndefinedValue{}
+			}
+
 			return v
 		}
 
@@ -94,6 +269,14 @@ func define(v types.Value, env EvaluationEnvironment) types.Value {
 			return types.MapValue{Name: o.V, V: m}
 		}
 
+		if s, ok := i.(string); ok {
+			return types.StringValue{V: s}
+		}
+
+		if c, ok := i.(types.Value); ok {
+			return c
+		}
+
 		return o
 
 	case types.ListElementValue:
@@ -0,0 +1,97 @@
+/*
+ * Copyright 2021 The Gort Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package rules
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/getgort/gort/types"
+)
+
+// Set sets the value of the given name. Lists and maps are stored as their
+// elements, so that they can be referenced by index or key, as in arg[0] or
+// option["env"], as well as by name.
+func (env EvaluationEnvironment) Set(key string, v types.Value) {
+	switch o := v.(type) {
+	case types.ListValue:
+		env[key] = o.V
+	case types.MapValue:
+		env[key] = o.V
+	default:
+		env[key] = v
+	}
+}
+
+// SetBool sets the name to a boolean value.
+func (env EvaluationEnvironment) SetBool(key string, b bool) {
+	env.Set(key, types.BoolValue{V: b})
+}
+
+// SetFloat sets the name to a floating point value.
+func (env EvaluationEnvironment) SetFloat(key string, f float64) {
+	env.Set(key, types.FloatValue{V: f})
+}
+
+// SetInt sets the name to an integer value.
+func (env EvaluationEnvironment) SetInt(key string, i int) {
+	env.Set(key, types.IntValue{V: i})
+}
+
+// SetList sets the name to a list of values.
+func (env EvaluationEnvironment) SetList(key string, values ...types.Value) {
+	env.Set(key, types.ListValue{V: values})
+}
+
+// SetMap sets the name to a map of values.
+func (env EvaluationEnvironment) SetMap(key string, values map[string]types.Value) {
+	env.Set(key, types.MapValue{V: values})
+}
+
+// SetString sets the name to a string value.
+func (env EvaluationEnvironment) SetString(key string, s string) {
+	env.Set(key, types.StringValue{V: s})
+}
+
+// LoadJSON reads a JSON object from r, and sets each of its members in the
+// environment, as though by Set. Values are converted as described by
+// types.UnmarshalJSONValue, so {"arg": ["deploy", 3], "option": {"force":
+// true}} makes arg[1] == 3 and option["force"] == true. Members not in the
+// object are left unchanged. An error is returned if r doesn't contain a
+// single JSON object.
+func (env EvaluationEnvironment) LoadJSON(r io.Reader) error {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+
+	v, err := types.UnmarshalJSONValue(b)
+	if err != nil {
+		return err
+	}
+
+	m, ok := v.(types.MapValue)
+	if !ok {
+		return fmt.Errorf("expected a JSON object; got %s", v.Kind())
+	}
+
+	for k, e := range m.V {
+		env.Set(k, e)
+	}
+
+	return nil
+}
@@ -0,0 +1,103 @@
+/*
+ * Copyright 2021 The Gort Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package rules
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/getgort/gort/types"
+)
+
+func TestEvaluationEnvironmentSetters(t *testing.T) {
+	env := EvaluationEnvironment{}
+	env.SetString("command", "deploy")
+	env.SetInt("replicas", 3)
+	env.SetFloat("ratio", 0.5)
+	env.SetBool("dry", true)
+	env.SetList("arg", types.StringValue{V: "web"}, types.IntValue{V: 2})
+	env.SetMap("option", map[string]types.Value{"env": types.StringValue{V: "prod"}})
+	env.Set("tags", types.ListValue{V: []types.Value{types.StringValue{V: "a"}}})
+
+	inputs := map[string]bool{
+		`ops:deploy with command == "deploy" allow`:      true,
+		`ops:deploy with replicas == 3 allow`:            true,
+		`ops:deploy with ratio < 1.0 allow`:              true,
+		`ops:deploy with dry == true allow`:              true,
+		`ops:deploy with arg[0] == "web" allow`:          true,
+		`ops:deploy with arg[1] == 2 allow`:              true,
+		`ops:deploy with count arg == 2 allow`:           true,
+		`ops:deploy with any arg == "web" allow`:         true,
+		`ops:deploy with option["env"] == "prod" allow`:  true,
+		`ops:deploy with option["env"] == "dev" allow`:   false,
+		`ops:deploy with option["x"] == undefined allow`: true,
+		`ops:deploy with count tags == 1 allow`:          true,
+	}
+
+	for in, expected := range inputs {
+		rule, err := TokenizeAndParse(in)
+		if !assert.NoError(t, err, in) {
+			continue
+		}
+
+		assert.Equal(t, expected, rule.Matches(env), in)
+	}
+}
+
+func TestEvaluationEnvironmentLoadJSON(t *testing.T) {
+	env := EvaluationEnvironment{}
+	env.SetString("bundle", "ops")
+
+	err := env.LoadJSON(strings.NewReader(`{
+		"command": "deploy",
+		"arg": ["web", 3, 1.5],
+		"option": {"force": true, "env": "prod"}
+	}`))
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	inputs := map[string]bool{
+		`ops:deploy with bundle == "ops" allow`:         true,
+		`ops:deploy with command == "deploy" allow`:     true,
+		`ops:deploy with arg[0] == "web" allow`:         true,
+		`ops:deploy with arg[1] == 3 allow`:             true,
+		`ops:deploy with arg[2] > 1.0 allow`:            true,
+		`ops:deploy with count arg == 3 allow`:          true,
+		`ops:deploy with option["force"] == true allow`: true,
+		`ops:deploy with option["env"] == "prod" allow`: true,
+	}
+
+	for in, expected := range inputs {
+		rule, err := TokenizeAndParse(in)
+		if !assert.NoError(t, err, in) {
+			continue
+		}
+
+		assert.Equal(t, expected, rule.Matches(env), in)
+	}
+
+	// Only a single JSON object can be loaded.
+	for _, in := range []string{`["web"]`, `"web"`, `{"a": 1`, `{} {}`, ``} {
+		assert.Error(t, EvaluationEnvironment{}.LoadJSON(strings.NewReader(in)), in)
+	}
+
+	err = EvaluationEnvironment{}.LoadJSON(strings.NewReader(`[1]`))
+	assert.EqualError(t, err, "expected a JSON object; got list")
+}
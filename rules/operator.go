@@ -30,19 +30,38 @@ func NotEquals(a, b types.Value) bool {
 	return !a.Equals(b)
 }
 
+// LessThan, LessThanOrEqualTo, GreaterThan, and GreaterThanOrEqualTo all
+// return false if either value is undefined.
+
 func LessThan(a, b types.Value) bool {
+	if !comparable(a, b) {
+		return false
+	}
+
 	return a.LessThan(b)
 }
 
 func LessThanOrEqualTo(a, b types.Value) bool {
+	if !comparable(a, b) {
+		return false
+	}
+
 	return a.LessThan(b) || a.Equals(b)
 }
 
 func GreaterThan(a, b types.Value) bool {
+	if !comparable(a, b) {
+		return false
+	}
+
 	return !(a.LessThan(b) || a.Equals(b))
 }
 
 func GreaterThanOrEqualTo(a, b types.Value) bool {
+	if !comparable(a, b) {
+		return false
+	}
+
 	return !a.LessThan(b)
 }
 
@@ -54,3 +73,9 @@ func In(a, b types.Value) bool {
 
 	return Equals(a, b)
 }
+
+// comparable returns false if either a or b is undefined, in which case they
+// can't be ordered.
+func comparable(a, b types.Value) bool {
+	return !types.IsUndefined(a) && !types.IsUndefined(b)
+}
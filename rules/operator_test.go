@@ -94,3 +94,34 @@ func TestOperatorGreaterThanOrEqualTo(t *testing.T) {
 	result = evaluate(types.IntValue{V: 42}, types.IntValue{V: 21})
 	assert.True(t, result)
 }
+
+func TestOperatorsUndefined(t *testing.T) {
+	undefined := types.UndefinedValue{}
+	missing := types.MapElementValue{V: types.MapValue{Name: "option"}, Key: "env"}
+	defined := types.IntValue{V: 42}
+
+	// undefined == undefined
+	for _, a := range []types.Value{undefined, missing} {
+		for _, b := range []types.Value{undefined, missing} {
+			assert.True(t, Equals(a, b))
+			assert.False(t, NotEquals(a, b))
+			assert.False(t, LessThan(a, b))
+			assert.False(t, LessThanOrEqualTo(a, b))
+			assert.False(t, GreaterThan(a, b))
+			assert.False(t, GreaterThanOrEqualTo(a, b))
+		}
+	}
+
+	// undefined == anything else
+	for _, u := range []types.Value{undefined, missing} {
+		for _, pair := range [][2]types.Value{{u, defined}, {defined, u}} {
+			a, b := pair[0], pair[1]
+			assert.False(t, Equals(a, b))
+			assert.True(t, NotEquals(a, b))
+			assert.False(t, LessThan(a, b))
+			assert.False(t, LessThanOrEqualTo(a, b))
+			assert.False(t, GreaterThan(a, b))
+			assert.False(t, GreaterThanOrEqualTo(a, b))
+		}
+	}
+}
@@ -19,17 +19,21 @@ package rules
 import (
 	"fmt"
 	"regexp"
+	"strings"
 
 	"github.com/getgort/gort/types"
 )
 
-func Parse(rt RuleTokens) (Rule, error) {
-	infer := types.Inferrer{}.ComplexTypes(true).StrictStrings(true)
+// ruleInferrer is used by Parse to infer the types of condition values,
+// which may be list or map literals and so are limited in depth.
+var ruleInferrer = types.Inferrer{}.ComplexTypes(true).StrictStrings(true).MaxDepth(types.RecommendedMaxDepth)
 
+func Parse(rt RuleTokens) (Rule, error) {
 	r := Rule{
 		Command:     rt.Command,
 		Conditions:  []Expression{},
 		Permissions: []Permission{},
+		Deny:        rt.Deny,
 	}
 
 	lastCondition := Undefined
@@ -63,17 +67,22 @@ func Parse(rt RuleTokens) (Rule, error) {
 			continue
 		}
 
+		c, negated, err := parseNegation(c)
+		if err != nil {
+			return r, fmt.Errorf("can't parse condition: %w", err)
+		}
+
 		a, b, o, m, err := ParseExpression(c)
 		if err != nil {
 			return r, fmt.Errorf("can't parse condition: %w", err)
 		}
 
-		va, err := infer.Infer(a)
+		va, err := ruleInferrer.Infer(a)
 		if err != nil {
 			return r, fmt.Errorf("can't infer value: %w", err)
 		}
 
-		vb, err := infer.Infer(b)
+		vb, err := ruleInferrer.Infer(b)
 		if err != nil {
 			return r, fmt.Errorf("can't infer value: %w", err)
 		}
@@ -83,16 +92,83 @@ func Parse(rt RuleTokens) (Rule, error) {
 			B:         vb,
 			Operator:  o,
 			Modifier:  m,
+			Negated:   negated,
 			Condition: lastCondition})
 	}
 
 	return r, nil
 }
 
+// parseNegation strips the "not" keyword from the start of a condition,
+// along with any parentheses that enclose the rest of it, and reports
+// whether it did so: "not a == b" and "not (a == b)" both return "a == b".
+// A condition in which "not" isn't followed by an expression, such as
+// "not == 1", is returned as is. Since conditions can't be grouped, a
+// negated group such as "not (a == b or c == d)", which the tokenizer splits
+// at the "or", returns an error.
+func parseNegation(c string) (string, bool, error) {
+	if !strings.HasPrefix(c, "not ") && !strings.HasPrefix(c, "not(") {
+		return c, false, nil
+	}
+
+	rest := strings.TrimSpace(c[3:])
+
+	if strings.HasPrefix(rest, "(") {
+		enclosed, balanced := enclosedInParens(rest)
+
+		switch {
+		case !balanced:
+			return "", false, fmt.Errorf("unbalanced parentheses in %q: conditions can't be grouped", c)
+		case enclosed:
+			return strings.TrimSpace(rest[1 : len(rest)-1]), true, nil
+		}
+	}
+
+	if _, _, _, _, err := ParseExpression(rest); err != nil {
+		return c, false, nil
+	}
+
+	return rest, true, nil
+}
+
+// enclosedInParens reports whether s, which begins with "(", ends with the
+// ")" that matches it, and whether its parentheses are balanced.
+// Parentheses within quoted strings and regular expressions are ignored.
+func enclosedInParens(s string) (enclosed, balanced bool) {
+	var quote rune
+	depth := 0
+	closedAt := -1
+
+	for i, ch := range s {
+		switch {
+		case quote != 0:
+			if ch == quote {
+				quote = 0
+			}
+		case ch == '"' || ch == '\'' || ch == '/':
+			quote = ch
+		case ch == '(':
+			depth++
+		case ch == ')':
+			depth--
+			if depth == 0 && closedAt < 0 {
+				closedAt = i
+			}
+		}
+	}
+
+	return closedAt == len(s)-1, depth == 0 && quote == 0
+}
+
 var (
-	reOperatorParts = regexp.MustCompile(`^(?:(all|any)\s+)?(.*)\s+([!<>=]{1,2}|in)\s+(.*)$`)
+	reOperatorParts = regexp.MustCompile(`^(?:(all|any|count)\s+)?(.*)\s+([!<>=]{1,2}|in)\s+(.*)$`)
 )
 
+// ParseExpression splits an expression of the form "[all|any|count] A OP B"
+// into its operands, operator, and collection modifier. If the expression can't
+// be parsed, a non-nil error is returned and all other results are zero
+// values. Otherwise the operands are non-empty and have no surrounding
+// whitespace. The SQL-style "<>" is accepted as a synonym for "!=".
 func ParseExpression(expr string) (a, b string, o Operator, m CollectionOperationModifier, err error) {
 	subs := reOperatorParts.FindStringSubmatch(expr)
 
@@ -103,12 +179,16 @@ func ParseExpression(expr string) (a, b string, o Operator, m CollectionOperatio
 
 	modifier := subs[1]
 	op := subs[3]
-	a, b = subs[2], subs[4]
+	a, b = strings.TrimSpace(subs[2]), strings.TrimSpace(subs[4])
+
+	if a == "" || b == "" {
+		return "", "", nil, CollOne, fmt.Errorf("expression doesn't conform to form A OP B")
+	}
 
 	switch op {
 	case "==":
 		o = Equals
-	case "!=":
+	case "!=", "<>":
 		o = NotEquals
 	case "<":
 		o = LessThan
@@ -121,7 +201,7 @@ func ParseExpression(expr string) (a, b string, o Operator, m CollectionOperatio
 	case "in":
 		o = In
 	default:
-		err = fmt.Errorf("unsupported operator: %s", op)
+		return "", "", nil, CollOne, fmt.Errorf("unsupported operator: %s", op)
 	}
 
 	switch modifier {
@@ -129,6 +209,8 @@ func ParseExpression(expr string) (a, b string, o Operator, m CollectionOperatio
 		m = CollAll
 	case "any":
 		m = CollAny
+	case "count":
+		m = CollCount
 	default:
 		m = CollOne
 	}
@@ -138,11 +220,12 @@ func ParseExpression(expr string) (a, b string, o Operator, m CollectionOperatio
 
 // TokenizeAndParse is a helper function that wraps the Tokenize and Parse
 // functions. It accepts a raw Gort rule of the form "COMMAND [when CONDITION
-// (and|or)]? [allow|must have PERMISSION (and|or)]", and returns a RuleTokens
-// value. A parsing error will produce a non-nil error. The RuleTokens' Command
+// (and|or)]? [allow|deny|must have PERMISSION (and|or)]", and returns a Rule
+// value. A parsing error will produce a non-nil error. The Rule's Command
 // value should always be non-empty; Conditions and Permissions can both be
 // empty (but non-nil). Empty Conditions always match the command. Empty
-// Permissions indicating the use of the "allow" keyword and always pass.
+// Permissions indicating the use of the "allow" keyword and always pass,
+// unless Deny is set by the "deny" keyword, in which case they never do.
 func TokenizeAndParse(s string) (Rule, error) {
 	rt, err := Tokenize(s)
 	if err != nil {
@@ -0,0 +1,81 @@
+//go:build go1.18
+// +build go1.18
+
+/*
+ * Copyright 2021 The Gort Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package rules
+
+import (
+	"strings"
+	"testing"
+)
+
+// FuzzParseExpression checks that ParseExpression never panics, and that its
+// results are consistent: either an error and zero values, or no error, a
+// non-nil operator, and non-empty, trimmed operands.
+func FuzzParseExpression(f *testing.F) {
+	seeds := []string{
+		``,
+		` `,
+		`==`,
+		` == `,
+		`a ==`,
+		`== b`,
+		`a == b`,
+		`a == b == c`,
+		`a < b > c`,
+		`a in b in c`,
+		`a <= >= b`,
+		`a =! b`,
+		`a !! b`,
+		`all a in b`,
+		`any  in b`,
+		`all == b`,
+		`arg[0] == "a == b"`,
+		`option['x'] != 'in'`,
+		`"quoted" in ["a", "b"]`,
+		`a in in`,
+		`inin in inin`,
+		"a\t==\tb",
+		"a ==\n",
+	}
+
+	for _, s := range seeds {
+		f.Add(s)
+	}
+
+	f.Fuzz(func(t *testing.T, expr string) {
+		a, b, o, m, err := ParseExpression(expr)
+
+		if err != nil {
+			if a != "" || b != "" || o != nil || m != CollOne {
+				t.Fatalf("%q: error %v with non-zero results a=%q b=%q o=%v m=%v", expr, err, a, b, o != nil, m)
+			}
+			return
+		}
+
+		if o == nil {
+			t.Fatalf("%q: nil operator without an error", expr)
+		}
+		if a == "" || b == "" {
+			t.Fatalf("%q: empty operand without an error: a=%q b=%q", expr, a, b)
+		}
+		if a != strings.TrimSpace(a) || b != strings.TrimSpace(b) {
+			t.Fatalf("%q: untrimmed operand: a=%q b=%q", expr, a, b)
+		}
+	})
+}
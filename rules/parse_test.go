@@ -18,6 +18,7 @@ package rules
 
 import (
 	"fmt"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -96,6 +97,10 @@ func TestParse(t *testing.T) {
 
 		assert.Equal(t, expected.Permissions, rule.Permissions, in)
 	}
+
+	// Pathologically nested values are rejected.
+	_, err := TokenizeAndParse(`foo:bar with arg[0] in ` + strings.Repeat("[", 9) + strings.Repeat("]", 9) + ` allow`)
+	assert.ErrorIs(t, err, types.ErrMaxDepthExceeded)
 }
 
 func TestParseExpression(t *testing.T) {
@@ -115,6 +120,9 @@ func TestParseExpression(t *testing.T) {
 		`foo:bar with any arg in ['wubba', /^f.*/, 10] must have foo:read`:                                  {{a: `arg`, b: `['wubba', /^f.*/, 10]`, o: In, m: CollAny}},
 		`foo:bar with all arg in [10, 'baz', 'wubba'] must have foo:read`:                                   {{a: `arg`, b: `[10, 'baz', 'wubba']`, o: In, m: CollAll}},
 		`foo:bar with arg[0] in ['baz', false, 100] must have foo:read`:                                     {{a: `arg[0]`, b: `['baz', false, 100]`, o: In}},
+		`foo:bar with count arg > 2 must have foo:read`:                                                     {{a: `arg`, b: `2`, o: GreaterThan, m: CollCount}},
+		`foo:bar with count option["tags"] <= 3 allow`:                                                      {{a: `option["tags"]`, b: `3`, o: LessThanOrEqualTo, m: CollCount}},
+		`foo:bar with count == 1 allow`:                                                                     {{a: `count`, b: `1`, o: Equals}},
 		`foo:bar with any option != /^prod.*/ must have foo:read`:                                           {{a: `option`, b: `/^prod.*/`, o: NotEquals, m: CollAny}},
 		`foo:bar with all option == 10 must have foo:read`:                                                  {{a: `option`, b: `10`, o: Equals, m: CollAll}},
 		`foo:bar with all option < 10 must have foo:read`:                                                   {{a: `option`, b: `10`, o: LessThan, m: CollAll}},
@@ -122,6 +130,8 @@ func TestParseExpression(t *testing.T) {
 		`foo:bar with all option > 10 must have foo:read`:                                                   {{a: `option`, b: `10`, o: GreaterThan, m: CollAll}},
 		`foo:bar with all option >= 10 must have foo:read`:                                                  {{a: `option`, b: `10`, o: GreaterThanOrEqualTo, m: CollAll}},
 		`foo:bar with all option != 10 must have foo:read`:                                                  {{a: `option`, b: `10`, o: NotEquals, m: CollAll}},
+		`foo:bar with all option <> 10 must have foo:read`:                                                  {{a: `option`, b: `10`, o: NotEquals, m: CollAll}},
+		`foo:bar with arg[0] <> 'x' and arg[1] < 5 must have foo:read`:                                      {{a: `arg[0]`, b: `'x'`, o: NotEquals}, {a: `arg[1]`, b: `5`, o: LessThan}},
 		`foo:deploy with option["environment"] == 'prod' must have all in [site:it, site:prod, foo:deploy]`: {{a: `option["environment"]`, b: `'prod'`, o: Equals}},
 		`foo:patch must have all in [foo:patch, site:it]
 			or all in [site:qa, site:test, foo:patch]
@@ -156,3 +166,43 @@ func TestParseExpression(t *testing.T) {
 		}
 	}
 }
+
+func TestParseExpressionNotEqualsSynonym(t *testing.T) {
+	for _, pair := range [][2]string{
+		{`a != b`, `a <> b`},
+		{`any option != 'x'`, `any option <> 'x'`},
+		{`arg[0] != 10`, `arg[0] <> 10`},
+	} {
+		a1, b1, o1, m1, err1 := ParseExpression(pair[0])
+		a2, b2, o2, m2, err2 := ParseExpression(pair[1])
+
+		assert.NoError(t, err1, pair[0])
+		assert.NoError(t, err2, pair[1])
+		assert.Equal(t, a1, a2, pair[1])
+		assert.Equal(t, b1, b2, pair[1])
+		assert.Equal(t, m1, m2, pair[1])
+		assert.Equal(t, fmt.Sprintf("%v", o1), fmt.Sprintf("%v", o2), pair[1])
+	}
+}
+
+func TestParseExpressionErrors(t *testing.T) {
+	inputs := map[string]string{
+		`foo`:        "expression doesn't conform to form A OP B",
+		` == `:       "expression doesn't conform to form A OP B",
+		"a == \t":    "expression doesn't conform to form A OP B",
+		`a =! b`:     "unsupported operator: =!",
+		`any a !! b`: "unsupported operator: !!",
+		`a >< b`:     "unsupported operator: ><",
+	}
+
+	for in, msg := range inputs {
+		a, b, o, m, err := ParseExpression(in)
+		assert.EqualError(t, err, msg, in)
+
+		// All other results are zero values
+		assert.Empty(t, a, in)
+		assert.Empty(t, b, in)
+		assert.Nil(t, o, in)
+		assert.Equal(t, CollOne, m, in)
+	}
+}
@@ -16,15 +16,49 @@
 
 package rules
 
+import (
+	"sort"
+
+	"github.com/getgort/gort/command"
+	"github.com/getgort/gort/types"
+)
+
 type Rule struct {
 	Command     string
 	Conditions  []Expression
 	Permissions []Permission
+
+	// Deny is true for a "deny" rule, which blocks a command outright
+	// whenever its conditions match.
+	Deny bool
+
+	// Coercion is the policy used by Matches to compare values of different
+	// types. The default is StrictCoercion.
+	Coercion CoercionPolicy
+
+	// Priority orders the rule relative to other rules that match the same
+	// command: rules with a higher priority are applied first. The default
+	// is 0. It's set programmatically; the rule syntax has no keyword for it.
+	Priority int
+}
+
+// SortByPriority sorts rules in place by descending Priority. Rules with the
+// same priority keep their original (definition) order.
+func SortByPriority(rules []Rule) {
+	sort.SliceStable(rules, func(i, j int) bool {
+		return rules[i].Priority > rules[j].Priority
+	})
 }
 
 // Allowed returns true iff the user has all required permissions (or the rule
-// is an "allow" rule).
+// is an "allow" rule). A "deny" rule is never allowed, regardless of the
+// user's permissions. Like Matches, it only checks a permission if doing so
+// can change the result.
 func (r Rule) Allowed(permissions []string) bool {
+	if r.Deny {
+		return false
+	}
+
 	if len(r.Permissions) == 0 {
 		return true
 	}
@@ -34,14 +68,12 @@ func (r Rule) Allowed(permissions []string) bool {
 	for i := 1; i < len(r.Permissions); i++ {
 		p := r.Permissions[i]
 
-		if p.Condition == And {
-			result = (result && hasPermission(p, permissions))
+		if shortCircuits(p.Condition, result) {
 			continue
 		}
 
-		if p.Condition == Or {
-			result = (result || hasPermission(p, permissions))
-			continue
+		if p.Condition == And || p.Condition == Or {
+			result = hasPermission(p, permissions)
 		}
 	}
 
@@ -58,28 +90,181 @@ func hasPermission(required Permission, permissions []string) bool {
 	return false
 }
 
+// HasAnyPermissionInBundle returns true if any of the granted permissions,
+// each of the form "bundle:permission", belongs to the named bundle.
+// Malformed permissions, and permissions without a bundle, are ignored.
+func HasAnyPermissionInBundle(permissions []string, bundle string) bool {
+	for _, p := range permissions {
+		if b, _, ok := splitPermission(p); ok && b == bundle {
+			return true
+		}
+	}
+
+	return false
+}
+
+// HasPermissionInBundle returns true if the granted permissions, each of the
+// form "bundle:permission", include the permission perm in the named bundle.
+func HasPermissionInBundle(permissions []string, bundle, perm string) bool {
+	for _, p := range permissions {
+		if b, n, ok := splitPermission(p); ok && b == bundle && n == perm {
+			return true
+		}
+	}
+
+	return false
+}
+
+// splitPermission splits a "bundle:permission" string into its bundle and
+// permission names. It returns false if p doesn't have exactly that form.
+func splitPermission(p string) (bundle, perm string, ok bool) {
+	bundle, perm, err := command.SplitCommand(p)
+	if err != nil || bundle == "" || perm == "" {
+		return "", "", false
+	}
+
+	return bundle, perm, true
+}
+
 // Matches returns true iff the Rule's stated conditions evaluate to true.
+// Conditions are combined from left to right, and a condition is only
+// evaluated if it can change the result: an "and" condition is skipped once
+// the result is false, and an "or" condition once it's true. Values are
+// compared according to the rule's Coercion policy.
 func (r Rule) Matches(env EvaluationEnvironment) bool {
 	// No conditions matches everything
 	if len(r.Conditions) == 0 {
 		return true
 	}
 
-	result := r.Conditions[0].Evaluate(env)
+	result := r.Conditions[0].EvaluateWithCoercion(env, r.Coercion)
 
 	for i := 1; i < len(r.Conditions); i++ {
 		c := r.Conditions[i]
 
-		if c.Condition == And {
-			result = (result && c.Evaluate(env))
+		if shortCircuits(c.Condition, result) {
 			continue
 		}
 
-		if c.Condition == Or {
-			result = (result || c.Evaluate(env))
-			continue
+		if c.Condition == And || c.Condition == Or {
+			result = c.EvaluateWithCoercion(env, r.Coercion)
 		}
 	}
 
 	return result
 }
+
+// shortCircuits returns true if combining result with another value using
+// op can't change result: false "and" anything is false, and true "or"
+// anything is true.
+func shortCircuits(op LogicalOperator, result bool) bool {
+	return (op == And && !result) || (op == Or && result)
+}
+
+// Clone returns a deep copy of the rule. The copy shares no memory with the
+// original, including the collection values used by its conditions, so
+// modifying one doesn't modify the other.
+func (r Rule) Clone() Rule {
+	c := Rule{Command: r.Command, Deny: r.Deny, Coercion: r.Coercion, Priority: r.Priority}
+
+	if r.Conditions != nil {
+		c.Conditions = make([]Expression, len(r.Conditions))
+
+		for i, e := range r.Conditions {
+			e.A, e.B = cloneValue(e.A), cloneValue(e.B)
+			c.Conditions[i] = e
+		}
+	}
+
+	if r.Permissions != nil {
+		c.Permissions = append([]Permission{}, r.Permissions...)
+	}
+
+	return c
+}
+
+// cloneValue returns a deep copy of v. Only the collection types (and
+// references into them) hold shared memory; other values are returned as is.
+func cloneValue(v types.Value) types.Value {
+	switch o := v.(type) {
+	case types.ListValue:
+		return cloneListValue(o)
+	case types.ListElementValue:
+		return types.ListElementValue{V: cloneListValue(o.V), Index: o.Index}
+	case types.MapValue:
+		return cloneMapValue(o)
+	case types.MapElementValue:
+		return types.MapElementValue{V: cloneMapValue(o.V), Key: o.Key}
+	default:
+		return v
+	}
+}
+
+func cloneListValue(v types.ListValue) types.ListValue {
+	if v.V == nil {
+		return v
+	}
+
+	l := make([]types.Value, len(v.V))
+	for i, e := range v.V {
+		l[i] = cloneValue(e)
+	}
+
+	return types.ListValue{V: l, Name: v.Name}
+}
+
+func cloneMapValue(v types.MapValue) types.MapValue {
+	if v.V == nil {
+		return v
+	}
+
+	m := make(map[string]types.Value, len(v.V))
+	for k, e := range v.V {
+		m[k] = cloneValue(e)
+	}
+
+	return types.MapValue{V: m, Name: v.Name}
+}
+
+// ReferencedVariables returns the distinct references to environment values
+// made by the rule's conditions, in the order they first appear. Collection
+// element references are returned in their canonical form, such as arg[0] or
+// option["env"]; bare references, such as arg in `any arg == "foo"`, are
+// returned as is. The undefined keyword isn't a reference, and isn't
+// included.
+func (r Rule) ReferencedVariables() []string {
+	refs := []string{}
+	seen := map[string]bool{}
+
+	for _, c := range r.Conditions {
+		for _, v := range []types.Value{c.A, c.B} {
+			name, ok := referenceName(v)
+			if !ok || seen[name] {
+				continue
+			}
+
+			seen[name] = true
+			refs = append(refs, name)
+		}
+	}
+
+	return refs
+}
+
+// referenceName returns the name of the environment value referenced by v,
+// and false if v isn't a reference.
+func referenceName(v types.Value) (string, bool) {
+	switch o := v.(type) {
+	case types.ListElementValue:
+		return o.String(), true
+	case types.MapElementValue:
+		return o.String(), true
+	case types.UnknownValue:
+		if o.V == "undefined" {
+			return "", false
+		}
+		return o.V, true
+	default:
+		return "", false
+	}
+}
@@ -19,15 +19,17 @@ package rules
 import (
 	"testing"
 
+	"github.com/getgort/gort/command"
 	"github.com/getgort/gort/types"
 	"github.com/stretchr/testify/assert"
 )
 
 func TestRuleMatches(t *testing.T) {
 	options := map[string]types.Value{
-		"foo": types.StringValue{V: "bar"},
-		"k":   types.BoolValue{V: true},
-		"n":   types.IntValue{V: 10},
+		"foo":  types.StringValue{V: "bar"},
+		"k":    types.BoolValue{V: true},
+		"n":    types.IntValue{V: 10},
+		"tags": types.ListValue{V: []types.Value{types.StringValue{V: "a"}, types.StringValue{V: "b"}, types.StringValue{V: "c"}}},
 	}
 	args := []types.Value{types.StringValue{V: "foo"}, types.StringValue{V: "bar"}}
 	env := EvaluationEnvironment{
@@ -60,6 +62,62 @@ func TestRuleMatches(t *testing.T) {
 		`foo:bar with all arg in [10, 'baz', 'wubba'] allow`:            false,
 		`foo:bar with all option < 10 allow`:                            false,
 		`foo:bar with all option in ['staging', 'list'] allow`:          false,
+		`foo:bar with option['foo'] != undefined allow`:                 true,
+		`foo:bar with option['env'] != undefined allow`:                 false,
+		`foo:bar with option['env'] == undefined allow`:                 true,
+		`foo:bar with undefined == option['env'] allow`:                 true,
+		`foo:bar with undefined == undefined allow`:                     true,
+		`foo:bar with arg[1] == undefined allow`:                        false,
+		`foo:bar with arg[2] == undefined allow`:                        true,
+		`foo:bar with option['env'] > 5 allow`:                          false,
+		`foo:bar with option['env'] <= 5 allow`:                         false,
+		`foo:bar with arg[2] >= 5 allow`:                                false,
+		`foo:bar with count arg == 2 allow`:                             true,
+		`foo:bar with count arg > 2 allow`:                              false,
+		`foo:bar with count option == 4 allow`:                          true,
+		`foo:bar with count option['tags'] == 3 allow`:                  true,
+		`foo:bar with count option['tags'] < 3 allow`:                   false,
+		`foo:bar with count option['env'] > 0 allow`:                    false,
+		`foo:bar with count option['env'] == undefined allow`:           true,
+		`foo:bar with count option['foo'] == undefined allow`:           true,
+		`foo:bar with count arg[0] == undefined allow`:                  true,
+	}
+
+	for in, expected := range inputs {
+		rt, err := Tokenize(in)
+		if !assert.NoError(t, err, in) {
+			continue
+		}
+
+		rule, err := Parse(rt)
+		if !assert.NoError(t, err, in) {
+			continue
+		}
+
+		assert.Equal(t, expected, rule.Matches(env), in)
+	}
+}
+
+func TestRuleMatchesCommandIdentity(t *testing.T) {
+	cmd, err := command.TokenizeAndParse(`ops:deploy --env prod web`,
+		command.ParseOptionHasArgument("env", true))
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	env := NewEvaluationEnvironment(cmd)
+
+	inputs := map[string]bool{
+		`ops:deploy with command == "deploy" allow`:                         true,
+		`ops:deploy with command == "rollback" allow`:                       false,
+		`ops:deploy with bundle == "ops" allow`:                             true,
+		`ops:deploy with bundle != "ops" allow`:                             false,
+		`ops:deploy with command == "deploy" and bundle == "ops" allow`:     true,
+		`ops:deploy with command == "deploy" and bundle == "dev" allow`:     false,
+		`ops:deploy with command == /^dep/ allow`:                           true,
+		`ops:deploy with bundle == "ops" and option['env'] == "prod" allow`: true,
+		`ops:deploy with command in ["deploy", "rollback"] allow`:           true,
+		`ops:deploy with arg[0] == "web" allow`:                             true,
 	}
 
 	for in, expected := range inputs {
@@ -77,3 +135,311 @@ func TestRuleMatches(t *testing.T) {
 		assert.Equal(t, expected, result, in)
 	}
 }
+
+func TestRuleMatchesCount(t *testing.T) {
+	inputs := map[string]bool{
+		`ops:deploy with count arg == 0 allow`:                   false,
+		`ops:deploy with count arg == 2 allow`:                   true,
+		`ops:deploy with count arg > 2 allow`:                    false,
+		`ops:deploy with count arg >= 1 and count arg < 3 allow`: true,
+		`ops:deploy with count option['point'] == 2 allow`:       true,
+		`ops:deploy with count option['point'] > 2 allow`:        false,
+		`ops:deploy with count option['env'] == 1 allow`:         false,
+	}
+
+	cmd, err := command.TokenizeAndParse(`ops:deploy --env prod --point 1 2 web db`,
+		command.ParseOptionHasArgument("env", true), command.ParseOptionNArgs("point", 2))
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	env := NewEvaluationEnvironment(cmd)
+
+	for in, expected := range inputs {
+		rule, err := TokenizeAndParse(in)
+		if !assert.NoError(t, err, in) {
+			continue
+		}
+
+		assert.Equal(t, expected, rule.Matches(env), in)
+	}
+}
+
+func TestRuleMatchesNot(t *testing.T) {
+	inputs := map[string]bool{
+		`ops:deploy with not arg[0] == "web" allow`:                            false,
+		`ops:deploy with not arg[0] == "db" allow`:                             true,
+		`ops:deploy with not (arg[0] == "db") allow`:                           true,
+		`ops:deploy with not(arg[0] == "web") allow`:                           false,
+		`ops:deploy with not option['env'] == "prod" allow`:                    false,
+		`ops:deploy with not option['force'] != undefined allow`:               true,
+		`ops:deploy with not any arg == "cache" allow`:                         true,
+		`ops:deploy with not all arg in ["web", "db"] allow`:                   false,
+		`ops:deploy with not count arg > 2 allow`:                              true,
+		`ops:deploy with arg[0] == "web" and not arg[1] == "web" allow`:        true,
+		`ops:deploy with arg[0] == "db" or not option['env'] == "stage" allow`: true,
+		`ops:deploy with not arg[0] == "(web)" allow`:                          true,
+	}
+
+	cmd, err := command.TokenizeAndParse(`ops:deploy --env prod web db`,
+		command.ParseOptionHasArgument("env", true))
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	env := NewEvaluationEnvironment(cmd)
+
+	for in, expected := range inputs {
+		rule, err := TokenizeAndParse(in)
+		if !assert.NoError(t, err, in) {
+			continue
+		}
+
+		assert.Equal(t, expected, rule.Matches(env), in)
+	}
+
+	// Conditions can't be grouped, so neither can negated conditions.
+	_, err = TokenizeAndParse(`ops:deploy with not (arg[0] == "web" or arg[1] == "db") allow`)
+	assert.EqualError(t, err, `can't parse condition: unbalanced parentheses in "not (arg[0] == \"web\"": conditions can't be grouped`)
+
+	// Without an expression after it, "not" is just a name.
+	rule, err := TokenizeAndParse(`ops:deploy with not == undefined allow`)
+	assert.NoError(t, err)
+	assert.False(t, rule.Conditions[0].Negated)
+	assert.Equal(t, types.UnknownValue{V: "not"}, rule.Conditions[0].A)
+}
+
+func TestRuleAllowedDeny(t *testing.T) {
+	inputs := map[string]bool{
+		`foo:bar allow`:                  true,
+		`foo:bar deny`:                   false,
+		`foo:bar must have foo:bar`:      true,
+		`foo:bar with arg[0] == 1 deny`:  false,
+		`foo:bar with arg[0] == 1 allow`: true,
+	}
+
+	for in, expected := range inputs {
+		rule, err := TokenizeAndParse(in)
+		if !assert.NoError(t, err, in) {
+			continue
+		}
+
+		assert.Equal(t, expected, rule.Allowed([]string{"foo:bar"}), in)
+		assert.Equal(t, !expected, rule.Deny, in)
+		assert.Equal(t, rule.Deny, rule.Clone().Deny, in)
+	}
+}
+
+func TestHasPermissionInBundle(t *testing.T) {
+	permissions := []string{
+		"gort:manage_users",
+		"gort:manage_groups",
+		"ops:deploy",
+		"orphan",
+		"bad:perm:name",
+		":empty",
+	}
+
+	assert.True(t, HasAnyPermissionInBundle(permissions, "gort"))
+	assert.True(t, HasAnyPermissionInBundle(permissions, "ops"))
+	assert.False(t, HasAnyPermissionInBundle(permissions, "aws"))
+	assert.False(t, HasAnyPermissionInBundle(permissions, "bad"))
+	assert.False(t, HasAnyPermissionInBundle(permissions, ""))
+	assert.False(t, HasAnyPermissionInBundle(nil, "gort"))
+
+	assert.True(t, HasPermissionInBundle(permissions, "gort", "manage_users"))
+	assert.True(t, HasPermissionInBundle(permissions, "gort", "manage_groups"))
+	assert.True(t, HasPermissionInBundle(permissions, "ops", "deploy"))
+	assert.False(t, HasPermissionInBundle(permissions, "ops", "manage_users"))
+	assert.False(t, HasPermissionInBundle(permissions, "gort", "deploy"))
+	assert.False(t, HasPermissionInBundle(permissions, "", "orphan"))
+	assert.False(t, HasPermissionInBundle(permissions, "bad", "perm:name"))
+	assert.False(t, HasPermissionInBundle(permissions, "", "empty"))
+}
+
+func TestRuleMatchesShortCircuit(t *testing.T) {
+	var evaluated []string
+
+	// condition returns an expression that records its name when evaluated.
+	condition := func(name string, result bool, op LogicalOperator) Expression {
+		return Expression{
+			A:         types.StringValue{V: name},
+			B:         types.StringValue{V: name},
+			Condition: op,
+			Operator: func(a, b types.Value) bool {
+				evaluated = append(evaluated, name)
+				return result
+			},
+		}
+	}
+
+	tests := []struct {
+		Conditions []Expression
+		Expected   bool
+		Evaluated  []string
+	}{
+		{[]Expression{condition("a", true, Undefined), condition("b", false, Or)}, true, []string{"a"}},
+		{[]Expression{condition("a", false, Undefined), condition("b", true, And)}, false, []string{"a"}},
+		{[]Expression{condition("a", false, Undefined), condition("b", true, Or)}, true, []string{"a", "b"}},
+		{[]Expression{condition("a", true, Undefined), condition("b", false, And)}, false, []string{"a", "b"}},
+		{[]Expression{condition("a", true, Undefined), condition("b", true, Or), condition("c", false, And)}, false, []string{"a", "c"}},
+		{[]Expression{condition("a", false, Undefined), condition("b", true, And), condition("c", true, Or)}, true, []string{"a", "c"}},
+	}
+
+	for i, test := range tests {
+		evaluated = nil
+		rule := Rule{Command: "foo:bar", Conditions: test.Conditions}
+		assert.Equal(t, test.Expected, rule.Matches(EvaluationEnvironment{}), i)
+		assert.Equal(t, test.Evaluated, evaluated, i)
+	}
+}
+
+func TestRuleAllowedShortCircuit(t *testing.T) {
+	inputs := map[string]bool{
+		`foo:bar must have foo:a or foo:b`:                   true,
+		`foo:bar must have foo:x or foo:b`:                   false,
+		`foo:bar must have foo:a and foo:x`:                  false,
+		`foo:bar must have foo:x and foo:a or foo:a`:         true,
+		`foo:bar must have foo:a or foo:x and foo:x`:         false,
+		`foo:bar must have foo:a or foo:x and foo:a`:         true,
+		`foo:bar must have foo:x and foo:a and foo:a`:        false,
+		`foo:bar must have foo:x or foo:x or foo:x or foo:a`: true,
+	}
+
+	for in, expected := range inputs {
+		rule, err := TokenizeAndParse(in)
+		if !assert.NoError(t, err, in) {
+			continue
+		}
+
+		assert.Equal(t, expected, rule.Allowed([]string{"foo:a"}), in)
+	}
+}
+
+func TestRuleMatchesCoercion(t *testing.T) {
+	type Test struct {
+		Rule    string
+		Strict  bool
+		Lenient bool
+	}
+
+	tests := []Test{
+		{`ops:deploy with arg[0] == 5 allow`, false, true},
+		{`ops:deploy with arg[0] != 5 allow`, true, false},
+		{`ops:deploy with 5 == arg[0] allow`, false, true},
+		{`ops:deploy with arg[0] == "5" allow`, true, true},
+		{`ops:deploy with arg[0] == 6 allow`, false, false},
+		{`ops:deploy with arg[0] < 6 allow`, false, true},
+		{`ops:deploy with arg[1] == 2.5 allow`, false, true},
+		{`ops:deploy with arg[2] == 0 allow`, false, false},
+		{`ops:deploy with arg[3] == 5 allow`, false, false},
+		{`ops:deploy with option["replicas"] == 3 allow`, false, true},
+		{`ops:deploy with any arg == 2.5 allow`, false, true},
+		{`ops:deploy with all arg < 10 allow`, false, false},
+	}
+
+	cmd, err := command.TokenizeAndParse(`ops:deploy --replicas "3" "5" "2.5" abc`,
+		command.ParseOptionHasArgument("replicas", true))
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	env := NewEvaluationEnvironment(cmd)
+
+	for _, test := range tests {
+		rule, err := TokenizeAndParse(test.Rule)
+		if !assert.NoError(t, err, test.Rule) {
+			continue
+		}
+
+		assert.Equal(t, test.Strict, rule.Matches(env), "%s (strict)", test.Rule)
+
+		rule.Coercion = LenientCoercion
+		assert.Equal(t, test.Lenient, rule.Matches(env), "%s (lenient)", test.Rule)
+	}
+}
+
+func TestRuleClone(t *testing.T) {
+	const in = `foo:bar with arg[0] in ["x", "y"] and option["env"] == "prod" must have foo:write`
+
+	rt, err := Tokenize(in)
+	assert.NoError(t, err)
+
+	original, err := Parse(rt)
+	assert.NoError(t, err)
+
+	clone := original.Clone()
+	assert.Equal(t, original.Command, clone.Command)
+	assert.Equal(t, original.Permissions, clone.Permissions)
+	assert.Len(t, clone.Conditions, len(original.Conditions))
+	for i := range original.Conditions {
+		assert.Equal(t, original.Conditions[i].A, clone.Conditions[i].A)
+		assert.Equal(t, original.Conditions[i].B, clone.Conditions[i].B)
+	}
+
+	// Modify the clone's conditions, including the list operand, and its
+	// permissions.
+	clone.Conditions[0].B.(types.ListValue).V[0] = types.StringValue{V: "z"}
+	clone.Conditions[1].B = types.StringValue{V: "dev"}
+	clone.Conditions = append(clone.Conditions[:1], clone.Conditions[0])
+	clone.Permissions[0].Name = "foo:read"
+
+	// The original is unchanged
+	assert.Len(t, original.Conditions, 2)
+	assert.Equal(t, types.ListValue{V: []types.Value{types.StringValue{V: "x", Quote: '"'}, types.StringValue{V: "y", Quote: '"'}}}, original.Conditions[0].B)
+	assert.Equal(t, types.StringValue{V: "prod", Quote: '"'}, original.Conditions[1].B)
+	assert.Equal(t, "foo:write", original.Permissions[0].Name)
+
+	// Cloning a zero-value rule produces a zero-value rule
+	assert.Equal(t, Rule{}, Rule{}.Clone())
+}
+
+func TestRuleReferencedVariables(t *testing.T) {
+	inputs := map[string][]string{
+		`foo:bar allow`:                                                    {},
+		`foo:bar with true == false allow`:                                 {},
+		`foo:bar with arg[0] == "foo" allow`:                               {`arg[0]`},
+		`foo:bar with option["env"] == "prod" allow`:                       {`option["env"]`},
+		`foo:bar with option['env'] == "prod" allow`:                       {`option["env"]`},
+		`foo:bar with any arg == /^f.*$/ allow`:                            {`arg`},
+		`foo:bar with user.roles == "admin" allow`:                         {`user.roles`},
+		`foo:bar with option['env'] != undefined allow`:                    {`option["env"]`},
+		`foo:bar with arg[0] == arg[1] and arg[0] == "x" allow`:            {`arg[0]`, `arg[1]`},
+		`foo:bar with command == "deploy" and bundle == "ops" allow`:       {`command`, `bundle`},
+		`foo:bar with option["a"] == 1 or arg[2] in ["x", "y"] allow`:      {`option["a"]`, `arg[2]`},
+		`foo:bar with option["a"] == 1 must have foo:write and foo:read`:   {`option["a"]`},
+		`foo:bar with "prod" == option["env"] and option["env"] > 1 allow`: {`option["env"]`},
+	}
+
+	for in, expected := range inputs {
+		rt, err := Tokenize(in)
+		if !assert.NoError(t, err, in) {
+			continue
+		}
+
+		rule, err := Parse(rt)
+		if !assert.NoError(t, err, in) {
+			continue
+		}
+
+		assert.Equal(t, expected, rule.ReferencedVariables(), in)
+	}
+}
+
+func TestSortByPriority(t *testing.T) {
+	rr := []Rule{
+		{Command: "foo:a", Priority: 0},
+		{Command: "foo:b", Priority: 10},
+		{Command: "foo:c", Priority: -1},
+		{Command: "foo:d", Priority: 10},
+		{Command: "foo:e", Priority: 0},
+	}
+
+	SortByPriority(rr)
+
+	var commands []string
+	for _, r := range rr {
+		commands = append(commands, r.Command)
+	}
+	assert.Equal(t, []string{"foo:b", "foo:d", "foo:a", "foo:e", "foo:c"}, commands)
+}
@@ -18,19 +18,47 @@ package rules
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"regexp"
 	"strings"
+	"unicode"
 )
 
-var reSplit = regexp.MustCompile(`\s+`)
+// ErrMissingCommand is returned by Tokenize when a rule begins with a
+// keyword, such as "with" or "allow", rather than the command that it
+// applies to.
+var ErrMissingCommand = errors.New("missing command")
 
 // RuleTokens represents a tokenized Gort rule of the form "COMMAND [when
-// CONDITION (and|or)]? [allow|must have PERMISSION (and|or)]".
+// CONDITION (and|or)]? [allow|deny|must have PERMISSION (and|or)]".
 type RuleTokens struct {
 	Command     string
 	Conditions  []string
 	Permissions []string
+
+	// Deny is true if the rule ends with the "deny" keyword.
+	Deny bool
+
+	// Clauses contains the original text of each of the rule's clauses.
+	Clauses RuleClauses
+}
+
+// RuleClauses contains the text of each clause of a tokenized rule, exactly
+// as it appears in the rule, including any irregular whitespace within it,
+// but with any comments removed. A clause that's missing from the rule is
+// empty.
+type RuleClauses struct {
+	// Command is the text of the command that the rule applies to.
+	Command string
+
+	// Conditions is the text that follows "with", up to the keyword that
+	// begins the permissions clause.
+	Conditions string
+
+	// Permissions is the text that follows "must have". It's empty for an
+	// "allow" or "deny" rule.
+	Permissions string
 }
 
 // String is mostly used for debugging.
@@ -40,11 +68,20 @@ func (r RuleTokens) String() string {
 }
 
 // Tokenize accepts a raw Gort rule of the form "COMMAND [when CONDITION
-// (and|or)]? [allow|must have PERMISSION (and|or)]", and returns a RuleTokens
-// value. A parsing error will produce a non-nil error. The RuleTokens' Command
-// value should always be non-empty; Conditions and Permissions can both be
-// empty (but non-nil). Empty Conditions always match the command. Empty
-// Permissions indicating the use of the "allow" keyword and always pass.
+// (and|or)]? [allow|deny|must have PERMISSION (and|or)]", and returns a
+// RuleTokens value. A parsing error will produce a non-nil error. The
+// RuleTokens' Command value is always non-empty: a rule that doesn't begin
+// with a command produces an error wrapping ErrMissingCommand. Conditions and
+// Permissions can both be empty (but non-nil). Empty Conditions always match
+// the command. Empty Permissions indicating the use of the "allow" keyword and
+// always pass, unless Deny is set, indicating the use of the "deny" keyword.
+// The original text of each clause is kept in Clauses; if there's an error,
+// the clauses read before it are still available.
+//
+// Comments are removed before the rule is tokenized: a word that begins with
+// "#" starts a comment that runs to the end of the line, unless it's within
+// a quoted string or a regular expression, so a rule stored in a file may
+// have a comment after any of its clauses.
 func Tokenize(s string) (RuleTokens, error) {
 	const (
 		StateCommand int = iota
@@ -56,42 +93,66 @@ func Tokenize(s string) (RuleTokens, error) {
 
 	rt := RuleTokens{Conditions: []string{}, Permissions: []string{}}
 
-	if s == "" {
+	// Any run of whitespace (spaces, tabs, newlines) separates words, and
+	// leading and trailing whitespace is ignored.
+	s = stripComments(s)
+	words := fields(s)
+
+	if len(words) == 0 {
 		return rt, fmt.Errorf("empty rule")
 	}
 
+	// span returns the original text from the start of words[from] to the
+	// end of words[to], or "" if there are no such words.
+	span := func(from, to int) string {
+		if from > to {
+			return ""
+		}
+		return s[words[from].start:words[to].end]
+	}
+
+	// clauseStart is the index of the first word of the current clause.
+	clauseStart := 0
+
 	// This is a primitive state machine. Regex just wasn't powerful enough.
 	// Sorry.
 
 	currentState := StateCommand
 	b := &strings.Builder{}
 
-	for _, s := range reSplit.Split(s, -1) {
+	for i, w := range words {
+		s := w.text
+
 		switch currentState {
 		case StateCommand:
 			switch s {
 			case "with":
-				if b.Len() == 0 && len(rt.Conditions) == 0 {
-					return rt, fmt.Errorf("expected command; got '%s'", s)
+				if b.Len() == 0 {
+					return rt, missingCommand(s)
 				}
 
 				rt.Command = b.String()
+				rt.Clauses.Command = span(0, i-1)
+				clauseStart = i + 1
 				b.Reset()
 				currentState = StateConditions
 			case "must":
-				if b.Len() == 0 && len(rt.Conditions) == 0 {
-					return rt, fmt.Errorf("expected command; got '%s'", s)
+				if b.Len() == 0 {
+					return rt, missingCommand(s)
 				}
 
 				rt.Command = b.String()
+				rt.Clauses.Command = span(0, i-1)
 				b.Reset()
 				currentState = StatePermissionsMust
-			case "allow":
-				if b.Len() == 0 && len(rt.Conditions) == 0 {
-					return rt, fmt.Errorf("expected command; got '%s'", s)
+			case "allow", "deny":
+				if b.Len() == 0 {
+					return rt, missingCommand(s)
 				}
 
 				rt.Command = b.String()
+				rt.Clauses.Command = span(0, i-1)
+				rt.Deny = s == "deny"
 				b.Reset()
 				currentState = StateEnd
 			case "and":
@@ -99,10 +160,14 @@ func Tokenize(s string) (RuleTokens, error) {
 			case "or":
 				fallthrough
 			case "have":
+				if b.Len() == 0 {
+					return rt, missingCommand(s)
+				}
+
 				return rt, fmt.Errorf("expected command; got '%s'", s)
 			default:
 				if !isNamespaced(s) {
-					return rt, fmt.Errorf("commands must be in the format 'bundle:command'")
+					return rt, fmt.Errorf("commands must be in the format 'bundle:command'; got '%s'", s)
 				}
 
 				bappend(b, s)
@@ -117,14 +182,17 @@ func Tokenize(s string) (RuleTokens, error) {
 				b.Reset()
 			case "must":
 				rt.Conditions = append(rt.Conditions, b.String())
+				rt.Clauses.Conditions = span(clauseStart, i-1)
 				b.Reset()
 				currentState = StatePermissionsMust
-			case "allow":
+			case "allow", "deny":
 				if b.Len() == 0 && len(rt.Conditions) == 0 {
 					return rt, fmt.Errorf("'with' missing conditions")
 				}
 
 				rt.Conditions = append(rt.Conditions, b.String())
+				rt.Clauses.Conditions = span(clauseStart, i-1)
+				rt.Deny = s == "deny"
 				b.Reset()
 				currentState = StateEnd
 			case "with":
@@ -138,9 +206,10 @@ func Tokenize(s string) (RuleTokens, error) {
 		case StatePermissionsMust:
 			switch s {
 			case "have":
+				clauseStart = i + 1
 				currentState = StatePermissionsHave
 			default:
-				return rt, fmt.Errorf("expected have; got %s", s)
+				return rt, fmt.Errorf("expected 'have' after 'must'; got '%s'", s)
 			}
 
 		case StatePermissionsHave:
@@ -156,6 +225,8 @@ func Tokenize(s string) (RuleTokens, error) {
 				b.Reset()
 			case "allow":
 				fallthrough
+			case "deny":
+				fallthrough
 			case "with":
 				fallthrough
 			case "must":
@@ -167,6 +238,9 @@ func Tokenize(s string) (RuleTokens, error) {
 			}
 
 		case StateEnd:
+			if rt.Deny {
+				return rt, fmt.Errorf("unexpected text after deny")
+			}
 			return rt, fmt.Errorf("unexpected text after allow")
 		}
 	}
@@ -175,20 +249,104 @@ func Tokenize(s string) (RuleTokens, error) {
 	case StateCommand:
 		return rt, fmt.Errorf("missing conditions and permissions clauses")
 	case StateConditions:
+		rt.Clauses.Conditions = span(clauseStart, len(words)-1)
 		return rt, fmt.Errorf("missing permissions clause")
 	case StatePermissionsMust:
-		return rt, fmt.Errorf("incomplete permissions clause")
+		return rt, fmt.Errorf("incomplete permissions clause: expected 'have' after 'must'")
 	case StatePermissionsHave:
 		if b.Len() == 0 && len(rt.Permissions) == 0 {
 			return rt, fmt.Errorf("'must have' missing permissions")
 		}
 
 		rt.Permissions = append(rt.Permissions, b.String())
+		rt.Clauses.Permissions = span(clauseStart, len(words)-1)
 	}
 
 	return rt, nil
 }
 
+// stripComments removes comments from a rule. A comment starts with a "#"
+// at the beginning of a word, outside of any quotes or regular expression,
+// and runs to the end of the line. The line break itself is kept.
+func stripComments(s string) string {
+	if strings.IndexByte(s, '#') < 0 {
+		return s
+	}
+
+	b := strings.Builder{}
+	inDoubleQuote := false
+	inSingleQuote := false
+	inRegex := false
+	inComment := false
+	prev := ' '
+
+	for _, ch := range s {
+		switch {
+		case inComment:
+			if ch != '\n' {
+				continue
+			}
+			inComment = false
+		case ch == '#' && unicode.IsSpace(prev) && !inDoubleQuote && !inSingleQuote && !inRegex:
+			inComment = true
+			continue
+		case ch == '"' || ch == '“' || ch == '”':
+			if !inSingleQuote {
+				inDoubleQuote = !inDoubleQuote
+			}
+		case ch == '\'':
+			if !inDoubleQuote {
+				inSingleQuote = !inSingleQuote
+			}
+		case ch == '/':
+			if !inDoubleQuote && !inSingleQuote {
+				inRegex = !inRegex
+			}
+		}
+
+		b.WriteRune(ch)
+		prev = ch
+	}
+
+	return b.String()
+}
+
+// word is a word of a rule, along with its byte offsets in the rule.
+type word struct {
+	text       string
+	start, end int
+}
+
+// fields splits s into words exactly as strings.Fields does, but also
+// records the position of each word so that the original text of a clause
+// can be recovered.
+func fields(s string) []word {
+	var words []word
+	start := -1
+
+	for i, ch := range s {
+		switch {
+		case unicode.IsSpace(ch) && start >= 0:
+			words = append(words, word{s[start:i], start, i})
+			start = -1
+		case !unicode.IsSpace(ch) && start < 0:
+			start = i
+		}
+	}
+
+	if start >= 0 {
+		words = append(words, word{s[start:], start, len(s)})
+	}
+
+	return words
+}
+
+// missingCommand returns an error wrapping ErrMissingCommand for a rule that
+// begins with the keyword s.
+func missingCommand(s string) error {
+	return fmt.Errorf("%w: rule begins with keyword '%s'", ErrMissingCommand, s)
+}
+
 func bappend(b *strings.Builder, s string) {
 	if b.Len() != 0 {
 		b.WriteRune(' ')
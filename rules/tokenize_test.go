@@ -20,34 +20,124 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+
+	"github.com/getgort/gort/types"
 )
 
 // TestTokenize tests that various valid rule constructions resolve to
 // produce the expected data structures.
 func TestTokenize(t *testing.T) {
 	inputs := map[string]RuleTokens{
-		`foo:bar allow`: {`foo:bar`, []string{}, []string{}},
-		`foo:bar with option[foo] in ["foo", "bar"] allow`:                                                  {`foo:bar`, []string{`option[foo] in ["foo", "bar"]`}, []string{}},
-		`foo:bar with option['delete'] == true must have foo:destroy`:                                       {`foo:bar`, []string{`option['delete'] == true`}, []string{`foo:destroy`}},
-		`foo:set with option['set'] == /.*/ must have foo:baz-set`:                                          {`foo:set`, []string{`option['set'] == /.*/`}, []string{`foo:baz-set`}},
-		`foo:qux with arg[0] == 'status' must have foo:view`:                                                {`foo:qux`, []string{`arg[0] == 'status'`}, []string{`foo:view`}},
-		`foo:barqux with option['delete'] == true and arg[0] > 5 must have foo:destroy`:                     {`foo:barqux`, []string{`option['delete'] == true`, `and`, `arg[0] > 5`}, []string{`foo:destroy`}},
-		`foo:bar with any arg in ['wubba'] must have foo:read`:                                              {`foo:bar`, []string{`any arg in ['wubba']`}, []string{`foo:read`}},
-		`foo:bar with any arg in ['wubba', /^f.*/, 10] must have foo:read`:                                  {`foo:bar`, []string{`any arg in ['wubba', /^f.*/, 10]`}, []string{`foo:read`}},
-		`foo:bar with all arg in [10, 'baz', 'wubba'] must have foo:read`:                                   {`foo:bar`, []string{`all arg in [10, 'baz', 'wubba']`}, []string{`foo:read`}},
-		`foo:bar with arg[0] in ['baz', false, 100] must have foo:read`:                                     {`foo:bar`, []string{`arg[0] in ['baz', false, 100]`}, []string{`foo:read`}},
-		`foo:bar with any option == /^prod.*/ must have foo:read`:                                           {`foo:bar`, []string{`any option == /^prod.*/`}, []string{`foo:read`}},
-		`foo:bar with all option < 10 must have foo:read`:                                                   {`foo:bar`, []string{`all option < 10`}, []string{`foo:read`}},
-		`foo:bar with all option in ['staging', 'list'] must have foo:read`:                                 {`foo:bar`, []string{`all option in ['staging', 'list']`}, []string{`foo:read`}},
-		`foo:deploy with option["environment"] == 'prod' must have all in [site:it, site:prod, foo:deploy]`: {`foo:deploy`, []string{`option["environment"] == 'prod'`}, []string{`all in [site:it, site:prod, foo:deploy]`}},
-		`foo:deploy with option["environment"] == 'qa' must have site:test and foo:deploy`:                  {`foo:deploy`, []string{`option["environment"] == 'qa'`}, []string{`site:test`, `and`, `foo:deploy`}},
-		`foo:deploy with option["environment"] == 'stage' must have site:stage and foo:deploy`:              {`foo:deploy`, []string{`option["environment"] == 'stage'`}, []string{`site:stage`, `and`, `foo:deploy`}},
+		`foo:bar allow`: {`foo:bar`, []string{}, []string{}, false, RuleClauses{}},
+		`foo:bar with option[foo] in ["foo", "bar"] allow`:                                                  {`foo:bar`, []string{`option[foo] in ["foo", "bar"]`}, []string{}, false, RuleClauses{}},
+		`foo:bar with option['delete'] == true must have foo:destroy`:                                       {`foo:bar`, []string{`option['delete'] == true`}, []string{`foo:destroy`}, false, RuleClauses{}},
+		`foo:set with option['set'] == /.*/ must have foo:baz-set`:                                          {`foo:set`, []string{`option['set'] == /.*/`}, []string{`foo:baz-set`}, false, RuleClauses{}},
+		`foo:qux with arg[0] == 'status' must have foo:view`:                                                {`foo:qux`, []string{`arg[0] == 'status'`}, []string{`foo:view`}, false, RuleClauses{}},
+		`foo:barqux with option['delete'] == true and arg[0] > 5 must have foo:destroy`:                     {`foo:barqux`, []string{`option['delete'] == true`, `and`, `arg[0] > 5`}, []string{`foo:destroy`}, false, RuleClauses{}},
+		`foo:bar with any arg in ['wubba'] must have foo:read`:                                              {`foo:bar`, []string{`any arg in ['wubba']`}, []string{`foo:read`}, false, RuleClauses{}},
+		`foo:bar with any arg in ['wubba', /^f.*/, 10] must have foo:read`:                                  {`foo:bar`, []string{`any arg in ['wubba', /^f.*/, 10]`}, []string{`foo:read`}, false, RuleClauses{}},
+		`foo:bar with all arg in [10, 'baz', 'wubba'] must have foo:read`:                                   {`foo:bar`, []string{`all arg in [10, 'baz', 'wubba']`}, []string{`foo:read`}, false, RuleClauses{}},
+		`foo:bar with arg[0] in ['baz', false, 100] must have foo:read`:                                     {`foo:bar`, []string{`arg[0] in ['baz', false, 100]`}, []string{`foo:read`}, false, RuleClauses{}},
+		`foo:bar with any option == /^prod.*/ must have foo:read`:                                           {`foo:bar`, []string{`any option == /^prod.*/`}, []string{`foo:read`}, false, RuleClauses{}},
+		`foo:bar with all option < 10 must have foo:read`:                                                   {`foo:bar`, []string{`all option < 10`}, []string{`foo:read`}, false, RuleClauses{}},
+		`foo:bar with all option in ['staging', 'list'] must have foo:read`:                                 {`foo:bar`, []string{`all option in ['staging', 'list']`}, []string{`foo:read`}, false, RuleClauses{}},
+		`foo:deploy with option["environment"] == 'prod' must have all in [site:it, site:prod, foo:deploy]`: {`foo:deploy`, []string{`option["environment"] == 'prod'`}, []string{`all in [site:it, site:prod, foo:deploy]`}, false, RuleClauses{}},
+		`foo:deploy with option["environment"] == 'qa' must have site:test and foo:deploy`:                  {`foo:deploy`, []string{`option["environment"] == 'qa'`}, []string{`site:test`, `and`, `foo:deploy`}, false, RuleClauses{}},
+		`foo:deploy with option["environment"] == 'stage' must have site:stage and foo:deploy`:              {`foo:deploy`, []string{`option["environment"] == 'stage'`}, []string{`site:stage`, `and`, `foo:deploy`}, false, RuleClauses{}},
 		`foo:patch must have all in [foo:patch, site:it]
 			or all in [site:qa, site:test, foo:patch]
-			or all in [site:eng, site:stage, foo:patch]`: {`foo:patch`, []string{}, []string{`all in [foo:patch, site:it]`, `or`, `all in [site:qa, site:test, foo:patch]`, `or`, `all in [site:eng, site:stage, foo:patch]`}},
+			or all in [site:eng, site:stage, foo:patch]`: {`foo:patch`, []string{}, []string{`all in [foo:patch, site:it]`, `or`, `all in [site:qa, site:test, foo:patch]`, `or`, `all in [site:eng, site:stage, foo:patch]`}, false, RuleClauses{}},
 		`foo:bar
 		    with option['delete'] == true
-			   must have foo:destroy`: {`foo:bar`, []string{`option['delete'] == true`}, []string{`foo:destroy`}},
+			   must have foo:destroy`: {`foo:bar`, []string{`option['delete'] == true`}, []string{`foo:destroy`}, false, RuleClauses{}},
+	}
+
+	for str, expected := range inputs {
+		actual, err := Tokenize(str)
+		if !assert.NoError(t, err, str) {
+			continue
+		}
+
+		// The clauses are tested by TestTokenizeClauses.
+		actual.Clauses = RuleClauses{}
+		assert.Equal(t, expected, actual, str)
+	}
+}
+
+// TestTokenizeClauses tests that the text of each clause is captured
+// verbatim, including any irregular whitespace within it.
+func TestTokenizeClauses(t *testing.T) {
+	inputs := map[string]RuleClauses{
+		`foo:bar allow`: {`foo:bar`, ``, ``},
+		`foo:bar deny`:  {`foo:bar`, ``, ``},
+		`foo:bar with option['delete'] == true must have foo:destroy`:                       {`foo:bar`, `option['delete'] == true`, `foo:destroy`},
+		`foo:bar with arg[0] == 'rm' deny`:                                                  {`foo:bar`, `arg[0] == 'rm'`, ``},
+		`foo:bar must have site:test and foo:deploy`:                                        {`foo:bar`, ``, `site:test and foo:deploy`},
+		"  foo:bar\twith  option['a'] ==\t1\n\tand arg[0] > 5  must  have  x:y  \n":         {`foo:bar`, "option['a'] ==\t1\n\tand arg[0] > 5", `x:y`},
+		`foo:bar with arg[0] in ["a  b", 'c'] allow`:                                        {`foo:bar`, `arg[0] in ["a  b", 'c']`, ``},
+		"foo:patch must have all in [foo:patch, site:it]\n\tor all in [site:qa, foo:patch]": {`foo:patch`, ``, "all in [foo:patch, site:it]\n\tor all in [site:qa, foo:patch]"},
+	}
+
+	for str, expected := range inputs {
+		actual, err := Tokenize(str)
+		if !assert.NoError(t, err, str) {
+			continue
+		}
+
+		assert.Equal(t, expected, actual.Clauses, str)
+	}
+
+	// The clauses that were read before an error are available, so that
+	// the failing clause can be identified.
+	rt, err := Tokenize(`foo:bar with arg[0]  == 1 must have x:y must`)
+	assert.Error(t, err)
+	assert.Equal(t, RuleClauses{Command: `foo:bar`, Conditions: `arg[0]  == 1`}, rt.Clauses)
+
+	rt, err = Tokenize(`foo:bar with arg[0]  == 1`)
+	assert.Error(t, err)
+	assert.Equal(t, RuleClauses{Command: `foo:bar`, Conditions: `arg[0]  == 1`}, rt.Clauses)
+}
+
+// TestTokenizeIrregularWhitespace tests that arbitrary runs of spaces, tabs,
+// and newlines between (and around) words don't affect tokenization.
+func TestTokenizeIrregularWhitespace(t *testing.T) {
+	expected := RuleTokens{`foo:bar`, []string{`option['delete'] == true`, `and`, `arg[0] > 5`}, []string{`foo:destroy`}, false, RuleClauses{}}
+
+	inputs := []string{
+		`foo:bar with option['delete'] == true and arg[0] > 5 must have foo:destroy`,
+		`foo:bar  with  option['delete']  ==  true  and  arg[0]  >  5  must  have  foo:destroy`,
+		"foo:bar\twith\toption['delete']\t==\ttrue\tand\targ[0]\t>\t5\tmust\thave\tfoo:destroy",
+		" \t foo:bar with option['delete'] ==\t \ttrue and arg[0] > 5 must have foo:destroy \t\n",
+		"foo:bar with option['delete']\t== true\n\tand arg[0] > 5\n\tmust \t have foo:destroy",
+	}
+
+	for _, str := range inputs {
+		actual, err := Tokenize(str)
+		if !assert.NoError(t, err, str) {
+			continue
+		}
+
+		// The clauses are tested by TestTokenizeClauses.
+		actual.Clauses = RuleClauses{}
+		assert.Equal(t, expected, actual, str)
+	}
+}
+
+// TestTokenizePermissionClause tests the "allow" and "must have" permission
+// clauses, including the edge cases that come with "must have" being two
+// words.
+func TestTokenizePermissionClause(t *testing.T) {
+	inputs := map[string]RuleTokens{
+		`foo:bar allow`:                               {`foo:bar`, []string{}, []string{}, false, RuleClauses{}},
+		`foo:bar must have foo:bar`:                   {`foo:bar`, []string{}, []string{`foo:bar`}, false, RuleClauses{}},
+		`foo:bar must have foo:have`:                  {`foo:bar`, []string{}, []string{`foo:have`}, false, RuleClauses{}},
+		`foo:bar must have have:must`:                 {`foo:bar`, []string{}, []string{`have:must`}, false, RuleClauses{}},
+		"foo:bar must\t\thave   foo:bar":              {`foo:bar`, []string{}, []string{`foo:bar`}, false, RuleClauses{}},
+		"foo:bar must\nhave foo:bar or foo:baz":       {`foo:bar`, []string{}, []string{`foo:bar`, `or`, `foo:baz`}, false, RuleClauses{}},
+		`foo:bar with arg[0] == 'must' allow`:         {`foo:bar`, []string{`arg[0] == 'must'`}, []string{}, false, RuleClauses{}},
+		`foo:bar with arg[0] == 'have' must have x:y`: {`foo:bar`, []string{`arg[0] == 'have'`}, []string{`x:y`}, false, RuleClauses{}},
+		`foo:bar deny`:                                {`foo:bar`, []string{}, []string{}, true, RuleClauses{}},
+		`foo:bar with arg[0] == 'rm' deny`:            {`foo:bar`, []string{`arg[0] == 'rm'`}, []string{}, true, RuleClauses{}},
 	}
 
 	for str, expected := range inputs {
@@ -56,8 +146,64 @@ func TestTokenize(t *testing.T) {
 			continue
 		}
 
+		// The clauses are tested by TestTokenizeClauses.
+		actual.Clauses = RuleClauses{}
 		assert.Equal(t, expected, actual, str)
 	}
+
+	errors := map[string]string{
+		`foo:bar must`:                 `expected 'have' after 'must'`,
+		`foo:bar must foo:bar`:         `expected 'have' after 'must'; got 'foo:bar'`,
+		`foo:bar must must have x:y`:   `expected 'have' after 'must'; got 'must'`,
+		`foo:bar must have`:            `'must have' missing permissions`,
+		`foo:bar must have have`:       `unexpected keyword 'have'`,
+		`foo:bar must have x:y must`:   `unexpected keyword 'must'`,
+		`foo:bar have x:y`:             `expected command; got 'have'`,
+		`foo:bar with arg[0] have x:y`: `unexpected keyword 'have'`,
+		`foo:bar deny x:y`:             `unexpected text after deny`,
+		`foo:bar must have x:y deny`:   `unexpected keyword 'deny'`,
+		`foo:bar with deny`:            `'with' missing conditions`,
+		`foobar allow`:                 `commands must be in the format 'bundle:command'; got 'foobar'`,
+		`when x == y allow`:            `commands must be in the format 'bundle:command'; got 'when'`,
+	}
+
+	for str, msg := range errors {
+		_, err := Tokenize(str)
+		if assert.Error(t, err, str) {
+			assert.Contains(t, err.Error(), msg, str)
+		}
+	}
+}
+
+func TestTokenizeMissingCommand(t *testing.T) {
+	inputs := map[string]string{
+		`with x == y allow`:        `missing command: rule begins with keyword 'with'`,
+		`must have foo:read`:       `missing command: rule begins with keyword 'must'`,
+		`allow`:                    `missing command: rule begins with keyword 'allow'`,
+		`deny`:                     `missing command: rule begins with keyword 'deny'`,
+		`and foo:bar allow`:        `missing command: rule begins with keyword 'and'`,
+		"\t with arg[0] == 1 deny": `missing command: rule begins with keyword 'with'`,
+	}
+
+	for in, msg := range inputs {
+		rt, err := Tokenize(in)
+		if assert.ErrorIs(t, err, ErrMissingCommand, in) {
+			assert.Equal(t, msg, err.Error(), in)
+		}
+		assert.Empty(t, rt.Command, in)
+	}
+
+	// A rule with a command but no clauses is an error, but not a missing
+	// command.
+	_, err := Tokenize(`foo:bar`)
+	if assert.Error(t, err) {
+		assert.NotErrorIs(t, err, ErrMissingCommand)
+		assert.Equal(t, "missing conditions and permissions clauses", err.Error())
+	}
+
+	rt, err := Tokenize(`foo:bar allow`)
+	assert.NoError(t, err)
+	assert.Equal(t, "foo:bar", rt.Command)
 }
 
 // TestTokenizeErrors tests that various invalid rule constructions generate
@@ -65,6 +211,7 @@ func TestTokenize(t *testing.T) {
 func TestTokenizeErrors(t *testing.T) {
 	inputs := []string{
 		``,
+		" \t\n ",
 		`foo:bar`,
 		`foobar allow`,
 		`foo:bar allow foo`,
@@ -87,3 +234,51 @@ func TestTokenizeErrors(t *testing.T) {
 		assert.Error(t, err, str)
 	}
 }
+
+// TestTokenizeComments tests that comments are removed, wherever they
+// appear, and that a "#" that doesn't begin a comment is preserved.
+func TestTokenizeComments(t *testing.T) {
+	inputs := map[string]RuleTokens{
+		`foo:bar allow # prod only`: {`foo:bar`, []string{}, []string{}, false, RuleClauses{`foo:bar`, ``, ``}},
+		`foo:bar deny #prod only`:   {`foo:bar`, []string{}, []string{}, true, RuleClauses{`foo:bar`, ``, ``}},
+		"foo:bar # the command\n with arg[0] == 1 # the conditions\n must have foo:baz # the permissions": {
+			`foo:bar`, []string{`arg[0] == 1`}, []string{`foo:baz`}, false, RuleClauses{`foo:bar`, `arg[0] == 1`, `foo:baz`},
+		},
+		"foo:bar with arg[0] == 1 # first\n and arg[1] == 2 # second\n allow": {
+			`foo:bar`, []string{`arg[0] == 1`, `and`, `arg[1] == 2`}, []string{}, false, RuleClauses{`foo:bar`, "arg[0] == 1 \n and arg[1] == 2", ``},
+		},
+		"# a comment on its own line\nfoo:bar allow": {`foo:bar`, []string{}, []string{}, false, RuleClauses{`foo:bar`, ``, ``}},
+		`foo:bar with arg[0] == '# not a comment' allow # a comment`: {
+			`foo:bar`, []string{`arg[0] == '# not a comment'`}, []string{}, false, RuleClauses{`foo:bar`, `arg[0] == '# not a comment'`, ``},
+		},
+		`foo:bar with arg[0] in ["a #1", 'b #2'] allow`: {
+			`foo:bar`, []string{`arg[0] in ["a #1", 'b #2']`}, []string{}, false, RuleClauses{`foo:bar`, `arg[0] in ["a #1", 'b #2']`, ``},
+		},
+		`foo:bar with arg[0] == /^ #x/ must have foo:baz # regex`: {
+			`foo:bar`, []string{`arg[0] == /^ #x/`}, []string{`foo:baz`}, false, RuleClauses{`foo:bar`, `arg[0] == /^ #x/`, `foo:baz`},
+		},
+		`foo:bar with arg[0] == a#b allow`: {
+			`foo:bar`, []string{`arg[0] == a#b`}, []string{}, false, RuleClauses{`foo:bar`, `arg[0] == a#b`, ``},
+		},
+	}
+
+	for str, expected := range inputs {
+		actual, err := Tokenize(str)
+		if !assert.NoError(t, err, str) {
+			continue
+		}
+
+		assert.Equal(t, expected, actual, str)
+	}
+
+	// A comment can't stand in for a clause.
+	_, err := Tokenize(`foo:bar # allow`)
+	assert.Error(t, err)
+
+	// Comments are removed before the rule is parsed.
+	r, err := TokenizeAndParse(`foo:bar with arg[0] == '#1' allow # prod only`)
+	if assert.NoError(t, err) {
+		assert.Len(t, r.Conditions, 1)
+		assert.Equal(t, types.StringValue{V: "#1", Quote: '\''}, r.Conditions[0].B)
+	}
+}
@@ -29,7 +29,6 @@ import (
 
 	"github.com/getgort/gort/data"
 	"github.com/getgort/gort/dataaccess/errs"
-	gerrs "github.com/getgort/gort/errors"
 )
 
 var (
@@ -134,9 +133,9 @@ func handlePatchBundleVersion(w http.ResponseWriter, r *http.Request) {
 	if r.ContentLength > 0 {
 		var bundle data.Bundle
 
-		err = json.NewDecoder(r.Body).Decode(&bundle)
+		err = decodeRequestBody(r, &bundle)
 		if err != nil {
-			respondAndLogError(r.Context(), w, gerrs.ErrUnmarshal)
+			respondAndLogError(r.Context(), w, err)
 			return
 		}
 
@@ -166,9 +165,9 @@ func handlePutBundleVersion(w http.ResponseWriter, r *http.Request) {
 	var bundle data.Bundle
 	var err error
 
-	err = json.NewDecoder(r.Body).Decode(&bundle)
+	err = decodeRequestBody(r, &bundle)
 	if err != nil {
-		respondAndLogError(r.Context(), w, gerrs.ErrUnmarshal)
+		respondAndLogError(r.Context(), w, err)
 		return
 	}
 
@@ -0,0 +1,109 @@
+/*
+ * Copyright 2021 The Gort Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+
+	"github.com/getgort/gort/data/rest"
+)
+
+// handleGetExport handles "GET /v2/export". It responds with a snapshot of
+// all users, groups, and roles. Users' passwords are excluded unless the
+// "passwords" query parameter is true, and their tokens unless the "tokens"
+// query parameter is true.
+func handleGetExport(w http.ResponseWriter, r *http.Request) {
+	passwords, err := parseBoolQuery(r, "passwords")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	tokens, err := parseBoolQuery(r, "tokens")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	snapshot, err := dataAccessLayer.Export(r.Context())
+	if err != nil {
+		respondAndLogError(r.Context(), w, err)
+		return
+	}
+
+	if !passwords {
+		for i := range snapshot.Users {
+			snapshot.Users[i].Password = ""
+		}
+	}
+
+	if !tokens {
+		snapshot.Tokens = nil
+	}
+
+	json.NewEncoder(w).Encode(snapshot)
+}
+
+// handlePostImport handles "POST /v2/import". The body is a snapshot, as
+// produced by "GET /v2/export", which is merged into the data store, or
+// replaces its contents if the "replace" query parameter is true. Existing
+// users whose passwords were excluded from the export keep their passwords.
+func handlePostImport(w http.ResponseWriter, r *http.Request) {
+	replace, err := parseBoolQuery(r, "replace")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var snapshot rest.Snapshot
+	if err := decodeRequestBody(r, &snapshot); err != nil {
+		respondAndLogError(r.Context(), w, err)
+		return
+	}
+
+	if err := dataAccessLayer.Import(r.Context(), snapshot, replace); err != nil {
+		respondAndLogError(r.Context(), w, err)
+		return
+	}
+}
+
+// parseBoolQuery returns the value of the named boolean query parameter, or
+// false if it's absent.
+func parseBoolQuery(r *http.Request, name string) (bool, error) {
+	s := r.URL.Query().Get(name)
+	if s == "" {
+		return false, nil
+	}
+
+	b, err := strconv.ParseBool(s)
+	if err != nil {
+		return false, fmt.Errorf("invalid %s value: %q", name, s)
+	}
+
+	return b, nil
+}
+
+func addExportMethodsToRouter(router *mux.Router) {
+	router.Handle("/v2/export", otelhttp.NewHandler(authCommand(handleGetExport, "export", ""), "handleGetExport")).Methods("GET")
+	router.Handle("/v2/import", otelhttp.NewHandler(authCommand(handlePostImport, "import", ""), "handlePostImport")).Methods("POST")
+}
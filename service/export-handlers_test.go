@@ -0,0 +1,136 @@
+/*
+ * Copyright 2021 The Gort Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/getgort/gort/data/rest"
+)
+
+func TestExportImport(t *testing.T) {
+	router := createTestRouter()
+	ctx := context.Background()
+
+	usernames := func(s rest.Snapshot) []string {
+		names := []string{}
+		for _, u := range s.Users {
+			names = append(names, u.Username)
+		}
+		return names
+	}
+
+	// Passwords and tokens are excluded by default.
+	snapshot := rest.Snapshot{}
+	NewResponseTester("GET", "http://example.com/v2/export").WithOutput(&snapshot).WithStatus(http.StatusOK).Test(t, router)
+	assert.Equal(t, []string{"admin"}, usernames(snapshot))
+	assert.Empty(t, snapshot.Users[0].Password)
+	assert.Empty(t, snapshot.Tokens)
+	assert.NotEmpty(t, snapshot.Groups)
+	assert.NotEmpty(t, snapshot.Roles)
+
+	withPasswords := rest.Snapshot{}
+	NewResponseTester("GET", "http://example.com/v2/export?passwords=true").WithOutput(&withPasswords).WithStatus(http.StatusOK).Test(t, router)
+	if assert.Len(t, withPasswords.Users, 1) {
+		assert.NotEmpty(t, withPasswords.Users[0].Password)
+	}
+
+	NewResponseTester("GET", "http://example.com/v2/export?passwords=maybe").WithStatus(http.StatusBadRequest).Test(t, router)
+
+	withTokens := rest.Snapshot{}
+	NewResponseTester("GET", "http://example.com/v2/export?tokens=true").WithOutput(&withTokens).WithStatus(http.StatusOK).Test(t, router)
+	if assert.Len(t, withTokens.Tokens, 1) {
+		assert.Equal(t, adminToken.Token, withTokens.Tokens[0].Token)
+	}
+
+	NewResponseTester("GET", "http://example.com/v2/export?tokens=maybe").WithStatus(http.StatusBadRequest).Test(t, router)
+
+	// Merging adds the snapshot's users to the existing ones.
+	merge := rest.Snapshot{
+		Users:  []rest.User{{Username: "imported", Email: "imported@testing.com"}},
+		Groups: []rest.SnapshotGroup{{Name: "imported", Users: []string{"imported"}}},
+	}
+	NewResponseTester("POST", "http://example.com/v2/import").WithBody(merge).WithStatus(http.StatusOK).Test(t, router)
+
+	exists, err := dataAccessLayer.UserExists(ctx, "imported")
+	assert.NoError(t, err)
+	assert.True(t, exists)
+
+	users, err := dataAccessLayer.GroupUserList(ctx, "imported")
+	assert.NoError(t, err)
+	assert.Len(t, users, 1)
+
+	// Replacing restores the original export. The admin's token survives,
+	// because the admin is in the snapshot, and so does its password, which
+	// was excluded from it.
+	NewResponseTester("POST", "http://example.com/v2/import?replace=true").WithBody(snapshot).WithStatus(http.StatusOK).Test(t, router)
+
+	exists, err = dataAccessLayer.UserExists(ctx, "imported")
+	assert.NoError(t, err)
+	assert.False(t, exists)
+
+	restored := rest.Snapshot{}
+	NewResponseTester("GET", "http://example.com/v2/export").WithOutput(&restored).WithStatus(http.StatusOK).Test(t, router)
+	assert.Equal(t, snapshot, restored)
+
+	restored = rest.Snapshot{}
+	NewResponseTester("GET", "http://example.com/v2/export?passwords=true").WithOutput(&restored).WithStatus(http.StatusOK).Test(t, router)
+	assert.Equal(t, withPasswords.Users, restored.Users)
+
+	// A snapshot that can't be restored is rejected.
+	bad := rest.Snapshot{Groups: []rest.SnapshotGroup{{Name: "bad", Roles: []string{"no-such-role"}}}}
+	NewResponseTester("POST", "http://example.com/v2/import").WithBody(bad).WithStatus(http.StatusNotFound).Test(t, router)
+	NewResponseTester("POST", "http://example.com/v2/import?replace=maybe").WithBody(merge).WithStatus(http.StatusBadRequest).Test(t, router)
+}
+
+func TestPostImportMaxBodySize(t *testing.T) {
+	const max = 256
+
+	router := createTestRouter()
+	router.Use(buildMaxBodySizeMiddleware(max))
+
+	merge := rest.Snapshot{
+		Users: []rest.User{{Username: "imported", FullName: strings.Repeat("x", max)}},
+	}
+	b, err := json.Marshal(merge)
+	assert.NoError(t, err)
+
+	post := func(path string, contentLength int64) int {
+		req := httptest.NewRequest("POST", "http://example.com"+path, strings.NewReader(string(b)))
+		req.Header.Add("X-Session-Token", adminToken.Token)
+		req.ContentLength = contentLength
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Result().StatusCode
+	}
+
+	// Imports may be larger than other requests...
+	assert.Equal(t, http.StatusRequestEntityTooLarge, post("/v2/users/bulk", int64(len(b))))
+	assert.Equal(t, http.StatusOK, post("/v2/import", int64(len(b))))
+	assert.Equal(t, http.StatusOK, post("/v2/import", -1))
+
+	// ...but have a limit of their own.
+	assert.Equal(t, http.StatusRequestEntityTooLarge, post("/v2/import", MaxImportRequestBodySize+1))
+}
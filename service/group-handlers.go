@@ -18,13 +18,13 @@ package service
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
 
 	"github.com/gorilla/mux"
 	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
 
 	"github.com/getgort/gort/data/rest"
-	gerrs "github.com/getgort/gort/errors"
 )
 
 // handleDeleteGroup handles "DELETE /v2/groups/{groupname}"
@@ -73,7 +73,7 @@ func handleDeleteGroupMember(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-// handleDeleteGroupRole handles "DELETE "/v2/groups/{groupname}/roles/{rolename}""
+// handleDeleteGroupRole handles "DELETE /v2/groups/{groupname}/roles/{rolename}"
 func handleDeleteGroupRole(w http.ResponseWriter, r *http.Request) {
 	var exists bool
 	var err error
@@ -131,16 +131,50 @@ func handleGetGroup(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(group)
 }
 
-// handleGetGroups handles "GET /v2/groups"
+// handleGetGroups handles "GET /v2/groups". The optional "offset" and "limit"
+// query parameters select a page of groups, sorted by name; see
+// setPageHeaders for the headers used to navigate between pages. The response
+// has an ETag; see writeJSONWithETag. If the "summary" query parameter is
+// true, a summary of every group is returned instead; see
+// handleGetGroupsSummary.
 func handleGetGroups(w http.ResponseWriter, r *http.Request) {
-	groups, err := dataAccessLayer.GroupList(r.Context())
+	summary, err := parseBoolQuery(r, "summary")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if summary {
+		handleGetGroupsSummary(w, r)
+		return
+	}
+
+	offset, limit, err := parsePageQuery(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
+	groups, total, err := dataAccessLayer.GroupListPage(r.Context(), offset, limit)
 	if err != nil {
 		respondAndLogError(r.Context(), w, err)
 		return
 	}
 
-	json.NewEncoder(w).Encode(groups)
+	setPageHeaders(w, r, offset, limit, total)
+	writeJSONWithETag(w, r, groups)
+}
+
+// handleGetGroupsSummary handles "GET /v2/groups?summary=true". It returns
+// the name and the number of members and roles of every group, sorted by
+// name, without the members and roles themselves.
+func handleGetGroupsSummary(w http.ResponseWriter, r *http.Request) {
+	summaries, err := dataAccessLayer.GroupListSummary(r.Context())
+	if err != nil {
+		respondAndLogError(r.Context(), w, err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(summaries)
 }
 
 // handleGetGroupMembers handles "GET /v2/groups/{groupname}/members"
@@ -191,16 +225,25 @@ func handleGetGroupRoles(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(roles)
 }
 
-// handlePutGroup handles "PUT /v2/groups/{groupname}"
+// handlePutGroup handles "PUT /v2/groups/{groupname}". If the body includes
+// a group name it must match the one in the URL.
 func handlePutGroup(w http.ResponseWriter, r *http.Request) {
 	var group rest.Group
 	var err error
 
 	params := mux.Vars(r)
 
-	err = json.NewDecoder(r.Body).Decode(&group)
+	err = decodeRequestBody(r, &group)
 	if err != nil {
-		respondAndLogError(r.Context(), w, gerrs.ErrUnmarshal)
+		respondAndLogError(r.Context(), w, err)
+		return
+	}
+
+	// The name in the body is optional, but if it's present it has to agree
+	// with the one in the URL.
+	if group.Name != "" && group.Name != params["groupname"] {
+		msg := fmt.Sprintf("group name %q in body doesn't match %q in URL", group.Name, params["groupname"])
+		http.Error(w, msg, http.StatusBadRequest)
 		return
 	}
 
@@ -224,6 +267,16 @@ func handlePutGroup(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// handlePostGroupRename handles "POST /v2/groups/{groupname}/rename/{newname}"
+func handlePostGroupRename(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+
+	err := dataAccessLayer.GroupRename(r.Context(), params["groupname"], params["newname"])
+	if err != nil {
+		respondAndLogError(r.Context(), w, err)
+	}
+}
+
 // handlePutGroupMember handles "PUT "/v2/groups/{groupname}/members/{username}""
 func handlePutGroupMember(w http.ResponseWriter, r *http.Request) {
 	var exists bool
@@ -259,7 +312,7 @@ func handlePutGroupMember(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-// handlePutGroupRole handles "PUT "/v2/groups/{groupname}/roles/{rolename}""
+// handlePutGroupRole handles "PUT /v2/groups/{groupname}/roles/{rolename}"
 func handlePutGroupRole(w http.ResponseWriter, r *http.Request) {
 	var exists bool
 	var err error
@@ -300,6 +353,7 @@ func addGroupMethodsToRouter(router *mux.Router) {
 	router.Handle("/v2/groups/{groupname}", otelhttp.NewHandler(authCommand(handleGetGroup, "group", "info"), "handleGetGroup")).Methods("GET")
 	router.Handle("/v2/groups/{groupname}", otelhttp.NewHandler(authCommand(handlePutGroup, "group", "create"), "handlePutGroup")).Methods("PUT")
 	router.Handle("/v2/groups/{groupname}", otelhttp.NewHandler(authCommand(handleDeleteGroup, "group", "delete"), "handleDeleteGroup")).Methods("DELETE")
+	router.Handle("/v2/groups/{groupname}/rename/{newname}", otelhttp.NewHandler(authCommand(handlePostGroupRename, "group", "rename"), "handlePostGroupRename")).Methods("POST")
 
 	// Group user membership
 	router.Handle("/v2/groups/{groupname}/members", otelhttp.NewHandler(authCommand(handleGetGroupMembers, "group", ""), "handleGetGroupMembers")).Methods("GET")
@@ -307,7 +361,7 @@ func addGroupMethodsToRouter(router *mux.Router) {
 	router.Handle("/v2/groups/{groupname}/members/{username}", otelhttp.NewHandler(authCommand(handlePutGroupMember, "group", ""), "handlePutGroupMember")).Methods("PUT")
 
 	// Group roles
-	router.Handle("/v2/groups/{groupname}/roles", otelhttp.NewHandler(authCommand(handleGetGroupRoles, "group", "info"), "handleGetGroupMembers")).Methods("GET")
-	router.Handle("/v2/groups/{groupname}/roles/{rolename}", otelhttp.NewHandler(authCommand(handleDeleteGroupRole, "group", "add"), "handleDeleteGroupMember")).Methods("DELETE")
-	router.Handle("/v2/groups/{groupname}/roles/{rolename}", otelhttp.NewHandler(authCommand(handlePutGroupRole, "group", "add"), "handlePutGroupMember")).Methods("PUT")
+	router.Handle("/v2/groups/{groupname}/roles", otelhttp.NewHandler(authCommand(handleGetGroupRoles, "group", "info"), "handleGetGroupRoles")).Methods("GET")
+	router.Handle("/v2/groups/{groupname}/roles/{rolename}", otelhttp.NewHandler(authCommand(handleDeleteGroupRole, "group", "revoke"), "handleDeleteGroupRole")).Methods("DELETE")
+	router.Handle("/v2/groups/{groupname}/roles/{rolename}", otelhttp.NewHandler(authCommand(handlePutGroupRole, "group", "grant"), "handlePutGroupRole")).Methods("PUT")
 }
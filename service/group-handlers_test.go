@@ -9,6 +9,49 @@ import (
 	"github.com/getgort/gort/data/rest"
 )
 
+func TestGroupMembershipConflicts(t *testing.T) {
+	router := createTestRouter()
+
+	// Create group and user
+	NewResponseTester("PUT", "http://example.com/v2/groups/testgroup").WithBody(rest.Group{Name: "testgroup"}).WithStatus(http.StatusOK).Test(t, router)
+	NewResponseTester("PUT", "http://example.com/v2/users/testuser").WithBody(rest.User{Username: "testuser"}).WithStatus(http.StatusOK).Test(t, router)
+
+	// Removing a non-member is a 404
+	NewResponseTester("DELETE", "http://example.com/v2/groups/testgroup/members/testuser").WithStatus(http.StatusNotFound).Test(t, router)
+
+	// Adding a member twice is a 409
+	NewResponseTester("PUT", "http://example.com/v2/groups/testgroup/members/testuser").WithStatus(http.StatusOK).Test(t, router)
+	NewResponseTester("PUT", "http://example.com/v2/groups/testgroup/members/testuser").WithStatus(http.StatusConflict).Test(t, router)
+
+	NewResponseTester("DELETE", "http://example.com/v2/groups/testgroup/members/testuser").WithStatus(http.StatusOK).Test(t, router)
+}
+
+func TestRenameGroup(t *testing.T) {
+	router := createTestRouter()
+
+	// Create groups, user, and role
+	NewResponseTester("PUT", "http://example.com/v2/groups/testgroup").WithBody(rest.Group{Name: "testgroup"}).WithStatus(http.StatusOK).Test(t, router)
+	NewResponseTester("PUT", "http://example.com/v2/groups/othergroup").WithBody(rest.Group{Name: "othergroup"}).WithStatus(http.StatusOK).Test(t, router)
+	NewResponseTester("PUT", "http://example.com/v2/users/testuser").WithBody(rest.User{Username: "testuser"}).WithStatus(http.StatusOK).Test(t, router)
+	NewResponseTester("PUT", "http://example.com/v2/roles/testrole").WithStatus(http.StatusOK).Test(t, router)
+	NewResponseTester("PUT", "http://example.com/v2/groups/testgroup/members/testuser").WithStatus(http.StatusOK).Test(t, router)
+	NewResponseTester("PUT", "http://example.com/v2/groups/testgroup/roles/testrole").WithStatus(http.StatusOK).Test(t, router)
+
+	// Renaming a missing group is a 404; renaming onto an existing one is a 409
+	NewResponseTester("POST", "http://example.com/v2/groups/nogroup/rename/newgroup").WithStatus(http.StatusNotFound).Test(t, router)
+	NewResponseTester("POST", "http://example.com/v2/groups/testgroup/rename/othergroup").WithStatus(http.StatusConflict).Test(t, router)
+
+	NewResponseTester("POST", "http://example.com/v2/groups/testgroup/rename/newgroup").WithStatus(http.StatusOK).Test(t, router)
+	NewResponseTester("GET", "http://example.com/v2/groups/testgroup").WithStatus(http.StatusNotFound).Test(t, router)
+
+	// Check members and roles moved with the group
+	group := rest.Group{}
+	NewResponseTester("GET", "http://example.com/v2/groups/newgroup").WithOutput(&group).WithStatus(http.StatusOK).Test(t, router)
+	assert.Equal(t, "newgroup", group.Name)
+	assert.Len(t, group.Users, 1)
+	assert.Len(t, group.Roles, 1)
+}
+
 func TestGrantGroupRole(t *testing.T) {
 	router := createTestRouter()
 
@@ -31,6 +74,33 @@ func TestGrantGroupRole(t *testing.T) {
 	assert.Equal(t, len(roles), 1)
 }
 
+func TestGroupRoleRoundTrip(t *testing.T) {
+	router := createTestRouter()
+
+	NewResponseTester("PUT", "http://example.com/v2/groups/testgroup").WithBody(rest.Group{Name: "testgroup"}).WithStatus(http.StatusOK).Test(t, router)
+	NewResponseTester("PUT", "http://example.com/v2/roles/testrole").WithStatus(http.StatusOK).Test(t, router)
+
+	// Unknown groups and roles are a 404 for every method
+	NewResponseTester("GET", "http://example.com/v2/groups/nogroup/roles").WithStatus(http.StatusNotFound).Test(t, router)
+	NewResponseTester("PUT", "http://example.com/v2/groups/nogroup/roles/testrole").WithStatus(http.StatusNotFound).Test(t, router)
+	NewResponseTester("PUT", "http://example.com/v2/groups/testgroup/roles/norole").WithStatus(http.StatusNotFound).Test(t, router)
+	NewResponseTester("DELETE", "http://example.com/v2/groups/nogroup/roles/testrole").WithStatus(http.StatusNotFound).Test(t, router)
+	NewResponseTester("DELETE", "http://example.com/v2/groups/testgroup/roles/norole").WithStatus(http.StatusNotFound).Test(t, router)
+
+	// Grant, list, revoke, list
+	roles := []rest.Role{}
+	NewResponseTester("PUT", "http://example.com/v2/groups/testgroup/roles/testrole").WithStatus(http.StatusOK).Test(t, router)
+	NewResponseTester("GET", "http://example.com/v2/groups/testgroup/roles").WithOutput(&roles).WithStatus(http.StatusOK).Test(t, router)
+	if assert.Len(t, roles, 1) {
+		assert.Equal(t, "testrole", roles[0].Name)
+	}
+
+	roles = []rest.Role{}
+	NewResponseTester("DELETE", "http://example.com/v2/groups/testgroup/roles/testrole").WithStatus(http.StatusOK).Test(t, router)
+	NewResponseTester("GET", "http://example.com/v2/groups/testgroup/roles").WithOutput(&roles).WithStatus(http.StatusOK).Test(t, router)
+	assert.Empty(t, roles)
+}
+
 func TestGrantGroupRoleInvalidGroup(t *testing.T) {
 	router := createTestRouter()
 
@@ -129,3 +199,104 @@ func TestRevokeGroupRoleInvalidRole(t *testing.T) {
 	NewResponseTester("GET", "http://example.com/v2/groups/testgroup/roles").WithOutput(&roles).WithStatus(http.StatusOK).Test(t, router)
 	assert.Equal(t, len(roles), 1)
 }
+
+func TestGetGroupRolesIncludesPermissions(t *testing.T) {
+	router := createTestRouter()
+
+	// Create group and role, and grant role to group
+	NewResponseTester("PUT", "http://example.com/v2/groups/testgroup").WithBody(rest.Group{Name: "testgroup"}).WithStatus(http.StatusOK).Test(t, router)
+	NewResponseTester("PUT", "http://example.com/v2/roles/testrole").WithStatus(http.StatusOK).Test(t, router)
+	NewResponseTester("PUT", "http://example.com/v2/groups/testgroup/roles/testrole").WithStatus(http.StatusOK).Test(t, router)
+
+	// Grant permission to role after it's been granted to the group
+	NewResponseTester("PUT", "http://example.com/v2/roles/testrole/bundles/testbundle/permissions/testpermission").WithStatus(http.StatusOK).Test(t, router)
+
+	roles := []rest.Role{}
+	NewResponseTester("GET", "http://example.com/v2/groups/testgroup/roles").WithOutput(&roles).WithStatus(http.StatusOK).Test(t, router)
+	if assert.Len(t, roles, 1) {
+		assert.Equal(t, "testrole", roles[0].Name)
+		assert.Equal(t, rest.RolePermissionList{{BundleName: "testbundle", Permission: "testpermission"}}, roles[0].Permissions)
+	}
+}
+
+func TestGetGroupsPaged(t *testing.T) {
+	router := createTestRouter()
+
+	// The admin group already exists, for 3 groups in all
+	for _, name := range []string{"group1", "group0"} {
+		NewResponseTester("PUT", "http://example.com/v2/groups/"+name).WithBody(rest.Group{Name: name}).WithStatus(http.StatusOK).Test(t, router)
+	}
+
+	var headers http.Header
+	groups := []rest.Group{}
+
+	NewResponseTester("GET", "http://example.com/v2/groups?offset=1&limit=1").WithOutput(&groups).WithHeaders(&headers).WithStatus(http.StatusOK).Test(t, router)
+	if assert.Len(t, groups, 1) {
+		assert.Equal(t, "group0", groups[0].Name)
+	}
+	assert.Equal(t, "3", headers.Get("X-Total-Count"))
+	assert.Equal(t, `</v2/groups?limit=1&offset=2>; rel="next", </v2/groups?limit=1&offset=0>; rel="prev"`, headers.Get("Link"))
+
+	NewResponseTester("GET", "http://example.com/v2/groups?limit=many").WithStatus(http.StatusBadRequest).Test(t, router)
+	NewResponseTester("GET", "http://example.com/v2/groups?offset=-1").WithStatus(http.StatusBadRequest).Test(t, router)
+}
+
+func TestPutGroupBodyName(t *testing.T) {
+	router := createTestRouter()
+
+	// A body name that matches the URL is fine
+	NewResponseTester("PUT", "http://example.com/v2/groups/testgroup").WithBody(rest.Group{Name: "testgroup"}).WithStatus(http.StatusOK).Test(t, router)
+
+	// A body without a name takes the one in the URL
+	NewResponseTester("PUT", "http://example.com/v2/groups/othergroup").WithBody(rest.Group{}).WithStatus(http.StatusOK).Test(t, router)
+	group := rest.Group{}
+	NewResponseTester("GET", "http://example.com/v2/groups/othergroup").WithOutput(&group).WithStatus(http.StatusOK).Test(t, router)
+	assert.Equal(t, "othergroup", group.Name)
+
+	// A body name that doesn't match the URL is a 400, and nothing is saved
+	NewResponseTester("PUT", "http://example.com/v2/groups/newgroup").WithBody(rest.Group{Name: "wronggroup"}).WithStatus(http.StatusBadRequest).Test(t, router)
+	NewResponseTester("GET", "http://example.com/v2/groups/newgroup").WithStatus(http.StatusNotFound).Test(t, router)
+	NewResponseTester("GET", "http://example.com/v2/groups/wronggroup").WithStatus(http.StatusNotFound).Test(t, router)
+}
+
+func TestGetGroupsSummary(t *testing.T) {
+	router := createTestRouter()
+
+	NewResponseTester("PUT", "http://example.com/v2/groups/testgroup").WithBody(rest.Group{Name: "testgroup"}).WithStatus(http.StatusOK).Test(t, router)
+	NewResponseTester("PUT", "http://example.com/v2/roles/testrole").WithStatus(http.StatusOK).Test(t, router)
+	NewResponseTester("PUT", "http://example.com/v2/groups/testgroup/roles/testrole").WithStatus(http.StatusOK).Test(t, router)
+	for _, name := range []string{"user0", "user1", "user2"} {
+		NewResponseTester("PUT", "http://example.com/v2/users/"+name).WithBody(rest.User{Username: name}).WithStatus(http.StatusOK).Test(t, router)
+		NewResponseTester("PUT", "http://example.com/v2/groups/testgroup/members/"+name).WithStatus(http.StatusOK).Test(t, router)
+	}
+
+	// The admin group already exists, with the admin user and role
+	summaries := []rest.GroupSummary{}
+	NewResponseTester("GET", "http://example.com/v2/groups?summary=true").WithOutput(&summaries).WithStatus(http.StatusOK).Test(t, router)
+	assert.Equal(t, []rest.GroupSummary{
+		{Name: "admin", MemberCount: 1, RoleCount: 1},
+		{Name: "testgroup", MemberCount: 3, RoleCount: 1},
+	}, summaries)
+
+	NewResponseTester("GET", "http://example.com/v2/groups?summary=maybe").WithStatus(http.StatusBadRequest).Test(t, router)
+}
+
+func TestGetGroupsETag(t *testing.T) {
+	router := createTestRouter()
+
+	var headers http.Header
+	NewResponseTester("GET", "http://example.com/v2/groups").WithHeaders(&headers).WithStatus(http.StatusOK).Test(t, router)
+	etag := headers.Get("ETag")
+	assert.NotEmpty(t, etag)
+
+	// An unchanged list isn't sent again
+	NewResponseTester("GET", "http://example.com/v2/groups").WithRequestHeader("If-None-Match", etag).WithStatus(http.StatusNotModified).Test(t, router)
+
+	// A changed list is sent again, with a new ETag
+	NewResponseTester("PUT", "http://example.com/v2/groups/group0").WithBody(rest.Group{Name: "group0"}).WithStatus(http.StatusOK).Test(t, router)
+
+	groups := []rest.Group{}
+	NewResponseTester("GET", "http://example.com/v2/groups").WithRequestHeader("If-None-Match", etag).WithOutput(&groups).WithHeaders(&headers).WithStatus(http.StatusOK).Test(t, router)
+	assert.Len(t, groups, 2)
+	assert.NotEqual(t, etag, headers.Get("ETag"))
+}
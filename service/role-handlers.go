@@ -165,6 +165,8 @@ func handleGrantRolePermission(w http.ResponseWriter, r *http.Request) {
 }
 
 // handleRevokeRolePermission handles "DELETE /v2/roles/{rolename}/bundles/{bundlename}/permissions/{permissionname}"
+// The response body is the number of permissions revoked: 0 if the role
+// hadn't been granted the permission.
 func handleRevokeRolePermission(w http.ResponseWriter, r *http.Request) {
 	params := mux.Vars(r)
 	rolename := params["rolename"]
@@ -181,11 +183,13 @@ func handleRevokeRolePermission(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = dataAccessLayer.RolePermissionDelete(r.Context(), rolename, bundlename, permissionname)
+	count, err := dataAccessLayer.RolePermissionDelete(r.Context(), rolename, bundlename, permissionname)
 	if err != nil {
 		respondAndLogError(r.Context(), w, err)
 		return
 	}
+
+	json.NewEncoder(w).Encode(count)
 }
 
 // handlePutRole handles "PUT /v2/roles/{rolename}"
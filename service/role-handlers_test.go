@@ -3,6 +3,9 @@ package service
 import (
 	"net/http"
 	"testing"
+
+	"github.com/getgort/gort/data/rest"
+	"github.com/stretchr/testify/assert"
 )
 
 func TestCreateRole(t *testing.T) {
@@ -51,6 +54,35 @@ func TestGrantRolePermissionInvalidRole(t *testing.T) {
 	NewResponseTester("PUT", "http://example.com/v2/roles/testrole2/bundles/testbundle/permissions/testpermission").WithStatus(http.StatusNotFound).Test(t, router)
 }
 
+func TestGetRolePermissions(t *testing.T) {
+	router := createTestRouter()
+
+	// Role doesn't exist
+	NewResponseTester("GET", "http://example.com/v2/roles/testrole/permissions").WithStatus(http.StatusNotFound).Test(t, router)
+
+	// Create role
+	NewResponseTester("PUT", "http://example.com/v2/roles/testrole").WithStatus(http.StatusOK).Test(t, router)
+
+	// No permissions yet
+	rpl := rest.RolePermissionList{}
+	NewResponseTester("GET", "http://example.com/v2/roles/testrole/permissions").WithOutput(&rpl).WithStatus(http.StatusOK).Test(t, router)
+	assert.Empty(t, rpl)
+
+	// Grant permission
+	NewResponseTester("PUT", "http://example.com/v2/roles/testrole/bundles/testbundle/permissions/testpermission").WithStatus(http.StatusOK).Test(t, router)
+
+	rpl = rest.RolePermissionList{}
+	NewResponseTester("GET", "http://example.com/v2/roles/testrole/permissions").WithOutput(&rpl).WithStatus(http.StatusOK).Test(t, router)
+	assert.Equal(t, rest.RolePermissionList{{BundleName: "testbundle", Permission: "testpermission"}}, rpl)
+}
+
+func TestPutRoleInvalidName(t *testing.T) {
+	router := createTestRouter()
+
+	NewResponseTester("PUT", "http://example.com/v2/roles/bad!name").WithStatus(http.StatusBadRequest).Test(t, router)
+	NewResponseTester("GET", "http://example.com/v2/roles/bad!name").WithStatus(http.StatusNotFound).Test(t, router)
+}
+
 func TestRevokeRolePermission(t *testing.T) {
 	router := createTestRouter()
 
@@ -61,7 +93,9 @@ func TestRevokeRolePermission(t *testing.T) {
 	NewResponseTester("PUT", "http://example.com/v2/roles/testrole/bundles/testbundle/permissions/testpermission").WithStatus(http.StatusOK).Test(t, router)
 
 	// Revoke permission
-	NewResponseTester("DELETE", "http://example.com/v2/roles/testrole/bundles/testbundle/permissions/testpermission").WithStatus(http.StatusOK).Test(t, router)
+	var count int
+	NewResponseTester("DELETE", "http://example.com/v2/roles/testrole/bundles/testbundle/permissions/testpermission").WithOutput(&count).WithStatus(http.StatusOK).Test(t, router)
+	assert.Equal(t, 1, count)
 }
 
 func TestRevokeRolePermissionInvalidRole(t *testing.T) {
@@ -87,6 +121,9 @@ func TestRevokeRolePermissionInvalidPermission(t *testing.T) {
 	NewResponseTester("PUT", "http://example.com/v2/roles/testrole/bundles/testbundle/permissions/testpermission").WithStatus(http.StatusOK).Test(t, router)
 
 	// Revoke permission that doesn't exist, will be ignored and return 200
+	// with a count of 0.
 	// NOTE: Should we check for this? There may be implications with versioning.
-	NewResponseTester("DELETE", "http://example.com/v2/roles/testrole/bundles/testbundle/permissions/testpermission2").WithStatus(http.StatusOK).Test(t, router)
+	count := -1
+	NewResponseTester("DELETE", "http://example.com/v2/roles/testrole/bundles/testbundle/permissions/testpermission2").WithOutput(&count).WithStatus(http.StatusOK).Test(t, router)
+	assert.Equal(t, 0, count)
 }
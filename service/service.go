@@ -17,11 +17,16 @@
 package service
 
 import (
+	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
@@ -31,6 +36,7 @@ import (
 
 	"github.com/getgort/gort/auth"
 	"github.com/getgort/gort/bundles"
+	"github.com/getgort/gort/config"
 	"github.com/getgort/gort/data"
 	"github.com/getgort/gort/data/rest"
 	"github.com/getgort/gort/dataaccess"
@@ -39,6 +45,25 @@ import (
 	"github.com/getgort/gort/rules"
 	"github.com/getgort/gort/telemetry"
 	"github.com/getgort/gort/types"
+	"github.com/getgort/gort/version"
+)
+
+const (
+	// DefaultMaxRequestBodySize is the maximum size, in bytes, of a request
+	// body if the max_request_body_size setting isn't set.
+	DefaultMaxRequestBodySize = 1 << 20
+
+	// MaxImportRequestBodySize is the maximum size, in bytes, of the body of
+	// a "POST /v2/import" request, which carries an entire export and so is
+	// allowed to be larger than other requests.
+	MaxImportRequestBodySize = 64 << 20
+
+	// RequestIDHeader is the header that carries a request's correlation ID.
+	RequestIDHeader = "X-Request-ID"
+
+	// maxRequestIDLength is the maximum length of a request ID supplied by
+	// a client. Longer IDs are replaced.
+	maxRequestIDLength = 128
 )
 
 var (
@@ -48,7 +73,17 @@ var (
 
 	ErrNoSuchCommand = errors.New("no such command")
 
+	// ErrRequestTooLarge is returned by decodeRequestBody when the request
+	// body exceeds the limit set by the maximum body size middleware.
+	ErrRequestTooLarge = errors.New("request body too large")
+
 	ErrGortBundleDisabled = errors.New("gort bundle disabled")
+
+	// maxRequestBodySizesByPath holds the body size limits of the paths
+	// that accept larger request bodies than the configured maximum.
+	maxRequestBodySizesByPath = map[string]int64{
+		"/v2/import": MaxImportRequestBodySize,
+	}
 )
 
 // RequestEvent represents a request of a service endpoint.
@@ -57,6 +92,7 @@ type RequestEvent struct {
 	UserID    string
 	Timestamp time.Time
 	Request   string
+	RequestID string
 	Status    int
 	Size      int64
 }
@@ -128,7 +164,8 @@ func BuildRESTServer(ctx context.Context, addr string) *RESTServer {
 	requests := make(chan RequestEvent)
 
 	router := mux.NewRouter()
-	router.Use(buildLoggingMiddleware(requests), tokenObservingMiddleware)
+	maxBodySize := config.GetGortServerConfigs().MaxRequestBodySize
+	router.Use(requestIDMiddleware, buildLoggingMiddleware(requests), buildMaxBodySizeMiddleware(maxBodySize), tokenObservingMiddleware)
 
 	err = addMetricsToRouter(router)
 	if err != nil {
@@ -146,8 +183,10 @@ func BuildRESTServer(ctx context.Context, addr string) *RESTServer {
 func addAllMethodsToRouter(router *mux.Router) {
 	addHealthzMethodToRouter(router)
 	addBundleMethodsToRouter(router)
+	addExportMethodsToRouter(router)
 	addGroupMethodsToRouter(router)
 	addRoleMethodsToRouter(router)
+	addTokenMethodsToRouter(router)
 	addUserMethodsToRouter(router)
 }
 
@@ -167,6 +206,8 @@ func addHealthzMethodToRouter(router *mux.Router) {
 	router.Handle("/v2/authenticate", otelhttp.NewHandler(http.HandlerFunc(handleAuthenticate), "authenticate")).Methods("POST")
 	router.Handle("/v2/bootstrap", otelhttp.NewHandler(http.HandlerFunc(handleBootstrap), "bootstrap")).Methods("POST")
 	router.Handle("/v2/healthz", otelhttp.NewHandler(http.HandlerFunc(handleHealthz), "healthz")).Methods("GET")
+	router.Handle("/v2/readyz", otelhttp.NewHandler(http.HandlerFunc(handleReadyz), "readyz")).Methods("GET")
+	router.Handle("/v2/version", otelhttp.NewHandler(http.HandlerFunc(handleGetVersion), "version")).Methods("GET")
 }
 
 func addMetricsToRouter(router *mux.Router) error {
@@ -200,6 +241,220 @@ func bootstrapUserWithDefaults(user rest.User) (rest.User, error) {
 	return user, nil
 }
 
+// buildMaxBodySizeMiddleware returns a middleware function that limits the
+// size of request bodies to max bytes, or to DefaultMaxRequestBodySize if max
+// is 0 or less. Paths in maxRequestBodySizesByPath may instead use their own,
+// larger, limit. Requests that declare a larger Content-Length are rejected
+// with a 413 status; otherwise reading past the limit causes
+// decodeRequestBody to return ErrRequestTooLarge.
+func buildMaxBodySizeMiddleware(max int64) func(http.Handler) http.Handler {
+	if max <= 0 {
+		max = DefaultMaxRequestBodySize
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			limit := max
+			if l := maxRequestBodySizesByPath[r.URL.Path]; l > limit {
+				limit = l
+			}
+
+			if r.ContentLength > limit {
+				respondAndLogError(r.Context(), w, ErrRequestTooLarge)
+				return
+			}
+
+			r.Body = &maxBytesBody{
+				ReadCloser: http.MaxBytesReader(w, r.Body, limit),
+				max:        limit,
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// requestIDKey is the context key under which a request's ID is stored.
+type requestIDKey struct{}
+
+// RequestID returns the ID of the request that ctx belongs to, or "" if it
+// doesn't have one.
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// requestIDMiddleware gives each request an ID that can be used to
+// correlate it with the log lines that it produces. The ID supplied by the
+// client in the X-Request-ID header is used if it's valid; otherwise one is
+// generated. It's stored in the request context, where it can be retrieved
+// using RequestID, and returned in the response's X-Request-ID header.
+func requestIDMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(RequestIDHeader)
+
+		if !isValidRequestID(id) {
+			var err error
+			if id, err = data.GenerateRequestID(); err != nil {
+				respondAndLogError(r.Context(), w, err)
+				return
+			}
+		}
+
+		w.Header().Set(RequestIDHeader, id)
+		ctx := context.WithValue(r.Context(), requestIDKey{}, id)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// isValidRequestID returns true if id is non-empty, no longer than
+// maxRequestIDLength, and consists only of printable ASCII characters other
+// than space.
+func isValidRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+
+	return true
+}
+
+// maxBytesBody wraps a body limited by http.MaxBytesReader, and converts
+// the error returned when the limit is exceeded into ErrRequestTooLarge.
+type maxBytesBody struct {
+	io.ReadCloser
+	max  int64
+	read int64
+}
+
+func (b *maxBytesBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.read += int64(n)
+
+	if err != nil && err != io.EOF && b.read >= b.max {
+		err = ErrRequestTooLarge
+	}
+
+	return n, err
+}
+
+// decodeRequestBody decodes the JSON-encoded request body into v. It returns
+// ErrRequestTooLarge if the body is too large, or gerrs.ErrUnmarshal if it
+// can't otherwise be decoded.
+func decodeRequestBody(r *http.Request, v interface{}) error {
+	err := json.NewDecoder(r.Body).Decode(v)
+
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, ErrRequestTooLarge):
+		return ErrRequestTooLarge
+	default:
+		return gerrs.Wrap(gerrs.ErrUnmarshal, err)
+	}
+}
+
+// parsePageQuery extracts the "offset" and "limit" query parameters used to
+// page through a list. Both must be non-negative integers if present; absent
+// values are returned as 0, which for limit means that there's no limit.
+func parsePageQuery(r *http.Request) (offset, limit int, err error) {
+	query := r.URL.Query()
+
+	if s := query.Get("offset"); s != "" {
+		if offset, err = strconv.Atoi(s); err != nil || offset < 0 {
+			return 0, 0, fmt.Errorf("invalid offset: %q", s)
+		}
+	}
+
+	if s := query.Get("limit"); s != "" {
+		if limit, err = strconv.Atoi(s); err != nil || limit < 0 {
+			return 0, 0, fmt.Errorf("invalid limit: %q", s)
+		}
+	}
+
+	return offset, limit, nil
+}
+
+// setPageHeaders sets the X-Total-Count header to the total number of items
+// in a paged list and, if the list is limited, a Link header with the URLs
+// of the next and previous pages, where they exist.
+func setPageHeaders(w http.ResponseWriter, r *http.Request, offset, limit, total int) {
+	w.Header().Set("X-Total-Count", strconv.Itoa(total))
+
+	if limit <= 0 {
+		return
+	}
+
+	pageURL := func(offset int) string {
+		u := *r.URL
+		q := u.Query()
+		q.Set("offset", strconv.Itoa(offset))
+		q.Set("limit", strconv.Itoa(limit))
+		u.RawQuery = q.Encode()
+		return u.RequestURI()
+	}
+
+	links := []string{}
+
+	if offset+limit < total {
+		links = append(links, fmt.Sprintf(`<%s>; rel="next"`, pageURL(offset+limit)))
+	}
+
+	if offset > 0 {
+		prev := offset - limit
+		if prev < 0 {
+			prev = 0
+		}
+		links = append(links, fmt.Sprintf(`<%s>; rel="prev"`, pageURL(prev)))
+	}
+
+	if len(links) > 0 {
+		w.Header().Set("Link", strings.Join(links, ", "))
+	}
+}
+
+// writeJSONWithETag writes v, encoded as JSON, along with a strong ETag
+// computed over the encoded body. If the request's If-None-Match header
+// matches the ETag, a 304 Not Modified status is written instead of the
+// body.
+func writeJSONWithETag(w http.ResponseWriter, r *http.Request, v interface{}) {
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(v); err != nil {
+		respondAndLogError(r.Context(), w, err)
+		return
+	}
+
+	sum := sha256.Sum256(buf.Bytes())
+	etag := `"` + hex.EncodeToString(sum[:]) + `"`
+	w.Header().Set("ETag", etag)
+
+	if etagMatches(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	w.Write(buf.Bytes())
+}
+
+// etagMatches returns true if the If-None-Match header value ifNoneMatch
+// matches etag. Per RFC 7232 this uses the weak comparison, so a "W/" prefix
+// on a listed tag is ignored.
+func etagMatches(ifNoneMatch, etag string) bool {
+	for _, tag := range strings.Split(ifNoneMatch, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" || strings.TrimPrefix(tag, "W/") == etag {
+			return true
+		}
+	}
+
+	return false
+}
+
 func buildLoggingMiddleware(logsous chan RequestEvent) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -227,6 +482,7 @@ func buildLoggingMiddleware(logsous chan RequestEvent) func(http.Handler) http.H
 				UserID:    userID,
 				Timestamp: time.Now(),
 				Request:   requestLine,
+				RequestID: RequestID(r.Context()),
 				Status:    status,
 				Size:      int64(bytelen),
 			}
@@ -241,9 +497,9 @@ func handleAuthenticate(w http.ResponseWriter, r *http.Request) {
 	// Grab the user struct from the request. If it doesn't exist, respond with
 	// a client error.
 	user := rest.User{}
-	err := json.NewDecoder(r.Body).Decode(&user)
+	err := decodeRequestBody(r, &user)
 	if err != nil {
-		respondAndLogError(r.Context(), w, gerrs.ErrUnmarshal)
+		respondAndLogError(r.Context(), w, err)
 		return
 	}
 
@@ -379,9 +635,9 @@ func handleBootstrap(w http.ResponseWriter, r *http.Request) {
 	// Grab the user struct from the request. If it doesn't exist, respond with
 	// a client error.
 	user := rest.User{}
-	err = json.NewDecoder(r.Body).Decode(&user)
+	err = decodeRequestBody(r, &user)
 	if err != nil {
-		respondAndLogError(r.Context(), w, gerrs.ErrUnmarshal)
+		respondAndLogError(r.Context(), w, err)
 		return
 	}
 
@@ -394,33 +650,50 @@ func handleBootstrap(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(user)
 }
 
-// handleHealthz handles "GET /v2/healthz"
+// handleHealthz handles "GET /v2/healthz". It's a liveness check: if the
+// service can respond at all it's alive, so it always returns 200.
 func handleHealthz(w http.ResponseWriter, r *http.Request) {
-	testUsername, _ := data.HashPassword(time.Now().Local().String())
-	testPassword, _ := data.HashPassword(testUsername)
-	testUser := rest.User{
-		Email:    "healthz@test.user",
-		FullName: "Health Check User",
-		Username: "healthz" + testUsername[:8],
-		Password: testPassword,
+	log.Trace("health check pass")
+	m := map[string]bool{"healthy": true}
+	json.NewEncoder(w).Encode(m)
+}
+
+// handleReadyz handles "GET /v2/readyz". It's a readiness check: it returns
+// 200 only if the data access layer is responding, and 503 otherwise.
+func handleReadyz(w http.ResponseWriter, r *http.Request) {
+	var err error
+
+	if dataAccessLayer == nil {
+		err = fmt.Errorf("data access layer not initialized")
+	} else {
+		err = dataAccessLayer.Ping(r.Context())
 	}
 
-	err := dataAccessLayer.UserCreate(r.Context(), testUser)
 	if err != nil {
-		log.WithError(err).Warning("health check failure")
-		http.Error(w, `{"healthy":false}`, http.StatusServiceUnavailable)
+		log.WithError(err).Warning("readiness check failure")
+		w.WriteHeader(http.StatusServiceUnavailable)
+		json.NewEncoder(w).Encode(map[string]interface{}{"ready": false, "error": err.Error()})
 		return
 	}
-	defer dataAccessLayer.UserDelete(r.Context(), testUser.Username)
 
-	log.Trace("health check pass")
-	m := map[string]bool{"healthy": true}
-	json.NewEncoder(w).Encode(m)
+	log.Trace("readiness check pass")
+	json.NewEncoder(w).Encode(map[string]bool{"ready": true})
+}
+
+// handleGetVersion handles "GET /v2/version". It returns the version of the
+// service and the details of the build it's running.
+func handleGetVersion(w http.ResponseWriter, r *http.Request) {
+	json.NewEncoder(w).Encode(rest.VersionInfo{
+		Version:   version.Version,
+		GitCommit: version.GitCommit,
+		BuildTime: version.BuildTime,
+	})
 }
 
 func respondAndLogError(ctx context.Context, w http.ResponseWriter, err error) {
 	status := http.StatusInternalServerError
 	msg := err.Error()
+	logger := log.WithField("request.id", RequestID(ctx))
 
 	switch {
 	// A required field is empty or missing
@@ -436,7 +709,16 @@ func respondAndLogError(ctx context.Context, w http.ResponseWriter, err error) {
 		fallthrough
 	case gerrs.Is(err, errs.ErrFieldRequired):
 		status = http.StatusExpectationFailed
-		log.WithError(err).WithField("status", status).Info(msg)
+		logger.WithError(err).WithField("status", status).Info(msg)
+
+	// A name contains characters that aren't allowed
+	case gerrs.Is(err, errs.ErrInvalidGroupName):
+		fallthrough
+	case gerrs.Is(err, errs.ErrInvalidRoleName):
+		fallthrough
+	case gerrs.Is(err, errs.ErrInvalidUserName):
+		status = http.StatusBadRequest
+		logger.WithError(err).WithField("status", status).Info(msg)
 
 	// Requested resource doesn't exist
 	case gerrs.Is(err, errs.ErrNoSuchBundle):
@@ -448,27 +730,31 @@ func respondAndLogError(ctx context.Context, w http.ResponseWriter, err error) {
 	case gerrs.Is(err, errs.ErrNoSuchToken):
 		fallthrough
 	case gerrs.Is(err, errs.ErrNoSuchUser):
+		fallthrough
+	case gerrs.Is(err, errs.ErrUserNotInGroup):
 		status = http.StatusNotFound
-		log.WithError(err).WithField("status", status).Info(msg)
+		logger.WithError(err).WithField("status", status).Info(msg)
 
 	// Nope
 	case gerrs.Is(err, errs.ErrAdminUndeletable):
 		status = http.StatusForbidden
-		log.WithError(err).WithField("status", status).Warn(msg)
+		logger.WithError(err).WithField("status", status).Warn(msg)
 
 	// Can't insert over something that already exists
 	case gerrs.Is(err, errs.ErrBundleExists):
 		fallthrough
 	case gerrs.Is(err, errs.ErrGroupExists):
 		fallthrough
+	case gerrs.Is(err, errs.ErrUserAlreadyInGroup):
+		fallthrough
 	case gerrs.Is(err, errs.ErrUserExists):
 		status = http.StatusConflict
-		log.WithError(err).WithField("status", status).Info(msg)
+		logger.WithError(err).WithField("status", status).Info(msg)
 
 	// Not done yet
 	case gerrs.Is(err, errs.ErrNotImplemented):
 		status = http.StatusNotImplemented
-		log.WithError(err).WithField("status", status).Info(msg)
+		logger.WithError(err).WithField("status", status).Info(msg)
 
 	// Data access errors
 	case gerrs.Is(err, errs.ErrDataAccessNotInitialized):
@@ -479,17 +765,21 @@ func respondAndLogError(ctx context.Context, w http.ResponseWriter, err error) {
 		fallthrough
 	case gerrs.Is(err, errs.ErrDataAccess):
 		status = http.StatusInternalServerError
-		log.WithError(err).WithField("status", status).Error(msg)
+		logger.WithError(err).WithField("status", status).Error(msg)
+
+	case gerrs.Is(err, ErrRequestTooLarge):
+		status = http.StatusRequestEntityTooLarge
+		logger.WithError(err).WithField("status", status).Info(msg)
 
 	// Bad context
 	case gerrs.Is(err, gerrs.ErrUnmarshal):
 		msg = "Corrupt JSON payload"
 		status = http.StatusNotAcceptable
-		log.WithError(err).WithField("status", status).Error(msg)
+		logger.WithError(err).WithField("status", status).Error(msg)
 
 	case gerrs.Is(err, ErrUnauthorized):
 		status = http.StatusUnauthorized
-		log.WithError(err).WithField("status", status).Error(msg)
+		logger.WithError(err).WithField("status", status).Error(msg)
 
 	case gerrs.Is(err, ErrGortBundleDisabled):
 		status = http.StatusUnauthorized
@@ -497,13 +787,13 @@ func respondAndLogError(ctx context.Context, w http.ResponseWriter, err error) {
 			err = e.Err
 		}
 		telemetry.Errors().WithError(err).Commit(ctx)
-		log.WithError(err).WithField("status", status).Error(msg)
+		logger.WithError(err).WithField("status", status).Error(msg)
 
 	// Something else?
 	default:
 		telemetry.Errors().WithError(err).Commit(ctx)
 		status = http.StatusInternalServerError
-		log.WithError(err).WithField("status", status).Error("Unhandled server error")
+		logger.WithError(err).WithField("status", status).Error("Unhandled server error")
 	}
 
 	http.Error(w, msg, status)
@@ -517,6 +807,8 @@ func tokenObservingMiddleware(next http.Handler) http.Handler {
 		"/v2/bootstrap":    true,
 		"/v2/healthz":      true,
 		"/v2/metrics":      true,
+		"/v2/readyz":       true,
+		"/v2/version":      true,
 	}
 
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -609,7 +901,11 @@ func doAuthenticateUser(r *http.Request, gortCommand string, args ...string) (bo
 		return false, err
 	}
 
-	env := rules.EvaluationEnvironment{"arg": argValues}
+	env := rules.EvaluationEnvironment{
+		"arg":     argValues,
+		"bundle":  bundle.Name,
+		"command": command.Name,
+	}
 
 	return auth.EvaluateCommandEntry(perms.Strings(), ce, env)
 }
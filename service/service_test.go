@@ -21,7 +21,9 @@ import (
 	"context"
 	"encoding/json"
 	"io"
+	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
@@ -30,6 +32,7 @@ import (
 
 	"github.com/getgort/gort/data/rest"
 	"github.com/getgort/gort/dataaccess/memory"
+	"github.com/getgort/gort/version"
 )
 
 var adminToken rest.Token
@@ -39,6 +42,8 @@ var adminToken rest.Token
 type ResponseTester struct {
 	body           interface{}
 	out            interface{}
+	headers        *http.Header
+	requestHeaders http.Header
 	method         string
 	target         string
 	expectedStatus *int
@@ -74,6 +79,24 @@ func (r ResponseTester) WithOutput(out interface{}) ResponseTester {
 	return r
 }
 
+// WithHeaders requests the response headers. The provided pointer will be
+// populated with the headers of the response.
+func (r ResponseTester) WithHeaders(out *http.Header) ResponseTester {
+	r.headers = out
+	return r
+}
+
+// WithRequestHeader adds a header to the request to be tested.
+func (r ResponseTester) WithRequestHeader(key, value string) ResponseTester {
+	h := r.requestHeaders.Clone()
+	if h == nil {
+		h = http.Header{}
+	}
+	h.Set(key, value)
+	r.requestHeaders = h
+	return r
+}
+
 // Test sends a constructed request to the provided router and performs any
 // required checks on it.
 func (r ResponseTester) Test(t *testing.T, router *mux.Router) {
@@ -90,6 +113,9 @@ func (r ResponseTester) Test(t *testing.T, router *mux.Router) {
 
 	req := httptest.NewRequest(r.method, r.target, bodyReader)
 	req.Header.Add("X-Session-Token", adminToken.Token)
+	for k, v := range r.requestHeaders {
+		req.Header[k] = v
+	}
 	w := httptest.NewRecorder()
 
 	router.ServeHTTP(w, req)
@@ -106,6 +132,10 @@ func (r ResponseTester) Test(t *testing.T, router *mux.Router) {
 		}
 	}
 
+	if r.headers != nil {
+		*r.headers = resp.Header
+	}
+
 	if r.expectedStatus != nil {
 		assert.Equal(t, *r.expectedStatus, resp.StatusCode)
 	}
@@ -132,3 +162,104 @@ func createTestRouter() *mux.Router {
 
 	return router
 }
+
+func TestHealthz(t *testing.T) {
+	router := createTestRouter()
+
+	var out map[string]bool
+	NewResponseTester("GET", "http://example.com/v2/healthz").
+		WithOutput(&out).
+		WithStatus(http.StatusOK).
+		Test(t, router)
+	assert.True(t, out["healthy"])
+}
+
+func TestRequestID(t *testing.T) {
+	var seen string
+
+	router := mux.NewRouter()
+	router.Use(requestIDMiddleware)
+	router.HandleFunc("/v2/test", func(w http.ResponseWriter, r *http.Request) {
+		seen = RequestID(r.Context())
+		respondAndLogError(r.Context(), w, ErrNoSuchCommand)
+	})
+
+	// A valid ID supplied by the client is used and returned.
+	req := httptest.NewRequest("GET", "http://example.com/v2/test", nil)
+	req.Header.Set(RequestIDHeader, "abc-123")
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusInternalServerError, rr.Code)
+	assert.Equal(t, "abc-123", seen)
+	assert.Equal(t, "abc-123", rr.Header().Get(RequestIDHeader))
+
+	// Otherwise an ID is generated.
+	for _, id := range []string{"", "has space", strings.Repeat("x", maxRequestIDLength+1)} {
+		req = httptest.NewRequest("GET", "http://example.com/v2/test", nil)
+		if id != "" {
+			req.Header.Set(RequestIDHeader, id)
+		}
+		rr = httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		assert.NotEmpty(t, seen, id)
+		assert.NotEqual(t, id, seen)
+		assert.Equal(t, seen, rr.Header().Get(RequestIDHeader), id)
+	}
+
+	assert.Equal(t, "", RequestID(context.Background()))
+}
+
+func TestGetVersion(t *testing.T) {
+	router := createTestRouter()
+
+	var out rest.VersionInfo
+	NewResponseTester("GET", "http://example.com/v2/version").
+		WithOutput(&out).
+		WithStatus(http.StatusOK).
+		Test(t, router)
+	assert.Equal(t, version.Version, out.Version)
+	assert.Equal(t, version.GitCommit, out.GitCommit)
+	assert.Equal(t, version.BuildTime, out.BuildTime)
+}
+
+func TestReadyz(t *testing.T) {
+	router := createTestRouter()
+
+	t.Run("Ready", func(t *testing.T) {
+		var out map[string]interface{}
+		NewResponseTester("GET", "http://example.com/v2/readyz").
+			WithOutput(&out).
+			WithStatus(http.StatusOK).
+			Test(t, router)
+		assert.Equal(t, true, out["ready"])
+	})
+
+	t.Run("NotReady", func(t *testing.T) {
+		// A cancelled context causes the data access layer to fail.
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		req := httptest.NewRequest("GET", "http://example.com/v2/readyz", nil).WithContext(ctx)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		resp := w.Result()
+		assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+
+		var out map[string]interface{}
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(&out))
+		assert.Equal(t, false, out["ready"])
+		assert.NotEmpty(t, out["error"])
+	})
+}
+
+func TestHealthChecksDontRequireToken(t *testing.T) {
+	handler := tokenObservingMiddleware(createTestRouter())
+
+	for _, path := range []string{"/v2/healthz", "/v2/readyz"} {
+		req := httptest.NewRequest("GET", path, nil)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Result().StatusCode, path)
+	}
+}
@@ -0,0 +1,142 @@
+/*
+ * Copyright 2021 The Gort Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+
+	"github.com/getgort/gort/data/rest"
+)
+
+// handleGetTokens handles "GET /v2/tokens". The optional "since" and "until"
+// query parameters (RFC 3339 timestamps) restrict the list to tokens valid
+// at some point in that interval; "active=true" restricts it to tokens that
+// are currently valid. Expired tokens are included unless filtered out.
+// Token values are never included in the response.
+func handleGetTokens(w http.ResponseWriter, r *http.Request) {
+	since, until, err := parseTokenListQuery(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	tokens, err := dataAccessLayer.TokenListValidBetween(r.Context(), since, until)
+	if err != nil {
+		respondAndLogError(r.Context(), w, err)
+		return
+	}
+
+	for i := range tokens {
+		tokens[i].Token = ""
+	}
+
+	json.NewEncoder(w).Encode(tokens)
+}
+
+// handleGetUserTokens handles "GET /v2/users/{username}/tokens". It returns
+// the user's currently valid tokens. As with handleGetTokens, token values
+// are never included in the response.
+func handleGetUserTokens(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+
+	exists, err := dataAccessLayer.UserExists(r.Context(), params["username"])
+	if err != nil {
+		respondAndLogError(r.Context(), w, err)
+		return
+	}
+	if !exists {
+		http.Error(w, "No such user", http.StatusNotFound)
+		return
+	}
+
+	now := time.Now().UTC()
+	tokens, err := dataAccessLayer.TokenListValidBetween(r.Context(), now, now)
+	if err != nil {
+		respondAndLogError(r.Context(), w, err)
+		return
+	}
+
+	userTokens := []rest.Token{}
+	for _, t := range tokens {
+		if t.User == params["username"] {
+			t.Token = ""
+			userTokens = append(userTokens, t)
+		}
+	}
+
+	json.NewEncoder(w).Encode(userTokens)
+}
+
+// handleDeleteUserTokens handles "DELETE /v2/users/{username}/tokens". It
+// invalidates all of the user's tokens, and responds with the number of
+// tokens invalidated.
+func handleDeleteUserTokens(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+
+	count, err := dataAccessLayer.TokenInvalidateAllForUser(r.Context(), params["username"])
+	if err != nil {
+		respondAndLogError(r.Context(), w, err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(count)
+}
+
+// parseTokenListQuery extracts the since and until bounds from a token list
+// request's query parameters. Absent bounds are returned as zero values.
+func parseTokenListQuery(r *http.Request) (since, until time.Time, err error) {
+	query := r.URL.Query()
+
+	if s := query.Get("active"); s != "" {
+		active, err := strconv.ParseBool(s)
+		if err != nil {
+			return since, until, fmt.Errorf("invalid active value: %q", s)
+		}
+
+		if active {
+			now := time.Now().UTC()
+			return now, now, nil
+		}
+	}
+
+	if s := query.Get("since"); s != "" {
+		if since, err = time.Parse(time.RFC3339, s); err != nil {
+			return since, until, fmt.Errorf("invalid since time: %q", s)
+		}
+	}
+
+	if s := query.Get("until"); s != "" {
+		if until, err = time.Parse(time.RFC3339, s); err != nil {
+			return since, until, fmt.Errorf("invalid until time: %q", s)
+		}
+	}
+
+	return since, until, nil
+}
+
+func addTokenMethodsToRouter(router *mux.Router) {
+	router.Handle("/v2/tokens", otelhttp.NewHandler(authCommand(handleGetTokens, "token", "list"), "handleGetTokens")).Methods("GET")
+	router.Handle("/v2/users/{username}/tokens", otelhttp.NewHandler(authCommand(handleGetUserTokens, "token", "list"), "handleGetUserTokens")).Methods("GET")
+	router.Handle("/v2/users/{username}/tokens", otelhttp.NewHandler(authCommand(handleDeleteUserTokens, "token", "revoke"), "handleDeleteUserTokens")).Methods("DELETE")
+}
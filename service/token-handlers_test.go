@@ -0,0 +1,125 @@
+/*
+ * Copyright 2021 The Gort Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package service
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/getgort/gort/data/rest"
+)
+
+func TestGetTokens(t *testing.T) {
+	router := createTestRouter()
+	ctx := context.Background()
+
+	err := dataAccessLayer.UserCreate(ctx, rest.User{Username: "expired", Email: "expired@testing.com"})
+	assert.NoError(t, err)
+	expired, err := dataAccessLayer.TokenGenerate(ctx, "expired", time.Millisecond)
+	assert.NoError(t, err)
+	defer dataAccessLayer.TokenInvalidate(ctx, expired.Token)
+
+	time.Sleep(10 * time.Millisecond)
+
+	users := func(tokens []rest.Token) []string {
+		u := []string{}
+		for _, tk := range tokens {
+			assert.Empty(t, tk.Token, "token values must not be exposed")
+			u = append(u, tk.User)
+		}
+		return u
+	}
+
+	// All tokens, including expired ones
+	tokens := []rest.Token{}
+	NewResponseTester("GET", "http://example.com/v2/tokens").WithOutput(&tokens).WithStatus(http.StatusOK).Test(t, router)
+	assert.ElementsMatch(t, []string{"admin", "expired"}, users(tokens))
+
+	// Active tokens only
+	tokens = []rest.Token{}
+	NewResponseTester("GET", "http://example.com/v2/tokens?active=true").WithOutput(&tokens).WithStatus(http.StatusOK).Test(t, router)
+	assert.Equal(t, []string{"admin"}, users(tokens))
+
+	// Nothing valid after the admin token expires
+	since := url.QueryEscape(adminToken.ValidUntil.Add(time.Minute).Format(time.RFC3339))
+	tokens = []rest.Token{}
+	NewResponseTester("GET", "http://example.com/v2/tokens?since="+since).WithOutput(&tokens).WithStatus(http.StatusOK).Test(t, router)
+	assert.Empty(t, tokens)
+
+	// Bad parameters
+	NewResponseTester("GET", "http://example.com/v2/tokens?since=yesterday").WithStatus(http.StatusBadRequest).Test(t, router)
+	NewResponseTester("GET", "http://example.com/v2/tokens?active=maybe").WithStatus(http.StatusBadRequest).Test(t, router)
+}
+
+func TestGetUserTokens(t *testing.T) {
+	router := createTestRouter()
+	ctx := context.Background()
+
+	err := dataAccessLayer.UserCreate(ctx, rest.User{Username: "testuser", Email: "testuser@testing.com"})
+	assert.NoError(t, err)
+
+	// Unknown user
+	NewResponseTester("GET", "http://example.com/v2/users/nobody/tokens").WithStatus(http.StatusNotFound).Test(t, router)
+
+	// No tokens
+	tokens := []rest.Token{}
+	NewResponseTester("GET", "http://example.com/v2/users/testuser/tokens").WithOutput(&tokens).WithStatus(http.StatusOK).Test(t, router)
+	assert.Empty(t, tokens)
+
+	// One active token, which isn't exposed
+	token, err := dataAccessLayer.TokenGenerate(ctx, "testuser", time.Minute)
+	assert.NoError(t, err)
+
+	tokens = []rest.Token{}
+	NewResponseTester("GET", "http://example.com/v2/users/testuser/tokens").WithOutput(&tokens).WithStatus(http.StatusOK).Test(t, router)
+	if assert.Len(t, tokens, 1) {
+		assert.Equal(t, "testuser", tokens[0].User)
+		assert.Empty(t, tokens[0].Token, "token values must not be exposed")
+		assert.True(t, token.ValidUntil.Equal(tokens[0].ValidUntil))
+	}
+}
+
+func TestDeleteUserTokens(t *testing.T) {
+	router := createTestRouter()
+	ctx := context.Background()
+
+	err := dataAccessLayer.UserCreate(ctx, rest.User{Username: "testuser", Email: "testuser@testing.com"})
+	assert.NoError(t, err)
+
+	token, err := dataAccessLayer.TokenGenerate(ctx, "testuser", time.Minute)
+	assert.NoError(t, err)
+
+	// Unknown user
+	NewResponseTester("DELETE", "http://example.com/v2/users/nobody/tokens").WithStatus(http.StatusNotFound).Test(t, router)
+
+	var count int
+	NewResponseTester("DELETE", "http://example.com/v2/users/testuser/tokens").WithOutput(&count).WithStatus(http.StatusOK).Test(t, router)
+	assert.Equal(t, 1, count)
+	assert.False(t, dataAccessLayer.TokenEvaluate(ctx, token.Token))
+
+	// Nothing left to revoke
+	NewResponseTester("DELETE", "http://example.com/v2/users/testuser/tokens").WithOutput(&count).WithStatus(http.StatusOK).Test(t, router)
+	assert.Equal(t, 0, count)
+
+	// Other users' tokens are unaffected
+	assert.True(t, dataAccessLayer.TokenEvaluate(ctx, adminToken.Token))
+}
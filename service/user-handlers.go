@@ -88,22 +88,43 @@ func handleGetUserGroups(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(groups)
 }
 
-// handleGetUsers handles "GET /v2/users"
+// handleGetUsers handles "GET /v2/users". The optional "offset" and "limit"
+// query parameters select a page of users, sorted by username; see
+// setPageHeaders for the headers used to navigate between pages. The response
+// has an ETag; see writeJSONWithETag.
 func handleGetUsers(w http.ResponseWriter, r *http.Request) {
-	users, err := dataAccessLayer.UserList(r.Context())
+	offset, limit, err := parsePageQuery(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
+	users, total, err := dataAccessLayer.UserListPage(r.Context(), offset, limit)
 	if err != nil {
 		respondAndLogError(r.Context(), w, err)
 		return
 	}
 
-	json.NewEncoder(w).Encode(users)
+	setPageHeaders(w, r, offset, limit, total)
+	writeJSONWithETag(w, r, users)
 }
 
-// handleGetUserPermissions handles "GET /v2/users/{username}/groups"
+// handleGetUserPermissions handles "GET /v2/users/{username}/permissions". It
+// returns the user's effective permissions: the permissions of every role
+// attached to every group that the user belongs to.
 func handleGetUserPermissions(w http.ResponseWriter, r *http.Request) {
 	params := mux.Vars(r)
 
+	exists, err := dataAccessLayer.UserExists(r.Context(), params["username"])
+	if err != nil {
+		respondAndLogError(r.Context(), w, err)
+		return
+	}
+	if !exists {
+		http.Error(w, "No such user", http.StatusNotFound)
+		return
+	}
+
 	perms, err := dataAccessLayer.UserPermissionList(r.Context(), params["username"])
 	if err != nil {
 		respondAndLogError(r.Context(), w, err)
@@ -113,6 +134,31 @@ func handleGetUserPermissions(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(perms)
 }
 
+// handlePostUsersBulk handles "POST /v2/users/bulk". The body is an array of
+// users, each of which is created if it doesn't exist, or updated if it
+// does. An invalid user doesn't stop the others from being saved, so the
+// response has a "207 Multi-Status" status, and its body is an array of
+// results, in the same order as the users, that reports whether each one was
+// created or updated, or why it wasn't.
+func handlePostUsersBulk(w http.ResponseWriter, r *http.Request) {
+	var users []rest.User
+
+	err := decodeRequestBody(r, &users)
+	if err != nil {
+		respondAndLogError(r.Context(), w, err)
+		return
+	}
+
+	results, err := dataAccessLayer.UserCreateBulk(r.Context(), users)
+	if err != nil {
+		respondAndLogError(r.Context(), w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusMultiStatus)
+	json.NewEncoder(w).Encode(results)
+}
+
 // handlePutUser handles "POST /v2/users/{username}"
 func handlePutUser(w http.ResponseWriter, r *http.Request) {
 	var user rest.User
@@ -120,9 +166,9 @@ func handlePutUser(w http.ResponseWriter, r *http.Request) {
 
 	params := mux.Vars(r)
 
-	err = json.NewDecoder(r.Body).Decode(&user)
+	err = decodeRequestBody(r, &user)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusNotAcceptable)
+		respondAndLogError(r.Context(), w, err)
 		return
 	}
 
@@ -146,6 +192,26 @@ func handlePutUser(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// handlePatchUser handles "PATCH /v2/users/{username}". Only the fields
+// included in the body are changed; the others are left intact.
+func handlePatchUser(w http.ResponseWriter, r *http.Request) {
+	var patch rest.UserPatch
+
+	params := mux.Vars(r)
+
+	err := decodeRequestBody(r, &patch)
+	if err != nil {
+		respondAndLogError(r.Context(), w, err)
+		return
+	}
+
+	err = dataAccessLayer.UserUpdatePartial(r.Context(), params["username"], patch)
+	if err != nil {
+		respondAndLogError(r.Context(), w, err)
+		return
+	}
+}
+
 // handlePutUserGroup handles "PUT /v2/users/{username}/groups/{username}"
 func handlePutUserGroup(w http.ResponseWriter, r *http.Request) {
 	http.Error(w, "Not Implemented", http.StatusNotImplemented)
@@ -153,8 +219,10 @@ func handlePutUserGroup(w http.ResponseWriter, r *http.Request) {
 
 func addUserMethodsToRouter(router *mux.Router) {
 	router.Handle("/v2/users", otelhttp.NewHandler(authCommand(handleGetUsers, "user", "info"), "handleGetUsers")).Methods("GET")
+	router.Handle("/v2/users/bulk", otelhttp.NewHandler(authCommand(handlePostUsersBulk, "user", "create"), "handlePostUsersBulk")).Methods("POST")
 	router.Handle("/v2/users/{username}", otelhttp.NewHandler(authCommand(handleGetUser, "user", "info"), "handleGetUser")).Methods("GET")
 	router.Handle("/v2/users/{username}", otelhttp.NewHandler(authCommand(handlePutUser, "user", "update"), "handlePutUser")).Methods("PUT")
+	router.Handle("/v2/users/{username}", otelhttp.NewHandler(authCommand(handlePatchUser, "user", "update"), "handlePatchUser")).Methods("PATCH")
 	router.Handle("/v2/users/{username}", otelhttp.NewHandler(authCommand(handleDeleteUser, "user", "delete"), "handleDeleteUser")).Methods("DELETE")
 
 	// User group membership
@@ -0,0 +1,242 @@
+/*
+ * Copyright 2021 The Gort Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package service
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/getgort/gort/data/rest"
+)
+
+func TestGetUserPermissions(t *testing.T) {
+	router := createTestRouter()
+
+	// User doesn't exist
+	NewResponseTester("GET", "http://example.com/v2/users/testuser/permissions").WithStatus(http.StatusNotFound).Test(t, router)
+
+	// Create user
+	NewResponseTester("PUT", "http://example.com/v2/users/testuser").WithBody(rest.User{Username: "testuser", Email: "testuser@testing.com"}).WithStatus(http.StatusOK).Test(t, router)
+
+	// No groups, so no permissions
+	rpl := rest.RolePermissionList{}
+	NewResponseTester("GET", "http://example.com/v2/users/testuser/permissions").WithOutput(&rpl).WithStatus(http.StatusOK).Test(t, router)
+	assert.Empty(t, rpl)
+
+	// Create a group with two roles and add the user to it
+	NewResponseTester("PUT", "http://example.com/v2/groups/testgroup").WithBody(rest.Group{Name: "testgroup"}).WithStatus(http.StatusOK).Test(t, router)
+	NewResponseTester("PUT", "http://example.com/v2/groups/testgroup/members/testuser").WithStatus(http.StatusOK).Test(t, router)
+	NewResponseTester("PUT", "http://example.com/v2/roles/role1").WithStatus(http.StatusOK).Test(t, router)
+	NewResponseTester("PUT", "http://example.com/v2/roles/role2").WithStatus(http.StatusOK).Test(t, router)
+	NewResponseTester("PUT", "http://example.com/v2/roles/role1/bundles/testbundle/permissions/perm-b").WithStatus(http.StatusOK).Test(t, router)
+	NewResponseTester("PUT", "http://example.com/v2/roles/role2/bundles/testbundle/permissions/perm-a").WithStatus(http.StatusOK).Test(t, router)
+	NewResponseTester("PUT", "http://example.com/v2/roles/role2/bundles/testbundle/permissions/perm-b").WithStatus(http.StatusOK).Test(t, router)
+	NewResponseTester("PUT", "http://example.com/v2/groups/testgroup/roles/role1").WithStatus(http.StatusOK).Test(t, router)
+	NewResponseTester("PUT", "http://example.com/v2/groups/testgroup/roles/role2").WithStatus(http.StatusOK).Test(t, router)
+
+	// The effective permissions are the union of the roles' permissions
+	rpl = rest.RolePermissionList{}
+	NewResponseTester("GET", "http://example.com/v2/users/testuser/permissions").WithOutput(&rpl).WithStatus(http.StatusOK).Test(t, router)
+	assert.Equal(t, []string{"testbundle:perm-a", "testbundle:perm-b"}, rpl.Strings())
+}
+
+func TestPutUserMaxBodySize(t *testing.T) {
+	const max = 256
+
+	router := createTestRouter()
+	router.Use(buildMaxBodySizeMiddleware(max))
+
+	oversized := `{"email":"` + strings.Repeat("x", max) + `@testing.com"}`
+
+	put := func(body io.Reader, contentLength int64) int {
+		req := httptest.NewRequest("PUT", "http://example.com/v2/users/testuser", body)
+		req.Header.Add("X-Session-Token", adminToken.Token)
+		req.ContentLength = contentLength
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Result().StatusCode
+	}
+
+	// A declared Content-Length that's too large is rejected up front
+	assert.Equal(t, http.StatusRequestEntityTooLarge, put(strings.NewReader(oversized), int64(len(oversized))))
+
+	// A body of unknown length is rejected once it's read past the limit,
+	// which is distinguished from a malformed body.
+	assert.Equal(t, http.StatusRequestEntityTooLarge, put(io.MultiReader(strings.NewReader(oversized)), -1))
+	assert.Equal(t, http.StatusNotAcceptable, put(strings.NewReader(`{"email":`), -1))
+
+	// Bodies within the limit are fine
+	assert.Equal(t, http.StatusOK, put(strings.NewReader(`{"email":"testuser@testing.com"}`), -1))
+}
+
+func TestGetUsersPaged(t *testing.T) {
+	router := createTestRouter()
+
+	// The admin user already exists, for 5 users in all
+	for _, name := range []string{"user3", "user1", "user0", "user2"} {
+		NewResponseTester("PUT", "http://example.com/v2/users/"+name).WithBody(rest.User{Username: name}).WithStatus(http.StatusOK).Test(t, router)
+	}
+
+	var headers http.Header
+	users := []rest.User{}
+
+	NewResponseTester("GET", "http://example.com/v2/users").WithOutput(&users).WithHeaders(&headers).WithStatus(http.StatusOK).Test(t, router)
+	assert.Len(t, users, 5)
+	assert.Equal(t, "5", headers.Get("X-Total-Count"))
+	assert.Empty(t, headers.Get("Link"))
+
+	NewResponseTester("GET", "http://example.com/v2/users?limit=2").WithOutput(&users).WithHeaders(&headers).WithStatus(http.StatusOK).Test(t, router)
+	if assert.Len(t, users, 2) {
+		assert.Equal(t, "admin", users[0].Username)
+		assert.Equal(t, "user0", users[1].Username)
+	}
+	assert.Equal(t, "5", headers.Get("X-Total-Count"))
+	assert.Equal(t, `</v2/users?limit=2&offset=2>; rel="next"`, headers.Get("Link"))
+
+	NewResponseTester("GET", "http://example.com/v2/users?limit=2&offset=2").WithOutput(&users).WithHeaders(&headers).WithStatus(http.StatusOK).Test(t, router)
+	if assert.Len(t, users, 2) {
+		assert.Equal(t, "user1", users[0].Username)
+		assert.Equal(t, "user2", users[1].Username)
+	}
+	assert.Equal(t, `</v2/users?limit=2&offset=4>; rel="next", </v2/users?limit=2&offset=0>; rel="prev"`, headers.Get("Link"))
+
+	NewResponseTester("GET", "http://example.com/v2/users?limit=2&offset=4").WithOutput(&users).WithHeaders(&headers).WithStatus(http.StatusOK).Test(t, router)
+	if assert.Len(t, users, 1) {
+		assert.Equal(t, "user3", users[0].Username)
+	}
+	assert.Equal(t, `</v2/users?limit=2&offset=2>; rel="prev"`, headers.Get("Link"))
+
+	NewResponseTester("GET", "http://example.com/v2/users?limit=-1").WithStatus(http.StatusBadRequest).Test(t, router)
+	NewResponseTester("GET", "http://example.com/v2/users?offset=x").WithStatus(http.StatusBadRequest).Test(t, router)
+}
+
+func TestPatchUser(t *testing.T) {
+	router := createTestRouter()
+
+	// User doesn't exist
+	NewResponseTester("PATCH", "http://example.com/v2/users/testuser").WithBody(map[string]string{"email": "x@testing.com"}).WithStatus(http.StatusNotFound).Test(t, router)
+
+	// Create user
+	NewResponseTester("PUT", "http://example.com/v2/users/testuser").WithBody(rest.User{Username: "testuser", Email: "testuser@testing.com", FullName: "Test User"}).WithStatus(http.StatusOK).Test(t, router)
+
+	// Only the full name is supplied, so the email is preserved
+	NewResponseTester("PATCH", "http://example.com/v2/users/testuser").WithBody(map[string]string{"fullname": "New Name"}).WithStatus(http.StatusOK).Test(t, router)
+
+	user := rest.User{}
+	NewResponseTester("GET", "http://example.com/v2/users/testuser").WithOutput(&user).WithStatus(http.StatusOK).Test(t, router)
+	assert.Equal(t, "testuser", user.Username)
+	assert.Equal(t, "testuser@testing.com", user.Email)
+	assert.Equal(t, "New Name", user.FullName)
+
+	// An empty body changes nothing
+	NewResponseTester("PATCH", "http://example.com/v2/users/testuser").WithBody(map[string]string{}).WithStatus(http.StatusOK).Test(t, router)
+
+	user = rest.User{}
+	NewResponseTester("GET", "http://example.com/v2/users/testuser").WithOutput(&user).WithStatus(http.StatusOK).Test(t, router)
+	assert.Equal(t, "testuser@testing.com", user.Email)
+	assert.Equal(t, "New Name", user.FullName)
+
+	// A supplied empty value clears the field
+	NewResponseTester("PATCH", "http://example.com/v2/users/testuser").WithBody(map[string]string{"email": ""}).WithStatus(http.StatusOK).Test(t, router)
+
+	user = rest.User{}
+	NewResponseTester("GET", "http://example.com/v2/users/testuser").WithOutput(&user).WithStatus(http.StatusOK).Test(t, router)
+	assert.Equal(t, "", user.Email)
+	assert.Equal(t, "New Name", user.FullName)
+}
+
+func TestPostUsersBulk(t *testing.T) {
+	router := createTestRouter()
+
+	// Create a user to be updated
+	NewResponseTester("PUT", "http://example.com/v2/users/existing").WithBody(rest.User{Username: "existing", Email: "old@testing.com"}).WithStatus(http.StatusOK).Test(t, router)
+
+	users := []rest.User{
+		{Username: "alice", Email: "alice@testing.com", FullName: "Alice"},
+		{Username: "", Email: "nobody@testing.com"},
+		{Username: "existing", Email: "new@testing.com"},
+		{Username: "not valid!"},
+		{Username: "bob", Email: "bob@testing.com"},
+	}
+
+	results := []rest.UserBulkResult{}
+	NewResponseTester("POST", "http://example.com/v2/users/bulk").WithBody(users).WithOutput(&results).WithStatus(http.StatusMultiStatus).Test(t, router)
+
+	if assert.Len(t, results, len(users)) {
+		assert.Equal(t, rest.UserBulkResult{Username: "alice", Status: rest.UserBulkCreated}, results[0])
+		assert.Equal(t, rest.UserBulkError, results[1].Status)
+		assert.NotEmpty(t, results[1].Error)
+		assert.Equal(t, rest.UserBulkResult{Username: "existing", Status: rest.UserBulkUpdated}, results[2])
+		assert.Equal(t, "not valid!", results[3].Username)
+		assert.Equal(t, rest.UserBulkError, results[3].Status)
+		assert.NotEmpty(t, results[3].Error)
+		assert.Equal(t, rest.UserBulkResult{Username: "bob", Status: rest.UserBulkCreated}, results[4])
+	}
+
+	// The invalid users don't stop the valid ones from being saved
+	user := rest.User{}
+	NewResponseTester("GET", "http://example.com/v2/users/alice").WithOutput(&user).WithStatus(http.StatusOK).Test(t, router)
+	assert.Equal(t, "alice@testing.com", user.Email)
+
+	user = rest.User{}
+	NewResponseTester("GET", "http://example.com/v2/users/existing").WithOutput(&user).WithStatus(http.StatusOK).Test(t, router)
+	assert.Equal(t, "new@testing.com", user.Email)
+
+	NewResponseTester("GET", "http://example.com/v2/users/bob").WithStatus(http.StatusOK).Test(t, router)
+
+	// An empty array has no results
+	results = nil
+	NewResponseTester("POST", "http://example.com/v2/users/bulk").WithBody([]rest.User{}).WithOutput(&results).WithStatus(http.StatusMultiStatus).Test(t, router)
+	assert.Empty(t, results)
+
+	// A body that isn't an array of users is rejected outright
+	NewResponseTester("POST", "http://example.com/v2/users/bulk").WithBody(rest.User{Username: "carol"}).WithStatus(http.StatusNotAcceptable).Test(t, router)
+	NewResponseTester("GET", "http://example.com/v2/users/carol").WithStatus(http.StatusNotFound).Test(t, router)
+}
+
+func TestGetUsersETag(t *testing.T) {
+	router := createTestRouter()
+
+	var headers http.Header
+	NewResponseTester("GET", "http://example.com/v2/users").WithHeaders(&headers).WithStatus(http.StatusOK).Test(t, router)
+	etag := headers.Get("ETag")
+	assert.NotEmpty(t, etag)
+
+	// An unchanged list isn't sent again
+	NewResponseTester("GET", "http://example.com/v2/users").WithRequestHeader("If-None-Match", etag).WithHeaders(&headers).WithStatus(http.StatusNotModified).Test(t, router)
+	assert.Equal(t, etag, headers.Get("ETag"))
+	NewResponseTester("GET", "http://example.com/v2/users").WithRequestHeader("If-None-Match", `"other", W/`+etag).WithStatus(http.StatusNotModified).Test(t, router)
+
+	// A changed list is sent again, with a new ETag
+	NewResponseTester("PUT", "http://example.com/v2/users/user0").WithBody(rest.User{Username: "user0"}).WithStatus(http.StatusOK).Test(t, router)
+
+	users := []rest.User{}
+	NewResponseTester("GET", "http://example.com/v2/users").WithRequestHeader("If-None-Match", etag).WithOutput(&users).WithHeaders(&headers).WithStatus(http.StatusOK).Test(t, router)
+	assert.Len(t, users, 2)
+	assert.NotEqual(t, etag, headers.Get("ETag"))
+
+	// Each page has its own ETag
+	etag = headers.Get("ETag")
+	NewResponseTester("GET", "http://example.com/v2/users?limit=1").WithRequestHeader("If-None-Match", etag).WithHeaders(&headers).WithStatus(http.StatusOK).Test(t, router)
+	assert.NotEqual(t, etag, headers.Get("ETag"))
+}
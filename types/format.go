@@ -0,0 +1,131 @@
+/*
+ * Copyright 2021 The Gort Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package types
+
+import (
+	"sort"
+	"strings"
+)
+
+// FormatValue renders v as text that can be used in a command line, and that
+// Tokenize and the Inferrer will read back as an equivalent value.
+//
+// Strings are written as is if they weren't quoted originally, contain only
+// letters, digits, and the characters -_./:@%+=, don't start with - or +
+// (which would be read back as an option or a toggle), and wouldn't be read
+// back as another type (such as "true" or "10"). Otherwise, including when they're
+// empty, they're quoted: with their original quote character if they have
+// one, or with single quotes, unless the string contains that character, in
+// which case the other is used. Regular expressions are
+// enclosed in slashes. Lists and maps are written as literals, such as
+// [a,'b c',1] and {k:v}, with their elements formatted recursively and map
+// entries sorted by key; a named collection with no elements is written as
+// its name. Unknown values are written as is, and other values are formatted
+// using their String method.
+func FormatValue(v Value) string {
+	switch o := v.(type) {
+	case StringValue:
+		return formatString(o.V, o.Quote)
+
+	case RegexValue:
+		return "/" + o.V + "/"
+
+	case UnknownValue:
+		return o.V
+
+	case ListValue:
+		if o.V == nil && o.Name != "" {
+			return o.Name
+		}
+
+		elements := make([]string, len(o.V))
+		for i, e := range o.V {
+			elements[i] = FormatValue(e)
+		}
+
+		return "[" + strings.Join(elements, ",") + "]"
+
+	case MapValue:
+		if o.V == nil && o.Name != "" {
+			return o.Name
+		}
+
+		keys := make([]string, 0, len(o.V))
+		for k := range o.V {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		entries := make([]string, len(keys))
+		for i, k := range keys {
+			key := formatString(k, 0)
+			if key == k && strings.ContainsRune(k, ':') {
+				key = formatString(k, '\'')
+			}
+
+			entries[i] = key + ":" + FormatValue(o.V[k])
+		}
+
+		return "{" + strings.Join(entries, ",") + "}"
+
+	case nil:
+		return ""
+
+	default:
+		return v.String()
+	}
+}
+
+// formatString quotes s if it isn't safe to write as is, as described by
+// FormatValue. If quote is non-zero, s is always quoted.
+func formatString(s string, quote rune) string {
+	if quote == 0 {
+		if s != "" && s[0] != '-' && s[0] != '+' && strings.IndexFunc(s, isUnsafeRune) < 0 && !looksTyped(s) {
+			return s
+		}
+
+		quote = '\''
+	}
+
+	if strings.ContainsRune(s, quote) {
+		if quote == '\'' {
+			quote = '"'
+		} else {
+			quote = '\''
+		}
+	}
+
+	return string(quote) + s + string(quote)
+}
+
+// looksTyped returns true if s, unquoted, would be inferred as something
+// other than a string.
+func looksTyped(s string) bool {
+	return reBool.MatchString(s) || reFloat.MatchString(s) || reInt.MatchString(s) || reRegex.MatchString(s)
+}
+
+// isUnsafeRune returns true if r can't appear in an unquoted string.
+func isUnsafeRune(r rune) bool {
+	switch {
+	case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		return false
+	case strings.ContainsRune("-_./:@%+=", r):
+		return false
+	default:
+		return true
+	}
+}
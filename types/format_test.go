@@ -0,0 +1,92 @@
+/*
+ * Copyright 2021 The Gort Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package types
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormatValue(t *testing.T) {
+	tests := []struct {
+		Value    Value
+		Expected string
+	}{
+		{BoolValue{V: true}, `true`},
+		{BoolValue{V: false}, `false`},
+		{IntValue{V: -42}, `-42`},
+		{FloatValue{V: 1.5}, `1.5`},
+		{StringValue{V: "foo"}, `foo`},
+		{StringValue{V: "gort:manage_users"}, `gort:manage_users`},
+		{StringValue{V: "foo", Quote: '"'}, `"foo"`},
+		{StringValue{V: ""}, `''`},
+		{StringValue{V: "foo bar"}, `'foo bar'`},
+		{StringValue{V: "foo\tbar"}, "'foo\tbar'"},
+		{StringValue{V: "what's this?"}, `"what's this?"`},
+		{StringValue{V: `say "hi"`, Quote: '"'}, `'say "hi"'`},
+		{StringValue{V: "$HOME"}, `'$HOME'`},
+		{StringValue{V: "a;b"}, `'a;b'`},
+		{StringValue{V: "a,b"}, `'a,b'`},
+		{StringValue{V: "true"}, `'true'`},
+		{StringValue{V: "10"}, `'10'`},
+		{StringValue{V: "1.5"}, `'1.5'`},
+		{StringValue{V: "-"}, `'-'`},
+		{StringValue{V: "--ssl"}, `'--ssl'`},
+		{StringValue{V: "-v"}, `'-v'`},
+		{StringValue{V: "+v"}, `'+v'`},
+		{StringValue{V: "a-b+c"}, `a-b+c`},
+		{RegexValue{V: "^f.*$"}, `/^f.*$/`},
+		{NullValue{}, `NULL`},
+		{UndefinedValue{}, `undefined`},
+		{UnknownValue{V: "arg"}, `arg`},
+		{ListValue{V: []Value{}}, `[]`},
+		{ListValue{V: []Value{StringValue{V: "a"}, StringValue{V: "b c"}, IntValue{V: 1}, BoolValue{V: true}}}, `[a,'b c',1,true]`},
+		{ListValue{V: []Value{IntValue{V: 1}, ListValue{V: []Value{StringValue{V: "x"}, ListValue{V: []Value{}}}}}}, `[1,[x,[]]]`},
+		{ListValue{Name: "arg"}, `arg`},
+		{MapValue{V: map[string]Value{"b": IntValue{V: 2}, "a": StringValue{V: "x y"}, "c:d": BoolValue{V: false}}}, `{a:'x y',b:2,'c:d':false}`},
+		{MapValue{Name: "option"}, `option`},
+		{ListElementValue{V: ListValue{Name: "arg"}, Index: 0}, `arg[0]`},
+		{MapElementValue{V: MapValue{Name: "option"}, Key: "env"}, `option["env"]`},
+		{nil, ``},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.Expected, FormatValue(test.Value), "%#v", test.Value)
+	}
+}
+
+func TestFormatValueRoundTrip(t *testing.T) {
+	inferrer := Inferrer{}.ComplexTypes(true)
+
+	values := []Value{
+		BoolValue{V: true},
+		IntValue{V: 7},
+		StringValue{V: "foo bar", Quote: '\''},
+		StringValue{V: "10", Quote: '\''},
+		StringValue{V: "--ssl", Quote: '\''},
+		ListValue{V: []Value{StringValue{V: "a b", Quote: '\''}, IntValue{V: 1}, BoolValue{V: false}}},
+		MapValue{V: map[string]Value{"k": IntValue{V: 1}, "s": StringValue{V: "x y", Quote: '\''}}},
+	}
+
+	for _, v := range values {
+		actual, err := inferrer.Infer(FormatValue(v))
+		if assert.NoError(t, err, FormatValue(v)) {
+			assert.Equal(t, v, actual, FormatValue(v))
+		}
+	}
+}
@@ -17,12 +17,29 @@
 package types
 
 import (
+	"errors"
 	"fmt"
 	"regexp"
 	"strconv"
 	"strings"
 )
 
+// RecommendedMaxDepth is a limit for Inferrer.MaxDepth that's far deeper
+// than any value a person is likely to type, such as a list of maps of
+// lists, but that still rejects pathological input before it's parsed.
+const RecommendedMaxDepth = 8
+
+var (
+	// ErrUnbalancedBrackets is returned by Infer when complex types are
+	// enabled and a value contains a bracket or brace that isn't matched.
+	ErrUnbalancedBrackets = errors.New("unbalanced brackets")
+
+	// ErrMaxDepthExceeded is returned by Infer when complex types are
+	// enabled and a value's brackets and braces are nested more deeply than
+	// the Inferrer's MaxDepth allows.
+	ErrMaxDepthExceeded = errors.New("maximum nesting depth exceeded")
+)
+
 var (
 	reBool                = regexp.MustCompile(`^(true|True|TRUE|false|False|FALSE)$`)
 	reFloat               = regexp.MustCompile(`^-?[0-9]*\.[0-9]+$`)
@@ -33,22 +50,37 @@ var (
 	reStringTrim          = regexp.MustCompile(`(^[“”\"\']?|[“”\"\']?$)`)
 	reCollectionReference = regexp.MustCompile(`^([A-Za-z0-9_]*)\[(.*)\]$`)
 	reList                = regexp.MustCompile(`^\[(.*)\]$`)
+	reMap                 = regexp.MustCompile(`^\{(.*)\}$`)
+
+	// elementInferrer is used to infer the types of list literal elements
+	// and collection reference parameters.
+	elementInferrer = Inferrer{}.ComplexTypes(false).RegularExpressions(true).StrictStrings(true)
 )
 
 // Inferrer is used to infer data types from string representations and
 // retrieve the coresponding appropriately-typed Value.
+//
+// An Inferrer is an immutable value: its builder methods (ComplexTypes,
+// StrictStrings, etc) return a modified copy rather than changing the
+// receiver, and Infer and InferAll only read from it. An Inferrer is
+// therefore safe for concurrent use by multiple goroutines, and may be
+// constructed once and shared, typically as a package-level variable.
 type Inferrer struct {
 	literalLists         bool
+	literalMaps          bool
 	collectionReferences bool
 	regularExpressions   bool
 	strictStrings        bool
+	stringSigil          string
+	maxDepth             int
 }
 
 // Setting ComplexTypes is a helper function that enables the Infer method to
-// identify literal lists ([ "foo", "bar" ]), collection references
-// (options["foo"]), and regular expressions (/^foo$/).
+// identify literal lists ([ "foo", "bar" ]), literal maps ({ foo: "bar" }),
+// collection references (options["foo"]), and regular expressions (/^foo$/).
 func (i Inferrer) ComplexTypes(enabled bool) Inferrer {
 	i.literalLists = enabled
+	i.literalMaps = enabled
 	i.collectionReferences = enabled
 	i.regularExpressions = enabled
 	return i
@@ -62,6 +94,14 @@ func (i Inferrer) LiteralLists(enabled bool) Inferrer {
 	return i
 }
 
+// LiteralMaps allows the Infer method to infer map literals ({foo: "bar"}).
+// Keys may be quoted or unquoted; values follow the same rules as list
+// literal elements.
+func (i Inferrer) LiteralMaps(enabled bool) Inferrer {
+	i.literalMaps = enabled
+	return i
+}
+
 // CollectionReferences allows the Infer method to identify map
 // (options["foo"]) and list references (arg[0]), returning MapElementValue and
 // ListElementValue values, respectively. An argument that isn't a string or
@@ -87,6 +127,29 @@ func (i Inferrer) StrictStrings(enabled bool) Inferrer {
 	return i
 }
 
+// StringSigil sets a prefix that forces a value to be inferred as a string.
+// This is useful when a literal looks like another type but can't be quoted.
+// For example, if the sigil is "\", the value \42 is inferred as a
+// StringValue of "42" rather than an IntValue. The sigil is removed from the
+// value, and the value's Quote is \u0000. An empty sigil (the default)
+// disables the feature.
+func (i Inferrer) StringSigil(sigil string) Inferrer {
+	i.stringSigil = sigil
+	return i
+}
+
+// MaxDepth limits how deeply the brackets and braces of a value may be
+// nested when complex types are enabled: "[1]" and "arg[0]" have a depth of
+// 1, and "[[1]]" a depth of 2. A value that exceeds the limit causes Infer to
+// return an error wrapping ErrMaxDepthExceeded before any attempt is made to
+// parse it, which protects callers from pathological input such as
+// "[[[[...]]]]". A depth of 0 or less (the default) means that there's no
+// limit.
+func (i Inferrer) MaxDepth(n int) Inferrer {
+	i.maxDepth = n
+	return i
+}
+
 // Infer accepts a string, attempts to determine its type, and based
 // on the outcome returns an appropriate Value value. if strictStrings
 // is true unquoted values that aren't obviously another type will return an
@@ -94,9 +157,10 @@ func (i Inferrer) StrictStrings(enabled bool) Inferrer {
 // of null ('\u0000). If basicsTypes is set then only the "basic" types (bool,
 // float, int, string) will be returned.
 func (i Inferrer) Infer(str string) (Value, error) {
-	subinferrer := Inferrer{}.ComplexTypes(false).RegularExpressions(true).StrictStrings(true)
-
 	switch {
+	case i.stringSigil != "" && strings.HasPrefix(str, i.stringSigil):
+		return StringValue{V: str[len(i.stringSigil):]}, nil
+
 	case reBool.MatchString(str):
 		value, err := strconv.ParseBool(str)
 		return BoolValue{V: value}, err
@@ -118,6 +182,9 @@ func (i Inferrer) Infer(str string) (Value, error) {
 		value := reStringTrim.ReplaceAllString(str, "")
 		return StringValue{V: value, Quote: rune(quoteFlavor)}, nil
 
+	case (i.literalLists || i.literalMaps || i.collectionReferences) && checkBrackets(str, i.maxDepth) != nil:
+		return NullValue{}, checkBrackets(str, i.maxDepth)
+
 	case i.literalLists && reList.MatchString(str):
 		submatches := reList.FindStringSubmatch(str)
 		if len(submatches) != 2 {
@@ -125,18 +192,41 @@ func (i Inferrer) Infer(str string) (Value, error) {
 		}
 
 		strs := splitListLiteral(submatches[1])
-		values, err := subinferrer.InferAll(strs)
+		values, err := elementInferrer.InferAll(strs)
 		if err != nil {
 			return NullValue{}, fmt.Errorf("cannot parse list: %w", err)
 		}
 
 		return ListValue{V: values}, nil
 
+	case i.literalMaps && reMap.MatchString(str):
+		submatches := reMap.FindStringSubmatch(str)
+		if len(submatches) != 2 {
+			return NullValue{}, fmt.Errorf("cannot parse map: %s", str)
+		}
+
+		values := map[string]Value{}
+		for _, entry := range splitListLiteral(submatches[1]) {
+			key, value, ok := splitMapEntry(entry)
+			if !ok {
+				return NullValue{}, fmt.Errorf("cannot parse map entry: %q", entry)
+			}
+
+			v, err := elementInferrer.Infer(value)
+			if err != nil {
+				return NullValue{}, fmt.Errorf("cannot parse map: %w", err)
+			}
+
+			values[key] = v
+		}
+
+		return MapValue{V: values}, nil
+
 	case i.collectionReferences && reCollectionReference.MatchString(str):
 		subs := reCollectionReference.FindStringSubmatch(str)
 		name, param := subs[1], subs[2]
 
-		paramValue, err := subinferrer.Infer(param)
+		paramValue, err := elementInferrer.Infer(param)
 		if err != nil {
 			return NullValue{}, err
 		}
@@ -163,8 +253,23 @@ func (i Inferrer) Infer(str string) (Value, error) {
 	}
 }
 
+// InferTyped is like Infer, but also returns the Kind of the inferred value,
+// so that a caller can check that a value has the expected type, such as an
+// int, without a type switch. A collection reference, such as arg[0], has
+// nothing to refer to until it's resolved, so its Kind is KindUndefined. If
+// the value can't be inferred, a NullValue, KindNull, and a non-nil error are
+// returned.
+func (i Inferrer) InferTyped(str string) (Value, Kind, error) {
+	value, err := i.Infer(str)
+	if err != nil {
+		return NullValue{}, KindNull, err
+	}
+
+	return value, value.Kind(), nil
+}
+
 func (i Inferrer) InferAll(strs []string) ([]Value, error) {
-	values := []Value{}
+	values := make([]Value, 0, len(strs))
 
 	for _, s := range strs {
 		v, err := i.Infer(s)
@@ -178,6 +283,89 @@ func (i Inferrer) InferAll(strs []string) ([]Value, error) {
 	return values, nil
 }
 
+// checkBrackets returns an error wrapping ErrUnbalancedBrackets if str
+// contains a square bracket or curly brace that isn't correctly matched, or
+// ErrMaxDepthExceeded if maxDepth is positive and they're nested more than
+// maxDepth deep. Brackets within quotes or regular expressions are ignored.
+func checkBrackets(str string, maxDepth int) error {
+	type open struct {
+		ch  rune
+		pos int
+	}
+
+	pairs := map[rune]rune{']': '[', '}': '{'}
+	stack := []open{}
+	var quote rune
+
+	for pos, ch := range str {
+		if quote != 0 {
+			if ch == quote {
+				quote = 0
+			}
+			continue
+		}
+
+		switch ch {
+		case '"', '\'', '/':
+			quote = ch
+		case '“':
+			quote = '”'
+		case '[', '{':
+			if maxDepth > 0 && len(stack) == maxDepth {
+				return fmt.Errorf("%w: '%c' at position %d is nested more than %d deep in %q", ErrMaxDepthExceeded, ch, pos, maxDepth, str)
+			}
+
+			stack = append(stack, open{ch, pos})
+		case ']', '}':
+			if len(stack) == 0 {
+				return fmt.Errorf("%w: unexpected '%c' at position %d in %q", ErrUnbalancedBrackets, ch, pos, str)
+			}
+
+			top := stack[len(stack)-1]
+			if top.ch != pairs[ch] {
+				return fmt.Errorf("%w: '%c' at position %d closed by '%c' at position %d in %q", ErrUnbalancedBrackets, top.ch, top.pos, ch, pos, str)
+			}
+
+			stack = stack[:len(stack)-1]
+		}
+	}
+
+	if len(stack) > 0 {
+		top := stack[len(stack)-1]
+		return fmt.Errorf("%w: '%c' at position %d is never closed in %q", ErrUnbalancedBrackets, top.ch, top.pos, str)
+	}
+
+	return nil
+}
+
+// splitMapEntry splits a map literal entry of the form "key: value" at the
+// first colon that isn't within quotes. Quotes around the key are removed.
+func splitMapEntry(entry string) (key, value string, ok bool) {
+	var quote rune
+
+	for pos, ch := range entry {
+		switch {
+		case quote != 0:
+			if ch == quote {
+				quote = 0
+			}
+		case ch == '"' || ch == '\'':
+			quote = ch
+		case ch == ':':
+			key = strings.TrimSpace(entry[:pos])
+			value = strings.TrimSpace(entry[pos+1:])
+
+			if reString.MatchString(key) {
+				key = reStringTrim.ReplaceAllString(key, "")
+			}
+
+			return key, value, key != "" && value != ""
+		}
+	}
+
+	return "", "", false
+}
+
 func splitListLiteral(str string) []string {
 	str = strings.TrimSpace(str)
 
@@ -17,6 +17,9 @@
 package types
 
 import (
+	"fmt"
+	"strings"
+	"sync"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -60,6 +63,74 @@ func TestInferrerBuilders(t *testing.T) {
 	assertStrictStrings(t, i, true)
 }
 
+func TestInferrerBuildersDontModifyReceiver(t *testing.T) {
+	base := Inferrer{}.StrictStrings(true)
+
+	_ = base.ComplexTypes(true)
+	_ = base.StrictStrings(false)
+
+	assertCanInferCollection(t, base, false)
+	assertCanInferLiteralLists(t, base, false)
+	assertCanInferRegularExpressions(t, base, false)
+	assertStrictStrings(t, base, true)
+}
+
+func TestInferrerConcurrentUse(t *testing.T) {
+	infer := Inferrer{}.ComplexTypes(true).StrictStrings(true)
+
+	inputs := map[string]Value{
+		`true`:               BoolValue{true},
+		`-1.5`:               FloatValue{-1.5},
+		`42`:                 IntValue{42},
+		`/^foo$/`:            RegexValue{`^foo$`},
+		`"quoted"`:           StringValue{"quoted", '"'},
+		`arg[1]`:             ListElementValue{V: ListValue{Name: "arg"}, Index: 1},
+		`option["env"]`:      MapElementValue{V: MapValue{Name: "option"}, Key: "env"},
+		`["a", 1, /b/]`:      ListValue{V: []Value{StringValue{"a", '"'}, IntValue{1}, RegexValue{"b"}}},
+		`unquoted`:           UnknownValue{"unquoted"},
+		`[/^[a-z]+$/, 'x']`:  ListValue{V: []Value{RegexValue{"^[a-z]+$"}, StringValue{"x", '\''}}},
+		`'single "double"'`:  StringValue{`single "double"`, '\''},
+		`"double 'single'"`:  StringValue{`double 'single'`, '"'},
+		`["one", "two", 3]`:  ListValue{V: []Value{StringValue{"one", '"'}, StringValue{"two", '"'}, IntValue{3}}},
+		`[true, false, 0.5]`: ListValue{V: []Value{BoolValue{true}, BoolValue{false}, FloatValue{0.5}}},
+	}
+
+	const goroutines = 32
+	const iterations = 100
+
+	var wg sync.WaitGroup
+	errs := make(chan error, goroutines)
+
+	for g := 0; g < goroutines; g++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for i := 0; i < iterations; i++ {
+				for input, expected := range inputs {
+					actual, err := infer.Infer(input)
+					if err != nil {
+						errs <- fmt.Errorf("%s: %w", input, err)
+						return
+					}
+					if !assert.ObjectsAreEqual(expected, actual) {
+						errs <- fmt.Errorf("%s: expected %#v, got %#v", input, expected, actual)
+						return
+					}
+				}
+			}
+		}()
+	}
+
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Error(err)
+	}
+}
+
 func assertCanInferCollection(t *testing.T, i Inferrer, enabled bool) {
 	t.Helper()
 	s := `arg[0]`
@@ -206,6 +277,168 @@ func TestInferInvalid(t *testing.T) {
 	}
 }
 
+func TestInferLiteralMaps(t *testing.T) {
+	infer := Inferrer{}.ComplexTypes(true).StrictStrings(true)
+
+	tests := map[string]Value{
+		`{}`:                  MapValue{V: map[string]Value{}},
+		`{k:v}`:               MapValue{V: map[string]Value{"k": UnknownValue{"v"}}},
+		`{a: 1, b: true}`:     MapValue{V: map[string]Value{"a": IntValue{1}, "b": BoolValue{true}}},
+		`{"a:b": "c:d"}`:      MapValue{V: map[string]Value{"a:b": StringValue{"c:d", '"'}}},
+		`{'x': /^y$/, z: .5}`: MapValue{V: map[string]Value{"x": RegexValue{"^y$"}, "z": FloatValue{0.5}}},
+	}
+
+	for input, expected := range tests {
+		actual, err := infer.Infer(input)
+		if !assert.NoError(t, err, input) {
+			continue
+		}
+
+		assert.Equal(t, expected, actual, input)
+	}
+
+	for _, input := range []string{`{a}`, `{:b}`, `{a:}`, `{a:1, b}`} {
+		actual, err := infer.Infer(input)
+		assert.Error(t, err, input)
+		assert.Equal(t, NullValue{}, actual, input)
+	}
+
+	// Map literals aren't inferred unless enabled.
+	actual, err := Inferrer{}.LiteralLists(true).Infer(`{k:v}`)
+	assert.NoError(t, err)
+	assert.Equal(t, StringValue{V: `{k:v}`}, actual)
+
+	actual, err = Inferrer{}.LiteralMaps(true).Infer(`{k:v}`)
+	assert.NoError(t, err)
+	assert.Equal(t, MapValue{V: map[string]Value{"k": UnknownValue{"v"}}}, actual)
+}
+
+func TestInferStringSigil(t *testing.T) {
+	tests := map[string]Value{
+		`\42`:      StringValue{V: "42"},
+		`\-1.5`:    StringValue{V: "-1.5"},
+		`\true`:    StringValue{V: "true"},
+		`\FALSE`:   StringValue{V: "FALSE"},
+		`\"quote"`: StringValue{V: `"quote"`},
+		`\`:        StringValue{V: ""},
+		`\\42`:     StringValue{V: `\42`},
+		`42`:       IntValue{V: 42},
+		`true`:     BoolValue{V: true},
+		`"42"`:     StringValue{V: "42", Quote: '"'},
+	}
+
+	for _, strict := range []bool{false, true} {
+		infer := Inferrer{}.ComplexTypes(true).StrictStrings(strict).StringSigil(`\`)
+
+		for input, expected := range tests {
+			actual, err := infer.Infer(input)
+			assert.NoError(t, err, input)
+			assert.Equal(t, expected, actual, "%s (strict=%v)", input, strict)
+		}
+	}
+
+	// The sigil is configurable
+	infer := Inferrer{}.StringSigil("s:")
+	actual, err := infer.Infer("s:42")
+	assert.NoError(t, err)
+	assert.Equal(t, StringValue{V: "42"}, actual)
+
+	// Without a sigil, there's no special treatment
+	actual, err = Inferrer{}.Infer(`\42`)
+	assert.NoError(t, err)
+	assert.Equal(t, StringValue{V: `\42`}, actual)
+}
+
+func TestInferUnbalancedBrackets(t *testing.T) {
+	infer := Inferrer{}.ComplexTypes(true).StrictStrings(false)
+
+	tests := map[string]string{
+		`[1,2`:       `unbalanced brackets: '[' at position 0 is never closed in "[1,2"`,
+		`{a:`:        `unbalanced brackets: '{' at position 0 is never closed in "{a:"`,
+		`]extra`:     `unbalanced brackets: unexpected ']' at position 0 in "]extra"`,
+		`[1,2}`:      `unbalanced brackets: '[' at position 0 closed by '}' at position 4 in "[1,2}"`,
+		`arg[0`:      `unbalanced brackets: '[' at position 3 is never closed in "arg[0"`,
+		`["a", [1]`:  `unbalanced brackets: '[' at position 0 is never closed in "[\"a\", [1]"`,
+		`[1]]`:       `unbalanced brackets: unexpected ']' at position 3 in "[1]]"`,
+		`option[foo`: `unbalanced brackets: '[' at position 6 is never closed in "option[foo"`,
+	}
+
+	for input, expected := range tests {
+		actual, err := infer.Infer(input)
+		assert.ErrorIs(t, err, ErrUnbalancedBrackets, input)
+		assert.EqualError(t, err, expected, input)
+		assert.Equal(t, NullValue{}, actual, input)
+
+		_, err = infer.InferAll([]string{"foo", input})
+		assert.ErrorIs(t, err, ErrUnbalancedBrackets, input)
+	}
+
+	// Brackets in quotes and regular expressions are ignored
+	balanced := []string{`["[", "}"]`, `[/^[a-z]+$/]`, `['{']`, `[]`}
+	for _, input := range balanced {
+		_, err := infer.Infer(input)
+		assert.NoError(t, err, input)
+	}
+
+	// Without complex types, brackets have no special meaning
+	v, err := Inferrer{}.Infer(`[1,2`)
+	assert.NoError(t, err)
+	assert.Equal(t, StringValue{V: `[1,2`}, v)
+}
+
+func TestInferMaxDepth(t *testing.T) {
+	infer := Inferrer{}.ComplexTypes(true).StrictStrings(true).MaxDepth(2)
+
+	// At or below the limit, values are unaffected.
+	shallow := map[string]Value{
+		`1`:              IntValue{V: 1},
+		`[1, 2]`:         ListValue{V: []Value{IntValue{V: 1}, IntValue{V: 2}}},
+		`{a: 1}`:         MapValue{V: map[string]Value{"a": IntValue{V: 1}}},
+		`arg[0]`:         ListElementValue{V: ListValue{Name: "arg"}, Index: 0},
+		`["[[[", "]]]"]`: ListValue{V: []Value{StringValue{V: "[[[", Quote: '"'}, StringValue{V: "]]]", Quote: '"'}}},
+		`[/[[[a]]]/]`:    ListValue{V: []Value{RegexValue{V: "[[[a]]]"}}},
+	}
+
+	for input, expected := range shallow {
+		actual, err := infer.Infer(input)
+		assert.NoError(t, err, input)
+		assert.Equal(t, expected, actual, input)
+	}
+
+	// Exactly at the limit.
+	_, err := infer.Infer(`[[1]]`)
+	assert.NoError(t, err)
+
+	_, err = infer.Infer(`[{a: 1}, [2]]`)
+	assert.NoError(t, err)
+
+	// One level past the limit.
+	tests := map[string]string{
+		`[[[1]]]`:   `maximum nesting depth exceeded: '[' at position 2 is nested more than 2 deep in "[[[1]]]"`,
+		`{a: [{}]}`: `maximum nesting depth exceeded: '{' at position 5 is nested more than 2 deep in "{a: [{}]}"`,
+		`x[[[0]]]`:  `maximum nesting depth exceeded: '[' at position 3 is nested more than 2 deep in "x[[[0]]]"`,
+	}
+
+	for input, expected := range tests {
+		actual, err := infer.Infer(input)
+		assert.ErrorIs(t, err, ErrMaxDepthExceeded, input)
+		assert.EqualError(t, err, expected, input)
+		assert.Equal(t, NullValue{}, actual, input)
+
+		_, err = infer.InferAll([]string{"1", input})
+		assert.ErrorIs(t, err, ErrMaxDepthExceeded, input)
+	}
+
+	// Pathological input fails fast, even if it's unbalanced.
+	deep := strings.Repeat("[", 100000)
+	_, err = infer.Infer(deep)
+	assert.ErrorIs(t, err, ErrMaxDepthExceeded)
+
+	// Without a limit, deeply nested values are permitted.
+	_, err = infer.MaxDepth(0).Infer(strings.Repeat("[", 50) + strings.Repeat("]", 50))
+	assert.NoError(t, err)
+}
+
 func TestGuessTypesValue(t *testing.T) {
 	infer := Inferrer{}.ComplexTypes(true).StrictStrings(true)
 
@@ -252,6 +485,49 @@ func TestSplitListLiteral(t *testing.T) {
 	}
 }
 
+func TestInferTyped(t *testing.T) {
+	infer := Inferrer{}.ComplexTypes(true).StrictStrings(true)
+
+	tests := []struct {
+		Input    string
+		Expected Value
+		Kind     Kind
+	}{
+		{`true`, BoolValue{V: true}, KindBool},
+		{`1.5`, FloatValue{V: 1.5}, KindFloat},
+		{`-10`, IntValue{V: -10}, KindInt},
+		{`[1, "a"]`, ListValue{V: []Value{IntValue{V: 1}, StringValue{V: "a", Quote: '"'}}}, KindList},
+		{`{a: 1}`, MapValue{V: map[string]Value{"a": IntValue{V: 1}}}, KindMap},
+		{`/^foo$/`, RegexValue{V: "^foo$"}, KindRegex},
+		{`"foo"`, StringValue{V: "foo", Quote: '"'}, KindString},
+		{`foo`, UnknownValue{V: "foo"}, KindUnknown},
+		{`arg[0]`, ListElementValue{V: ListValue{Name: "arg"}, Index: 0}, KindUndefined},
+		{`option["env"]`, MapElementValue{V: MapValue{Name: "option"}, Key: "env"}, KindUndefined},
+	}
+
+	for _, test := range tests {
+		value, kind, err := infer.InferTyped(test.Input)
+		assert.NoError(t, err, test.Input)
+		assert.Equal(t, test.Expected, value, test.Input)
+		assert.Equal(t, test.Kind, kind, test.Input)
+		assert.Equal(t, value.Kind(), kind, test.Input)
+	}
+
+	// Without strict strings, an unquoted string is a string.
+	value, kind, err := Inferrer{}.InferTyped(`foo`)
+	assert.NoError(t, err)
+	assert.Equal(t, StringValue{V: "foo"}, value)
+	assert.Equal(t, KindString, kind)
+
+	// A value that can't be inferred is null.
+	for _, input := range []string{`[1, 2`, `99999999999999999999`} {
+		value, kind, err = infer.InferTyped(input)
+		assert.Error(t, err, input)
+		assert.Equal(t, NullValue{}, value, input)
+		assert.Equal(t, KindNull, kind, input)
+	}
+}
+
 func TestInferAll(t *testing.T) {
 	infer := Inferrer{}.ComplexTypes(true).StrictStrings(true)
 
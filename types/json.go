@@ -0,0 +1,173 @@
+/*
+ * Copyright 2021 The Gort Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package types
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// MarshalJSON encodes the value as a JSON boolean.
+func (v BoolValue) MarshalJSON() ([]byte, error) {
+	return json.Marshal(v.V)
+}
+
+// MarshalJSON encodes the value as a JSON number.
+func (v FloatValue) MarshalJSON() ([]byte, error) {
+	return json.Marshal(v.V)
+}
+
+// MarshalJSON encodes the value as a JSON number.
+func (v IntValue) MarshalJSON() ([]byte, error) {
+	return json.Marshal(v.V)
+}
+
+// MarshalJSON encodes the list as a JSON array of its elements. A nil list
+// is encoded as an empty array.
+func (v ListValue) MarshalJSON() ([]byte, error) {
+	if v.V == nil {
+		return []byte("[]"), nil
+	}
+
+	return json.Marshal(v.V)
+}
+
+// MarshalJSON encodes the referenced element, or null if it's undefined.
+func (v ListElementValue) MarshalJSON() ([]byte, error) {
+	if IsUndefined(v) {
+		return []byte("null"), nil
+	}
+
+	return json.Marshal(v.V.V[v.Index])
+}
+
+// MarshalJSON encodes the map as a JSON object. A nil map is encoded as an
+// empty object.
+func (v MapValue) MarshalJSON() ([]byte, error) {
+	if v.V == nil {
+		return []byte("{}"), nil
+	}
+
+	return json.Marshal(v.V)
+}
+
+// MarshalJSON encodes the referenced element, or null if it's undefined.
+func (v MapElementValue) MarshalJSON() ([]byte, error) {
+	if IsUndefined(v) {
+		return []byte("null"), nil
+	}
+
+	return json.Marshal(v.V.V[v.Key])
+}
+
+// MarshalJSON encodes the value as a JSON null.
+func (v NullValue) MarshalJSON() ([]byte, error) {
+	return []byte("null"), nil
+}
+
+// MarshalJSON encodes the expression's pattern as a JSON string. Note that
+// it will be decoded by UnmarshalJSONValue as a StringValue.
+func (v RegexValue) MarshalJSON() ([]byte, error) {
+	return json.Marshal(v.V)
+}
+
+// MarshalJSON encodes the value as a JSON string. The Quote is not included.
+func (v StringValue) MarshalJSON() ([]byte, error) {
+	return json.Marshal(v.V)
+}
+
+// MarshalJSON encodes the value as a JSON null.
+func (v UndefinedValue) MarshalJSON() ([]byte, error) {
+	return []byte("null"), nil
+}
+
+// MarshalJSON encodes the unknown value's text as a JSON string.
+func (v UnknownValue) MarshalJSON() ([]byte, error) {
+	return json.Marshal(v.V)
+}
+
+// UnmarshalJSONValue decodes a JSON value into the corresponding Value:
+// booleans become BoolValue, strings become StringValue, numbers become
+// IntValue (if they're integers) or FloatValue, arrays become ListValue,
+// objects become MapValue, and null becomes NullValue.
+func UnmarshalJSONValue(data []byte) (Value, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	var i interface{}
+	if err := dec.Decode(&i); err != nil {
+		return NullValue{}, err
+	}
+
+	if dec.More() {
+		return NullValue{}, fmt.Errorf("unexpected data after JSON value")
+	}
+
+	return fromJSON(i)
+}
+
+// fromJSON converts a value produced by a json.Decoder with UseNumber set
+// into a Value.
+func fromJSON(i interface{}) (Value, error) {
+	switch o := i.(type) {
+	case nil:
+		return NullValue{}, nil
+
+	case bool:
+		return BoolValue{V: o}, nil
+
+	case json.Number:
+		if n, err := o.Int64(); err == nil && int64(int(n)) == n {
+			return IntValue{V: int(n)}, nil
+		}
+
+		f, err := o.Float64()
+		if err != nil {
+			return NullValue{}, err
+		}
+		return FloatValue{V: f}, nil
+
+	case string:
+		return StringValue{V: o}, nil
+
+	case []interface{}:
+		values := make([]Value, len(o))
+		for n, e := range o {
+			v, err := fromJSON(e)
+			if err != nil {
+				return NullValue{}, err
+			}
+			values[n] = v
+		}
+		return ListValue{V: values}, nil
+
+	case map[string]interface{}:
+		values := make(map[string]Value, len(o))
+		for k, e := range o {
+			v, err := fromJSON(e)
+			if err != nil {
+				return NullValue{}, err
+			}
+			values[k] = v
+		}
+		return MapValue{V: values}, nil
+
+	default:
+		return NullValue{}, fmt.Errorf("unsupported JSON type: %T", i)
+	}
+}
@@ -0,0 +1,106 @@
+/*
+ * Copyright 2021 The Gort Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package types
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValueMarshalJSON(t *testing.T) {
+	list := ListValue{Name: "arg", V: []Value{StringValue{V: "foo"}, IntValue{V: 42}}}
+	dict := MapValue{Name: "option", V: map[string]Value{"k": BoolValue{V: true}}}
+
+	tests := []struct {
+		Value    Value
+		Expected string
+	}{
+		{BoolValue{V: true}, `true`},
+		{FloatValue{V: 0.5}, `0.5`},
+		{IntValue{V: -10}, `-10`},
+		{StringValue{V: "foo bar", Quote: '"'}, `"foo bar"`},
+		{RegexValue{V: `^foo$`}, `"^foo$"`},
+		{NullValue{}, `null`},
+		{UndefinedValue{}, `null`},
+		{UnknownValue{V: "arg"}, `"arg"`},
+		{list, `["foo",42]`},
+		{ListValue{}, `[]`},
+		{ListElementValue{V: list, Index: 1}, `42`},
+		{ListElementValue{V: list, Index: 2}, `null`},
+		{dict, `{"k":true}`},
+		{MapValue{}, `{}`},
+		{MapElementValue{V: dict, Key: "k"}, `true`},
+		{MapElementValue{V: dict, Key: "x"}, `null`},
+		{ListValue{V: []Value{list, dict}}, `[["foo",42],{"k":true}]`},
+	}
+
+	for _, test := range tests {
+		b, err := json.Marshal(test.Value)
+		assert.NoError(t, err, test.Expected)
+		assert.Equal(t, test.Expected, string(b))
+	}
+}
+
+func TestUnmarshalJSONValue(t *testing.T) {
+	tests := map[string]Value{
+		`true`:          BoolValue{V: true},
+		`0.5`:           FloatValue{V: 0.5},
+		`1e3`:           FloatValue{V: 1000},
+		`-10`:           IntValue{V: -10},
+		`"foo bar"`:     StringValue{V: "foo bar"},
+		`null`:          NullValue{},
+		`[]`:            ListValue{V: []Value{}},
+		`["foo", 42]`:   ListValue{V: []Value{StringValue{V: "foo"}, IntValue{V: 42}}},
+		`{"k": true}`:   MapValue{V: map[string]Value{"k": BoolValue{V: true}}},
+		` [[1], {}] `:   ListValue{V: []Value{ListValue{V: []Value{IntValue{V: 1}}}, MapValue{V: map[string]Value{}}}},
+		`{"a": [null]}`: MapValue{V: map[string]Value{"a": ListValue{V: []Value{NullValue{}}}}},
+	}
+
+	for test, expected := range tests {
+		actual, err := UnmarshalJSONValue([]byte(test))
+		assert.NoError(t, err, test)
+		assert.Equal(t, expected, actual, test)
+	}
+
+	for _, test := range []string{``, `[1,`, `{"a":}`, `1 2`} {
+		_, err := UnmarshalJSONValue([]byte(test))
+		assert.Error(t, err, test)
+	}
+}
+
+func TestValueJSONRoundTrip(t *testing.T) {
+	values := []Value{
+		BoolValue{V: false},
+		FloatValue{V: 3.25},
+		IntValue{V: 7},
+		StringValue{V: "foo"},
+		NullValue{},
+		ListValue{V: []Value{StringValue{V: "a"}, BoolValue{V: true}}},
+		MapValue{V: map[string]Value{"n": IntValue{V: 1}}},
+	}
+
+	for _, v := range values {
+		b, err := json.Marshal(v)
+		assert.NoError(t, err)
+
+		actual, err := UnmarshalJSONValue(b)
+		assert.NoError(t, err)
+		assert.True(t, v.Equals(actual), string(b))
+	}
+}
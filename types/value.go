@@ -24,11 +24,59 @@ import (
 	"strings"
 )
 
+// Kind identifies the type of data described by a Value, so callers can
+// branch on it without type assertions.
+type Kind int
+
+const (
+	// KindUndefined is the Kind of an UndefinedValue, and of a reference to
+	// a list or map element that doesn't exist.
+	KindUndefined Kind = iota
+	KindBool
+	KindFloat
+	KindInt
+	KindList
+	KindMap
+	KindNull
+	KindRegex
+	KindString
+	KindUnknown
+)
+
+var kindNames = map[Kind]string{
+	KindUndefined: "undefined",
+	KindBool:      "bool",
+	KindFloat:     "float",
+	KindInt:       "int",
+	KindList:      "list",
+	KindMap:       "map",
+	KindNull:      "null",
+	KindRegex:     "regex",
+	KindString:    "string",
+	KindUnknown:   "unknown",
+}
+
+func (k Kind) String() string {
+	if name, ok := kindNames[k]; ok {
+		return name
+	}
+
+	return fmt.Sprintf("Kind(%d)", int(k))
+}
+
 type Value interface {
 	Equals(Value) bool
 	LessThan(Value) bool
 	Value() interface{}
 	String() string
+
+	// Kind returns the Kind of the value. A reference to a list or map
+	// element returns the Kind of the element it refers to.
+	Kind() Kind
+
+	// MarshalJSON encodes the value as its natural JSON type. See
+	// UnmarshalJSONValue for the reverse.
+	MarshalJSON() ([]byte, error)
 }
 
 type CollectionValue interface {
@@ -68,6 +116,10 @@ func (v BoolValue) Equals(q Value) bool {
 	return false
 }
 
+func (v BoolValue) Kind() Kind {
+	return KindBool
+}
+
 func (v BoolValue) LessThan(q Value) bool {
 	return false
 }
@@ -99,6 +151,10 @@ func (v FloatValue) Equals(q Value) bool {
 	return false
 }
 
+func (v FloatValue) Kind() Kind {
+	return KindFloat
+}
+
 func (v FloatValue) LessThan(q Value) bool {
 	switch o := q.(type) {
 	case FloatValue:
@@ -139,6 +195,10 @@ func (v IntValue) Equals(q Value) bool {
 	return false
 }
 
+func (v IntValue) Kind() Kind {
+	return KindInt
+}
+
 func (v IntValue) LessThan(q Value) bool {
 	switch o := q.(type) {
 	case IntValue:
@@ -204,6 +264,10 @@ func (v ListValue) String() string {
 	return v.Name
 }
 
+func (v ListValue) Kind() Kind {
+	return KindList
+}
+
 func (v ListValue) LessThan(q Value) bool {
 	return false
 }
@@ -220,12 +284,20 @@ type ListElementValue struct {
 
 func (v ListElementValue) Equals(q Value) bool {
 	if v.Index < 0 || v.Index >= len(v.V.V) {
-		return false
+		return IsUndefined(q)
 	}
 
 	return v.V.V[v.Index].Equals(q)
 }
 
+func (v ListElementValue) Kind() Kind {
+	if v.Index < 0 || v.Index >= len(v.V.V) {
+		return KindUndefined
+	}
+
+	return v.V.V[v.Index].Kind()
+}
+
 func (v ListElementValue) LessThan(q Value) bool {
 	if v.Index < 0 || v.Index >= len(v.V.V) {
 		return false
@@ -239,6 +311,10 @@ func (v ListElementValue) String() string {
 }
 
 func (v ListElementValue) Value() interface{} {
+	if v.Index < 0 || v.Index >= len(v.V.V) {
+		return nil
+	}
+
 	return v.V.V[v.Index]
 }
 
@@ -291,6 +367,10 @@ func (v MapValue) Equals(q Value) bool {
 	return true
 }
 
+func (v MapValue) Kind() Kind {
+	return KindMap
+}
+
 func (v MapValue) LessThan(q Value) bool {
 	return false
 }
@@ -321,12 +401,21 @@ func (v MapElementValue) Equals(q Value) bool {
 	}
 
 	if !exists {
-		return false
+		return IsUndefined(q)
 	}
 
 	return value.Equals(q)
 }
 
+func (v MapElementValue) Kind() Kind {
+	value, exists := v.V.V[v.Key]
+	if !exists {
+		return KindUndefined
+	}
+
+	return value.Kind()
+}
+
 func (v MapElementValue) LessThan(q Value) bool {
 	if v.Key == "" {
 		return false
@@ -360,6 +449,10 @@ func (v NullValue) Equals(q Value) bool {
 	}
 }
 
+func (v NullValue) Kind() Kind {
+	return KindNull
+}
+
 func (v NullValue) LessThan(q Value) bool {
 	return false
 }
@@ -391,10 +484,18 @@ func (v RegexValue) Equals(q Value) bool {
 	case RegexValue:
 		return v.V == o.V
 	default:
+		if IsUndefined(q) {
+			return false
+		}
+
 		return re.MatchString(fmt.Sprintf("%v", q.Value()))
 	}
 }
 
+func (v RegexValue) Kind() Kind {
+	return KindRegex
+}
+
 func (v RegexValue) LessThan(q Value) bool {
 	return false
 }
@@ -426,6 +527,10 @@ func (v StringValue) Equals(q Value) bool {
 	return false
 }
 
+func (v StringValue) Kind() Kind {
+	return KindString
+}
+
 func (v StringValue) LessThan(q Value) bool {
 	return false
 }
@@ -448,6 +553,49 @@ func (v StringValue) Value() interface{} {
 	return v.V
 }
 
+// UndefinedValue represents the absence of a value, such as an option that
+// wasn't supplied or an argument index that's out of range. An undefined
+// value is equal only to another undefined value (including a reference to a
+// collection element that doesn't exist), and is never less than or greater
+// than anything.
+type UndefinedValue struct{}
+
+func (v UndefinedValue) Equals(q Value) bool {
+	return IsUndefined(q)
+}
+
+func (v UndefinedValue) Kind() Kind {
+	return KindUndefined
+}
+
+func (v UndefinedValue) LessThan(q Value) bool {
+	return false
+}
+
+func (v UndefinedValue) String() string {
+	return "undefined"
+}
+
+func (v UndefinedValue) Value() interface{} {
+	return nil
+}
+
+// IsUndefined returns true if v is an UndefinedValue, or is a reference to a
+// list or map element that doesn't exist.
+func IsUndefined(v Value) bool {
+	switch o := v.(type) {
+	case UndefinedValue:
+		return true
+	case ListElementValue:
+		return o.Index < 0 || o.Index >= len(o.V.V)
+	case MapElementValue:
+		_, exists := o.V.V[o.Key]
+		return !exists
+	}
+
+	return false
+}
+
 // UnknownValue is returned by Parse when it can't determine a value type
 // solely by looking at it. This could indicate a function, named
 // collection(arg, option), or other named entity.
@@ -459,6 +607,10 @@ func (v UnknownValue) Equals(q Value) bool {
 	return false
 }
 
+func (v UnknownValue) Kind() Kind {
+	return KindUnknown
+}
+
 func (v UnknownValue) LessThan(q Value) bool {
 	return false
 }
@@ -189,6 +189,75 @@ func TestStringValueEquals(t *testing.T) {
 	}
 }
 
+func TestUndefinedValueEquals(t *testing.T) {
+	list := ListValue{Name: "arg", V: []Value{StringValue{V: "foo"}}}
+	dict := MapValue{Name: "option", V: map[string]Value{"foo": StringValue{V: "bar"}}}
+
+	tests := []struct {
+		ComparedTo Value
+		Expected   bool
+	}{
+		{UndefinedValue{}, true},
+		{ListElementValue{V: list, Index: 1}, true},
+		{ListElementValue{V: list, Index: -1}, true},
+		{MapElementValue{V: dict, Key: "bar"}, true},
+		{ListElementValue{V: list, Index: 0}, false},
+		{MapElementValue{V: dict, Key: "foo"}, false},
+		{BoolValue{V: false}, false},
+		{FloatValue{V: 0.0}, false},
+		{IntValue{V: 0}, false},
+		{NullValue{}, false},
+		{RegexValue{V: `^.*$`}, false},
+		{StringValue{V: ""}, false},
+		{StringValue{V: "undefined"}, false},
+	}
+
+	for _, test := range tests {
+		input := UndefinedValue{}
+
+		result := input.Equals(test.ComparedTo)
+		assert.Equal(t, test.Expected, result, msg(input, test.ComparedTo))
+
+		result = test.ComparedTo.Equals(input)
+		assert.Equal(t, test.Expected, result, msg(input, test.ComparedTo))
+
+		assert.False(t, input.LessThan(test.ComparedTo), msg(input, test.ComparedTo))
+	}
+}
+
 func msg(input interface{}, comparedTo Value) string {
 	return fmt.Sprintf("Input=%v (%T) ComparedTo=%v (%T)", input, input, comparedTo, comparedTo)
 }
+
+func TestValueKind(t *testing.T) {
+	list := ListValue{V: []Value{IntValue{V: 1}, StringValue{V: "x"}}}
+	m := MapValue{V: map[string]Value{"b": BoolValue{V: true}}}
+
+	tests := []struct {
+		Value    Value
+		Expected Kind
+	}{
+		{BoolValue{V: true}, KindBool},
+		{FloatValue{V: 1.5}, KindFloat},
+		{IntValue{V: 1}, KindInt},
+		{list, KindList},
+		{ListElementValue{V: list, Index: 0}, KindInt},
+		{ListElementValue{V: list, Index: 1}, KindString},
+		{ListElementValue{V: list, Index: 2}, KindUndefined},
+		{m, KindMap},
+		{MapElementValue{V: m, Key: "b"}, KindBool},
+		{MapElementValue{V: m, Key: "c"}, KindUndefined},
+		{NullValue{}, KindNull},
+		{RegexValue{V: ".*"}, KindRegex},
+		{StringValue{V: "x"}, KindString},
+		{UndefinedValue{}, KindUndefined},
+		{UnknownValue{V: "x"}, KindUnknown},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.Expected, test.Value.Kind(), "%#v", test.Value)
+	}
+
+	assert.Equal(t, "map", KindMap.String())
+	assert.Equal(t, "Kind(99)", Kind(99).String())
+}
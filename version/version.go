@@ -20,3 +20,13 @@ const (
 	// Version is the current version of Gort
 	Version = "0.8.0-beta.0"
 )
+
+// These are set at build time using the linker's -X flag. See the Makefile's
+// build target for an example.
+var (
+	// GitCommit is the git commit that the binary was built from.
+	GitCommit = "unknown"
+
+	// BuildTime is the time at which the binary was built.
+	BuildTime = "unknown"
+)